@@ -0,0 +1,82 @@
+package mongory
+
+import (
+	"context"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// BatchMode selects how the batch helpers (FilterFunc, MatchAll, Filter,
+// Partition, Count, First, Exists, FilterChan and MatchSharded) manage the
+// native memory documents are converted into.
+type BatchMode int
+
+const (
+	// ResetPerDocument resets the scratch pool after every document, so a
+	// batch never holds more than one document's memory. It is the default.
+	ResetPerDocument BatchMode = iota
+	// ArenaPerBatch converts a run of documents into one pool and resets it
+	// only once it reaches the limits set by SetArenaLimits, trading peak
+	// memory for fewer resets. It can win for streams of small documents;
+	// run `go test -bench BatchMode` to compare on a workload.
+	ArenaPerBatch
+)
+
+func (m BatchMode) String() string {
+	switch m {
+	case ResetPerDocument:
+		return "reset-per-document"
+	case ArenaPerBatch:
+		return "arena-per-batch"
+	default:
+		return "unknown"
+	}
+}
+
+// SetBatchMode sets the batch mode for every batch started from now on.
+func SetBatchMode(mode BatchMode) {
+	cgo.SetArenaBatching(mode == ArenaPerBatch)
+}
+
+// SetArenaLimits bounds the arenas of ArenaPerBatch: an arena is reset after
+// documents documents or once it holds bytes of native memory, whichever
+// comes first. The defaults are 256 documents and 4 MiB. Zero or less leaves
+// a limit unchanged. Memory limits set with SetMemoryLimit still apply to
+// each document on its own.
+func SetArenaLimits(documents int, bytes int64) {
+	cgo.SetArenaLimits(documents, bytes)
+}
+
+// ArenaLimits returns the limits set by SetArenaLimits.
+func ArenaLimits() (documents int, bytes int64) {
+	return cgo.ArenaLimits()
+}
+
+// batchMatch returns the match function a batch helper runs documents
+// through, sharing scratch memory across them as the BatchMode says, and the
+// function ending the batch.
+func batchMatch(m Matcher) (match func(any) (bool, error), done func()) {
+	return batchMatchContext(context.Background(), m)
+}
+
+// batchMatchContext is batchMatch matching in ctx.
+func batchMatchContext(ctx context.Context, m Matcher) (match func(any) (bool, error), done func()) {
+	inner := unwrapMatcher(m)
+	if inner == nil || inner.Matcher == nil {
+		return func(doc any) (bool, error) { return m.MatchContext(ctx, doc) }, func() {}
+	}
+	b := inner.Matcher.NewBatch()
+	return func(doc any) (bool, error) { return b.MatchContext(ctx, doc) }, b.Close
+}
+
+// unwrapMatcher returns the compiled matcher behind m, or nil if m is not
+// one of this package's.
+func unwrapMatcher(m Matcher) *matcher {
+	switch m := m.(type) {
+	case *matcher:
+		return m
+	case *cachedMatcher:
+		return m.matcher
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package mongory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func withBatchMode(t testing.TB, mode BatchMode) {
+	SetBatchMode(mode)
+	t.Cleanup(func() { SetBatchMode(ResetPerDocument) })
+}
+
+func arenaRecords(n int) []any {
+	records := make([]any, n)
+	for i := range records {
+		records[i] = map[string]any{"age": i % 100, "name": fmt.Sprint("user", i)}
+	}
+	return records
+}
+
+func TestBatchMode(t *testing.T) {
+	if ResetPerDocument.String() != "reset-per-document" || ArenaPerBatch.String() != "arena-per-batch" {
+		t.Fatalf("String = %q, %q", ResetPerDocument, ArenaPerBatch)
+	}
+	records := arenaRecords(1000)
+	condition := map[string]any{"age": map[string]any{"$gte": 50}}
+
+	peaks := map[BatchMode]int64{}
+	for _, mode := range []BatchMode{ResetPerDocument, ArenaPerBatch} {
+		withBatchMode(t, mode)
+		m, err := NewCMatcher(condition, nil)
+		if err != nil {
+			t.Fatalf("NewCMatcher failed: %v", err)
+		}
+		defer m.Close()
+		// Each document fits the limit on its own but the arena as a whole
+		// does not.
+		m.SetMemoryLimit(4096)
+		n := 0
+		err = m.FilterFunc(records, func(int, any) bool {
+			n++
+			return true
+		})
+		if err != nil || n != 500 {
+			t.Fatalf("%v: FilterFunc matched %d, %v; want 500", mode, n, err)
+		}
+		peaks[mode] = m.PeakNativeBytes()
+
+		matched, err := Filter(records, condition)
+		if err != nil || len(matched) != 500 {
+			t.Fatalf("%v: Filter = %d, %v; want 500", mode, len(matched), err)
+		}
+		count, err := Count(records, condition)
+		if err != nil || count != 500 {
+			t.Fatalf("%v: Count = %d, %v; want 500", mode, count, err)
+		}
+	}
+	if peaks[ArenaPerBatch] <= peaks[ResetPerDocument] {
+		t.Fatalf("arena peak %d, want above reset-per-document peak %d", peaks[ArenaPerBatch], peaks[ResetPerDocument])
+	}
+}
+
+func TestArenaLimits(t *testing.T) {
+	documents, bytes := ArenaLimits()
+	t.Cleanup(func() { SetArenaLimits(documents, bytes) })
+	withBatchMode(t, ArenaPerBatch)
+
+	SetArenaLimits(3, 0)
+	if got, gotBytes := ArenaLimits(); got != 3 || gotBytes != bytes {
+		t.Fatalf("ArenaLimits = %d, %d; want 3, %d", got, gotBytes, bytes)
+	}
+	m, err := NewCMatcher(map[string]any{"name": map[string]any{"$regex": "^user1"}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	records := arenaRecords(20)
+	small := m.PeakNativeBytes()
+	var hits []int
+	if err := m.FilterFunc(records, func(i int, _ any) bool {
+		hits = append(hits, i)
+		return true
+	}); err != nil {
+		t.Fatalf("FilterFunc failed: %v", err)
+	}
+	if fmt.Sprint(hits) != "[1 10 11 12 13 14 15 16 17 18 19]" {
+		t.Fatalf("hits = %v", hits)
+	}
+
+	SetArenaLimits(1000, 0)
+	if err := m.FilterFunc(arenaRecords(1000), func(int, any) bool { return true }); err != nil {
+		t.Fatalf("FilterFunc failed: %v", err)
+	}
+	if m.PeakNativeBytes() <= small {
+		t.Fatalf("PeakNativeBytes did not grow with the arena")
+	}
+
+	// A matcher closed during a batch fails the remaining documents.
+	var seen int
+	err = m.FilterFunc(records, func(int, any) bool {
+		seen++
+		m.Close()
+		return true
+	})
+	if err == nil || seen != 1 {
+		t.Fatalf("FilterFunc after Close = %d, %v; want an error after one match", seen, err)
+	}
+}
+
+func BenchmarkBatchMode(b *testing.B) {
+	records := arenaRecords(1000)
+	condition := map[string]any{"age": map[string]any{"$gte": 50}, "name": map[string]any{"$regex": "9$"}}
+	for _, mode := range []BatchMode{ResetPerDocument, ArenaPerBatch} {
+		b.Run(mode.String(), func(b *testing.B) {
+			withBatchMode(b, mode)
+			m, err := NewCMatcher(condition, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer m.Close()
+			for b.Loop() {
+				if err := m.FilterFunc(records, func(int, any) bool { return true }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMatchBatch(b *testing.B) {
+	records := arenaRecords(1000)
+	m, err := NewMatcher(map[string]any{"age": map[string]any{"$gte": 50}, "name": map[string]any{"$regex": "9$"}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer m.Close()
+	b.Run("Match", func(b *testing.B) {
+		for b.Loop() {
+			for _, record := range records {
+				if _, err := m.Match(record); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("MatchBatch", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := m.MatchBatch(records); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
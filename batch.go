@@ -0,0 +1,161 @@
+package mongory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type errorMode int
+
+const (
+	failFast errorMode = iota
+	skipAndCollect
+	callback
+)
+
+// ErrorPolicy decides what a batch operation does with a document that fails
+// to convert or match. The zero value is FailFast.
+type ErrorPolicy struct {
+	mode    errorMode
+	onError func(index int, doc any, err error) error
+}
+
+var (
+	// FailFast stops at the first failing document and returns its
+	// *DocumentError. This is the default.
+	FailFast = ErrorPolicy{mode: failFast}
+	// SkipAndCollect treats failing documents as non-matching, finishes the
+	// batch and returns every failure in a *MultiError.
+	SkipAndCollect = ErrorPolicy{mode: skipAndCollect}
+)
+
+// Callback hands each failing document to fn. Returning nil skips the
+// document as non-matching; returning an error stops the batch with it.
+func Callback(fn func(index int, doc any, err error) error) ErrorPolicy {
+	return ErrorPolicy{mode: callback, onError: fn}
+}
+
+// DocumentError is a failure of one document in a batch.
+type DocumentError struct {
+	Index int
+	Err   error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("mongory: document %d: %v", e.Index, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error { return e.Err }
+
+// MultiError lists every document that failed under SkipAndCollect, in
+// input order.
+type MultiError struct {
+	Errors []*DocumentError
+}
+
+func (e *MultiError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		parts[i] = fmt.Sprintf("document %d: %v", err.Index, err.Err)
+	}
+	return fmt.Sprintf("mongory: %d documents failed: %s", len(e.Errors), strings.Join(parts, "; "))
+}
+
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Indices returns the positions of the failed documents.
+func (e *MultiError) Indices() []int {
+	indices := make([]int, len(e.Errors))
+	for i, err := range e.Errors {
+		indices[i] = err.Index
+	}
+	return indices
+}
+
+func resolvePolicy(policy []ErrorPolicy) ErrorPolicy {
+	if len(policy) == 0 {
+		return FailFast
+	}
+	return policy[len(policy)-1]
+}
+
+// batch applies an ErrorPolicy across one pass over a record slice.
+type batch struct {
+	policy   ErrorPolicy
+	failures []*DocumentError
+}
+
+// fail records that document i failed with err and returns a non-nil error
+// when the batch must stop.
+func (b *batch) fail(i int, doc any, err error) error {
+	docErr := &DocumentError{Index: i, Err: err}
+	switch b.policy.mode {
+	case skipAndCollect:
+		b.failures = append(b.failures, docErr)
+		return nil
+	case callback:
+		if b.policy.onError == nil {
+			return nil
+		}
+		return b.policy.onError(i, doc, err)
+	default:
+		return docErr
+	}
+}
+
+// err returns the aggregated failures once the batch has completed.
+func (b *batch) err() error {
+	if len(b.failures) == 0 {
+		return nil
+	}
+	return &MultiError{Errors: b.failures}
+}
+
+// contextCheckInterval is how many records runBatchContext matches between
+// checks of its context.
+const contextCheckInterval = 256
+
+// runBatch matches every record under policy, calling fn with the result for
+// each document that did not fail. fn returning false stops the scan.
+func runBatch[T any](policy ErrorPolicy, records []T, match func(any) (bool, error), fn func(i int, doc T, matched bool) bool) error {
+	return runBatchContext(context.Background(), policy, records, match, fn)
+}
+
+// runBatchContext is runBatch checking ctx before the first record and every
+// contextCheckInterval records after it, returning ctx.Err() once it is
+// done. A match failing while ctx is done, as MatchContext does, returns
+// ctx.Err() too rather than failing the record under the policy.
+func runBatchContext[T any](ctx context.Context, policy ErrorPolicy, records []T, match func(any) (bool, error), fn func(i int, doc T, matched bool) bool) error {
+	b := batch{policy: policy}
+	done := ctx.Done()
+	for i, record := range records {
+		if done != nil && i%contextCheckInterval == 0 {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+		}
+		matched, err := match(record)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err := b.fail(i, record, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if !fn(i, record, matched) {
+			break
+		}
+	}
+	return b.err()
+}
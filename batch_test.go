@@ -0,0 +1,68 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+)
+
+var errOdd = errors.New("odd document")
+
+func failOdd(doc any) (bool, error) {
+	if doc.(int)%2 == 1 {
+		return false, errOdd
+	}
+	return true, nil
+}
+
+func TestErrorPolicy(t *testing.T) {
+	records := []int{0, 1, 2, 3, 4}
+	collect := func(policy ErrorPolicy) ([]int, error) {
+		var seen []int
+		err := runBatch(policy, records, failOdd, func(i int, _ int, _ bool) bool {
+			seen = append(seen, i)
+			return true
+		})
+		return seen, err
+	}
+
+	seen, err := collect(FailFast)
+	var docErr *DocumentError
+	if !errors.As(err, &docErr) || docErr.Index != 1 || !errors.Is(err, errOdd) {
+		t.Fatalf("FailFast error = %v", err)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("FailFast visited %v, want [0]", seen)
+	}
+
+	seen, err = collect(SkipAndCollect)
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("SkipAndCollect error = %v, want *MultiError", err)
+	}
+	if got := multi.Indices(); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("MultiError indices = %v, want [1 3]", got)
+	}
+	if !errors.Is(err, errOdd) || len(seen) != 3 {
+		t.Fatalf("SkipAndCollect visited %v, error %v", seen, err)
+	}
+
+	stop := errors.New("stop")
+	var reported []int
+	seen, err = collect(Callback(func(i int, doc any, err error) error {
+		reported = append(reported, i)
+		if i == 3 {
+			return stop
+		}
+		return nil
+	}))
+	if err != stop || len(reported) != 2 || len(seen) != 2 {
+		t.Fatalf("Callback: err %v, reported %v, seen %v", err, reported, seen)
+	}
+}
+
+func TestPartitionPolicy(t *testing.T) {
+	matched, rest, err := Partition(adultRecords(), map[string]any{"age": map[string]any{"$gte": 18}}, SkipAndCollect)
+	if err != nil || len(matched) != 3 || len(rest) != 1 {
+		t.Fatalf("Partition = %v, %v, %v", matched, rest, err)
+	}
+}
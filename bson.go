@@ -0,0 +1,210 @@
+//go:build !mongory_noformats
+
+package mongory
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// FromBSON converts a MongoDB filter built from the official driver's bson
+// types into a condition, so filters written for a collection can be reused
+// verbatim:
+//
+//	mongory.NewMatcherFromBSON(bson.D{{"age", bson.M{"$gte": 18}}})
+//
+// bson.M, bson.D, bson.E, bson.A and primitive.Regex are recognised by their
+// shape, so this package does not depend on the driver. A bson.D is read in
+// order and may not repeat a key. A primitive.Regex, or a $regex with a
+// sibling $options, becomes a *regexp.Regexp with the i, m and s options
+// applied as inline flags; other options are rejected.
+func FromBSON(filter any) (map[string]any, error) {
+	converted, err := bsonValue(reflect.ValueOf(filter))
+	if err != nil {
+		return nil, err
+	}
+	condition, ok := converted.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("mongory: BSON filter must be a document, got %T", filter)
+	}
+	return condition, nil
+}
+
+// NewMatcherFromBSON compiles a MongoDB filter built from bson types.
+func NewMatcherFromBSON(filter any) (Matcher, error) {
+	condition, err := FromBSON(filter)
+	if err != nil {
+		return nil, err
+	}
+	return NewMatcher(condition)
+}
+
+// bsonValue converts one filter value. Values that are not bson containers or
+// regexes are returned unchanged.
+func bsonValue(rv reflect.Value) (any, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return rv.Interface(), nil
+		}
+		if rv.Type() == reflect.TypeFor[*regexp.Regexp]() {
+			return rv.Interface(), nil
+		}
+		return bsonValue(rv.Elem())
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return rv.Interface(), nil
+		}
+		entries := make([]bsonEntry, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			entries = append(entries, bsonEntry{iter.Key().String(), iter.Value()})
+		}
+		return bsonDocument(entries)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return rv.Interface(), nil
+		}
+		if isBSONElement(rv.Type().Elem()) {
+			entries := make([]bsonEntry, rv.Len())
+			for i := range entries {
+				entries[i] = bsonElement(rv.Index(i))
+			}
+			return bsonDocument(entries)
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Interface(), nil
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			item, err := bsonValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Struct:
+		if isBSONElement(rv.Type()) {
+			return bsonDocument([]bsonEntry{bsonElement(rv)})
+		}
+		if pattern, options, ok := bsonRegex(rv); ok {
+			return compileBSONRegex(pattern, options)
+		}
+	}
+	return rv.Interface(), nil
+}
+
+type bsonEntry struct {
+	key   string
+	value reflect.Value
+}
+
+func bsonDocument(entries []bsonEntry) (map[string]any, error) {
+	document := make(map[string]any, len(entries))
+	options, hasOptions := "", false
+	for _, entry := range entries {
+		if _, exists := document[entry.key]; exists || (entry.key == "$options" && hasOptions) {
+			return nil, fmt.Errorf("mongory: BSON document repeats key %q", entry.key)
+		}
+		value, err := bsonValue(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		if entry.key == "$options" {
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("mongory: $options must be a string, got %T", value)
+			}
+			options, hasOptions = text, true
+			continue
+		}
+		if entry.key == "$in" || entry.key == "$nin" {
+			if items, ok := value.([]any); ok {
+				for _, item := range items {
+					if _, isRegex := item.(*regexp.Regexp); isRegex {
+						return nil, fmt.Errorf("mongory: regular expressions in %s are not supported", entry.key)
+					}
+				}
+			}
+		}
+		document[entry.key] = value
+	}
+	if !hasOptions {
+		return document, nil
+	}
+	var pattern string
+	switch re := document["$regex"].(type) {
+	case string:
+		pattern = re
+	case *regexp.Regexp:
+		pattern = re.String()
+	default:
+		return nil, fmt.Errorf("mongory: $options requires a $regex, got %T", document["$regex"])
+	}
+	re, err := compileBSONRegex(pattern, options)
+	if err != nil {
+		return nil, err
+	}
+	document["$regex"] = re
+	return document, nil
+}
+
+// isBSONElement reports whether t has the shape of bson.E:
+// struct{ Key string; Value any }.
+func isBSONElement(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return false
+	}
+	key, value := t.Field(0), t.Field(1)
+	return key.Name == "Key" && key.Type.Kind() == reflect.String &&
+		value.Name == "Value" && value.Type.Kind() == reflect.Interface
+}
+
+func bsonElement(rv reflect.Value) bsonEntry {
+	return bsonEntry{rv.Field(0).String(), rv.Field(1)}
+}
+
+// bsonRegex recognises primitive.Regex: a struct named Regex with string
+// Pattern and Options fields.
+func bsonRegex(rv reflect.Value) (pattern, options string, ok bool) {
+	t := rv.Type()
+	if t.Name() != "Regex" {
+		return "", "", false
+	}
+	p, hasPattern := t.FieldByName("Pattern")
+	o, hasOptions := t.FieldByName("Options")
+	if !hasPattern || !hasOptions || p.Type.Kind() != reflect.String || o.Type.Kind() != reflect.String {
+		return "", "", false
+	}
+	return rv.FieldByIndex(p.Index).String(), rv.FieldByIndex(o.Index).String(), true
+}
+
+func compileBSONRegex(pattern, options string) (*regexp.Regexp, error) {
+	var flags strings.Builder
+	for _, option := range options {
+		switch option {
+		case 'i', 'm', 's':
+			if !strings.ContainsRune(flags.String(), option) {
+				flags.WriteRune(option)
+			}
+		case 'u':
+			// Go regular expressions always match Unicode.
+		default:
+			return nil, fmt.Errorf("mongory: unsupported regex option %q", option)
+		}
+	}
+	if flags.Len() > 0 {
+		pattern = "(?" + flags.String() + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("mongory: invalid regex %q: %w", pattern, err)
+	}
+	return re, nil
+}
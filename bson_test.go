@@ -0,0 +1,80 @@
+//go:build !mongory_noformats
+
+package mongory
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// These mirror the driver's bson.M, bson.D, bson.E, bson.A and
+// primitive.Regex without importing it.
+type (
+	testBSONM map[string]any
+	testBSOND []testBSONE
+	testBSONE struct {
+		Key   string
+		Value any
+	}
+	testBSONA []any
+	Regex     struct{ Pattern, Options string }
+)
+
+func TestFromBSON(t *testing.T) {
+	filter := testBSOND{
+		{"age", testBSONM{"$gte": 18}},
+		{"$or", testBSONA{
+			testBSOND{{"role", "admin"}},
+			testBSONM{"tags": testBSONM{"$in": testBSONA{"a", "b"}}},
+		}},
+	}
+	got, err := FromBSON(filter)
+	if err != nil {
+		t.Fatalf("FromBSON failed: %v", err)
+	}
+	want := map[string]any{
+		"age": map[string]any{"$gte": 18},
+		"$or": []any{
+			map[string]any{"role": "admin"},
+			map[string]any{"tags": map[string]any{"$in": []any{"a", "b"}}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FromBSON = %#v, want %#v", got, want)
+	}
+
+	for _, filter := range []any{
+		testBSONM{"name": Regex{"^an", "i"}},
+		testBSOND{{"name", testBSOND{{"$options", "i"}, {"$regex", "^an"}}}},
+		testBSONE{"name", testBSONM{"$regex": Regex{Pattern: "^an", Options: "i"}}},
+	} {
+		matcher, err := NewMatcherFromBSON(filter)
+		if err != nil {
+			t.Fatalf("NewMatcherFromBSON(%v) failed: %v", filter, err)
+		}
+		for doc, want := range map[string]bool{"Ann": true, "ann": true, "Bob": false} {
+			if got, err := matcher.Match(map[string]any{"name": doc}); err != nil || got != want {
+				t.Fatalf("NewMatcherFromBSON(%v).Match(%q) = %v, %v; want %v", filter, doc, got, err, want)
+			}
+		}
+	}
+
+	for _, filter := range []any{
+		testBSOND{{"a", 1}, {"a", 2}},
+		testBSONM{"name": Regex{"^a", "x"}},
+		testBSONM{"name": testBSONM{"$options": "i"}},
+		testBSONM{"name": testBSONM{"$in": testBSONA{Regex{"^a", ""}}}},
+		testBSONA{1, 2},
+	} {
+		if _, err := FromBSON(filter); err == nil {
+			t.Fatalf("FromBSON(%v) succeeded, want an error", filter)
+		}
+	}
+
+	re := regexp.MustCompile("^a")
+	got, err = FromBSON(map[string]any{"name": re})
+	if err != nil || got["name"] != re {
+		t.Fatalf("FromBSON kept %v, %v; want the regexp unchanged", got, err)
+	}
+}
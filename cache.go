@@ -0,0 +1,187 @@
+package mongory
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MatcherCache keeps recently compiled matchers by the ConditionHash of
+// their condition, so conditions that differ only in key order or in the
+// order of $in, $nin, $and and $or lists compile once. Matchers leave the
+// cache once it holds more than its size, least recently used first, or
+// once they are older than its TTL.
+//
+// Matchers from a cache are shared: Close gives up the caller's use, and
+// the matcher is freed once the cache has dropped it and every caller has
+// closed it. Settings such as trace mode and memory limits apply to every
+// caller, so use Clone for a matcher of one's own. Conditions are compiled
+// in the conversion and UTF-8 modes in effect when they were first seen;
+// call Purge after changing them. Conditions holding $ago expressions are
+// compiled on every Get and never cached, so each matcher folds them at its
+// own compilation.
+type MatcherCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of *cacheEntry
+	lru     list.List                // most recently used first
+	hits    int64
+	misses  int64
+}
+
+type cacheEntry struct {
+	key      string
+	matcher  *matcher
+	compiled time.Time
+	// refs counts the callers holding the matcher, plus one while it is
+	// cached. It is guarded by the cache's mu.
+	refs int
+}
+
+// NewMatcherCache returns a cache holding up to size matchers, 128 if zero
+// or less, each for at most ttl, or until evicted if ttl is zero or less.
+func NewMatcherCache(size int, ttl time.Duration) *MatcherCache {
+	if size <= 0 {
+		size = 128
+	}
+	return &MatcherCache{size: size, ttl: ttl, entries: map[string]*list.Element{}}
+}
+
+var matcherCache atomic.Pointer[MatcherCache]
+
+// SetMatcherCache makes NewMatcher, and the constructors built on it such as
+// NewMatcherFromJSON, get their matchers from cache. Constructors taking a
+// context or options always compile. A nil cache, the default, turns caching
+// off; matchers already handed out stay valid.
+func SetMatcherCache(cache *MatcherCache) {
+	matcherCache.Store(cache)
+}
+
+// Get returns a matcher for condition, compiling it unless the cache holds
+// one for an equivalent condition. Close the matcher when done with it.
+func (c *MatcherCache) Get(condition map[string]any) (Matcher, error) {
+	if err := InitE(); err != nil {
+		return nil, err
+	}
+	// Key on the expanded condition, so redefining a macro is not served a
+	// matcher compiled from its old definition.
+	expanded, err := expandMacros(condition)
+	if err != nil {
+		return nil, err
+	}
+	if hasFoldable(expanded) {
+		// A cached matcher would keep the instant of its first compilation.
+		c.mu.Lock()
+		c.misses++
+		c.mu.Unlock()
+		return newMatcher(expanded, nil)
+	}
+	key := ConditionHash(expanded)
+
+	c.mu.Lock()
+	if e := c.lookup(key); e != nil {
+		c.hits++
+		e.refs++
+		c.mu.Unlock()
+		return &cachedMatcher{matcher: e.matcher, cache: c, entry: e}, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Compile without holding the lock. Callers racing on one condition may
+	// each compile it; the first to finish is cached.
+	compiled, err := newMatcher(expanded, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.lookup(key); e != nil {
+		compiled.Close()
+		e.refs++
+		return &cachedMatcher{matcher: e.matcher, cache: c, entry: e}, nil
+	}
+	e := &cacheEntry{key: key, matcher: compiled.(*matcher), compiled: time.Now(), refs: 2}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.drop(c.lru.Back())
+	}
+	return &cachedMatcher{matcher: e.matcher, cache: c, entry: e}, nil
+}
+
+// lookup returns the live entry for key, marking it most recently used, and
+// drops it if it has expired. The caller holds c.mu.
+func (c *MatcherCache) lookup(key string) *cacheEntry {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := elem.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Since(e.compiled) >= c.ttl {
+		c.drop(elem)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return e
+}
+
+// drop removes elem from the cache. The caller holds c.mu.
+func (c *MatcherCache) drop(elem *list.Element) {
+	e := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.release(e)
+}
+
+// release gives up one reference to e. The caller holds c.mu.
+func (c *MatcherCache) release(e *cacheEntry) {
+	if e.refs--; e.refs == 0 {
+		e.matcher.Close()
+	}
+}
+
+// Purge drops every cached matcher. Matchers handed out stay valid until
+// closed.
+func (c *MatcherCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.drop(c.lru.Back())
+	}
+}
+
+// MatcherCacheStats are a cache's counts.
+type MatcherCacheStats struct {
+	Len    int
+	Hits   int64
+	Misses int64
+}
+
+// Stats returns the number of cached matchers and how many Get calls found
+// one or compiled.
+func (c *MatcherCache) Stats() MatcherCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return MatcherCacheStats{Len: c.lru.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+// cachedMatcher is one caller's use of a cached matcher.
+type cachedMatcher struct {
+	*matcher
+	cache  *MatcherCache
+	entry  *cacheEntry
+	closed sync.Once
+}
+
+// Close gives up the caller's use of the matcher, freeing it if the cache
+// has dropped it and no other caller holds it.
+func (m *cachedMatcher) Close() error {
+	m.closed.Do(func() {
+		m.cache.mu.Lock()
+		defer m.cache.mu.Unlock()
+		m.cache.release(m.entry)
+	})
+	return nil
+}
@@ -0,0 +1,124 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMatcherCache(t *testing.T) {
+	c := NewMatcherCache(2, 0)
+	defer c.Purge()
+	a, err := c.Get(map[string]any{"status": "active", "age": map[string]any{"$in": []any{1, 2}}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	b, err := c.Get(map[string]any{"age": map[string]any{"$in": []any{2, 1}}, "status": "active"})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if unwrapMatcher(a) != unwrapMatcher(b) {
+		t.Fatalf("equivalent conditions should share a matcher")
+	}
+	if s := c.Stats(); s != (MatcherCacheStats{Len: 1, Hits: 1, Misses: 1}) {
+		t.Fatalf("Stats = %+v", s)
+	}
+
+	// Closing one caller's matcher leaves it usable by the other.
+	a.Close()
+	a.Close()
+	if ok, err := b.Match(map[string]any{"status": "active", "age": 2}); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+
+	// Filling the cache drops the least recently used matcher, which stays
+	// valid until its last caller closes it.
+	for _, status := range []string{"x", "y"} {
+		m, err := c.Get(map[string]any{"status": status})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		m.Close()
+	}
+	if s := c.Stats(); s.Len != 2 {
+		t.Fatalf("Len = %d, want 2", s.Len)
+	}
+	if ok, err := b.Match(map[string]any{"status": "active", "age": 1}); err != nil || !ok {
+		t.Fatalf("evicted matcher Match = %v, %v; want true", ok, err)
+	}
+	b.Close()
+	if _, err := unwrapMatcher(b).Match(map[string]any{}); !errors.Is(err, ErrMatcherFreed) {
+		t.Fatalf("evicted matcher should be freed after its last Close, got %v", err)
+	}
+
+	if _, err := c.Get(map[string]any{"$and": "bad"}); err == nil {
+		t.Fatalf("Get should reject invalid conditions")
+	}
+}
+
+func TestMatcherCacheTTL(t *testing.T) {
+	c := NewMatcherCache(0, 10*time.Millisecond)
+	defer c.Purge()
+	first, err := c.Get(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	first.Close()
+	time.Sleep(20 * time.Millisecond)
+	second, err := c.Get(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer second.Close()
+	if s := c.Stats(); s.Misses != 2 || s.Len != 1 {
+		t.Fatalf("Stats = %+v, want an expired entry recompiled", s)
+	}
+}
+
+func TestMatcherCacheAgo(t *testing.T) {
+	c := NewMatcherCache(0, 0)
+	defer c.Purge()
+	condition := map[string]any{"created": map[string]any{"$gte": map[string]any{"$ago": "50ms"}}}
+	doc := map[string]any{"created": time.Now()}
+	for i, want := range []bool{true, false} {
+		if i > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		m, err := c.Get(condition)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		got, err := m.Match(doc)
+		m.Close()
+		if err != nil || got != want {
+			t.Fatalf("Get %d: Match = %v, %v; want %v", i, got, err, want)
+		}
+	}
+	if s := c.Stats(); s.Misses != 2 || s.Len != 0 {
+		t.Fatalf("Stats = %+v, want $ago conditions compiled each time and not cached", s)
+	}
+}
+
+func TestSetMatcherCache(t *testing.T) {
+	c := NewMatcherCache(8, 0)
+	SetMatcherCache(c)
+	defer SetMatcherCache(nil)
+	defer c.Purge()
+
+	for i := 0; i < 3; i++ {
+		m, err := NewMatcherFromJSON([]byte(`{"age": {"$gte": 18}}`))
+		if err != nil {
+			t.Fatalf("NewMatcherFromJSON failed: %v", err)
+		}
+		if err := m.FilterFunc([]any{map[string]any{"age": 20}}, func(int, any) bool { return true }); err != nil {
+			t.Fatalf("FilterFunc failed: %v", err)
+		}
+		m.Close()
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("Stats = %+v, want 2 hits and 1 miss", s)
+	}
+	if _, err := ExplainDiff(map[string]any{"a": 1}, map[string]any{"a": 2}); err != nil {
+		t.Fatalf("ExplainDiff with a cache failed: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package mongory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// unorderedOperators take lists whose order does not affect the result, so
+// canonicalization sorts their elements.
+var unorderedOperators = map[string]bool{
+	"$and": true,
+	"$or":  true,
+	"$in":  true,
+	"$nin": true,
+}
+
+// CanonicalCondition returns a copy of condition in canonical form: nested
+// maps become map[string]any, lists become []any, and the children of $and,
+// $or, $in and $nin are sorted by their canonical serialization. Two
+// conditions that differ only by such permutations canonicalize identically.
+func CanonicalCondition(condition map[string]any) map[string]any {
+	canonical, _ := canonicalize(condition).(map[string]any)
+	return canonical
+}
+
+// CanonicalJSON serializes condition in canonical form with sorted keys and
+// numbers normalized so that 2 and 2.0 encode the same way.
+func CanonicalJSON(condition map[string]any) []byte {
+	var buf bytes.Buffer
+	writeCanonical(&buf, canonicalize(condition))
+	return buf.Bytes()
+}
+
+// ConditionHash returns a stable hex-encoded SHA-256 of CanonicalJSON.
+func ConditionHash(condition map[string]any) string {
+	sum := sha256.Sum256(CanonicalJSON(condition))
+	return hex.EncodeToString(sum[:])
+}
+
+// EquivalentConditions reports whether a and b are the same condition up to
+// key order and permutation of unordered operator lists.
+func EquivalentConditions(a, b map[string]any) bool {
+	return bytes.Equal(CanonicalJSON(a), CanonicalJSON(b))
+}
+
+func canonicalize(value any) any {
+	switch v := value.(type) {
+	case *SharedValue:
+		if v != nil {
+			return canonicalize(v.Value())
+		}
+	case *regexp.Regexp:
+		if v != nil {
+			return v
+		}
+	}
+	if v, ok := cgo.SQLValue(value); ok {
+		return canonicalize(v)
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			item := canonicalize(iter.Value().Interface())
+			if list, ok := item.([]any); ok && unorderedOperators[key] {
+				sortCanonical(list)
+			}
+			out[key] = item
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = canonicalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return canonicalize(rv.Elem().Interface())
+	default:
+		return value
+	}
+}
+
+func sortCanonical(list []any) {
+	keys := make([]string, len(list))
+	for i, item := range list {
+		var buf bytes.Buffer
+		writeCanonical(&buf, item)
+		keys[i] = buf.String()
+	}
+	sort.Sort(byKey{list, keys})
+}
+
+type byKey struct {
+	items []any
+	keys  []string
+}
+
+func (b byKey) Len() int           { return len(b.items) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.items[i], b.items[j] = b.items[j], b.items[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+func writeCanonical(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, key)
+			buf.WriteByte(':')
+			writeCanonical(buf, v[key])
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonical(buf, item)
+		}
+		buf.WriteByte(']')
+	case *regexp.Regexp:
+		// A regular expression literal matches like {"$regex": source}.
+		buf.WriteString(`{"$regex":`)
+		writeJSONString(buf, v.String())
+		buf.WriteByte('}')
+	case json.Number:
+		// A number matches by value, so it is written as the number it
+		// converts to.
+		if number, ok := cgo.JSONNumber(v); ok {
+			writeCanonicalScalar(buf, number)
+		} else {
+			writeJSONString(buf, v.String())
+		}
+	case time.Time:
+		// A time matches as its UTC form, so times naming the same instant
+		// are written alike.
+		writeJSONString(buf, cgo.FormatTime(v))
+	default:
+		writeCanonicalScalar(buf, value)
+	}
+}
+
+func writeCanonicalScalar(buf *bytes.Buffer, value any) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			buf.WriteString(strconv.FormatInt(int64(f), 10))
+		} else {
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	case reflect.String:
+		writeJSONString(buf, rv.String())
+	case reflect.Slice, reflect.Array:
+		// Only byte slices and arrays are left as they are by canonicalize.
+		// They match as strings holding the bytes, so are written as one.
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+		b := make([]byte, rv.Len())
+		for i := range b {
+			b[i] = byte(rv.Index(i).Uint())
+		}
+		writeJSONString(buf, string(b))
+	case reflect.Func:
+		// Funcs have no JSON form, so each is written as its identity and
+		// equal only to itself.
+		writeJSONString(buf, fmt.Sprintf("%T@%p", value, funcIdentity(value)))
+	default:
+		if encoded, err := json.Marshal(value); err == nil {
+			buf.Write(encoded)
+			return
+		}
+		writeJSONString(buf, fmt.Sprintf("%T(%v)", value, value))
+	}
+}
+
+// funcIdentity returns the closure a func value refers to. reflect only
+// reports its code pointer, which closures made by one literal share.
+func funcIdentity(fn any) unsafe.Pointer {
+	return (*[2]unsafe.Pointer)(unsafe.Pointer(&fn))[1]
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	encoded, _ := json.Marshal(s)
+	buf.Write(encoded)
+}
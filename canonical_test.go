@@ -0,0 +1,60 @@
+package mongory
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestConditionHashPermutation(t *testing.T) {
+	a := map[string]any{
+		"$or": []any{
+			map[string]any{"status": "active"},
+			map[string]any{"age": map[string]any{"$in": []any{3, 1, 2}}},
+		},
+		"score": 2.0,
+	}
+	b := map[string]any{
+		"score": 2,
+		"$or": []any{
+			map[string]any{"age": map[string]any{"$in": []int{1, 2, 3}}},
+			map[string]any{"status": "active"},
+		},
+	}
+	if !EquivalentConditions(a, b) {
+		t.Fatalf("conditions should be equivalent:\n%s\n%s", CanonicalJSON(a), CanonicalJSON(b))
+	}
+	if ConditionHash(a) != ConditionHash(b) {
+		t.Fatalf("ConditionHash differs for equivalent conditions")
+	}
+
+	c := map[string]any{"score": 3}
+	if EquivalentConditions(a, c) || ConditionHash(a) == ConditionHash(c) {
+		t.Fatalf("different conditions must not be equivalent")
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	got := string(CanonicalJSON(map[string]any{"b": 1.5, "a": []any{"x", nil, true}}))
+	want := `{"a":["x",null,true],"b":1.5}`
+	if got != want {
+		t.Fatalf("CanonicalJSON = %s, want %s", got, want)
+	}
+}
+
+func TestCanonicalRegexp(t *testing.T) {
+	condition := map[string]any{"email": regexp.MustCompile(`@example\.com$`)}
+	canonical := CanonicalCondition(condition)
+	if _, ok := canonical["email"].(*regexp.Regexp); !ok {
+		t.Fatalf("CanonicalCondition changed the regexp to %T", canonical["email"])
+	}
+	if _, err := NewCMatcher(canonical, nil); err != nil {
+		t.Fatalf("NewMatcher(canonical) failed: %v", err)
+	}
+	if got := string(CanonicalJSON(condition)); got != `{"email":{"$regex":"@example\\.com$"}}` {
+		t.Fatalf("CanonicalJSON = %s", got)
+	}
+	other := map[string]any{"email": regexp.MustCompile(`@example\.org$`)}
+	if ConditionHash(condition) == ConditionHash(other) {
+		t.Fatalf("different regexps hash the same")
+	}
+}
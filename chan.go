@@ -0,0 +1,100 @@
+package mongory
+
+import (
+	"context"
+	"sync"
+)
+
+// FilterChanOptions tunes FilterChan. Zero fields use the defaults.
+type FilterChanOptions struct {
+	// Workers is the number of goroutines matching concurrently, each with
+	// its own clone of the compiled matcher. Defaults to 1, which preserves
+	// input order; with more workers matched documents may be emitted out of
+	// order.
+	Workers int
+	// Buffer is the capacity of the output channel. Defaults to 0, so the
+	// stage only pulls from in as fast as the consumer drains the output.
+	Buffer int
+	// OnError receives documents that fail to convert or match. They are
+	// dropped either way. It may be called from several workers at once.
+	OnError func(doc any, err error)
+}
+
+// FilterChan is a pipeline stage that forwards the documents from in that
+// match condition. The output channel is closed once in is closed and every
+// pending document has been matched, or once ctx is done, so a consumer that
+// stops reading early cancels ctx to stop the workers. The condition is
+// compiled before FilterChan returns, so an invalid condition is reported
+// immediately; the workers close their matchers when they exit.
+func FilterChan(ctx context.Context, in <-chan any, condition map[string]any, options ...FilterChanOptions) (<-chan any, error) {
+	var opts FilterChanOptions
+	if len(options) > 0 {
+		opts = options[len(options)-1]
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Buffer < 0 {
+		opts.Buffer = 0
+	}
+
+	first, err := NewMatcher(condition)
+	if err != nil {
+		return nil, err
+	}
+	matchers := []Matcher{first}
+	for len(matchers) < opts.Workers {
+		m, err := first.Clone()
+		if err != nil {
+			for _, m := range matchers {
+				m.Close()
+			}
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	out := make(chan any, opts.Buffer)
+	var wg sync.WaitGroup
+	wg.Add(len(matchers))
+	for _, m := range matchers {
+		go func(m Matcher) {
+			defer wg.Done()
+			defer m.Close()
+			match, done := batchMatch(m)
+			defer done()
+			for {
+				var doc any
+				select {
+				case <-ctx.Done():
+					return
+				case next, ok := <-in:
+					if !ok {
+						return
+					}
+					doc = next
+				}
+				matched, err := match(doc)
+				if err != nil {
+					if opts.OnError != nil {
+						opts.OnError(doc, err)
+					}
+					continue
+				}
+				if !matched {
+					continue
+				}
+				select {
+				case out <- doc:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(m)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
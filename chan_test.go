@@ -0,0 +1,64 @@
+package mongory
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+func TestFilterChan(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		in := make(chan any)
+		go func() {
+			defer close(in)
+			for _, record := range adultRecords() {
+				in <- record
+			}
+		}()
+		out, err := FilterChan(context.Background(), in, map[string]any{"age": map[string]any{"$gte": 18}}, FilterChanOptions{Workers: workers, Buffer: 1})
+		if err != nil {
+			t.Fatalf("FilterChan failed: %v", err)
+		}
+		var names []string
+		for doc := range out {
+			names = append(names, doc.(map[string]any)["name"].(string))
+		}
+		if workers > 1 {
+			sort.Strings(names)
+		}
+		if len(names) != 3 || names[0] != "a" || names[1] != "c" || names[2] != "d" {
+			t.Fatalf("FilterChan with %d workers emitted %v", workers, names)
+		}
+	}
+}
+
+func TestFilterChanCancel(t *testing.T) {
+	settlePools(t)
+	live := cgo.LivePools()
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan any)
+	go func() {
+		// Never closed: only the cancellation stops the workers.
+		for i := 0; ; i++ {
+			select {
+			case in <- map[string]any{"n": i}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	out, err := FilterChan(ctx, in, map[string]any{"n": map[string]any{"$gte": 0}}, FilterChanOptions{Workers: 3})
+	if err != nil {
+		t.Fatalf("FilterChan failed: %v", err)
+	}
+	<-out
+	// The consumer stops reading; cancelling ends the blocked workers.
+	cancel()
+	for range out {
+	}
+	if got := cgo.LivePools(); got != live {
+		t.Fatalf("FilterChan left %d live pools after cancel, want %d", got, live)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// batchConversions are the conversion modes each batch mode is measured in.
+var batchConversions = []mongory.ConversionMode{mongory.ShallowConversion, mongory.DeepConversion}
+
+func timeBatch(matcher mongory.Matcher, records []any, loops int) time.Duration {
+	var best time.Duration
+	for l := 0; l < loops; l++ {
+		start := time.Now()
+		if err := matcher.FilterFunc(records, func(int, any) bool { return true }); err != nil {
+			panic(err)
+		}
+		if elapsed := time.Since(start); l == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	return best / time.Duration(len(records))
+}
+
+// runBatch compares resetting scratch memory per document with one arena per
+// batch, reporting time per document and the native memory peak of each.
+func runBatch(size, loops int) {
+	defer mongory.SetBatchMode(mongory.ResetPerDocument)
+	defer mongory.SetConversionMode(mongory.ShallowConversion)
+	if size > 20_000 {
+		size = 20_000
+	}
+	documents, bytes := mongory.ArenaLimits()
+	fmt.Printf("Reset per document vs arena per batch (%d documents or %d bytes), %d records, best of %d runs\n\n",
+		documents, bytes, size, loops)
+	fmt.Printf("%8s  %8s  %12s  %12s  %12s  %12s\n", "fields", "convert", "reset/op", "arena/op", "reset peak", "arena peak")
+	for _, conversion := range batchConversions {
+		mongory.SetConversionMode(conversion)
+		for _, width := range conversionWidths {
+			records := genWideRecords(size, width)
+			var elapsed [2]time.Duration
+			var peaks [2]int64
+			for i, mode := range []mongory.BatchMode{mongory.ResetPerDocument, mongory.ArenaPerBatch} {
+				mongory.SetBatchMode(mode)
+				matcher, err := mongory.NewMatcher(map[string]any{
+					"age":  map[string]any{"$gte": 18},
+					"tags": map[string]any{"$in": []any{"c"}},
+				})
+				if err != nil {
+					panic(err)
+				}
+				elapsed[i] = timeBatch(matcher, records, loops)
+				peaks[i] = matcher.PeakNativeBytes()
+				matcher.Close()
+			}
+			fmt.Printf("%8d  %8v  %12v  %12v  %12d  %12d\n", width, conversion, elapsed[0], elapsed[1], peaks[0], peaks[1])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// conversionWidths are the document sizes (number of fields) compared.
+var conversionWidths = []int{1, 4, 16, 64, 256}
+
+// genWideRecords builds documents with width fields plus a nested tags list,
+// so deep conversion pays for every field while the condition reads two.
+func genWideRecords(size, width int) []any {
+	records := make([]any, size)
+	for i := range records {
+		record := make(map[string]any, width+2)
+		for f := 0; f < width; f++ {
+			record[fmt.Sprintf("f%d", f)] = i + f
+		}
+		record["age"] = i % 100
+		record["tags"] = []any{"a", "b", "c"}
+		records[i] = record
+	}
+	return records
+}
+
+func timeMatches(matcher mongory.Matcher, records []any, loops int) time.Duration {
+	var best time.Duration
+	for l := 0; l < loops; l++ {
+		start := time.Now()
+		for _, record := range records {
+			if _, err := matcher.Match(record); err != nil {
+				panic(err)
+			}
+		}
+		if elapsed := time.Since(start); l == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	return best / time.Duration(len(records))
+}
+
+// runConversion compares shallow and deep document conversion across
+// document widths and reports where the faster mode changes.
+func runConversion(size, loops int) {
+	defer mongory.SetConversionMode(mongory.ShallowConversion)
+	matcher, err := mongory.NewMatcher(map[string]any{
+		"age":  map[string]any{"$gte": 18},
+		"tags": map[string]any{"$in": []any{"c"}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	if size > 20_000 {
+		size = 20_000
+	}
+	fmt.Printf("Shallow vs deep conversion, %d records, best of %d runs\n\n", size, loops)
+	fmt.Printf("%8s  %12s  %12s  %s\n", "fields", "shallow/op", "deep/op", "faster")
+	var winners []mongory.ConversionMode
+	for _, width := range conversionWidths {
+		records := genWideRecords(size, width)
+		mongory.SetConversionMode(mongory.ShallowConversion)
+		shallow := timeMatches(matcher, records, loops)
+		mongory.SetConversionMode(mongory.DeepConversion)
+		deep := timeMatches(matcher, records, loops)
+		faster := mongory.ShallowConversion
+		if deep < shallow {
+			faster = mongory.DeepConversion
+		}
+		winners = append(winners, faster)
+		fmt.Printf("%8d  %12v  %12v  %v\n", width, shallow, deep, faster)
+	}
+	fmt.Println()
+	crossed := false
+	for i := 1; i < len(winners); i++ {
+		if winners[i] != winners[i-1] {
+			crossed = true
+			fmt.Printf("Crossover between %d and %d fields: %v becomes faster.\n",
+				conversionWidths[i-1], conversionWidths[i], winners[i])
+		}
+	}
+	if !crossed {
+		fmt.Printf("%v conversion was faster at every measured width.\n", winners[0])
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math/rand/v2"
+	"os"
 	"runtime"
 	"runtime/debug"
 	"time"
@@ -117,13 +119,32 @@ func bench(title string, loops int, fn func()) {
 }
 
 func main() {
+	scenario := flag.String("scenario", "queries", "benchmark to run: queries, conversion, batch or operators")
+	size := flag.Int("size", 100_000, "number of records")
+	loops := flag.Int("loops", 5, "timed runs per benchmark")
+	asJSON := flag.Bool("json", false, "print the operators cost table as JSON")
+	flag.Parse()
+
 	// Ensure native runtime is initialized
 	mongory.Init()
 	defer mongory.Cleanup()
 
-	size := 100_000
-	loops := 5
+	switch *scenario {
+	case "queries":
+		runQueries(*size, *loops)
+	case "conversion":
+		runConversion(*size, *loops)
+	case "batch":
+		runBatch(*size, *loops)
+	case "operators":
+		runOperators(*loops, *asJSON)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown scenario %q\n", *scenario)
+		os.Exit(2)
+	}
+}
 
+func runQueries(size, loops int) {
 	fmt.Printf("Testing with %d records:\n", size)
 	records := genRecords(size)
 
@@ -136,9 +157,9 @@ func main() {
 	})
 
 	// Matcher simple query (reuse matcher across runs)
-	matcherSimple, err := mongory.NewCMatcher(map[string]any{
+	matcherSimple, err := mongory.NewMatcher(map[string]any{
 		"age": map[string]any{"$gte": 18},
-	}, nil)
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -164,12 +185,12 @@ func main() {
 	})
 
 	// Matcher complex query: $or of age>=18 or status==active (reuse matcher)
-	matcherComplex, err := mongory.NewCMatcher(map[string]any{
+	matcherComplex, err := mongory.NewMatcher(map[string]any{
 		"$or": []any{
 			map[string]any{"age": map[string]any{"$gte": 18}},
 			map[string]any{"status": "active"},
 		},
-	}, nil)
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -188,5 +209,4 @@ func main() {
 			panic(fmt.Sprintf("count mismatch: got %d want %d", cnt, expectedComplex))
 		}
 	})
-
 }
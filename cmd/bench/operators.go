@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// runOperators times every operator of the default suite in isolation and
+// prints the cost table, as JSON for SetOperatorCosts when asJSON is set.
+func runOperators(loops int, asJSON bool) {
+	table, err := mongory.MeasureOperators(mongory.OperatorBenchmarks(), time.Duration(loops)*50*time.Millisecond)
+	if err != nil {
+		panic(err)
+	}
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(table); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if err := table.WriteText(os.Stdout); err != nil {
+		panic(err)
+	}
+}
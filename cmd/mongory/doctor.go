@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/mongoryhq/mongory-go"
+	"github.com/mongoryhq/mongory-go/native"
+)
+
+func runDoctor(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	rounds := flags.Int("rounds", 10000, "matches used to measure cgo round-trip latency")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	fmt.Fprintln(stdout, "Build")
+	fmt.Fprintf(stdout, "  %-13s %s %s/%s\n", "go:", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(stdout, "  %-13s %s %s\n", "module:", info.Main.Path, info.Main.Version)
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "CGO_ENABLED", "CGO_CFLAGS", "CGO_LDFLAGS", "-tags", "-race", "vcs.revision", "vcs.modified":
+				fmt.Fprintf(stdout, "  %-13s %s\n", setting.Key+":", setting.Value)
+			}
+		}
+	}
+	// What the linked core registers, rather than what this build expects.
+	if names := native.CoreOperators(); len(names) > 0 {
+		fmt.Fprintf(stdout, "  %-13s mongory-core, %d operators: %s\n", "core:", len(names), strings.Join(names, " "))
+	} else {
+		fmt.Fprintf(stdout, "  %-13s mongory-core, no operators registered\n", "core:")
+	}
+
+	failed := 0
+	fmt.Fprintln(stdout, "\nRuntime")
+	if err := mongory.InitE(); err != nil {
+		fmt.Fprintf(stdout, "  init:      FAIL %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, "  init:      ok")
+	if err := mongory.Healthy(); err != nil {
+		fmt.Fprintf(stdout, "  health:    FAIL %v\n", err)
+		failed++
+	} else {
+		fmt.Fprintln(stdout, "  health:    ok")
+	}
+
+	fmt.Fprintln(stdout, "\nOperators")
+	for _, doc := range mongory.Operators() {
+		if len(doc.Examples) == 0 {
+			fmt.Fprintf(stdout, "  %-11s skipped (no examples)\n", doc.Name)
+			continue
+		}
+		if err := runExamples(doc.Examples); err != nil {
+			fmt.Fprintf(stdout, "  %-11s FAIL %v\n", doc.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "  %-11s ok\n", doc.Name)
+	}
+
+	fmt.Fprintln(stdout, "\nLatency")
+	if latency, err := measureRoundTrip(*rounds); err != nil {
+		fmt.Fprintf(stdout, "  match:     FAIL %v\n", err)
+		failed++
+	} else {
+		fmt.Fprintf(stdout, "  match:     %v per call (%d calls)\n", latency, *rounds)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(stdout, "\n%d check(s) failed\n", failed)
+		return 1
+	}
+	fmt.Fprintln(stdout, "\nall checks passed")
+	return 0
+}
+
+func runExamples(examples []mongory.OperatorExample) error {
+	for _, example := range examples {
+		matcher, err := mongory.NewMatcher(example.Condition)
+		if err != nil {
+			return err
+		}
+		got, err := matcher.Match(example.Document)
+		matcher.Close()
+		if err != nil {
+			return err
+		}
+		if got != example.Matches {
+			return fmt.Errorf("%v against %v: got %v, want %v", example.Condition, example.Document, got, example.Matches)
+		}
+	}
+	return nil
+}
+
+func measureRoundTrip(rounds int) (time.Duration, error) {
+	if rounds <= 0 {
+		rounds = 1
+	}
+	matcher, err := mongory.NewMatcher(map[string]any{"a": 1})
+	if err != nil {
+		return 0, err
+	}
+	defer matcher.Close()
+	record := map[string]any{"a": 1}
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		if _, err := matcher.Match(record); err != nil {
+			return 0, err
+		}
+	}
+	return time.Since(start) / time.Duration(rounds), nil
+}
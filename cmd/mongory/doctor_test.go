@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mongoryhq/mongory-go/native"
+)
+
+func TestDoctor(t *testing.T) {
+	code, stdout, stderr := runCommand("", "doctor", "-rounds", "1")
+	if code != 0 {
+		t.Fatalf("doctor = %d, want 0; stdout:\n%s\nstderr: %s", code, stdout, stderr)
+	}
+	core := native.CoreOperators()
+	if len(core) == 0 {
+		t.Fatalf("CoreOperators() is empty after Init")
+	}
+	for _, want := range []string{
+		fmt.Sprintf("core:         mongory-core, %d operators: ", len(core)),
+		" $eq ", " $elemMatch ",
+		"  init:      ok\n",
+		"  health:    ok\n",
+		"all checks passed\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("doctor output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	if code, _, stderr := runCommand("", "doctor", "-rounds", "x"); code != 2 || !strings.Contains(stderr, "-rounds") {
+		t.Fatalf("doctor -rounds x = %d, stderr %q; want 2 and a flag error", code, stderr)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"doctor", "report build configuration and run native self-tests", runDoctor},
+	{"query", "print the JSONL records matching a condition, like grep", runQuery},
+	{"repl", "interactively test conditions against a JSONL dataset", runREPL},
+	{"replay", "check a recorded match corpus against this engine", runReplay},
+	{"schema", "print the JSON Schema for condition documents", runSchema},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: mongory <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+func main() {
+	code := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	mongory.Cleanup()
+	os.Exit(code)
+}
+
+// run runs the command named by args[0] and returns its exit code. The
+// native runtime is left initialized for main to clean up.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		usage(stderr)
+		return 2
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(stdout)
+		return 0
+	}
+	for _, c := range commands {
+		if c.name == name {
+			mongory.Init()
+			return c.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "mongory: unknown command %q\n\n", name)
+	usage(stderr)
+	return 2
+}
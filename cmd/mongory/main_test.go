@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// runCommand runs the mongory command line with input as stdin.
+func runCommand(input string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(input), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		args       []string
+		code       int
+		stdout     string
+		stderrPart string
+	}{
+		{nil, 2, "", "usage: mongory <command>"},
+		{[]string{"help"}, 0, "", ""},
+		{[]string{"bogus"}, 2, "", `unknown command "bogus"`},
+	}
+	for _, tc := range cases {
+		code, stdout, stderr := runCommand("", tc.args...)
+		if code != tc.code {
+			t.Fatalf("run(%q) = %d, want %d; stderr: %s", tc.args, code, tc.code, stderr)
+		}
+		if tc.code == 0 && !strings.Contains(stdout, "usage: mongory <command>") {
+			t.Fatalf("run(%q) printed %q, want the usage", tc.args, stdout)
+		}
+		if !strings.Contains(stderr, tc.stderrPart) {
+			t.Fatalf("run(%q) stderr = %q, want it to contain %q", tc.args, stderr, tc.stderrPart)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Exit codes of query, as grep uses them.
+const (
+	exitMatched   = 0
+	exitNoMatch   = 1
+	exitQueryFail = 2
+)
+
+func runQuery(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: mongory query [flags] <condition> [file ...]")
+		fmt.Fprintln(stderr, "Prints the JSONL records from the files, or stdin, that match the JSON")
+		fmt.Fprintln(stderr, "condition. Exits 0 if any record matched, 1 if none did and 2 on error.")
+		flags.PrintDefaults()
+	}
+	output := flags.String("output", "json", "output format: json (one record per line), csv or table")
+	fields := flags.String("fields", "", "comma-separated top-level fields for csv and table (default: those of the first match)")
+	nul := flags.Bool("0", false, "end json records with NUL instead of a newline, for xargs -0")
+	crlf := flags.Bool("crlf", false, "end lines with CRLF")
+	count := flags.Bool("c", false, "print only the number of matching records")
+	exact := flags.Bool("exact", false, "compare integers with floats exactly, as MongoDB does")
+	if err := flags.Parse(args); err != nil {
+		return exitQueryFail
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return exitQueryFail
+	}
+	w, err := newRecordWriter(*output, splitFields(*fields), *nul, *crlf)
+	if err != nil {
+		fmt.Fprintf(stderr, "mongory query: %v\n", err)
+		return exitQueryFail
+	}
+	condition, err := mongory.ParseConditionJSON([]byte(flags.Arg(0)))
+	if err != nil {
+		fmt.Fprintf(stderr, "mongory query: condition: %v\n", err)
+		return exitQueryFail
+	}
+	opts := mongory.MatcherOptions{}
+	if *exact {
+		opts.Numeric = mongory.NumericExact
+	}
+	matcher, err := mongory.NewMatcherWithOptions(condition, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "mongory query: condition: %v\n", err)
+		return exitQueryFail
+	}
+	defer matcher.Close()
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	matched := 0
+	onMatch := func(raw json.RawMessage, record any) error {
+		matched++
+		if *count {
+			return nil
+		}
+		return w.write(out, raw, record)
+	}
+	files := flags.Args()[1:]
+	if len(files) == 0 {
+		err = queryJSONL(matcher, "stdin", stdin, onMatch)
+	}
+	for _, name := range files {
+		if err = queryFile(matcher, name, onMatch); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		if *count {
+			_, err = out.WriteString(fmt.Sprint(matched) + w.eol)
+		} else {
+			err = w.flush(out)
+		}
+	}
+	if err != nil {
+		out.Flush()
+		fmt.Fprintf(stderr, "mongory query: %v\n", err)
+		return exitQueryFail
+	}
+	if matched == 0 {
+		return exitNoMatch
+	}
+	return exitMatched
+}
+
+func splitFields(list string) []string {
+	if list == "" {
+		return nil
+	}
+	fields := strings.Split(list, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+func queryFile(matcher mongory.Matcher, name string, onMatch func(json.RawMessage, any) error) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return queryJSONL(matcher, name, file, onMatch)
+}
+
+// queryJSONL matches each record of r, passing matches to onMatch with
+// their text as read. Records may span lines and end in LF or CRLF.
+func queryJSONL(matcher mongory.Matcher, name string, r io.Reader, onMatch func(json.RawMessage, any) error) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: record %d: %w", name, n, err)
+		}
+		// Parsed apart from the raw text so integer IDs keep every digit.
+		record, err := mongory.ParseDocumentJSON(raw)
+		if err != nil {
+			return fmt.Errorf("%s: record %d: %w", name, n, err)
+		}
+		ok, err := matcher.Match(record)
+		if err != nil {
+			return fmt.Errorf("%s: record %d: %w", name, n, err)
+		}
+		if ok {
+			if err := onMatch(raw, record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// recordWriter prints matching records in one output format.
+type recordWriter struct {
+	format string
+	fields []string
+	eol    string
+	// started is set once the header row is written.
+	started bool
+	csv     *csv.Writer
+	rows    [][]string // buffered table rows, header first
+}
+
+func newRecordWriter(format string, fields []string, nul, crlf bool) (*recordWriter, error) {
+	w := &recordWriter{format: format, fields: fields, eol: "\n"}
+	if crlf {
+		w.eol = "\r\n"
+	}
+	switch format {
+	case "json":
+		if nul {
+			w.eol = "\x00"
+		}
+	case "csv", "table":
+		if nul {
+			return nil, fmt.Errorf("-0 only applies to json output")
+		}
+	default:
+		return nil, fmt.Errorf("unknown output format %q; want json, csv or table", format)
+	}
+	return w, nil
+}
+
+func (w *recordWriter) write(out *bufio.Writer, raw json.RawMessage, record any) error {
+	if w.format == "json" {
+		// Records are printed as read, on one line; a JSON text never
+		// contains a raw newline or NUL outside whitespace.
+		var line bytes.Buffer
+		if err := json.Compact(&line, raw); err != nil {
+			return err
+		}
+		line.WriteString(w.eol)
+		_, err := out.Write(line.Bytes())
+		return err
+	}
+	if !w.started {
+		w.started = true
+		if w.fields == nil {
+			w.fields = recordFields(record)
+		}
+		if err := w.row(out, w.fields); err != nil {
+			return err
+		}
+	}
+	cells := make([]string, len(w.fields))
+	fields, _ := record.(map[string]any)
+	for i, field := range w.fields {
+		cells[i] = cellText(fields[field])
+	}
+	return w.row(out, cells)
+}
+
+// recordFields returns the sorted top-level keys of record, or "value" for
+// records that are not objects.
+func recordFields(record any) []string {
+	fields, ok := record.(map[string]any)
+	if !ok {
+		return []string{"value"}
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (w *recordWriter) row(out *bufio.Writer, cells []string) error {
+	if w.format == "table" {
+		w.rows = append(w.rows, cells)
+		return nil
+	}
+	if w.csv == nil {
+		w.csv = csv.NewWriter(out)
+		w.csv.UseCRLF = w.eol == "\r\n"
+	}
+	return w.csv.Write(cells)
+}
+
+// flush writes what the format holds back: the aligned table, or the rest
+// of the CSV.
+func (w *recordWriter) flush(out *bufio.Writer) error {
+	switch w.format {
+	case "csv":
+		if w.csv != nil {
+			w.csv.Flush()
+			return w.csv.Error()
+		}
+	case "table":
+		return writeTable(out, w.rows, w.eol)
+	}
+	return nil
+}
+
+// cellText formats a field value for csv and table output. Numbers are
+// written as JSON writes them, independent of locale, and objects and
+// arrays as compact JSON.
+func cellText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// tableEscaper keeps each table row on one line.
+var tableEscaper = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`, "\x00", `\0`)
+
+// writeTable writes rows with columns padded to their widest cell, counting
+// characters rather than bytes so non-ASCII text stays aligned.
+func writeTable(out *bufio.Writer, rows [][]string, eol string) error {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = tableEscaper.Replace(cell)
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			}
+		}
+		line.WriteString(eol)
+		if _, err := out.WriteString(line.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
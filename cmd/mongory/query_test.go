@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	const people = `{"name":"al","age":30,"tags":["a","b"]}
+{"name":"bo","age":12}
+{"name":"cy, jr","age":41}
+`
+	cases := []struct {
+		args       []string
+		input      string
+		code       int
+		stdout     string
+		stderrPart string
+	}{
+		{[]string{`{"age":{"$gte":18}}`}, people, exitMatched,
+			`{"name":"al","age":30,"tags":["a","b"]}` + "\n" + `{"name":"cy, jr","age":41}` + "\n", ""},
+		{[]string{`{"age":{"$gte":99}}`}, people, exitNoMatch, "", ""},
+		{[]string{"-c", `{"age":{"$gte":18}}`}, people, exitMatched, "2\n", ""},
+		{[]string{"-0", `{"name":"bo"}`}, people, exitMatched, `{"name":"bo","age":12}` + "\x00", ""},
+		// Records span lines and may end in CRLF.
+		{[]string{`{"a":1}`}, "{\"a\":1}\r\n{\"a\":2}\r\n{\r\n\"a\": 1\r\n}\r\n", exitMatched, "{\"a\":1}\n{\"a\":1}\n", ""},
+		{[]string{"-output", "csv", `{"age":{"$gte":18}}`}, people, exitMatched,
+			"age,name,tags\n30,al,\"[\"\"a\"\",\"\"b\"\"]\"\n41,\"cy, jr\",\n", ""},
+		{[]string{"-output", "csv", "-crlf", "-fields", "name", `{"age":{"$lt":18}}`}, people, exitMatched, "name\r\nbo\r\n", ""},
+		{[]string{"-output", "table", "-fields", "name, age", `{}`}, people, exitMatched,
+			"name    age\nal      30\nbo      12\ncy, jr  41\n", ""},
+		{[]string{"-output", "table", `{"name":"é"}`}, `{"name":"é","n":1}` + "\n", exitMatched, "n  name\n1  é\n", ""},
+		{[]string{}, people, exitQueryFail, "", "usage: mongory query"},
+		{[]string{"-output", "xml", `{}`}, people, exitQueryFail, "", `unknown output format "xml"`},
+		{[]string{"-output", "csv", "-0", `{}`}, people, exitQueryFail, "", "-0 only applies to json output"},
+		{[]string{`{"age":`}, people, exitQueryFail, "", "condition:"},
+		{[]string{`{"age":{"$rollout":"half"}}`}, people, exitQueryFail, "", "condition:"},
+		{[]string{`{}`}, "{\"a\":1}\n{\"a\":\n", exitQueryFail, `{"a":1}` + "\n", "stdin: record 2"},
+		{[]string{`{}`, "does-not-exist.jsonl"}, "", exitQueryFail, "", "does-not-exist.jsonl"},
+	}
+	for _, tc := range cases {
+		code, stdout, stderr := runCommand(tc.input, append([]string{"query"}, tc.args...)...)
+		if code != tc.code {
+			t.Fatalf("query %q = %d, want %d; stderr: %s", tc.args, code, tc.code, stderr)
+		}
+		if stdout != tc.stdout {
+			t.Fatalf("query %q printed %q, want %q", tc.args, stdout, tc.stdout)
+		}
+		if tc.stderrPart == "" && stderr != "" || !strings.Contains(stderr, tc.stderrPart) {
+			t.Fatalf("query %q stderr = %q, want it to contain %q", tc.args, stderr, tc.stderrPart)
+		}
+	}
+}
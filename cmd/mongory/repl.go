@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go"
+	"github.com/mongoryhq/mongory-go/query"
+)
+
+const replHelp = `Type a condition to count and sample matching records: JSON, or query
+builder calls such as Field("age").Gte(18).Or(Field("vip").Eq(true)).
+  :explain <condition>   print the compiled matcher tree
+  :sample <n>            number of sample matches to show (currently %d)
+  :help                  show this help
+  :quit                  exit
+`
+
+func runREPL(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	data := flags.String("data", "", "JSONL file with one record per line")
+	sample := flags.Int("sample", 3, "number of sample matches to show")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *data == "" {
+		fmt.Fprintln(stderr, "mongory repl: -data is required")
+		return 2
+	}
+	records, err := loadJSONL(*data)
+	if err != nil {
+		fmt.Fprintf(stderr, "mongory repl: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "loaded %d records from %s; :help for commands\n", len(records), *data)
+
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for {
+		fmt.Fprint(stdout, "mongory> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case line == ":quit" || line == ":q":
+			return 0
+		case line == ":help":
+			fmt.Fprintf(stdout, replHelp, *sample)
+		case strings.HasPrefix(line, ":sample"):
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, ":sample")))
+			if err != nil || n < 0 {
+				fmt.Fprintln(stdout, "usage: :sample <n>")
+				continue
+			}
+			*sample = n
+		case strings.HasPrefix(line, ":explain"):
+			matcher, err := compileLine(strings.TrimPrefix(line, ":explain"))
+			if err != nil {
+				fmt.Fprintf(stdout, "error: %v\n", err)
+				continue
+			}
+			plan, err := matcher.ExplainPlan()
+			matcher.Close()
+			if err != nil {
+				fmt.Fprintf(stdout, "error: %v\n", err)
+				continue
+			}
+			writePlan(stdout, plan, "", "")
+		case strings.HasPrefix(line, ":"):
+			fmt.Fprintf(stdout, "unknown command %s; :help for commands\n", line)
+		default:
+			evaluate(stdout, line, records, *sample)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "mongory repl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func evaluate(stdout io.Writer, line string, records []any, sample int) {
+	matcher, err := compileLine(line)
+	if err != nil {
+		fmt.Fprintf(stdout, "error: %v\n", err)
+		return
+	}
+	defer matcher.Close()
+	count := 0
+	var samples []any
+	for _, record := range records {
+		ok, err := matcher.Match(record)
+		if err != nil {
+			fmt.Fprintf(stdout, "error: %v\n", err)
+			return
+		}
+		if !ok {
+			continue
+		}
+		count++
+		if len(samples) < sample {
+			samples = append(samples, record)
+		}
+	}
+	fmt.Fprintf(stdout, "%d of %d records matched\n", count, len(records))
+	for _, record := range samples {
+		encoded, _ := json.Marshal(record)
+		fmt.Fprintf(stdout, "  %s\n", encoded)
+	}
+}
+
+// writePlan prints node after head and its children below it, each line
+// prefixed with indent, so the plan reads as a tree. It writes to w rather
+// than to the process's stdout, as Explain does.
+func writePlan(w io.Writer, node *mongory.ExplainNode, head, indent string) {
+	label := node.Operator
+	if node.Field != "" {
+		label += " " + strconv.Quote(node.Field)
+	}
+	operand, _ := json.Marshal(node.Operand)
+	fmt.Fprintf(w, "%s%s: %s\n", head, label, operand)
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			writePlan(w, child, indent+"└─ ", indent+"   ")
+		} else {
+			writePlan(w, child, indent+"├─ ", indent+"│  ")
+		}
+	}
+}
+
+// compileLine compiles a JSON condition, or builder calls as query.Parse
+// reads them when the line does not start with {.
+func compileLine(line string) (mongory.Matcher, error) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		return mongory.NewMatcherFromJSON([]byte(line))
+	}
+	cond, err := query.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	return cond.Matcher()
+}
+
+func loadJSONL(path string) ([]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodeJSONL(file)
+}
+
+func decodeJSONL(r io.Reader) ([]any, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var records []any
+	for {
+		var record any
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		if record, err = mongory.NormalizeJSONNumbers(record); err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
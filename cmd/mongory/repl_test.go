@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "people.jsonl")
+	records := `{"name":"al","age":30}
+{"name":"bo","age":12}
+{"name":"cy","age":41,"vip":true}
+`
+	if err := os.WriteFile(data, []byte(records), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{`{"age": {"$gte": 18}}`, []string{"2 of 3 records matched", `  {"age":30,"name":"al"}`}},
+		{`Field("age").Gte(18)`, []string{"2 of 3 records matched", `  {"age":41,"name":"cy","vip":true}`}},
+		{`query.Field("age").Lt(18).Or(Field("vip").Eq(true))`, []string{"2 of 3 records matched", `"name":"bo"`}},
+		{`Field("age").Gte(`, []string{"error: query: invalid condition: offset 17: unexpected end of the condition"}},
+		{`{"age":`, []string{"error: "}},
+		{`:explain Field("age").Gte(18).Or(Field("vip").Eq(true))`, []string{
+			"mongory> " + `Or: {"$or":[{"age":{"$gte":18}},{"vip":{"$eq":true}}]}` + "\n" +
+				`├─ Field "vip": {"$eq":true}` + "\n" +
+				`│  └─ Eq: true` + "\n" +
+				`└─ Field "age": {"$gte":18}` + "\n" +
+				`   └─ Gte: 18` + "\n",
+		}},
+		{`:explain Field("age").Like(1)`, []string{"error: query: invalid condition: offset 13: unknown method Like"}},
+		{":sample 0\n" + `Field("name").Eq("bo")`, []string{"1 of 3 records matched\nmongory> "}},
+		{":bogus", []string{"unknown command :bogus"}},
+	}
+	for _, tc := range cases {
+		code, stdout, stderr := runCommand(tc.input+"\n:quit\n", "repl", "-data", data)
+		if code != 0 {
+			t.Fatalf("repl with %q = %d, want 0; stderr: %s", tc.input, code, stderr)
+		}
+		if !strings.HasPrefix(stdout, "loaded 3 records from "+data) {
+			t.Fatalf("repl with %q printed %q", tc.input, stdout)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(stdout, want) {
+				t.Fatalf("repl with %q printed %q, want it to contain %q", tc.input, stdout, want)
+			}
+		}
+	}
+
+	if code, _, stderr := runCommand("", "repl"); code != 2 || !strings.Contains(stderr, "-data is required") {
+		t.Fatalf("repl without -data = %d, stderr %q", code, stderr)
+	}
+}
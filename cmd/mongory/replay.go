@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+func runReplay(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: mongory replay <corpus> ...")
+		fmt.Fprintln(stderr, "Matches every case of corpora written by a CorpusRecorder and reports")
+		fmt.Fprintln(stderr, "those whose result changed. Exits 1 if any did.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 2
+	}
+	cases, mismatches := 0, 0
+	for _, name := range flags.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "mongory replay: %v\n", err)
+			return 2
+		}
+		report, err := mongory.ReplayCorpus(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "mongory replay: %s: %v\n", name, err)
+			return 2
+		}
+		cases += report.Cases
+		mismatches += len(report.Mismatches)
+		for _, m := range report.Mismatches {
+			if m.Err != nil {
+				fmt.Fprintf(stdout, "%s:%d: %v\n", name, m.Line, m.Err)
+				continue
+			}
+			fmt.Fprintf(stdout, "%s:%d: matched %v, recorded %v\n", name, m.Line, m.Got, m.Case.Matched)
+		}
+	}
+	fmt.Fprintf(stdout, "%d cases, %d changed\n", cases, mismatches)
+	if mismatches > 0 {
+		return 1
+	}
+	return 0
+}
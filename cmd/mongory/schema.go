@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+func runSchema(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "write the schema to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	schema, err := mongory.ConditionSchema()
+	if err != nil {
+		fmt.Fprintf(stderr, "mongory schema: %v\n", err)
+		return 1
+	}
+	schema = append(schema, '\n')
+	if *output == "" {
+		stdout.Write(schema)
+		return 0
+	}
+	if err := os.WriteFile(*output, schema, 0o644); err != nil {
+		fmt.Fprintf(stderr, "mongory schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+//go:build !mongory_nohttp
+
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// serve is left out of builds tagged mongory_nohttp, with the rest of the
+// HTTP support.
+func init() {
+	commands = append(commands, command{"serve", "serve the evaluate API and optional web playground", runServe})
+}
+
+//go:embed playground.html
+var playgroundHTML []byte
+
+type evaluateRequest struct {
+	Condition map[string]any `json:"condition"`
+	Documents []any          `json:"documents"`
+}
+
+type evaluateResult struct {
+	Matched bool            `json:"matched"`
+	Trace   json.RawMessage `json:"trace"`
+}
+
+type evaluateResponse struct {
+	Explain json.RawMessage  `json:"explain,omitempty"`
+	Results []evaluateResult `json:"results,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+func runServe(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "127.0.0.1:8080", "listen address")
+	playground := flags.Bool("playground", false, "serve the interactive playground page at /")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/evaluate", handleEvaluate)
+	mux.Handle("GET /healthz", mongory.HealthHandler())
+	if *playground {
+		mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(playgroundHTML)
+		})
+	}
+
+	fmt.Fprintf(stdout, "listening on http://%s\n", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Fprintf(stderr, "mongory serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	var req evaluateRequest
+	if err := decodeEvaluate(http.MaxBytesReader(w, r.Body, 8<<20), &req); err != nil {
+		writeEvaluate(w, http.StatusBadRequest, evaluateResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	matcher, err := mongory.NewMatcher(req.Condition)
+	if err != nil {
+		writeEvaluate(w, http.StatusUnprocessableEntity, evaluateResponse{Error: err.Error()})
+		return
+	}
+	defer matcher.Close()
+	explain, err := matcher.ExplainJSON()
+	if err != nil {
+		writeEvaluate(w, http.StatusInternalServerError, evaluateResponse{Error: err.Error()})
+		return
+	}
+	resp := evaluateResponse{Explain: explain, Results: make([]evaluateResult, 0, len(req.Documents))}
+	for _, doc := range req.Documents {
+		matched, trace, err := matcher.TraceJSON(doc)
+		if err != nil {
+			writeEvaluate(w, http.StatusUnprocessableEntity, evaluateResponse{Error: err.Error()})
+			return
+		}
+		resp.Results = append(resp.Results, evaluateResult{Matched: matched, Trace: trace})
+	}
+	writeEvaluate(w, http.StatusOK, resp)
+}
+
+// decodeEvaluate decodes a request keeping integers exact, as
+// ParseDocumentJSON does.
+func decodeEvaluate(r io.Reader, req *evaluateRequest) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(req); err != nil {
+		return err
+	}
+	condition, err := mongory.NormalizeJSONNumbers(req.Condition)
+	if err != nil {
+		return err
+	}
+	req.Condition, _ = condition.(map[string]any)
+	for i, doc := range req.Documents {
+		if req.Documents[i], err = mongory.NormalizeJSONNumbers(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEvaluate(w http.ResponseWriter, status int, resp evaluateResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
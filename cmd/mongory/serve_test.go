@@ -0,0 +1,77 @@
+//go:build !mongory_nohttp
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeFlags(t *testing.T) {
+	cases := []struct {
+		args       []string
+		stderrPart string
+	}{
+		{[]string{"-bogus"}, "flag provided but not defined: -bogus"},
+		{[]string{"-playground=maybe"}, `invalid boolean value "maybe" for -playground`},
+		{[]string{"-addr"}, "flag needs an argument: -addr"},
+	}
+	for _, tc := range cases {
+		code, stdout, stderr := runCommand("", append([]string{"serve"}, tc.args...)...)
+		if code != 2 || stdout != "" || !strings.Contains(stderr, tc.stderrPart) {
+			t.Fatalf("serve %q = %d, stdout %q, stderr %q; want 2 and %q", tc.args, code, stdout, stderr, tc.stderrPart)
+		}
+	}
+
+	code, stdout, stderr := runCommand("", "serve", "-addr", "256.0.0.1:http")
+	if code != 1 || !strings.Contains(stdout, "listening on http://256.0.0.1:http") || !strings.HasPrefix(stderr, "mongory serve: ") {
+		t.Fatalf("serve on a bad address = %d, stdout %q, stderr %q; want 1 and the listen error", code, stdout, stderr)
+	}
+}
+
+func TestHandleEvaluate(t *testing.T) {
+	cases := []struct {
+		body      string
+		status    int
+		errorPart string
+		matched   []bool
+	}{
+		{`{"condition": {"n": {"$gt": 9007199254740992}}, "documents": [{"n": 9007199254740993}, {"n": 1}]}`, http.StatusOK, "", []bool{true, false}},
+		{`{"condition": {}}`, http.StatusOK, "", nil},
+		{`{"condition": `, http.StatusBadRequest, "invalid request: ", nil},
+		{`{"condition": [1]}`, http.StatusBadRequest, "invalid request: ", nil},
+		{`{"condition": {"n": {"$rollout": "half"}}, "documents": [{}]}`, http.StatusUnprocessableEntity, "$rollout", nil},
+		{`{"condition": {"n": 1}, "documents": {"n": 1}}`, http.StatusBadRequest, "invalid request: ", nil},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		handleEvaluate(rec, httptest.NewRequest(http.MethodPost, "/api/evaluate", strings.NewReader(tc.body)))
+		if rec.Code != tc.status {
+			t.Fatalf("POST %s: status %d, want %d; body %s", tc.body, rec.Code, tc.status, rec.Body)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Fatalf("POST %s: Content-Type %q", tc.body, got)
+		}
+		var resp evaluateResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("POST %s: invalid response %s: %v", tc.body, rec.Body, err)
+		}
+		if tc.errorPart != "" {
+			if !strings.Contains(resp.Error, tc.errorPart) || resp.Explain != nil || resp.Results != nil {
+				t.Fatalf("POST %s: response %s, want only an error containing %q", tc.body, rec.Body, tc.errorPart)
+			}
+			continue
+		}
+		if resp.Error != "" || len(resp.Explain) == 0 || len(resp.Results) != len(tc.matched) {
+			t.Fatalf("POST %s: response %s", tc.body, rec.Body)
+		}
+		for i, result := range resp.Results {
+			if result.Matched != tc.matched[i] || len(result.Trace) == 0 {
+				t.Fatalf("POST %s: result %d = %+v, want matched %v with a trace", tc.body, i, result, tc.matched[i])
+			}
+		}
+	}
+}
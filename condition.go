@@ -0,0 +1,124 @@
+package mongory
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Condition is a read-only snapshot of a condition. Documents and lists
+// inside it are copied on the way in and on the way out, so neither the
+// caller that built it nor one that reads it can change it. The zero
+// Condition is empty.
+type Condition struct {
+	fields map[string]any
+}
+
+// NewCondition snapshots condition. Nested maps with string keys become
+// map[string]any and slices become []any; other values are kept as they are.
+func NewCondition(condition map[string]any) Condition {
+	if condition == nil {
+		return Condition{}
+	}
+	return Condition{fields: snapshotCondition(reflect.ValueOf(condition)).(map[string]any)}
+}
+
+// Len returns the number of top-level keys.
+func (c Condition) Len() int {
+	return len(c.fields)
+}
+
+// Keys returns the top-level keys in sorted order.
+func (c Condition) Keys() []string {
+	return slices.Sorted(maps.Keys(c.fields))
+}
+
+// Get returns a copy of the value under a top-level key.
+func (c Condition) Get(key string) (any, bool) {
+	value, ok := c.fields[key]
+	return cloneCondition(value), ok
+}
+
+// Map returns a copy of the condition that the caller may modify.
+func (c Condition) Map() map[string]any {
+	if c.fields == nil {
+		return map[string]any{}
+	}
+	return cloneCondition(c.fields).(map[string]any)
+}
+
+// Equal reports whether c and other are equivalent conditions, as
+// EquivalentConditions does.
+func (c Condition) Equal(other Condition) bool {
+	return EquivalentConditions(c.fields, other.fields)
+}
+
+// Hash returns ConditionHash of the condition.
+func (c Condition) Hash() string {
+	return ConditionHash(c.fields)
+}
+
+// String returns the condition's canonical JSON.
+func (c Condition) String() string {
+	return string(c.canonicalJSON())
+}
+
+// MarshalJSON encodes the condition as canonical JSON, so equal conditions
+// always encode to the same bytes.
+func (c Condition) MarshalJSON() ([]byte, error) {
+	return c.canonicalJSON(), nil
+}
+
+// UnmarshalJSON decodes a condition as ParseConditionJSON does.
+func (c *Condition) UnmarshalJSON(data []byte) error {
+	condition, err := ParseConditionJSON(data)
+	if err != nil {
+		return err
+	}
+	*c = Condition{fields: condition}
+	return nil
+}
+
+func (c Condition) canonicalJSON() []byte {
+	if c.fields == nil {
+		return []byte("{}")
+	}
+	return CanonicalJSON(c.fields)
+}
+
+func snapshotCondition(rv reflect.Value) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return snapshotCondition(rv.Elem())
+	case reflect.Map:
+		if rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+			return rv.Interface()
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = snapshotCondition(iter.Value())
+		}
+		return out
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv.Interface()
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return reflect.AppendSlice(reflect.MakeSlice(rv.Type(), 0, rv.Len()), rv).Interface()
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = snapshotCondition(rv.Index(i))
+		}
+		return out
+	default:
+		return rv.Interface()
+	}
+}
@@ -0,0 +1,98 @@
+package mongory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCondition(t *testing.T) {
+	source := map[string]any{
+		"age":  map[string]any{"$gte": 18},
+		"tags": map[string][]string{"$in": {"b", "a"}},
+	}
+	m, err := NewCMatcher(source, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	condition := m.Condition()
+	source["age"].(map[string]any)["$gte"] = 99
+	source["tags"].(map[string][]string)["$in"][0] = "z"
+
+	want := map[string]any{
+		"age":  map[string]any{"$gte": 18},
+		"tags": map[string]any{"$in": []any{"b", "a"}},
+	}
+	if got := condition.Map(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Condition().Map() = %v, want %v", got, want)
+	}
+	condition.Map()["age"] = "changed"
+	age, ok := condition.Get("age")
+	if !ok {
+		t.Fatalf("Get(age) found nothing")
+	}
+	age.(map[string]any)["$gte"] = 0
+	if got, _ := condition.Get("age"); !reflect.DeepEqual(got, map[string]any{"$gte": 18}) {
+		t.Fatalf("Get(age) = %v after modifying copies", got)
+	}
+	if keys := condition.Keys(); !reflect.DeepEqual(keys, []string{"age", "tags"}) || condition.Len() != 2 {
+		t.Fatalf("Keys() = %v, Len() = %d", keys, condition.Len())
+	}
+
+	encoded, err := json.Marshal(condition)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(encoded) != `{"age":{"$gte":18},"tags":{"$in":["a","b"]}}` || condition.String() != string(encoded) {
+		t.Fatalf("Marshal = %s, String = %s", encoded, condition)
+	}
+	var decoded Condition
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !decoded.Equal(condition) || decoded.Hash() != condition.Hash() {
+		t.Fatalf("decoded condition %v differs from %v", decoded, condition)
+	}
+
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if !clone.Condition().Equal(condition) {
+		t.Fatalf("clone Condition() = %v, want %v", clone.Condition(), condition)
+	}
+
+	var zero Condition
+	if zero.Len() != 0 || zero.String() != "{}" || len(zero.Map()) != 0 {
+		t.Fatalf("zero Condition = %v", zero)
+	}
+}
+
+func TestMatcherStringer(t *testing.T) {
+	condition := map[string]any{"tags": map[string]any{"$in": []any{"a", "b"}}, "age": map[string]any{"$gte": 18}}
+	m, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	condition["age"] = 1
+	want := `{"age":{"$gte":18},"tags":{"$in":["a","b"]}}`
+	if got := m.String(); got != want {
+		t.Fatalf("String = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(m); got != want {
+		t.Fatalf("Sprint = %s, want %s", got, want)
+	}
+	encoded, err := json.Marshal(map[string]any{"rule": m})
+	if err != nil || string(encoded) != `{"rule":`+want+`}` {
+		t.Fatalf("json.Marshal = %s, %v", encoded, err)
+	}
+	parsed, err := ParseConditionJSON([]byte(m.String()))
+	if err != nil {
+		t.Fatalf("ParseConditionJSON failed: %v", err)
+	}
+	if !NewCondition(parsed).Equal(m.Condition()) {
+		t.Fatalf("round trip = %v, want %v", parsed, m.Condition())
+	}
+}
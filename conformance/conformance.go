@@ -0,0 +1,133 @@
+//go:build !mongory_noformats
+
+// Package conformance is a data-driven suite of condition, document and
+// expected result triples, stored as YAML under testdata and embedded in the
+// package. It is mongory's executable documentation and lets alternate
+// engines prove they match the same way:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(condition map[string]any, document any) (bool, error) {
+//			return myEngine.Match(condition, document)
+//		})
+//	}
+//
+// Cases follow MongoDB's query semantics except where marked: a case whose
+// MongoDB field is set records a known difference, with MongoDB's result
+// there and mongory's in Matches.
+package conformance
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"testing"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+//go:embed testdata/*.yaml
+var files embed.FS
+
+// Case is one condition and document pair with its expected result.
+type Case struct {
+	Suite     string
+	Name      string
+	Condition map[string]any
+	Document  any
+	Matches   bool
+	// MongoDB is MongoDB's result when it differs from Matches.
+	MongoDB *bool
+	Note    string
+}
+
+// MatchFunc matches a document against a condition, as an engine under test
+// does.
+type MatchFunc func(condition map[string]any, document any) (bool, error)
+
+// Cases loads every case, ordered by suite file and then as written.
+func Cases() ([]Case, error) {
+	names, err := fs.Glob(files, "testdata/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		suite, err := parseSuite(data)
+		if err != nil {
+			return nil, fmt.Errorf("conformance: %s: %w", path.Base(name), err)
+		}
+		cases = append(cases, suite...)
+	}
+	return cases, nil
+}
+
+// Run runs every case against match as a subtest named suite/case.
+func Run(t *testing.T, match MatchFunc) {
+	t.Helper()
+	cases, err := Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.Suite+"/"+c.Name, func(t *testing.T) {
+			got, err := match(c.Condition, c.Document)
+			if err != nil {
+				t.Fatalf("condition %v, document %v: %v", c.Condition, c.Document, err)
+			}
+			if got != c.Matches {
+				t.Fatalf("condition %v, document %v: matched %v, want %v", c.Condition, c.Document, got, c.Matches)
+			}
+		})
+	}
+}
+
+func parseSuite(data []byte) ([]Case, error) {
+	file, err := mongory.ParseConditionYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	suite, ok := file["suite"].(string)
+	if !ok {
+		return nil, fmt.Errorf("suite must be a string")
+	}
+	items, ok := file["cases"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("cases must be a list")
+	}
+	cases := make([]Case, 0, len(items))
+	for i, item := range items {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("case %d must be a mapping", i)
+		}
+		c := Case{Suite: suite, Document: fields["document"]}
+		if c.Name, ok = fields["name"].(string); !ok {
+			return nil, fmt.Errorf("case %d: name must be a string", i)
+		}
+		if c.Condition, ok = fields["condition"].(map[string]any); !ok {
+			return nil, fmt.Errorf("case %q: condition must be a mapping", c.Name)
+		}
+		if c.Matches, ok = fields["matches"].(bool); !ok {
+			return nil, fmt.Errorf("case %q: matches must be a boolean", c.Name)
+		}
+		if value, present := fields["mongodb"]; present {
+			mongodb, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("case %q: mongodb must be a boolean", c.Name)
+			}
+			c.MongoDB = &mongodb
+		}
+		if value, present := fields["note"]; present {
+			if c.Note, ok = value.(string); !ok {
+				return nil, fmt.Errorf("case %q: note must be a string", c.Name)
+			}
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
@@ -0,0 +1,44 @@
+//go:build !mongory_noformats
+
+package conformance
+
+import (
+	"testing"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+func TestConformance(t *testing.T) {
+	for _, mode := range []mongory.ConversionMode{mongory.ShallowConversion, mongory.DeepConversion} {
+		t.Run(mode.String(), func(t *testing.T) {
+			mongory.SetConversionMode(mode)
+			defer mongory.SetConversionMode(mongory.ShallowConversion)
+			Run(t, func(condition map[string]any, document any) (bool, error) {
+				m, err := mongory.NewCMatcher(condition, nil)
+				if err != nil {
+					return false, err
+				}
+				defer m.Close()
+				return m.Match(document)
+			})
+		})
+	}
+}
+
+func TestCases(t *testing.T) {
+	cases, err := Cases()
+	if err != nil {
+		t.Fatalf("Cases failed: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, c := range cases {
+		key := c.Suite + "/" + c.Name
+		if seen[key] {
+			t.Fatalf("duplicate case %s", key)
+		}
+		seen[key] = true
+		if c.MongoDB != nil && (*c.MongoDB == c.Matches || c.Note == "") {
+			t.Fatalf("%s: a MongoDB difference needs a differing result and a note", key)
+		}
+	}
+}
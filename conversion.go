@@ -0,0 +1,121 @@
+package mongory
+
+import "github.com/mongoryhq/mongory-go/internal/cgo"
+
+// ConversionMode selects how documents are handed to the native matcher.
+type ConversionMode int
+
+const (
+	// ShallowConversion bridges maps and slices lazily: the core reads
+	// fields through callbacks into Go, so only the values a condition
+	// visits are converted. It is the default and wins for wide documents
+	// and selective conditions.
+	ShallowConversion ConversionMode = iota
+	// DeepConversion copies the whole document into native memory first,
+	// avoiding a callback per field access. It can win for small documents
+	// that conditions read repeatedly; run `go run ./cmd/bench -scenario
+	// conversion` to find the crossover for a workload.
+	DeepConversion
+)
+
+func (m ConversionMode) String() string {
+	switch m {
+	case ShallowConversion:
+		return "shallow"
+	case DeepConversion:
+		return "deep"
+	default:
+		return "unknown"
+	}
+}
+
+// SetConversionMode sets the conversion mode for every document matched from
+// now on.
+func SetConversionMode(mode ConversionMode) {
+	cgo.SetDeepConversion(mode == DeepConversion)
+}
+
+// NilDocumentMode selects what matching a nil document does. A nil document
+// is nil itself or a nil map, slice or pointer; a nil or empty condition
+// matches every other document.
+type NilDocumentMode int
+
+const (
+	// NilDocumentNoMatch makes nil documents match no condition. It is the
+	// default.
+	NilDocumentNoMatch NilDocumentMode = iota
+	// NilDocumentError makes matching a nil document fail with
+	// ErrNilDocument, for callers that treat one as a bug upstream.
+	NilDocumentError
+)
+
+func (m NilDocumentMode) String() string {
+	switch m {
+	case NilDocumentNoMatch:
+		return "no-match"
+	case NilDocumentError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrNilDocument is returned for nil documents in NilDocumentError mode.
+var ErrNilDocument = cgo.ErrNilDocument
+
+// SetNilDocumentMode sets what matching a nil document does from now on.
+func SetNilDocumentMode(mode NilDocumentMode) {
+	cgo.SetNilDocumentError(mode == NilDocumentError)
+}
+
+// InvalidUTF8Mode selects how strings holding invalid UTF-8, common in log
+// data, are converted. It applies to condition strings when a matcher is
+// compiled and to document strings when they are matched, so set it before
+// compiling. Map keys are used as they are.
+type InvalidUTF8Mode int
+
+const (
+	// InvalidUTF8Bytewise keeps strings as they are; comparisons and
+	// equality treat them as bytes. It is the default.
+	InvalidUTF8Bytewise InvalidUTF8Mode = iota
+	// InvalidUTF8Replace replaces each invalid sequence with U+FFFD, as
+	// strings.ToValidUTF8 does, so such strings compare as their repaired
+	// forms.
+	InvalidUTF8Replace
+	// InvalidUTF8Reject fails with ErrInvalidUTF8: compiling a condition
+	// holding such a string, alongside ErrInvalidCondition, and matching a
+	// document holding one, alongside ErrConversion. Under shallow
+	// conversion only the strings the condition reads are checked.
+	InvalidUTF8Reject
+)
+
+func (m InvalidUTF8Mode) String() string {
+	switch m {
+	case InvalidUTF8Bytewise:
+		return "bytewise"
+	case InvalidUTF8Replace:
+		return "replace"
+	case InvalidUTF8Reject:
+		return "reject"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrInvalidUTF8 is wrapped by errors for strings rejected in
+// InvalidUTF8Reject mode.
+var ErrInvalidUTF8 = cgo.ErrInvalidUTF8
+
+// SetInvalidUTF8Mode sets how strings holding invalid UTF-8 are converted
+// from now on. Byte slices, which match as strings holding their bytes, are
+// binary data and always compared byte by byte.
+func SetInvalidUTF8Mode(mode InvalidUTF8Mode) {
+	switch mode {
+	case InvalidUTF8Replace:
+		cgo.SetInvalidUTF8Mode(cgo.UTF8Replace)
+	case InvalidUTF8Reject:
+		cgo.SetInvalidUTF8Mode(cgo.UTF8Reject)
+	default:
+		cgo.SetInvalidUTF8Mode(cgo.UTF8Bytewise)
+	}
+}
@@ -0,0 +1,538 @@
+package mongory
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConversionModes(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	matcher, err := NewCMatcher(map[string]any{
+		"user":  map[string]any{"age": map[string]any{"$gte": 18}},
+		"tags":  map[string]any{"$in": []any{"go"}},
+		"score": map[string]any{"$elemMatch": map[string]any{"$gt": 5}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	docs := []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"user": map[string]any{"age": 30}, "tags": []any{"c", "go"}, "score": []any{1, 9}}, true},
+		{map[string]any{"user": map[string]any{"age": 10}, "tags": []any{"go"}, "score": []any{9}}, false},
+		{map[string]any{"user": map[string]any{"age": 30}, "tags": []any{"c"}, "score": []any{9}}, false},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range docs {
+			got, err := matcher.Match(tc.doc)
+			if err != nil || got != tc.want {
+				t.Fatalf("%v: Match(%v) = %v, %v; want %v", mode, tc.doc, got, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestNilSemantics(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	var nilMap map[string]any
+	var nilPtr *map[string]any
+	for _, condition := range []map[string]any{nil, {}} {
+		m, err := NewCMatcher(condition, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher(%v) failed: %v", condition, err)
+		}
+		for _, doc := range []any{map[string]any{}, map[string]any{"a": 1}, []any{1}} {
+			if got, err := m.Match(doc); err != nil || !got {
+				t.Fatalf("condition %v: Match(%v) = %v, %v; want true", condition, doc, got, err)
+			}
+		}
+	}
+
+	cases := []struct {
+		condition map[string]any
+		doc       map[string]any
+		want      bool
+	}{
+		{map[string]any{"a": nil}, map[string]any{}, true},
+		{map[string]any{"a": nil}, map[string]any{"a": nil}, true},
+		{map[string]any{"a": nil}, map[string]any{"a": nilPtr}, true},
+		{map[string]any{"a": nil}, map[string]any{"a": 1}, false},
+		{map[string]any{"a": map[string]any{"$exists": false}}, map[string]any{"b": 1}, true},
+		{map[string]any{"a": map[string]any{"$exists": false}}, map[string]any{"a": nil}, false},
+		{map[string]any{"a": map[string]any{"$exists": true}}, map[string]any{}, false},
+		{map[string]any{"a": map[string]any{"$ne": 1}}, map[string]any{}, true},
+		{map[string]any{"a.b": nil}, map[string]any{"a": map[string]any{}}, true},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewCMatcher(tc.condition, nil)
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			if got, err := m.Match(tc.doc); err != nil || got != tc.want {
+				t.Fatalf("%v: %v Match(%v) = %v, %v; want %v", mode, tc.condition, tc.doc, got, err, tc.want)
+			}
+		}
+	}
+
+	m, err := NewCMatcher(map[string]any{"a": map[string]any{"$ne": 1}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	for _, doc := range []any{nil, nilMap, nilPtr, []any(nil)} {
+		SetNilDocumentMode(NilDocumentNoMatch)
+		if got, err := m.Match(doc); err != nil || got {
+			t.Fatalf("%v: Match(%#v) = %v, %v; want false", NilDocumentNoMatch, doc, got, err)
+		}
+		SetNilDocumentMode(NilDocumentError)
+		if _, err := m.Match(doc); !errors.Is(err, ErrNilDocument) {
+			t.Fatalf("%v: Match(%#v) error = %v, want ErrNilDocument", NilDocumentError, doc, err)
+		}
+		if _, err := m.Trace(doc); !errors.Is(err, ErrNilDocument) {
+			t.Fatalf("%v: Trace(%#v) error = %v, want ErrNilDocument", NilDocumentError, doc, err)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	defer SetInvalidUTF8Mode(InvalidUTF8Bytewise)
+	if InvalidUTF8Replace.String() != "replace" {
+		t.Fatalf("String = %q", InvalidUTF8Replace)
+	}
+	match := func(condition map[string]any, doc any) (bool, error) {
+		t.Helper()
+		m, err := NewCMatcher(condition, nil)
+		if err != nil {
+			t.Fatalf("NewCMatcher(%q) failed: %v", condition, err)
+		}
+		defer m.Close()
+		return m.Match(doc)
+	}
+	invalid := map[string]any{"s": "a\xffb"}
+
+	SetInvalidUTF8Mode(InvalidUTF8Bytewise)
+	if matched, err := match(map[string]any{"s": "a\xffb"}, invalid); err != nil || !matched {
+		t.Fatalf("bytewise: equal bytes = %v, %v; want true", matched, err)
+	}
+	if matched, err := match(map[string]any{"s": "a�b"}, invalid); err != nil || matched {
+		t.Fatalf("bytewise: replacement = %v, %v; want false", matched, err)
+	}
+	if matched, err := match(map[string]any{"s": map[string]any{"$gt": "az"}}, invalid); err != nil || !matched {
+		t.Fatalf("bytewise: $gt = %v, %v; want true", matched, err)
+	}
+
+	SetInvalidUTF8Mode(InvalidUTF8Replace)
+	if matched, err := match(map[string]any{"s": "a�b"}, invalid); err != nil || !matched {
+		t.Fatalf("replace: replacement = %v, %v; want true", matched, err)
+	}
+	if matched, err := match(map[string]any{"s": "a\xfeb"}, invalid); err != nil || !matched {
+		t.Fatalf("replace: other invalid byte = %v, %v; want true", matched, err)
+	}
+
+	SetInvalidUTF8Mode(InvalidUTF8Reject)
+	if _, err := NewCMatcher(map[string]any{"s": "a\xffb"}, nil); !errors.Is(err, ErrInvalidUTF8) || !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("reject: NewCMatcher err = %v, want ErrInvalidUTF8 and ErrInvalidCondition", err)
+	}
+	if _, err := NewSharedValue([]any{"ok", "a\xffb"}); !errors.Is(err, ErrInvalidUTF8) {
+		t.Fatalf("reject: NewSharedValue err = %v, want ErrInvalidUTF8", err)
+	}
+	if _, err := match(map[string]any{"s": "ab"}, invalid); !errors.Is(err, ErrInvalidUTF8) || !errors.Is(err, ErrConversion) {
+		t.Fatalf("reject: Match err = %v, want ErrInvalidUTF8 and ErrConversion", err)
+	}
+	doc := map[string]any{"s": "ok", "other": "a\xffb"}
+	if matched, err := match(map[string]any{"s": "ok"}, doc); err != nil || !matched {
+		t.Fatalf("reject: unread field = %v, %v; want true", matched, err)
+	}
+	if matched, err := match(map[string]any{"s": "ok"}, map[string]any{"s": "oké"}); err != nil || matched {
+		t.Fatalf("reject: valid string = %v, %v; want false", matched, err)
+	}
+}
+
+func TestLargeStrings(t *testing.T) {
+	text := strings.Repeat("héllo wörld ", 1000)
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		m, err := NewCMatcher(map[string]any{
+			"body":  text,
+			"title": map[string]any{"$regex": "wörld $"},
+			"empty": "",
+		}, nil)
+		if err != nil {
+			t.Fatalf("%v: NewCMatcher failed: %v", mode, err)
+		}
+		doc := map[string]any{"body": text, "title": text, "empty": ""}
+		if matched, err := m.Match(doc); err != nil || !matched {
+			t.Fatalf("%v: Match = %v, %v; want true", mode, matched, err)
+		}
+		doc["body"] = text[:len(text)-1]
+		if matched, err := m.Match(doc); err != nil || matched {
+			t.Fatalf("%v: Match truncated = %v, %v; want false", mode, matched, err)
+		}
+		m.Close()
+	}
+	SetConversionMode(ShallowConversion)
+}
+
+func BenchmarkLargeStrings(b *testing.B) {
+	for _, size := range []int{64, 4 << 10, 64 << 10} {
+		text := strings.Repeat("x", size)
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			m, err := NewCMatcher(map[string]any{"body": map[string]any{"$ne": "y"}}, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer m.Close()
+			doc := map[string]any{"body": text}
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if _, err := m.Match(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTimes(t *testing.T) {
+	type event struct {
+		Name string    `json:"name"`
+		At   time.Time `json:"at"`
+	}
+	if err := RegisterStruct[event](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	matcher, err := NewMatcher(map[string]any{"at": map[string]any{"$gte": start, "$lt": end}})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer matcher.Close()
+	inside := start.Add(36 * time.Hour)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	cases := []struct {
+		doc  any
+		want bool
+	}{
+		{map[string]any{"at": inside}, true},
+		{map[string]any{"at": &inside}, true},
+		{map[string]any{"at": start}, true},
+		{map[string]any{"at": end}, false},
+		{map[string]any{"at": start.Add(-time.Nanosecond)}, false},
+		// 08:00 on May 1 in Tokyo is still April 30 in UTC.
+		{map[string]any{"at": time.Date(2024, 5, 1, 8, 0, 0, 0, tokyo)}, false},
+		{map[string]any{"at": time.Date(2024, 5, 1, 10, 0, 0, 0, tokyo)}, true},
+		{event{Name: "deploy", At: inside}, true},
+		{&event{Name: "deploy", At: end}, false},
+	}
+	for _, tc := range cases {
+		got, err := matcher.Match(tc.doc)
+		if err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, got, err, tc.want)
+		}
+	}
+
+	equal, err := NewMatcher(map[string]any{"at": inside.In(tokyo)})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer equal.Close()
+	if got, err := equal.Match(map[string]any{"at": inside}); err != nil || !got {
+		t.Fatalf("times of one instant in different zones should be equal, got %v, %v", got, err)
+	}
+	if !EquivalentConditions(map[string]any{"at": inside}, map[string]any{"at": inside.In(tokyo)}) {
+		t.Fatalf("times of one instant should canonicalize alike")
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	matcher, err := NewMatcherWithOptions(map[string]any{
+		"at": map[string]any{"$gte": "2024-05-01T00:00:00Z", "$lt": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}, MatcherOptions{ParseTimes: true})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer matcher.Close()
+	cases := []struct {
+		at   any
+		want bool
+	}{
+		{"2024-05-01T00:00:00Z", true},
+		{"2024-05-01T00:00:00.5Z", true},
+		{"2024-05-01T01:00:00+02:00", false},
+		{"2024-05-31T20:00:00-03:00", true},
+		{"2024-05-31T22:00:00-03:00", false},
+		{time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), true},
+		{"May 15", false},
+	}
+	for _, tc := range cases {
+		got, err := matcher.Match(map[string]any{"at": tc.at})
+		if err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.at, got, err, tc.want)
+		}
+	}
+
+	data, err := matcher.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	loaded, err := LoadMatcher(data)
+	if err != nil {
+		t.Fatalf("LoadMatcher failed: %v", err)
+	}
+	defer loaded.Close()
+	if got, err := loaded.Match(map[string]any{"at": "2024-05-31T20:00:00-03:00"}); err != nil || !got {
+		t.Fatalf("loaded plan should parse times, got %v, %v", got, err)
+	}
+}
+
+func TestUnsignedIntegers(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	type row struct {
+		ID    uint64 `json:"id"`
+		Flags uint8  `json:"flags"`
+		Count uint   `json:"count"`
+	}
+	if err := RegisterStruct[row](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	cases := []struct {
+		condition map[string]any
+		doc       any
+		want      bool
+	}{
+		{map[string]any{"id": 42}, map[string]any{"id": uint64(42)}, true},
+		{map[string]any{"id": uint32(42)}, map[string]any{"id": 42}, true},
+		{map[string]any{"id": map[string]any{"$in": []uint64{7, 42}}}, map[string]any{"id": uint(42)}, true},
+		{map[string]any{"id": map[string]any{"$gt": 1e19}}, map[string]any{"id": uint64(math.MaxUint64)}, true},
+		{map[string]any{"id": map[string]any{"$gt": uint64(math.MaxUint64 - 1)}}, map[string]any{"id": uint64(math.MaxUint64)}, false},
+		{map[string]any{"id": map[string]any{"$lt": 0}}, map[string]any{"id": uint64(math.MaxUint64)}, false},
+		{map[string]any{"flags": map[string]any{"$gte": 200}}, map[string]any{"flags": uint8(255)}, true},
+		{map[string]any{"id": uint64(1 << 40), "flags": 3, "count": 9}, row{ID: 1 << 40, Flags: 3, Count: 9}, true},
+		{map[string]any{"id": map[string]any{"$gt": 1e19}}, &row{ID: math.MaxUint64}, true},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewMatcher(tc.condition)
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			got, err := m.Match(tc.doc)
+			m.Close()
+			if err != nil || got != tc.want {
+				t.Fatalf("%v: Match(%v) with %v = %v, %v; want %v", mode, tc.doc, tc.condition, got, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestNullableTypes(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	type user struct {
+		Name    sql.NullString   `json:"name"`
+		Age     *int             `json:"age"`
+		Score   *sql.NullFloat64 `json:"score"`
+		Email   *string          `json:"email"`
+		Visited sql.NullTime     `json:"visited"`
+	}
+	if err := RegisterStruct[user](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	age, email := 30, "ada@example.com"
+	visited := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	full := user{
+		Name:    sql.NullString{String: "ada", Valid: true},
+		Age:     &age,
+		Score:   &sql.NullFloat64{Float64: 9.5, Valid: true},
+		Email:   &email,
+		Visited: sql.NullTime{Time: visited, Valid: true},
+	}
+	cases := []struct {
+		condition map[string]any
+		doc       any
+		want      bool
+	}{
+		{map[string]any{"name": "ada"}, map[string]any{"name": sql.NullString{String: "ada", Valid: true}}, true},
+		{map[string]any{"name": nil}, map[string]any{"name": sql.NullString{}}, true},
+		{map[string]any{"name": ""}, map[string]any{"name": sql.NullString{}}, false},
+		{map[string]any{"n": map[string]any{"$gt": 5}}, map[string]any{"n": sql.NullInt64{Int64: 9, Valid: true}}, true},
+		{map[string]any{"n": 0}, map[string]any{"n": sql.NullInt64{}}, false},
+		{map[string]any{"n": map[string]any{"$in": []any{1, 2}}}, map[string]any{"n": sql.Null[int16]{V: 2, Valid: true}}, true},
+		{map[string]any{"n": sql.NullInt64{Int64: 2, Valid: true}}, map[string]any{"n": 2}, true},
+		{map[string]any{"n": sql.NullInt64{}}, map[string]any{}, true},
+		{map[string]any{"ok": true}, map[string]any{"ok": &sql.NullBool{Bool: true, Valid: true}}, true},
+		{map[string]any{"n": 3}, map[string]any{"n": &age}, false},
+		{map[string]any{"n": 30}, map[string]any{"n": &age}, true},
+		{map[string]any{"n": nil}, map[string]any{"n": (*int)(nil)}, true},
+		{map[string]any{"name": "ada", "age": 30, "score": map[string]any{"$gte": 9}, "email": map[string]any{"$regex": "@example"}, "visited": visited}, full, true},
+		{map[string]any{"name": nil, "age": nil, "score": nil, "email": nil, "visited": nil}, user{}, true},
+		{map[string]any{"age": 0}, &user{}, false},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewMatcher(tc.condition)
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			got, err := m.Match(tc.doc)
+			m.Close()
+			if err != nil || got != tc.want {
+				t.Fatalf("%v: Match(%+v) with %v = %v, %v; want %v", mode, tc.doc, tc.condition, got, err, tc.want)
+			}
+		}
+	}
+	if !EquivalentConditions(map[string]any{"n": sql.NullInt64{Int64: 2, Valid: true}}, map[string]any{"n": 2}) {
+		t.Fatalf("sql.NullInt64 should canonicalize as the number it holds")
+	}
+}
+
+func TestByteSlices(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	defer SetInvalidUTF8Mode(InvalidUTF8Bytewise)
+	type blob struct {
+		Name []byte  `json:"name"`
+		Hash [4]byte `json:"hash"`
+	}
+	if err := RegisterStruct[blob](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	SetInvalidUTF8Mode(InvalidUTF8Reject)
+	cases := []struct {
+		condition map[string]any
+		doc       any
+		want      bool
+	}{
+		{map[string]any{"name": "ada"}, map[string]any{"name": []byte("ada")}, true},
+		{map[string]any{"name": []byte("ada")}, map[string]any{"name": "ada"}, true},
+		{map[string]any{"name": map[string]any{"$regex": "^a.a$"}}, map[string]any{"name": []byte("ada")}, true},
+		{map[string]any{"name": map[string]any{"$in": []any{"bob", "ada"}}}, map[string]any{"name": []byte("ada")}, true},
+		{map[string]any{"name": map[string]any{"$size": 3}}, map[string]any{"name": []byte("ada")}, false},
+		{map[string]any{"name": nil}, map[string]any{"name": []byte(nil)}, true},
+		{map[string]any{"name": ""}, map[string]any{"name": []byte{}}, true},
+		// Binary data is compared byte by byte, even when UTF-8 is checked.
+		{map[string]any{"bin": []byte{0xff, 0xfe}}, map[string]any{"bin": []byte{0xff, 0xfe}}, true},
+		{map[string]any{"bin": []byte{0xff, 0xfe}}, map[string]any{"bin": []byte{0xff, 0xfd}}, false},
+		{map[string]any{"name": "ada", "hash": [4]byte{'a', 'b', 'c', 'd'}}, blob{Name: []byte("ada"), Hash: [4]byte{'a', 'b', 'c', 'd'}}, true},
+		{map[string]any{"hash": "abcd"}, &blob{Hash: [4]byte{'a', 'b', 'c', 'e'}}, false},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewMatcher(tc.condition)
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			got, err := m.Match(tc.doc)
+			m.Close()
+			if err != nil || got != tc.want {
+				t.Fatalf("%v: Match(%v) with %v = %v, %v; want %v", mode, tc.doc, tc.condition, got, err, tc.want)
+			}
+		}
+	}
+	if !EquivalentConditions(map[string]any{"name": []byte("ada")}, map[string]any{"name": "ada"}) {
+		t.Fatalf("[]byte and string conditions should canonicalize alike")
+	}
+}
+
+func TestTraversalMatrix(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	type line struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty"`
+	}
+	type field string
+	type lines []any
+	if err := RegisterStruct[line](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	item := line{SKU: "a", Qty: 2}
+	ptr := &item
+	elem := map[string]any{"sku": "a", "qty": 2}
+	// Each shape holds item, or an equivalent document, under "x".
+	shapes := []struct {
+		name string
+		x    any
+	}{
+		{"map of structs", map[string]line{"k": item}},
+		{"map of struct pointers", map[string]*line{"k": &item}},
+		{"map with named keys", map[field]any{"k": item}},
+		{"map of structs with named keys", map[field]line{"k": item}},
+		{"pointer to map", &map[string]any{"k": elem}},
+		{"map of pointers to pointers", map[string]**line{"k": &ptr}},
+		{"map of typed maps", map[string]map[string]int{"k": {"qty": 2}}},
+		{"slice of structs", map[string]any{"k": []line{item}}},
+		{"slice of struct pointers", map[string]any{"k": []*line{nil, &item}}},
+		{"pointer to slice", map[string]any{"k": &[]line{item}}},
+		{"named slice", map[string]any{"k": lines{elem}}},
+		{"array of maps", map[string]any{"k": [2]map[string]any{nil, elem}}},
+		{"slice of maps of structs", []map[string]line{{"k": item}}},
+		{"slice of slices", map[string]any{"k": [][]any{{elem}, {}}}},
+	}
+	// at nests cond under x.k; dotted keys name fields, not paths.
+	at := func(cond any) map[string]any {
+		return map[string]any{"x": map[string]any{"k": cond}}
+	}
+	conditions := []struct {
+		condition map[string]any
+		want      map[string]bool // by shape, else false
+	}{
+		{at(map[string]any{"qty": 2}), map[string]bool{
+			"map of structs": true, "map of struct pointers": true, "map with named keys": true,
+			"map of structs with named keys": true, "pointer to map": true, "map of pointers to pointers": true,
+			"map of typed maps": true, "slice of structs": true, "slice of struct pointers": true,
+			"pointer to slice": true, "named slice": true, "array of maps": true, "slice of maps of structs": true,
+		}},
+		{at(map[string]any{"sku": "a", "qty": map[string]any{"$gt": 1}}), map[string]bool{
+			"map of structs": true, "map of struct pointers": true, "map with named keys": true,
+			"map of structs with named keys": true, "pointer to map": true, "map of pointers to pointers": true,
+			"slice of structs": true, "slice of struct pointers": true, "pointer to slice": true,
+			"named slice": true, "array of maps": true, "slice of maps of structs": true,
+		}},
+		{at(map[string]any{"$elemMatch": map[string]any{"sku": "a"}}), map[string]bool{
+			"slice of structs": true, "slice of struct pointers": true, "pointer to slice": true,
+			"named slice": true, "array of maps": true,
+		}},
+		// Arrays nested in arrays are elements, not more elements to search.
+		{at(map[string]any{"$elemMatch": map[string]any{"$elemMatch": map[string]any{"sku": "a"}}}), map[string]bool{
+			"slice of slices": true,
+		}},
+		{at(map[string]any{"$size": 2}), map[string]bool{
+			"slice of struct pointers": true, "array of maps": true, "slice of slices": true,
+		}},
+		{map[string]any{"x": map[string]any{"missing": map[string]any{"$exists": false}}}, map[string]bool{
+			"map of structs": true, "map of struct pointers": true, "map with named keys": true,
+			"map of structs with named keys": true, "pointer to map": true, "map of pointers to pointers": true,
+			"map of typed maps": true, "slice of structs": true, "slice of struct pointers": true,
+			"pointer to slice": true, "named slice": true, "array of maps": true, "slice of maps of structs": true,
+			"slice of slices": true,
+		}},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range conditions {
+			m, err := NewMatcher(tc.condition)
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			for _, shape := range shapes {
+				got, err := m.Match(map[string]any{"x": shape.x})
+				if err != nil || got != tc.want[shape.name] {
+					t.Errorf("%v: %s: Match with %v = %v, %v; want %v", mode, shape.name, tc.condition, got, err, tc.want[shape.name])
+				}
+			}
+			m.Close()
+		}
+	}
+
+}
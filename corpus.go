@@ -0,0 +1,307 @@
+package mongory
+
+import (
+	"bufio"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// CorpusCase is one recorded match: a condition, a document and whether the
+// document matched.
+type CorpusCase struct {
+	Condition map[string]any `json:"condition"`
+	Document  any            `json:"document"`
+	Matched   bool           `json:"matched"`
+}
+
+// CorpusOptions configure a CorpusRecorder.
+type CorpusOptions struct {
+	// SampleRate is the fraction of matches Match records, between 0 and 1.
+	// Zero records every match.
+	SampleRate float64
+	// Salt keys the hash that redacts strings, so the same string redacts
+	// to the same token in every case of a corpus recorded with the salt.
+	// Nil uses a random salt of the recorder's own.
+	Salt []byte
+}
+
+// CorpusRecorder writes sampled matches as CorpusCase JSON lines, with the
+// values of conditions and documents redacted, for ReplayCorpus to check
+// after an engine upgrade. Field names and operators are kept. Strings are
+// replaced by keyed hashes, so equal strings stay equal, and numbers by their
+// rank among the numbers of the case, so comparisons keep their results.
+// Redaction can change the result, for instance of a regular expression or
+// $mod; such cases are dropped and counted as skipped, so every case written
+// matches as it did when recorded. Cases replay with default MatcherOptions.
+// A CorpusRecorder is safe for concurrent use.
+type CorpusRecorder struct {
+	w    io.Writer
+	rate float64
+	salt []byte
+
+	mu       sync.Mutex // serializes writes
+	recorded atomic.Int64
+	skipped  atomic.Int64
+	failed   atomic.Int64
+}
+
+// NewCorpusRecorder returns a recorder writing to w.
+func NewCorpusRecorder(w io.Writer, opts CorpusOptions) *CorpusRecorder {
+	salt := opts.Salt
+	if salt == nil {
+		salt = make([]byte, 32)
+		_, _ = cryptorand.Read(salt)
+	}
+	return &CorpusRecorder{w: w, rate: opts.SampleRate, salt: salt}
+}
+
+// Match matches doc with m and records the result if it is sampled. Errors
+// recording it are counted by Stats rather than returned, so recording never
+// fails a match.
+func (r *CorpusRecorder) Match(m Matcher, doc any) (bool, error) {
+	matched, err := m.Match(doc)
+	if err != nil || (r.rate > 0 && rand.Float64() >= r.rate) {
+		return matched, err
+	}
+	if err := r.Record(m.Condition(), doc, matched); err != nil && !errors.Is(err, errCorpusUnreproducible) {
+		r.failed.Add(1)
+	}
+	return matched, nil
+}
+
+var errCorpusUnreproducible = errors.New("mongory: redacted case does not reproduce its result")
+
+// Record redacts condition and doc and writes them with matched, unless the
+// redacted case no longer gives matched, which it reports as an error.
+func (r *CorpusRecorder) Record(condition Condition, doc any, matched bool) error {
+	c, err := r.redact(condition, doc, matched)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// Check the case as ReplayCorpus will read it.
+	if c, err = parseCorpusCase(line); err != nil {
+		return err
+	}
+	got, err := c.replay()
+	if err != nil {
+		return err
+	}
+	if got != matched {
+		r.skipped.Add(1)
+		return errCorpusUnreproducible
+	}
+	line = append(line, '\n')
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.w.Write(line); err != nil {
+		return err
+	}
+	r.recorded.Add(1)
+	return nil
+}
+
+// CorpusStats are a recorder's counts.
+type CorpusStats struct {
+	// Recorded counts the cases written, Skipped those dropped because
+	// redaction changed their result, and Failed those Match could not
+	// record for other reasons, such as a failed write.
+	Recorded int64
+	Skipped  int64
+	Failed   int64
+}
+
+// Stats returns the recorder's counts.
+func (r *CorpusRecorder) Stats() CorpusStats {
+	return CorpusStats{Recorded: r.recorded.Load(), Skipped: r.skipped.Load(), Failed: r.failed.Load()}
+}
+
+// redact returns the case with its values redacted, converted to plain JSON
+// values the way it will be read back.
+func (r *CorpusRecorder) redact(condition Condition, doc any, matched bool) (CorpusCase, error) {
+	expanded, err := expandMacros(condition.Map())
+	if err != nil {
+		return CorpusCase{}, err
+	}
+	plainCondition, err := ParseConditionJSON(CanonicalJSON(expanded))
+	if err != nil {
+		return CorpusCase{}, err
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return CorpusCase{}, fmt.Errorf("mongory: cannot record document: %w", err)
+	}
+	plainDoc, err := ParseDocumentJSON(encoded)
+	if err != nil {
+		return CorpusCase{}, err
+	}
+	red := &redactor{mac: hmac.New(sha256.New, r.salt)}
+	red.collect(plainCondition)
+	red.collect(plainDoc)
+	red.rankNumbers()
+	return CorpusCase{
+		Condition: red.value(plainCondition).(map[string]any),
+		Document:  red.value(plainDoc),
+		Matched:   matched,
+	}, nil
+}
+
+// redactOperands are operators whose operands describe the shape of a value
+// rather than hold one, and are kept.
+var redactOperands = map[string]bool{"$type": true, "$size": true, "$mod": true, "$options": true, "$exists": true}
+
+// redactor redacts the values of one case.
+type redactor struct {
+	mac     hash.Hash
+	numbers []float64
+	ranks   map[float64]int
+}
+
+// collect notes the numbers of v, outside kept operands.
+func (red *redactor) collect(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if !redactOperands[key] {
+				red.collect(item)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			red.collect(item)
+		}
+	case int64:
+		red.numbers = append(red.numbers, float64(v))
+	case float64:
+		red.numbers = append(red.numbers, v)
+	}
+}
+
+func (red *redactor) rankNumbers() {
+	sort.Float64s(red.numbers)
+	red.ranks = make(map[float64]int, len(red.numbers))
+	for _, n := range red.numbers {
+		if _, ok := red.ranks[n]; !ok {
+			red.ranks[n] = len(red.ranks)
+		}
+	}
+}
+
+// value returns v with strings hashed and numbers replaced by their rank,
+// keeping whether each number was an integer.
+func (red *redactor) value(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			if redactOperands[key] {
+				out[key] = item
+			} else {
+				out[key] = red.value(item)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = red.value(item)
+		}
+		return out
+	case string:
+		if v == "" {
+			return v
+		}
+		red.mac.Reset()
+		red.mac.Write([]byte(v))
+		return "r" + hex.EncodeToString(red.mac.Sum(nil)[:8])
+	case int64:
+		return int64(red.ranks[float64(v)])
+	case float64:
+		return float64(red.ranks[v])
+	default:
+		return v
+	}
+}
+
+// replay matches the case, as ReplayCorpus does.
+func (c CorpusCase) replay() (bool, error) {
+	m, err := NewMatcher(c.Condition)
+	if err != nil {
+		return false, err
+	}
+	defer m.Close()
+	return m.Match(c.Document)
+}
+
+// CorpusMismatch is a corpus case that no longer gives its recorded result.
+type CorpusMismatch struct {
+	Line int
+	Case CorpusCase
+	Got  bool
+	// Err is the error compiling or matching the case, if any.
+	Err error
+}
+
+// CorpusReport is the outcome of ReplayCorpus.
+type CorpusReport struct {
+	Cases      int
+	Mismatches []CorpusMismatch
+}
+
+// ReplayCorpus matches every case of a corpus written by a CorpusRecorder
+// and reports those whose result differs from the recorded one. It fails
+// only if the corpus cannot be read.
+func ReplayCorpus(r io.Reader) (CorpusReport, error) {
+	var report CorpusReport
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		c, err := parseCorpusCase(scanner.Bytes())
+		if err != nil {
+			return report, fmt.Errorf("mongory: corpus line %d: %w", line, err)
+		}
+		report.Cases++
+		got, err := c.replay()
+		if err != nil || got != c.Matched {
+			report.Mismatches = append(report.Mismatches, CorpusMismatch{Line: line, Case: c, Got: got, Err: err})
+		}
+	}
+	return report, scanner.Err()
+}
+
+func parseCorpusCase(data []byte) (CorpusCase, error) {
+	var raw struct {
+		Condition json.RawMessage `json:"condition"`
+		Document  json.RawMessage `json:"document"`
+		Matched   bool            `json:"matched"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return CorpusCase{}, err
+	}
+	condition, err := ParseConditionJSON(raw.Condition)
+	if err != nil {
+		return CorpusCase{}, err
+	}
+	doc, err := ParseDocumentJSON(raw.Document)
+	if err != nil {
+		return CorpusCase{}, err
+	}
+	return CorpusCase{Condition: condition, Document: doc, Matched: raw.Matched}, nil
+}
@@ -0,0 +1,91 @@
+package mongory
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCorpusRecorder(t *testing.T) {
+	var corpus bytes.Buffer
+	r := NewCorpusRecorder(&corpus, CorpusOptions{Salt: []byte("test")})
+	m, err := NewMatcher(map[string]any{
+		"email": "ada@example.com",
+		"age":   map[string]any{"$gte": 18, "$lt": 65},
+		"tags":  map[string]any{"$in": []any{"admin", "ops"}},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	docs := []map[string]any{
+		{"email": "ada@example.com", "age": 36, "tags": []any{"ops"}},
+		{"email": "ada@example.com", "age": 70, "tags": []any{"ops"}},
+		{"email": "bob@example.com", "age": 36.5, "tags": []any{"dev"}},
+	}
+	for _, doc := range docs {
+		if _, err := r.Match(m, doc); err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+	}
+	if s := r.Stats(); s != (CorpusStats{Recorded: 3}) {
+		t.Fatalf("Stats = %+v, want 3 recorded", s)
+	}
+	for _, secret := range []string{"ada", "example", "admin", ":36", ":70"} {
+		if strings.Contains(corpus.String(), secret) {
+			t.Fatalf("corpus leaks %q:\n%s", secret, corpus.String())
+		}
+	}
+
+	report, err := ReplayCorpus(bytes.NewReader(corpus.Bytes()))
+	if err != nil {
+		t.Fatalf("ReplayCorpus failed: %v", err)
+	}
+	if report.Cases != 3 || len(report.Mismatches) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// A regular expression no longer matches its redacted input.
+	re, err := NewMatcher(map[string]any{"name": regexp.MustCompile("^a")})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer re.Close()
+	if ok, err := r.Match(re, map[string]any{"name": "ada"}); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+	if s := r.Stats(); s.Recorded != 3 || s.Skipped != 1 || s.Failed != 0 {
+		t.Fatalf("Stats = %+v, want the regex case skipped", s)
+	}
+
+	tampered := strings.Replace(corpus.String(), `"matched":true`, `"matched":false`, 1)
+	report, err = ReplayCorpus(strings.NewReader(tampered))
+	if err != nil {
+		t.Fatalf("ReplayCorpus failed: %v", err)
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].Line != 1 || !report.Mismatches[0].Got {
+		t.Fatalf("report = %+v, want line 1 to mismatch", report)
+	}
+	if _, err := ReplayCorpus(strings.NewReader("{\n")); err == nil {
+		t.Fatalf("ReplayCorpus should reject malformed lines")
+	}
+}
+
+func TestCorpusSampleRate(t *testing.T) {
+	var corpus bytes.Buffer
+	r := NewCorpusRecorder(&corpus, CorpusOptions{SampleRate: 1e-9})
+	m, err := NewMatcher(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	for i := 0; i < 100; i++ {
+		if _, err := r.Match(m, map[string]any{"a": i}); err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+	}
+	if s := r.Stats(); s.Recorded != 0 || corpus.Len() != 0 {
+		t.Fatalf("Stats = %+v with a tiny sample rate", s)
+	}
+}
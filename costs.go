@@ -0,0 +1,226 @@
+package mongory
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// OperatorBenchmark times one operator in isolation: Condition is compiled
+// once and matched against each of Documents in turn.
+type OperatorBenchmark struct {
+	Operator  string
+	Case      string
+	Condition map[string]any
+	Documents []any
+}
+
+// OperatorCost is the measured cost of one OperatorBenchmark.
+type OperatorCost struct {
+	Operator string        `json:"operator"`
+	Case     string        `json:"case"`
+	PerMatch time.Duration `json:"nsPerMatch"`
+	// Selectivity is the fraction of the benchmark's documents that matched.
+	Selectivity float64 `json:"selectivity"`
+}
+
+// CostTable is a list of measured operator costs, as MeasureOperators
+// returns it. It encodes to JSON, so a table measured once on the target
+// machine can be saved and loaded with SetOperatorCosts at startup.
+type CostTable []OperatorCost
+
+// OperatorBenchmarks returns the default benchmark suite: the built-in
+// operators with typical operands, and $in with lists of several sizes.
+func OperatorBenchmarks() []OperatorBenchmark {
+	numbers := make([]any, 256)
+	strs := make([]any, 256)
+	lists := make([]any, 256)
+	for i := range numbers {
+		numbers[i] = map[string]any{"v": i}
+		strs[i] = map[string]any{"v": "user-" + strconv.Itoa(i) + "@example.com"}
+		lists[i] = map[string]any{"v": []any{i, i + 1, i + 2, i + 3}}
+	}
+	op := func(name string, operand any) map[string]any {
+		return map[string]any{"v": map[string]any{name: operand}}
+	}
+	inList := func(n int) []any {
+		items := make([]any, n)
+		for i := range items {
+			items[i] = i * 3
+		}
+		return items
+	}
+	benchmarks := []OperatorBenchmark{
+		{"$eq", "number", op("$eq", 128), numbers},
+		{"$eq", "string", op("$eq", "user-128@example.com"), strs},
+		{"$ne", "number", op("$ne", 128), numbers},
+		{"$gt", "number", op("$gt", 128), numbers},
+		{"$gte", "number", op("$gte", 128), numbers},
+		{"$lt", "number", op("$lt", 128), numbers},
+		{"$lte", "number", op("$lte", 128), numbers},
+		{"$exists", "present", op("$exists", true), numbers},
+		{"$nin", "10 items", op("$nin", inList(10)), numbers},
+		{"$regex", "anchored", op("$regex", "^user-1"), strs},
+		{"$regex", "unanchored", op("$regex", `\d+@example\.com$`), strs},
+		{"$glob", "suffix", op("$glob", "*@example.com"), strs},
+		{"$rollout", "50%", op("$rollout", 50), strs},
+		{"$size", "number", op("$size", 4), lists},
+		{"$elemMatch", "comparison", op("$elemMatch", map[string]any{"$gt": 200}), lists},
+		{"$every", "comparison", op("$every", map[string]any{"$gt": 10}), lists},
+		{"$not", "comparison", op("$not", map[string]any{"$gt": 128}), numbers},
+		{"$and", "2 conditions", map[string]any{"$and": []any{op("$gt", 10), op("$lt", 200)}}, numbers},
+		{"$or", "2 conditions", map[string]any{"$or": []any{op("$lt", 10), op("$gt", 200)}}, numbers},
+	}
+	for _, n := range []int{10, 1000, 10000} {
+		benchmarks = append(benchmarks, OperatorBenchmark{"$in", strconv.Itoa(n) + " items", op("$in", inList(n)), numbers})
+	}
+	return benchmarks
+}
+
+// MeasureOperators runs each benchmark for at least minTime, 100ms if zero
+// or less, and reports its cost per match. Benchmarks run one after another
+// in the calling goroutine, in the current conversion and batch modes.
+func MeasureOperators(benchmarks []OperatorBenchmark, minTime time.Duration) (CostTable, error) {
+	if minTime <= 0 {
+		minTime = 100 * time.Millisecond
+	}
+	table := make(CostTable, 0, len(benchmarks))
+	for _, b := range benchmarks {
+		cost, err := measureOperator(b, minTime)
+		if err != nil {
+			return nil, fmt.Errorf("mongory: benchmark %s %s: %w", b.Operator, b.Case, err)
+		}
+		table = append(table, cost)
+	}
+	return table, nil
+}
+
+func measureOperator(b OperatorBenchmark, minTime time.Duration) (OperatorCost, error) {
+	if len(b.Documents) == 0 {
+		return OperatorCost{}, fmt.Errorf("no documents")
+	}
+	m, err := NewMatcher(b.Condition)
+	if err != nil {
+		return OperatorCost{}, err
+	}
+	defer m.Close()
+	matched := 0
+	for _, doc := range b.Documents {
+		ok, err := m.Match(doc)
+		if err != nil {
+			return OperatorCost{}, err
+		}
+		if ok {
+			matched++
+		}
+	}
+	var runs int
+	start := time.Now()
+	for time.Since(start) < minTime {
+		if err := m.FilterFunc(b.Documents, func(int, any) bool { return true }); err != nil {
+			return OperatorCost{}, err
+		}
+		runs++
+	}
+	return OperatorCost{
+		Operator:    b.Operator,
+		Case:        b.Case,
+		PerMatch:    time.Since(start) / time.Duration(runs*len(b.Documents)),
+		Selectivity: float64(matched) / float64(len(b.Documents)),
+	}, nil
+}
+
+// Cost returns the mean cost per match of operator over its cases.
+func (t CostTable) Cost(operator string) (time.Duration, bool) {
+	var total time.Duration
+	var n int
+	for _, c := range t {
+		if c.Operator == operator {
+			total += c.PerMatch
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return total / time.Duration(n), true
+}
+
+// WriteText writes the table with aligned columns, sorted by operator and
+// then from cheapest to most expensive.
+func (t CostTable) WriteText(w io.Writer) error {
+	sorted := append(CostTable(nil), t...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Operator != sorted[j].Operator {
+			return sorted[i].Operator < sorted[j].Operator
+		}
+		return sorted[i].PerMatch < sorted[j].PerMatch
+	})
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operator\tcase\tper match\tselectivity\t")
+	for _, c := range sorted {
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%.2f\t\n", c.Operator, c.Case, c.PerMatch, c.Selectivity)
+	}
+	return tw.Flush()
+}
+
+var operatorCosts atomic.Pointer[CostTable]
+
+// SetOperatorCosts gives CompileRules a cost table to plan with: the
+// predicates and groups of every $and and $or are evaluated from cheapest to
+// most expensive, so their short-circuit skips the costly ones. Operators
+// missing from the table cost as much as its dearest one. A nil table, the
+// default, keeps the order the rules were compiled in.
+func SetOperatorCosts(table CostTable) {
+	if table == nil {
+		operatorCosts.Store(nil)
+		return
+	}
+	table = append(CostTable(nil), table...)
+	operatorCosts.Store(&table)
+}
+
+// conditionCost estimates the cost of matching condition under table: the
+// sum of its operators' costs, with literal field values costing as $eq.
+func (t CostTable) conditionCost(condition any) time.Duration {
+	table, ok := condition.(map[string]any)
+	if !ok {
+		return 0
+	}
+	var total time.Duration
+	for key, value := range table {
+		switch {
+		case !strings.HasPrefix(key, "$"):
+			if _, nested := value.(map[string]any); nested {
+				total += t.conditionCost(value)
+			} else {
+				total += t.operatorCost("$eq")
+			}
+		case key == "$and" || key == "$or":
+			total += t.operatorCost(key)
+			list, _ := value.([]any)
+			for _, item := range list {
+				total += t.conditionCost(item)
+			}
+		default:
+			total += t.operatorCost(key) + t.conditionCost(value)
+		}
+	}
+	return total
+}
+
+func (t CostTable) operatorCost(operator string) time.Duration {
+	if cost, ok := t.Cost(operator); ok {
+		return cost
+	}
+	var highest time.Duration
+	for _, c := range t {
+		highest = max(highest, c.PerMatch)
+	}
+	return highest
+}
@@ -0,0 +1,86 @@
+package mongory
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeasureOperators(t *testing.T) {
+	benchmarks := OperatorBenchmarks()
+	table, err := MeasureOperators(benchmarks, time.Millisecond)
+	if err != nil {
+		t.Fatalf("MeasureOperators failed: %v", err)
+	}
+	if len(table) != len(benchmarks) {
+		t.Fatalf("got %d costs, want %d", len(table), len(benchmarks))
+	}
+	for _, c := range table {
+		if c.PerMatch <= 0 || c.Selectivity < 0 || c.Selectivity > 1 {
+			t.Fatalf("implausible cost %+v", c)
+		}
+	}
+	if _, ok := table.Cost("$in"); !ok {
+		t.Fatalf("Cost($in) missing")
+	}
+	if _, ok := table.Cost("$unknown"); ok {
+		t.Fatalf("Cost($unknown) reported a cost")
+	}
+
+	var text bytes.Buffer
+	if err := table.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(text.String()), "\n"); len(lines) != len(table)+1 || !strings.Contains(lines[0], "per match") {
+		t.Fatalf("WriteText wrote:\n%s", text.String())
+	}
+	encoded, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded CostTable
+	if err := json.Unmarshal(encoded, &decoded); err != nil || !reflect.DeepEqual(decoded, table) {
+		t.Fatalf("JSON round trip = %v, %v", decoded, err)
+	}
+
+	if _, err := MeasureOperators([]OperatorBenchmark{{Operator: "$eq", Condition: map[string]any{"a": 1}}}, time.Millisecond); err == nil {
+		t.Fatalf("MeasureOperators without documents succeeded")
+	}
+}
+
+func TestOperatorCostPlanning(t *testing.T) {
+	defer SetOperatorCosts(nil)
+	var calls int
+	rule := Rule{ID: "r", Condition: map[string]any{
+		"$func": func(any) bool { calls++; return true },
+		"a":     1,
+	}}
+	matchRule := func() {
+		t.Helper()
+		rules, err := CompileRules(rule)
+		if err != nil {
+			t.Fatalf("CompileRules failed: %v", err)
+		}
+		defer rules.Close()
+		calls = 0
+		if ids, err := rules.Match(map[string]any{"a": 2}); err != nil || len(ids) != 0 {
+			t.Fatalf("Match = %v, %v; want no rules", ids, err)
+		}
+	}
+
+	matchRule()
+	if calls != 1 {
+		t.Fatalf("without costs $func was called %d times, want 1", calls)
+	}
+	SetOperatorCosts(CostTable{
+		{Operator: "$eq", PerMatch: 100 * time.Nanosecond},
+		{Operator: "$func", PerMatch: 10 * time.Microsecond},
+	})
+	matchRule()
+	if calls != 0 {
+		t.Fatalf("with costs $func was called %d times, want 0", calls)
+	}
+}
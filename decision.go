@@ -0,0 +1,340 @@
+package mongory
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule is a condition identified within a RuleSet.
+type Rule struct {
+	ID        string
+	Condition map[string]any
+}
+
+// RuleSet evaluates many conditions against a document at once. The
+// conditions are split into predicates, each a single field with one
+// operator (or one literal), and merged into a decision DAG in which equal
+// predicates, and equal $and and $or groups of them, are shared. A document
+// is matched against each shared predicate at most once, and only when a
+// rule still needs its result, so rule sets with heavy overlap cost far
+// less than a matcher per rule. It is safe for concurrent use.
+type RuleSet struct {
+	ids   []string
+	roots []int
+	nodes []decisionNode
+	memo  sync.Pool
+
+	predicates int
+}
+
+type decisionKind uint8
+
+const (
+	predicateNode decisionKind = iota
+	allNode
+	anyNode
+)
+
+type decisionNode struct {
+	kind      decisionKind
+	predicate Matcher
+	children  []int
+}
+
+// Results of a node in one evaluation; zero means not evaluated yet.
+const (
+	memoTrue uint8 = iota + 1
+	memoFalse
+)
+
+// CompileRules compiles rules into a RuleSet. Rule IDs must be unique and
+// not empty, and any invalid condition fails the whole call.
+func CompileRules(rules ...Rule) (*RuleSet, error) {
+	if err := InitE(); err != nil {
+		return nil, err
+	}
+	b := &dagBuilder{set: &RuleSet{}, index: map[string]int{}, costs: operatorCosts.Load()}
+	seen := make(map[string]bool, len(rules))
+	// Every rule folds $ago against the same instant.
+	now := time.Now()
+	for _, rule := range rules {
+		if rule.ID == "" {
+			b.set.Close()
+			return nil, fmt.Errorf("mongory: rule ID must not be empty")
+		}
+		if seen[rule.ID] {
+			b.set.Close()
+			return nil, fmt.Errorf("mongory: duplicate rule ID %q", rule.ID)
+		}
+		seen[rule.ID] = true
+		condition, err := expandMacros(rule.Condition)
+		if err == nil {
+			condition, err = foldConstants(condition, now)
+		}
+		if err == nil {
+			var root int
+			normalized, _ := snapshotCondition(reflect.ValueOf(condition)).(map[string]any)
+			if root, err = b.condition(normalized); err == nil {
+				b.set.ids = append(b.set.ids, rule.ID)
+				b.set.roots = append(b.set.roots, root)
+				continue
+			}
+		}
+		b.set.Close()
+		return nil, fmt.Errorf("mongory: rule %q: %w", rule.ID, err)
+	}
+	nodes := len(b.set.nodes)
+	b.set.memo.New = func() any {
+		memo := make([]uint8, nodes)
+		return &memo
+	}
+	return b.set, nil
+}
+
+// Match returns the IDs of the rules doc satisfies, in the order the rules
+// were compiled.
+func (s *RuleSet) Match(doc any) ([]string, error) {
+	var ids []string
+	err := s.match(doc, func(i int) {
+		ids = append(ids, s.ids[i])
+	})
+	return ids, err
+}
+
+// MatchIndices is Match reporting positions in the compiled rule list.
+func (s *RuleSet) MatchIndices(doc any) ([]int, error) {
+	var indices []int
+	err := s.match(doc, func(i int) {
+		indices = append(indices, i)
+	})
+	return indices, err
+}
+
+func (s *RuleSet) match(doc any, onMatch func(i int)) error {
+	memo := s.memo.Get().(*[]uint8)
+	defer func() {
+		clear(*memo)
+		s.memo.Put(memo)
+	}()
+	for i, root := range s.roots {
+		matched, err := s.eval(root, doc, *memo)
+		if err != nil {
+			return fmt.Errorf("mongory: rule %q: %w", s.ids[i], err)
+		}
+		if matched {
+			onMatch(i)
+		}
+	}
+	return nil
+}
+
+func (s *RuleSet) eval(id int, doc any, memo []uint8) (bool, error) {
+	switch memo[id] {
+	case memoTrue:
+		return true, nil
+	case memoFalse:
+		return false, nil
+	}
+	node := &s.nodes[id]
+	var result bool
+	switch node.kind {
+	case predicateNode:
+		matched, err := node.predicate.Match(doc)
+		if err != nil {
+			return false, err
+		}
+		result = matched
+	case allNode, anyNode:
+		// $and stops at the first false child and $or at the first true one.
+		stop := node.kind == anyNode
+		result = !stop
+		for _, child := range node.children {
+			matched, err := s.eval(child, doc, memo)
+			if err != nil {
+				return false, err
+			}
+			if matched == stop {
+				result = stop
+				break
+			}
+		}
+	}
+	if result {
+		memo[id] = memoTrue
+	} else {
+		memo[id] = memoFalse
+	}
+	return result, nil
+}
+
+// Len returns the number of rules.
+func (s *RuleSet) Len() int {
+	return len(s.ids)
+}
+
+// Predicates returns the number of distinct predicates the rules share,
+// which is at most the number of matches one document costs.
+func (s *RuleSet) Predicates() int {
+	return s.predicates
+}
+
+// Close releases the native memory of every predicate.
+func (s *RuleSet) Close() error {
+	for _, node := range s.nodes {
+		if node.predicate != nil {
+			node.predicate.Close()
+		}
+	}
+	return nil
+}
+
+// dagBuilder hash-conses nodes while compiling rules, so equal predicates
+// and groups are built once. With a cost table, it also estimates what each
+// node costs, and orders group children from cheapest to most expensive.
+type dagBuilder struct {
+	set   *RuleSet
+	index map[string]int
+	costs *CostTable
+	cost  []time.Duration
+}
+
+// condition builds the node for a normalized condition: the conjunction of
+// its keys.
+func (b *dagBuilder) condition(condition map[string]any) (int, error) {
+	if len(condition) == 0 {
+		return b.predicate(condition)
+	}
+	children := make([]int, 0, len(condition))
+	for _, key := range sortedKeys(condition) {
+		child, err := b.entry(key, condition[key])
+		if err != nil {
+			return 0, err
+		}
+		children = append(children, child)
+	}
+	return b.group(allNode, children), nil
+}
+
+func (b *dagBuilder) entry(key string, value any) (int, error) {
+	switch {
+	case key == "$and" || key == "$or":
+		branches, ok := value.([]any)
+		if !ok || len(branches) == 0 {
+			// Left to the core, which rejects or decides it.
+			return b.predicate(map[string]any{key: value})
+		}
+		children := make([]int, 0, len(branches))
+		for _, branch := range branches {
+			condition, ok := branch.(map[string]any)
+			if !ok {
+				return b.predicate(map[string]any{key: value})
+			}
+			child, err := b.condition(condition)
+			if err != nil {
+				return 0, err
+			}
+			children = append(children, child)
+		}
+		kind := allNode
+		if key == "$or" {
+			kind = anyNode
+		}
+		return b.group(kind, children), nil
+	case strings.HasPrefix(key, "$"):
+		return b.predicate(map[string]any{key: value})
+	}
+	operators, ok := value.(map[string]any)
+	if !ok || len(operators) == 0 {
+		return b.predicate(map[string]any{key: value})
+	}
+	// Operators on a field apply to its value independently and split into
+	// their own predicates. Subfield conditions and $elemMatch stay
+	// together, since on arrays one element must satisfy all of them.
+	var children []int
+	together := map[string]any{}
+	for _, op := range sortedKeys(operators) {
+		if !strings.HasPrefix(op, "$") || op == "$elemMatch" {
+			together[op] = operators[op]
+			continue
+		}
+		child, err := b.predicate(map[string]any{key: map[string]any{op: operators[op]}})
+		if err != nil {
+			return 0, err
+		}
+		children = append(children, child)
+	}
+	if len(together) > 0 {
+		child, err := b.predicate(map[string]any{key: together})
+		if err != nil {
+			return 0, err
+		}
+		children = append(children, child)
+	}
+	return b.group(allNode, children), nil
+}
+
+func (b *dagBuilder) predicate(condition map[string]any) (int, error) {
+	key := "p" + string(CanonicalJSON(condition))
+	if id, ok := b.index[key]; ok {
+		return id, nil
+	}
+	m, err := newMatcher(condition, nil)
+	if err != nil {
+		return 0, err
+	}
+	b.set.predicates++
+	var cost time.Duration
+	if b.costs != nil {
+		cost = b.costs.conditionCost(condition)
+	}
+	return b.add(key, decisionNode{kind: predicateNode, predicate: m}, cost), nil
+}
+
+func (b *dagBuilder) group(kind decisionKind, children []int) int {
+	slices.Sort(children)
+	children = slices.Compact(children)
+	if len(children) == 1 {
+		return children[0]
+	}
+	var key strings.Builder
+	key.WriteString(strconv.Itoa(int(kind)))
+	for _, child := range children {
+		key.WriteByte(',')
+		key.WriteString(strconv.Itoa(child))
+	}
+	if id, ok := b.index[key.String()]; ok {
+		return id
+	}
+	var cost time.Duration
+	if b.costs != nil {
+		for _, child := range children {
+			cost += b.cost[child]
+		}
+		slices.SortStableFunc(children, func(x, y int) int { return cmp.Compare(b.cost[x], b.cost[y]) })
+	}
+	return b.add(key.String(), decisionNode{kind: kind, children: children}, cost)
+}
+
+func (b *dagBuilder) add(key string, node decisionNode, cost time.Duration) int {
+	id := len(b.set.nodes)
+	b.set.nodes = append(b.set.nodes, node)
+	b.cost = append(b.cost, cost)
+	b.index[key] = id
+	return id
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package mongory
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func decisionRules() []Rule {
+	return []Rule{
+		{ID: "adult", Condition: map[string]any{"age": map[string]any{"$gte": 18}}},
+		{ID: "working-age", Condition: map[string]any{"age": map[string]any{"$gte": 18, "$lt": 65}}},
+		{ID: "adult-go", Condition: map[string]any{"age": map[string]any{"$gte": 18}, "tags": "go"}},
+		{ID: "vip-or-staff", Condition: map[string]any{"$or": []any{
+			map[string]any{"vip": true},
+			map[string]any{"email": map[string]any{"$regex": regexp.MustCompile("@example\\.com$")}},
+		}}},
+		{ID: "vip-adult", Condition: map[string]any{"$and": []any{
+			map[string]any{"$or": []any{
+				map[string]any{"email": map[string]any{"$regex": regexp.MustCompile("@example\\.com$")}},
+				map[string]any{"vip": true},
+			}},
+			map[string]any{"age": map[string]any{"$gte": 18.0}},
+		}}},
+		{ID: "orders", Condition: map[string]any{"orders": map[string]any{"$size": 2, "$elemMatch": map[string]any{"total": map[string]any{"$gt": 100}}}}},
+		{ID: "everyone", Condition: map[string]any{}},
+		{ID: "no-email", Condition: map[string]any{"email": nil}},
+		{ID: "other-regex", Condition: map[string]any{"email": map[string]any{"$regex": regexp.MustCompile("@example\\.org$")}}},
+	}
+}
+
+func TestRuleSet(t *testing.T) {
+	rules := decisionRules()
+	set, err := CompileRules(rules...)
+	if err != nil {
+		t.Fatalf("CompileRules failed: %v", err)
+	}
+	defer set.Close()
+	// age >= 18 (shared by four rules, 18 and 18.0 alike), age < 65, tags,
+	// vip, each regex, orders $size and $elemMatch, {} and email null.
+	if got := set.Predicates(); got != 10 {
+		t.Fatalf("Predicates() = %d, want 10", got)
+	}
+	if set.Len() != len(rules) {
+		t.Fatalf("Len() = %d, want %d", set.Len(), len(rules))
+	}
+
+	docs := []any{
+		map[string]any{"age": 30, "tags": []any{"go"}, "email": "a@example.com"},
+		map[string]any{"age": 70, "vip": true, "orders": []any{map[string]any{"total": 150}, map[string]any{"total": 5}}},
+		map[string]any{"age": 10, "email": "k@example.org"},
+		map[string]any{"orders": []any{map[string]any{"total": 150}}},
+		map[string]any{},
+	}
+	for _, doc := range docs {
+		var want []string
+		for _, rule := range rules {
+			m, err := NewCMatcher(rule.Condition, nil)
+			if err != nil {
+				t.Fatalf("NewMatcher(%s) failed: %v", rule.ID, err)
+			}
+			if matched, err := m.Match(doc); err != nil {
+				t.Fatalf("%s: Match failed: %v", rule.ID, err)
+			} else if matched {
+				want = append(want, rule.ID)
+			}
+			m.Close()
+		}
+		got, err := set.Match(doc)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("Match(%v) = %v, %v; want %v", doc, got, err, want)
+		}
+	}
+	if got, err := set.MatchIndices(docs[2]); err != nil || !reflect.DeepEqual(got, []int{6, 8}) {
+		t.Fatalf("MatchIndices = %v, %v; want [6 8]", got, err)
+	}
+
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	SetNilDocumentMode(NilDocumentError)
+	if _, err := set.Match(nil); err == nil || !strings.Contains(err.Error(), `rule "adult"`) {
+		t.Fatalf("Match(nil) error = %v", err)
+	}
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	cases := []struct {
+		rules []Rule
+		want  string
+	}{
+		{[]Rule{{Condition: map[string]any{}}}, "must not be empty"},
+		{[]Rule{{ID: "a"}, {ID: "a"}}, `duplicate rule ID "a"`},
+		{[]Rule{{ID: "a"}, {ID: "bad", Condition: map[string]any{"$and": "x"}}}, `rule "bad"`},
+		{[]Rule{{ID: "macro", Condition: map[string]any{"$macro": "missing"}}}, `undefined macro`},
+	}
+	for _, tc := range cases {
+		if _, err := CompileRules(tc.rules...); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("CompileRules(%v) error = %v, want %q", tc.rules, err, tc.want)
+		}
+	}
+}
+
+// overlappingRules models a rule set where most rules combine a few common
+// predicates with one of their own.
+func overlappingRules(n int) []Rule {
+	rules := make([]Rule, n)
+	for i := range rules {
+		rules[i] = Rule{ID: fmt.Sprint("rule-", i), Condition: map[string]any{
+			"age":     map[string]any{"$gte": 18},
+			"country": map[string]any{"$in": []any{"TW", "JP", "US"}},
+			"plan":    []any{"free", "pro", "team"}[i%3],
+			"score":   map[string]any{"$gt": i % 10},
+		}}
+	}
+	return rules
+}
+
+func BenchmarkRuleSet(b *testing.B) {
+	rules := overlappingRules(100)
+	doc := map[string]any{"age": 30, "country": "TW", "plan": "pro", "score": 5}
+	b.Run("dag", func(b *testing.B) {
+		set, err := CompileRules(rules...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer set.Close()
+		for b.Loop() {
+			if _, err := set.Match(doc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("separate", func(b *testing.B) {
+		matchers := make([]Matcher, len(rules))
+		for i, rule := range rules {
+			m, err := NewCMatcher(rule.Condition, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer m.Close()
+			matchers[i] = m
+		}
+		for b.Loop() {
+			for _, m := range matchers {
+				if _, err := m.Match(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package mongory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// ErrConditionDepth is wrapped, along with ErrInvalidCondition, by the error
+// for a condition nested deeper than SetConditionDepthLimit allows.
+var ErrConditionDepth = errors.New("mongory: condition nested too deeply")
+
+var conditionDepthLimit atomic.Int64
+
+func init() {
+	conditionDepthLimit.Store(1000)
+}
+
+// SetConditionDepthLimit bounds how deeply conditions compiled from now on
+// may nest maps and lists; the condition itself is level 1. The core
+// compiles and matches conditions recursively on the native stack, which a
+// condition nested tens of thousands of levels deep overflows, crashing the
+// process, so services compiling untrusted conditions should keep a limit.
+// The default is 1000. Zero or less removes the limit.
+//
+// The limit applies after nested $and and $or are flattened, so
+// {"$and": [{"$and": [a, b]}, c]} counts as {"$and": [a, b, c]}.
+//
+// Matching itself cannot be made iterative from here: each core matcher
+// calls its children's match functions directly, and the core is built from
+// its sources unchanged. This package instead shortens the recursion, by
+// flattening $and and $or chains and sharing the array matchers of nested
+// fields, and bounds the depth that remains with this limit.
+func SetConditionDepthLimit(depth int) {
+	conditionDepthLimit.Store(int64(max(depth, 0)))
+}
+
+// ConditionDepthLimit returns the limit set by SetConditionDepthLimit.
+func ConditionDepthLimit() int {
+	return int(conditionDepthLimit.Load())
+}
+
+// checkConditionDepth reports an error if condition nests deeper than the
+// limit.
+func checkConditionDepth(condition map[string]any) error {
+	limit := int(conditionDepthLimit.Load())
+	if limit <= 0 || !tooDeep(reflect.ValueOf(condition), 1, limit) {
+		return nil
+	}
+	return fmt.Errorf("%w: more than %d levels: %w", ErrConditionDepth, limit, ErrInvalidCondition)
+}
+
+// tooDeep reports whether v, found at depth, nests containers deeper than
+// limit. It stops descending once it has.
+func tooDeep(v reflect.Value, depth, limit int) bool {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if depth > limit {
+			return true
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if tooDeep(iter.Value(), depth+1, limit) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		if depth > limit {
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if tooDeep(v.Index(i), depth+1, limit) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flattenLogical returns condition with each $and or $or whose list holds a
+// condition made only of the same operator replaced by one list of both's
+// children, so chains such as {"$or": [a, {"$or": [b, {"$or": [c, d]}]}]}
+// compile to a single level instead of recursing once per link. Only
+// map[string]any and []any are rewritten, into copies; condition itself is
+// not modified.
+func flattenLogical(condition map[string]any) map[string]any {
+	flat, _ := flattenValue(condition).(map[string]any)
+	return flat
+}
+
+func flattenValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		flat := make(map[string]any, len(v))
+		for key, item := range v {
+			if list, ok := item.([]any); ok && (key == "$and" || key == "$or") {
+				flat[key] = flattenList(key, list)
+			} else {
+				flat[key] = flattenValue(item)
+			}
+		}
+		return flat
+	case []any:
+		flat := make([]any, len(v))
+		for i, item := range v {
+			flat[i] = flattenValue(item)
+		}
+		return flat
+	default:
+		return value
+	}
+}
+
+// flattenList returns the children of an op list, descending into items
+// that are a lone op with a non-empty list in place of keeping them.
+func flattenList(op string, list []any) []any {
+	flat := make([]any, 0, len(list))
+	var walk func(list []any)
+	walk = func(list []any) {
+		for _, item := range list {
+			inner, ok := item.(map[string]any)
+			if children, isList := inner[op].([]any); ok && len(inner) == 1 && isList && len(children) > 0 {
+				walk(children)
+				continue
+			}
+			flat = append(flat, flattenValue(item))
+		}
+	}
+	walk(list)
+	return flat
+}
@@ -0,0 +1,115 @@
+package mongory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenLogical(t *testing.T) {
+	a, b, c, d := map[string]any{"a": 1}, map[string]any{"b": 1}, map[string]any{"c": 1}, map[string]any{"d": 1}
+	condition := map[string]any{
+		"$or": []any{a, map[string]any{"$or": []any{b, map[string]any{"$or": []any{c, d}}}}},
+		"x": map[string]any{"$elemMatch": map[string]any{
+			"$and": []any{map[string]any{"$and": []any{a, b}}, map[string]any{"$and": []any{c}, "e": 1}},
+		}},
+	}
+	want := map[string]any{
+		"$or": []any{a, b, c, d},
+		"x": map[string]any{"$elemMatch": map[string]any{
+			"$and": []any{a, b, map[string]any{"$and": []any{c}, "e": 1}},
+		}},
+	}
+	if got := flattenLogical(condition); !reflect.DeepEqual(got, want) {
+		t.Fatalf("flattenLogical = %v, want %v", got, want)
+	}
+	if len(condition["$or"].([]any)) != 2 {
+		t.Fatalf("flattenLogical modified its argument")
+	}
+}
+
+func TestConditionDepthLimit(t *testing.T) {
+	if ConditionDepthLimit() != 1000 {
+		t.Fatalf("ConditionDepthLimit = %d, want the default 1000", ConditionDepthLimit())
+	}
+
+	// A chain of 100000 $and overflows the native stack unflattened.
+	chain := map[string]any{"a": 1}
+	for i := 0; i < 100000; i++ {
+		chain = map[string]any{"$and": []any{chain, map[string]any{"b": 2}}}
+	}
+	m, err := NewMatcher(chain)
+	if err != nil {
+		t.Fatalf("NewMatcher(flattenable chain) failed: %v", err)
+	}
+	defer m.Close()
+	if ok, err := m.Match(map[string]any{"a": 1, "b": 2}); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+
+	nested := func(levels int) map[string]any {
+		condition := map[string]any{"$eq": 1}
+		for i := 1; i < levels; i++ {
+			condition = map[string]any{"$not": condition}
+		}
+		return condition
+	}
+	if m, err := NewMatcher(map[string]any{"v": nested(500)}); err != nil {
+		t.Fatalf("NewMatcher(501 levels) failed: %v", err)
+	} else {
+		m.Close()
+	}
+	_, err = NewMatcher(map[string]any{"v": nested(1000)})
+	if !errors.Is(err, ErrConditionDepth) || !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("NewMatcher(1001 levels) error = %v, want ErrConditionDepth", err)
+	}
+
+	SetConditionDepthLimit(10)
+	defer SetConditionDepthLimit(1000)
+	if _, err := NewMatcher(map[string]any{"v": map[string]any{"$in": []any{nested(9)}}}); !errors.Is(err, ErrConditionDepth) {
+		t.Fatalf("lists should count as a level, got %v", err)
+	}
+	SetConditionDepthLimit(0)
+	if m, err := NewMatcher(map[string]any{"v": nested(1200)}); err != nil {
+		t.Fatalf("NewMatcher without a limit failed: %v", err)
+	} else {
+		m.Close()
+	}
+}
+
+func TestDeepFieldConditions(t *testing.T) {
+	// Nested field conditions used to build an array matcher per level of
+	// every literal, doubling the compile time per level.
+	condition := map[string]any{"c": map[string]any{"$gt": 1}}
+	for i := 0; i < 40; i++ {
+		condition = map[string]any{"f": condition}
+	}
+	m, err := NewMatcher(condition)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	build := func(leaf any, wrap func(any) any) any {
+		doc := map[string]any{"c": leaf}
+		var v any = doc
+		for i := 0; i < 40; i++ {
+			v = map[string]any{"f": wrap(v)}
+		}
+		return v
+	}
+	plain := func(v any) any { return v }
+	array := func(v any) any { return []any{map[string]any{"x": 1}, v} }
+	for _, tc := range []struct {
+		doc  any
+		want bool
+	}{
+		{build(2, plain), true},
+		{build(1, plain), false},
+		{build(2, array), true},
+		{build(1, array), false},
+	} {
+		if ok, err := m.Match(tc.doc); err != nil || ok != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, ok, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package mongory
+
+import "github.com/mongoryhq/mongory-go/internal/cgo"
+
+var (
+	// ErrInvalidCondition is wrapped by errors for malformed conditions:
+	// operands the core rejects when compiling, undefined or recursive
+	// macros, and every *ConditionError from ValidateCondition.
+	ErrInvalidCondition = cgo.ErrInvalidCondition
+	// ErrUnsupportedOperator is wrapped by errors for operators or
+	// operations that are not supported, including unknown operators found
+	// by ValidateCondition.
+	ErrUnsupportedOperator = cgo.ErrUnsupportedOperator
+	// ErrAllocation is wrapped by errors for native allocations that failed.
+	ErrAllocation = cgo.ErrAllocation
+	// ErrConversion is wrapped by errors for documents that could not be
+	// converted for matching, including panics while reading them.
+	ErrConversion = cgo.ErrConversion
+)
+
+// NativeError is an error reported by the native core, carrying its code
+// and message. errors.Is matches it against ErrInvalidCondition,
+// ErrUnsupportedOperator, ErrAllocation or ErrConversion.
+type NativeError = cgo.NativeError
+
+// ErrorCode is the type of a NativeError.
+type ErrorCode = cgo.ErrorCode
+
+const (
+	CodeMemory               = cgo.CodeMemory
+	CodeInvalidType          = cgo.CodeInvalidType
+	CodeOutOfBounds          = cgo.CodeOutOfBounds
+	CodeUnsupportedOperation = cgo.CodeUnsupportedOperation
+	CodeInvalidArgument      = cgo.CodeInvalidArgument
+	CodeIO                   = cgo.CodeIO
+	CodeParse                = cgo.CodeParse
+	CodeUnknown              = cgo.CodeUnknown
+)
@@ -0,0 +1,50 @@
+package mongory
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTypedErrors(t *testing.T) {
+	_, err := NewCMatcher(map[string]any{"tags": map[string]any{"$in": 5}}, nil)
+	var native *NativeError
+	if !errors.Is(err, ErrInvalidCondition) || !errors.As(err, &native) {
+		t.Fatalf("bad $in: err = %v, want a *NativeError matching ErrInvalidCondition", err)
+	}
+	if native.Code != CodeInvalidArgument || native.Code.String() != "invalid argument" || !strings.Contains(native.Message, "$in") {
+		t.Fatalf("bad $in: code %v, message %q", native.Code, native.Message)
+	}
+	if errors.Is(err, ErrAllocation) || errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatalf("bad $in: err = %v matches the wrong sentinel", err)
+	}
+	if _, err := NewCMatcher(map[string]any{"n": map[string]any{"$rollout": "half"}}, nil); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("bad $rollout: err = %v, want ErrInvalidCondition", err)
+	}
+	if _, err := NewCMatcher(map[string]any{"$macro": "errors-missing"}, nil); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("undefined macro: err = %v, want ErrInvalidCondition", err)
+	}
+
+	err = ValidateCondition(map[string]any{"a": map[string]any{"$near": 1}})
+	var condErr *ConditionError
+	if !errors.As(err, &condErr) || condErr.Path != "a.$near" {
+		t.Fatalf("ValidateCondition: err = %v, want a *ConditionError at a.$near", err)
+	}
+	if !errors.Is(err, ErrInvalidCondition) || !errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatalf("ValidateCondition: err = %v, want ErrInvalidCondition and ErrUnsupportedOperator", err)
+	}
+	err = ValidateCondition(map[string]any{"a": map[string]any{"$in": 1}})
+	if !errors.Is(err, ErrInvalidCondition) || errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatalf("ValidateCondition($in): err = %v, want only ErrInvalidCondition", err)
+	}
+
+	m, err := NewCMatcher(map[string]any{"a": 1}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	_, err = m.Match(map[int]any{1: 1})
+	if !errors.Is(err, ErrConversion) || !errors.Is(err, ErrCallbackPanic) || !strings.HasPrefix(err.Error(), "mongory: callback panicked") {
+		t.Fatalf("Match(map[int]any): err = %v, want ErrConversion and ErrCallbackPanic", err)
+	}
+}
@@ -0,0 +1,186 @@
+package mongory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// ChangeKind is how a predicate differs between two compiled plans.
+type ChangeKind int
+
+const (
+	PredicateAdded ChangeKind = iota
+	PredicateRemoved
+	PredicateChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case PredicateAdded:
+		return "added"
+	case PredicateRemoved:
+		return "removed"
+	case PredicateChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// PlanChange is one difference reported by ExplainDiff.
+type PlanChange struct {
+	Kind  ChangeKind
+	Path  []string // the enclosing nodes, outermost first, as `Field "age"` or "Or"
+	Name  string   // the node's matcher name, such as "Gte" or "Field"
+	Field string
+	Old   string // the node's condition in the old plan, empty when added
+	New   string // the node's condition in the new plan, empty when removed
+}
+
+func (c PlanChange) String() string {
+	label := planLabel(c.Name, c.Field)
+	if len(c.Path) > 0 {
+		label = strings.Join(c.Path, " > ") + " > " + label
+	}
+	switch c.Kind {
+	case PredicateAdded:
+		return fmt.Sprintf("+ %s: %s", label, c.New)
+	case PredicateRemoved:
+		return fmt.Sprintf("- %s: %s", label, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s => %s", label, c.Old, c.New)
+	}
+}
+
+// PlanDiff lists the differences between two compiled plans.
+type PlanDiff []PlanChange
+
+// String renders the diff one change per line, prefixed with +, - or ~.
+func (d PlanDiff) String() string {
+	var b strings.Builder
+	for _, change := range d {
+		b.WriteString(change.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ExplainDiff compiles both conditions and reports which predicates of the
+// compiled plan were added, removed or changed, for reviewing rule updates.
+// Nodes are paired by matcher name and field, so reordering keys or the
+// branches of $and and $or is not reported as a change.
+func ExplainDiff(oldCondition, newCondition map[string]any) (PlanDiff, error) {
+	oldPlan, err := planTree(oldCondition)
+	if err != nil {
+		return nil, fmt.Errorf("mongory: old condition: %w", err)
+	}
+	newPlan, err := planTree(newCondition)
+	if err != nil {
+		return nil, fmt.Errorf("mongory: new condition: %w", err)
+	}
+	var diff PlanDiff
+	diffPlanNodes(&diff, nil, oldPlan, newPlan)
+	return diff, nil
+}
+
+type planNode struct {
+	cgo.ExplainEntry
+	children []*planNode
+}
+
+func planTree(condition map[string]any) ([]*planNode, error) {
+	compiled, err := NewMatcher(CanonicalCondition(condition))
+	if err != nil {
+		return nil, err
+	}
+	defer compiled.Close()
+	entries, err := unwrapMatcher(compiled).ExplainEntries()
+	if err != nil {
+		return nil, err
+	}
+	var roots, stack []*planNode
+	for _, entry := range entries {
+		node := &planNode{ExplainEntry: entry}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots, nil
+}
+
+func diffPlanNodes(diff *PlanDiff, path []string, oldNodes, newNodes []*planNode) {
+	// Identical subtrees are dropped first, so that siblings with the same
+	// name and field, like the branches of an $or, pair up by what changed.
+	oldLeft := make([]*planNode, 0, len(oldNodes))
+	matched := make([]bool, len(newNodes))
+	for _, old := range oldNodes {
+		found := false
+		for i, node := range newNodes {
+			if !matched[i] && node.Name == old.Name && node.Field == old.Field && node.Condition == old.Condition {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			oldLeft = append(oldLeft, old)
+		}
+	}
+	for _, old := range oldLeft {
+		var pair *planNode
+		for i, node := range newNodes {
+			if !matched[i] && node.Name == old.Name && node.Field == old.Field {
+				matched[i], pair = true, node
+				break
+			}
+		}
+		switch {
+		case pair == nil:
+			*diff = append(*diff, planChange(PredicateRemoved, path, old, old.Condition, ""))
+		case len(old.children) == 0 || len(pair.children) == 0:
+			*diff = append(*diff, planChange(PredicateChanged, path, old, old.Condition, pair.Condition))
+		default:
+			diffPlanNodes(diff, planPath(path, old), old.children, pair.children)
+		}
+	}
+	for i, node := range newNodes {
+		if !matched[i] {
+			*diff = append(*diff, planChange(PredicateAdded, path, node, "", node.Condition))
+		}
+	}
+}
+
+func planChange(kind ChangeKind, path []string, node *planNode, old, new string) PlanChange {
+	return PlanChange{
+		Kind:  kind,
+		Path:  path,
+		Name:  node.Name,
+		Field: node.Field,
+		Old:   old,
+		New:   new,
+	}
+}
+
+// planPath extends path with node. Condition nodes only group their
+// children and are left out.
+func planPath(path []string, node *planNode) []string {
+	if node.Name == "Condition" {
+		return path
+	}
+	return append(path[:len(path):len(path)], planLabel(node.Name, node.Field))
+}
+
+func planLabel(name, field string) string {
+	if field == "" {
+		return name
+	}
+	return fmt.Sprintf("%s %q", name, field)
+}
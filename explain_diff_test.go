@@ -0,0 +1,49 @@
+package mongory
+
+import "testing"
+
+func TestExplainDiff(t *testing.T) {
+	old := map[string]any{
+		"age":  map[string]any{"$gte": 18, "$lt": 60},
+		"tags": "x",
+		"$or":  []any{map[string]any{"a": 1}, map[string]any{"b": map[string]any{"$in": []any{1, 2}}}},
+	}
+	updated := map[string]any{
+		"age":  map[string]any{"$gt": 18, "$lt": 60},
+		"tags": "y",
+		"new":  true,
+		"$or":  []any{map[string]any{"b": map[string]any{"$in": []any{1, 2, 3}}}, map[string]any{"a": 1}},
+	}
+	diff, err := ExplainDiff(old, updated)
+	if err != nil {
+		t.Fatalf("ExplainDiff failed: %v", err)
+	}
+	want := map[string]bool{
+		`~ Field "tags" > Eq: "x" => "y"`:         true,
+		`- Field "age" > Gte: 18`:                 true,
+		`+ Field "age" > Gt: 18`:                  true,
+		`~ Or > Field "b" > In: [1,2] => [1,2,3]`: true,
+		`+ Field "new": true`:                     true,
+	}
+	if len(diff) != len(want) {
+		t.Fatalf("ExplainDiff = %d changes, want %d:\n%s", len(diff), len(want), diff)
+	}
+	for _, change := range diff {
+		if !want[change.String()] {
+			t.Fatalf("unexpected change %q in:\n%s", change, diff)
+		}
+	}
+
+	diff, err = ExplainDiff(old, map[string]any{
+		"$or":  []any{map[string]any{"b": map[string]any{"$in": []any{1, 2}}}, map[string]any{"a": 1}},
+		"tags": "x",
+		"age":  map[string]any{"$lt": 60, "$gte": 18},
+	})
+	if err != nil || len(diff) != 0 {
+		t.Fatalf("ExplainDiff of a permuted condition = %v, %v; want no changes", diff, err)
+	}
+
+	if _, err := ExplainDiff(old, map[string]any{"$and": "hello"}); err == nil {
+		t.Fatalf("ExplainDiff with an invalid condition succeeded")
+	}
+}
@@ -0,0 +1,139 @@
+package mongory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+type planJSONNode struct {
+	Name      string          `json:"name"`
+	Field     string          `json:"field,omitempty"`
+	Condition string          `json:"condition,omitempty"`
+	Record    *string         `json:"record,omitempty"`
+	Matched   *bool           `json:"matched,omitempty"`
+	Children  []*planJSONNode `json:"children,omitempty"`
+	// Canonical is the root's condition as canonical JSON, which unlike
+	// the core's rendering in Condition reads back exactly.
+	Canonical json.RawMessage `json:"canonical,omitempty"`
+}
+
+type traceJSON struct {
+	Matched bool            `json:"matched"`
+	Trace   []*planJSONNode `json:"trace"`
+}
+
+// ExplainJSON renders the compiled matcher tree as JSON, one object per node
+// with its children nested, instead of printing it to stdout like Explain.
+func (m *matcher) ExplainJSON() ([]byte, error) {
+	entries, err := m.ExplainEntries()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*planJSONNode, len(entries))
+	levels := make([]int, len(entries))
+	for i, entry := range entries {
+		nodes[i] = explainJSONNode(entry)
+		levels[i] = entry.Level
+	}
+	roots := nestByLevel(nodes, levels)
+	if len(roots) == 0 {
+		return []byte("null"), nil
+	}
+	roots[0].Canonical = m.condition.canonicalJSON()
+	return json.Marshal(roots[0])
+}
+
+// NewMatcherFromExplainJSON compiles the condition of an ExplainJSON
+// snapshot and checks, as NewMatcherFromPlan does, that it compiles to the
+// same plan, failing with ErrPlanMismatch otherwise.
+func NewMatcherFromExplainJSON(data []byte) (Matcher, error) {
+	var snapshot planJSONNode
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("mongory: invalid explain JSON: %w", err)
+	}
+	if snapshot.Canonical == nil {
+		return nil, fmt.Errorf("mongory: explain JSON has no canonical condition: %w", ErrInvalidCondition)
+	}
+	condition, err := ParseConditionJSON(snapshot.Canonical)
+	if err != nil {
+		return nil, err
+	}
+	return compileSnapshot(condition, MatcherOptions{}, &snapshot)
+}
+
+// compileSnapshot compiles condition with opts and checks it compiles to the
+// tree of snapshot.
+func compileSnapshot(condition map[string]any, opts MatcherOptions, snapshot *planJSONNode) (Matcher, error) {
+	m, err := NewMatcherWithOptions(condition, opts)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := m.ExplainPlan()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	if compiled.shape() != snapshot.shape() {
+		m.Close()
+		return nil, ErrPlanMismatch
+	}
+	return m, nil
+}
+
+func (n *planJSONNode) shape() string {
+	children := make([]string, len(n.Children))
+	for i, child := range n.Children {
+		children[i] = child.shape()
+	}
+	return planShape(n.Name, n.Field, children)
+}
+
+// TraceJSON matches value with tracing and returns the evaluated nodes as
+// JSON alongside the result, instead of printing them like Trace.
+func (m *matcher) TraceJSON(value any) (bool, []byte, error) {
+	matched, entries, err := m.TraceEntries(value)
+	if err != nil {
+		return false, nil, err
+	}
+	nodes := make([]*planJSONNode, len(entries))
+	levels := make([]int, len(entries))
+	for i, entry := range entries {
+		node := explainJSONNode(entry.ExplainEntry)
+		record, ok := entry.Record, entry.Matched
+		node.Record = &record
+		node.Matched = &ok
+		nodes[i] = node
+		levels[i] = entry.Level
+	}
+	encoded, err := json.Marshal(traceJSON{Matched: matched, Trace: nestByLevel(nodes, levels)})
+	return matched, encoded, err
+}
+
+func explainJSONNode(entry cgo.ExplainEntry) *planJSONNode {
+	return &planJSONNode{Name: entry.Name, Field: entry.Field, Condition: entry.Condition}
+}
+
+// nestByLevel turns a parent-first node list annotated with depths into a
+// forest.
+func nestByLevel(nodes []*planJSONNode, levels []int) []*planJSONNode {
+	var roots []*planJSONNode
+	var stack []*planJSONNode
+	var stackLevels []int
+	for i, node := range nodes {
+		for len(stack) > 0 && stackLevels[len(stackLevels)-1] >= levels[i] {
+			stack = stack[:len(stack)-1]
+			stackLevels = stackLevels[:len(stackLevels)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+		stackLevels = append(stackLevels, levels[i])
+	}
+	return roots
+}
@@ -0,0 +1,71 @@
+package mongory
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExplainJSON(t *testing.T) {
+	matcher, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	encoded, err := matcher.ExplainJSON()
+	if err != nil {
+		t.Fatalf("ExplainJSON failed: %v", err)
+	}
+	var root planJSONNode
+	if err := json.Unmarshal(encoded, &root); err != nil {
+		t.Fatalf("ExplainJSON produced invalid JSON %s: %v", encoded, err)
+	}
+	if root.Name != "Field" || root.Field != "age" || len(root.Children) != 1 {
+		t.Fatalf("unexpected explain tree: %s", encoded)
+	}
+}
+
+func TestTraceJSON(t *testing.T) {
+	matcher, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	matched, encoded, err := matcher.TraceJSON(map[string]any{"age": 20})
+	if err != nil {
+		t.Fatalf("TraceJSON failed: %v", err)
+	}
+	if !matched {
+		t.Fatalf("TraceJSON matched = false, want true")
+	}
+	var trace traceJSON
+	if err := json.Unmarshal(encoded, &trace); err != nil {
+		t.Fatalf("TraceJSON produced invalid JSON %s: %v", encoded, err)
+	}
+	if !trace.Matched || len(trace.Trace) != 1 || len(trace.Trace[0].Children) != 1 {
+		t.Fatalf("unexpected trace: %s", encoded)
+	}
+	if got := *trace.Trace[0].Record; got != `{"age":20}` {
+		t.Fatalf("root record = %s, want {\"age\":20}", got)
+	}
+}
+
+func TestExplainDeterministic(t *testing.T) {
+	condition := map[string]any{}
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		condition[key] = map[string]any{"$gt": 1}
+	}
+	var first []byte
+	for i := 0; i < 10; i++ {
+		matcher, err := NewCMatcher(condition, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		encoded, err := matcher.ExplainJSON()
+		if err != nil {
+			t.Fatalf("ExplainJSON failed: %v", err)
+		}
+		if first == nil {
+			first = encoded
+		} else if string(encoded) != string(first) {
+			t.Fatalf("explain output differs between compilations:\n%s\n%s", first, encoded)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package mongory
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// mongoExplain is the subset of MongoDB's explain output that describes a
+// find without indexes: a collection scan filtering by the parsed query.
+type mongoExplain struct {
+	ExplainVersion string            `json:"explainVersion"`
+	QueryPlanner   mongoQueryPlanner `json:"queryPlanner"`
+	Command        mongoFindCommand  `json:"command"`
+	OK             float64           `json:"ok"`
+}
+
+type mongoQueryPlanner struct {
+	Namespace      string           `json:"namespace"`
+	IndexFilterSet bool             `json:"indexFilterSet"`
+	ParsedQuery    map[string]any   `json:"parsedQuery"`
+	WinningPlan    mongoPlanStage   `json:"winningPlan"`
+	RejectedPlans  []mongoPlanStage `json:"rejectedPlans"`
+}
+
+type mongoPlanStage struct {
+	Stage     string         `json:"stage"`
+	Filter    map[string]any `json:"filter,omitempty"`
+	Direction string         `json:"direction"`
+}
+
+type mongoFindCommand struct {
+	Find   string         `json:"find"`
+	Filter map[string]any `json:"filter"`
+	DB     string         `json:"$db,omitempty"`
+}
+
+// ExplainMongoJSON renders the matcher as MongoDB's explain() reports a find
+// with this condition as its filter: a queryPlanner whose winning plan is a
+// COLLSCAN stage, so tooling and dashboards built on MongoDB explain output
+// can read it. namespace is reported as given, in MongoDB's "db.collection"
+// form.
+//
+// The filter is shown parsed the way MongoDB shows it: field values become
+// $eq predicates, fields with several operators one predicate per operator,
+// fields nested in conditions dotted paths, and several predicates an $and,
+// with nested $and lists merged into it.
+func (m *matcher) ExplainMongoJSON(namespace string) ([]byte, error) {
+	expanded, err := expandMacros(m.condition.Map())
+	if err != nil {
+		return nil, err
+	}
+	// The canonical form resolves shared values and regular expressions to
+	// plain JSON.
+	condition, err := ParseConditionJSON(CanonicalJSON(expanded))
+	if err != nil {
+		return nil, err
+	}
+	parsed := mongoParsedQuery(condition)
+	db, collection, found := strings.Cut(namespace, ".")
+	if !found {
+		db, collection = "", namespace
+	}
+	return json.Marshal(mongoExplain{
+		ExplainVersion: "1",
+		QueryPlanner: mongoQueryPlanner{
+			Namespace:     namespace,
+			ParsedQuery:   parsed,
+			WinningPlan:   mongoPlanStage{Stage: "COLLSCAN", Filter: parsed, Direction: "forward"},
+			RejectedPlans: []mongoPlanStage{},
+		},
+		Command: mongoFindCommand{Find: collection, Filter: condition, DB: db},
+		OK:      1,
+	})
+}
+
+// mongoParsedQuery returns condition in MongoDB's parsed form.
+func mongoParsedQuery(condition map[string]any) map[string]any {
+	predicates := mongoPredicates("", condition)
+	switch len(predicates) {
+	case 0:
+		return map[string]any{}
+	case 1:
+		return predicates[0]
+	default:
+		return map[string]any{"$and": predicates}
+	}
+}
+
+// mongoPredicates lists the predicates of a condition on path, the empty
+// path for documents' top level, in key order.
+func mongoPredicates(path string, condition map[string]any) []map[string]any {
+	keys := make([]string, 0, len(condition))
+	for key := range condition {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var predicates []map[string]any
+	for _, key := range keys {
+		value := condition[key]
+		switch {
+		case key == "$and" && path == "":
+			for _, item := range conditionList(value) {
+				predicates = append(predicates, mongoPredicates("", item)...)
+			}
+		case (key == "$or" || key == "$nor") && path == "":
+			list := conditionList(value)
+			parsed := make([]any, len(list))
+			for i, item := range list {
+				parsed[i] = mongoParsedQuery(item)
+			}
+			predicates = append(predicates, map[string]any{key: parsed})
+		case strings.HasPrefix(key, "$") && path != "":
+			predicates = append(predicates, map[string]any{path: map[string]any{key: value}})
+		case strings.HasPrefix(key, "$"):
+			predicates = append(predicates, map[string]any{key: value})
+		default:
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			if inner, ok := value.(map[string]any); ok && len(inner) > 0 && !isRegexLiteral(inner) {
+				predicates = append(predicates, mongoPredicates(field, inner)...)
+				continue
+			}
+			if _, ok := value.(map[string]any); ok {
+				predicates = append(predicates, map[string]any{field: value})
+				continue
+			}
+			predicates = append(predicates, map[string]any{field: map[string]any{"$eq": value}})
+		}
+	}
+	return predicates
+}
+
+// conditionList returns the conditions of an $and, $or or $nor operand.
+func conditionList(value any) []map[string]any {
+	list, _ := value.([]any)
+	conditions := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if condition, ok := item.(map[string]any); ok {
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions
+}
+
+// isRegexLiteral reports whether m is a regular expression as the canonical
+// form writes one, which MongoDB shows as is.
+func isRegexLiteral(m map[string]any) bool {
+	_, ok := m["$regex"]
+	return ok
+}
@@ -0,0 +1,93 @@
+package mongory
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExplainMongoJSON(t *testing.T) {
+	m, err := NewMatcher(map[string]any{
+		"status": "active",
+		"age":    map[string]any{"$gte": 18, "$lt": 65},
+		"address": map[string]any{
+			"city": "Taipei",
+		},
+		"name": regexp.MustCompile("^a"),
+		"$and": []any{map[string]any{"score": map[string]any{"$gt": 1}}},
+		"$or":  []any{map[string]any{"a": 1}, map[string]any{"b": map[string]any{"$in": []any{1, 2}}}},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	data, err := m.ExplainMongoJSON("app.users")
+	if err != nil {
+		t.Fatalf("ExplainMongoJSON failed: %v", err)
+	}
+	var got struct {
+		QueryPlanner struct {
+			Namespace   string         `json:"namespace"`
+			ParsedQuery map[string]any `json:"parsedQuery"`
+			WinningPlan struct {
+				Stage  string         `json:"stage"`
+				Filter map[string]any `json:"filter"`
+			} `json:"winningPlan"`
+			RejectedPlans []any `json:"rejectedPlans"`
+		} `json:"queryPlanner"`
+		Command struct {
+			Find string `json:"find"`
+			DB   string `json:"$db"`
+		} `json:"command"`
+		OK float64 `json:"ok"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, data)
+	}
+	plan := got.QueryPlanner
+	if plan.Namespace != "app.users" || got.Command.Find != "users" || got.Command.DB != "app" || got.OK != 1 {
+		t.Fatalf("unexpected envelope: %s", data)
+	}
+	if plan.WinningPlan.Stage != "COLLSCAN" || plan.RejectedPlans == nil || len(plan.RejectedPlans) != 0 {
+		t.Fatalf("unexpected plan: %s", data)
+	}
+	want := map[string]any{"$and": []any{
+		map[string]any{"score": map[string]any{"$gt": 1.0}},
+		map[string]any{"$or": []any{
+			map[string]any{"a": map[string]any{"$eq": 1.0}},
+			map[string]any{"b": map[string]any{"$in": []any{1.0, 2.0}}},
+		}},
+		map[string]any{"address.city": map[string]any{"$eq": "Taipei"}},
+		map[string]any{"age": map[string]any{"$gte": 18.0}},
+		map[string]any{"age": map[string]any{"$lt": 65.0}},
+		map[string]any{"name": map[string]any{"$regex": "^a"}},
+		map[string]any{"status": map[string]any{"$eq": "active"}},
+	}}
+	if !reflect.DeepEqual(plan.ParsedQuery, want) {
+		t.Fatalf("parsedQuery = %v\nwant %v", plan.ParsedQuery, want)
+	}
+	if !reflect.DeepEqual(plan.WinningPlan.Filter, want) {
+		t.Fatalf("winningPlan.filter = %v, want the parsed query", plan.WinningPlan.Filter)
+	}
+
+	single, err := NewMatcher(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer single.Close()
+	data, err = single.ExplainMongoJSON("users")
+	if err != nil {
+		t.Fatalf("ExplainMongoJSON failed: %v", err)
+	}
+	var explain struct {
+		QueryPlanner map[string]any `json:"queryPlanner"`
+		Command      map[string]any `json:"command"`
+	}
+	if err := json.Unmarshal(data, &explain); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if want := map[string]any{"a": map[string]any{"$eq": 1.0}}; !reflect.DeepEqual(explain.QueryPlanner["parsedQuery"], want) || explain.Command["find"] != "users" {
+		t.Fatalf("single predicate explain = %s", data)
+	}
+}
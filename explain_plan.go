@@ -0,0 +1,155 @@
+package mongory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// ExplainNode is one node of a compiled matcher tree, as returned by
+// ExplainPlan.
+type ExplainNode struct {
+	// Operator is the core's name for the node, such as "Condition" for
+	// the root, "Field" for a field lookup, "Gt", "In" or "Or".
+	Operator string
+	// Field is the key a Field node looks up, and empty for other nodes.
+	Field string
+	// Path is the dotted path of the Field nodes from the root down to and
+	// including this one. Under $elemMatch and $every it continues from the
+	// array's path.
+	Path string
+	// Operand is the node's condition decoded as by ParseDocumentJSON: maps,
+	// lists and scalars, with integers as int64. Operands the core does not
+	// print as JSON, such as regular expressions, are kept as the printed
+	// string. The root's operand is the matcher's whole condition in its
+	// canonical form, with regular expressions as {"$regex": source}, so
+	// NewMatcherFromPlan can compile it again.
+	Operand any
+	// Children are the nodes this one evaluates, in evaluation order.
+	Children []*ExplainNode
+}
+
+// ExplainPlan returns the compiled matcher tree, the structure Explain
+// prints, for tools to inspect or render.
+func (m *matcher) ExplainPlan() (*ExplainNode, error) {
+	entries, err := m.ExplainEntries()
+	if err != nil {
+		return nil, err
+	}
+	condition, err := m.plainCondition()
+	if err != nil {
+		return nil, err
+	}
+	var root *ExplainNode
+	var stack []*ExplainNode
+	paths := fieldPaths{}
+	for _, entry := range entries {
+		node := &ExplainNode{Operator: entry.Name, Field: entry.Field, Operand: explainOperand(entry.Condition)}
+		node.Path = paths.next(entry)
+		stack = stack[:min(len(stack), entry.Level)]
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		} else if root == nil {
+			node.Operand = condition
+			root = node
+		}
+		stack = append(stack, node)
+	}
+	return root, nil
+}
+
+// plainCondition returns the matcher's condition as ParseConditionJSON
+// reads its canonical JSON.
+func (m *matcher) plainCondition() (map[string]any, error) {
+	return ParseConditionJSON(m.condition.canonicalJSON())
+}
+
+// ErrPlanMismatch is returned when a condition restored from an explain
+// snapshot compiles to a different plan than the snapshot records, as after
+// an engine upgrade that changes how conditions compile.
+var ErrPlanMismatch = errors.New("mongory: compiled plan differs from the snapshot")
+
+// NewMatcherFromPlan compiles the condition of plan, the root returned by
+// ExplainPlan, and checks that it compiles to the same plan, so config
+// systems can store the compiled, normalized form of a condition instead of
+// the user's input. It fails with ErrPlanMismatch if the operators or
+// fields of the plan differ.
+func NewMatcherFromPlan(plan *ExplainNode) (Matcher, error) {
+	if plan == nil {
+		return nil, fmt.Errorf("mongory: nil plan: %w", ErrInvalidCondition)
+	}
+	condition, ok := plan.Operand.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("mongory: plan root has no condition: %w", ErrInvalidCondition)
+	}
+	m, err := NewMatcher(condition)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := m.ExplainPlan()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	if compiled.shape() != plan.shape() {
+		m.Close()
+		return nil, ErrPlanMismatch
+	}
+	return m, nil
+}
+
+// planShape renders the operators and fields of a plan, with each node's
+// children in sorted order, as $or and $and lists are sorted by the
+// canonical form. Operands are left out: the core renders a regular
+// expression literal and the {"$regex": source} it canonicalizes to
+// differently.
+func planShape(operator, field string, children []string) string {
+	sort.Strings(children)
+	return strconv.Quote(operator) + strconv.Quote(field) + "[" + strings.Join(children, ",") + "]"
+}
+
+func (n *ExplainNode) shape() string {
+	children := make([]string, len(n.Children))
+	for i, child := range n.Children {
+		children[i] = child.shape()
+	}
+	return planShape(n.Operator, n.Field, children)
+}
+
+// fieldPaths computes the dotted field path of each node of a parent-first
+// entry list.
+type fieldPaths struct {
+	stack []string // the path at each level above the current entry
+}
+
+func (p *fieldPaths) next(entry cgo.ExplainEntry) string {
+	p.stack = p.stack[:min(len(p.stack), entry.Level)]
+	path := ""
+	if len(p.stack) > 0 {
+		path = p.stack[len(p.stack)-1]
+	}
+	if entry.Field != "" {
+		if path != "" {
+			path += "."
+		}
+		path += entry.Field
+	}
+	p.stack = append(p.stack, path)
+	return path
+}
+
+func explainOperand(condition string) any {
+	if condition == "" {
+		return nil
+	}
+	operand, err := ParseDocumentJSON([]byte(condition))
+	if err != nil {
+		return condition
+	}
+	return operand
+}
@@ -0,0 +1,134 @@
+package mongory
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExplainPlan(t *testing.T) {
+	m, err := NewMatcher(map[string]any{
+		"a.b":  map[string]any{"$gt": 1},
+		"tags": map[string]any{"$elemMatch": map[string]any{"x": 1}},
+		"$or":  []any{map[string]any{"c": map[string]any{"d": 3}}, map[string]any{"e": map[string]any{"$in": []any{1, 2}}}},
+		"name": regexp.MustCompile("^a"),
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	root, err := m.ExplainPlan()
+	if err != nil {
+		t.Fatalf("ExplainPlan failed: %v", err)
+	}
+	if root.Operator != "Condition" || len(root.Children) != 4 {
+		t.Fatalf("root = %s with %d children", root.Operator, len(root.Children))
+	}
+
+	nodes := map[string]*ExplainNode{}
+	var walk func(n *ExplainNode)
+	walk = func(n *ExplainNode) {
+		nodes[n.Operator+" "+n.Path] = n
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	gt := nodes["Gt a.b"]
+	if gt == nil || gt.Operand != int64(1) || gt.Field != "" {
+		t.Fatalf("Gt node = %+v", gt)
+	}
+	if field := nodes["Field a.b"]; field == nil || field.Field != "a.b" || !reflect.DeepEqual(field.Operand, map[string]any{"$gt": int64(1)}) {
+		t.Fatalf("Field a.b node = %+v", field)
+	}
+	if eq := nodes["Eq tags.x"]; eq == nil || eq.Operand != int64(1) {
+		t.Fatalf("$elemMatch should nest paths, got %v", planKeys(nodes))
+	}
+	if in := nodes["In e"]; in == nil || !reflect.DeepEqual(in.Operand, []any{int64(1), int64(2)}) {
+		t.Fatalf("In node = %+v", in)
+	}
+	if nodes["Eq c.d"] == nil {
+		t.Fatalf("$or branches should keep their paths, got %v", planKeys(nodes))
+	}
+	if field := nodes["Field name"]; field == nil || len(field.Children) != 1 {
+		t.Fatalf("Field name node = %+v", field)
+	} else if _, ok := field.Children[0].Operand.(string); !ok {
+		t.Fatalf("regex operand = %#v, want its printed form", field.Children[0].Operand)
+	}
+}
+
+func planKeys(nodes map[string]*ExplainNode) []string {
+	out := make([]string, 0, len(nodes))
+	for key := range nodes {
+		out = append(out, key)
+	}
+	return out
+}
+
+func TestNewMatcherFromPlan(t *testing.T) {
+	original, err := NewMatcher(map[string]any{
+		"name": regexp.MustCompile(`^a"b`),
+		"f":    1.5,
+		"id":   int64(1<<60 + 1),
+		"$or":  []any{map[string]any{"z": 1}, map[string]any{"a": map[string]any{"$in": []any{3, 1, 2}}}},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer original.Close()
+	plan, err := original.ExplainPlan()
+	if err != nil {
+		t.Fatalf("ExplainPlan failed: %v", err)
+	}
+	snapshot, err := original.ExplainJSON()
+	if err != nil {
+		t.Fatalf("ExplainJSON failed: %v", err)
+	}
+
+	fromPlan, err := NewMatcherFromPlan(plan)
+	if err != nil {
+		t.Fatalf("NewMatcherFromPlan failed: %v", err)
+	}
+	defer fromPlan.Close()
+	fromJSON, err := NewMatcherFromExplainJSON(snapshot)
+	if err != nil {
+		t.Fatalf("NewMatcherFromExplainJSON failed: %v", err)
+	}
+	defer fromJSON.Close()
+
+	for _, restored := range []Matcher{fromPlan, fromJSON} {
+		if !restored.Condition().Equal(original.Condition()) {
+			t.Fatalf("restored condition %s, want %s", restored.Condition(), original.Condition())
+		}
+		again, err := restored.ExplainJSON()
+		if err != nil {
+			t.Fatalf("ExplainJSON failed: %v", err)
+		}
+		twice, err := NewMatcherFromExplainJSON(again)
+		if err != nil {
+			t.Fatalf("restoring a restored snapshot failed: %v", err)
+		}
+		twice.Close()
+		for _, doc := range []map[string]any{
+			{"name": `a"bc`, "f": 1.5, "id": int64(1<<60 + 1), "a": 2},
+			{"name": `a"bc`, "f": 1.5, "id": int64(1 << 60), "a": 2},
+		} {
+			want, _ := original.Match(doc)
+			if got, err := restored.Match(doc); err != nil || got != want {
+				t.Fatalf("restored Match(%v) = %v, %v; want %v", doc, got, err, want)
+			}
+		}
+	}
+
+	plan.Children = plan.Children[1:]
+	if _, err := NewMatcherFromPlan(plan); !errors.Is(err, ErrPlanMismatch) {
+		t.Fatalf("NewMatcherFromPlan of an edited plan = %v, want ErrPlanMismatch", err)
+	}
+	if _, err := NewMatcherFromPlan(&ExplainNode{Operator: "Condition", Operand: "{"}); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("NewMatcherFromPlan without a condition = %v", err)
+	}
+	if _, err := NewMatcherFromExplainJSON([]byte(`{"name":"Condition"}`)); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("NewMatcherFromExplainJSON without a canonical condition = %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package mongory
+
+import "fmt"
+
+// FieldType is the type of a field's values. FromURLValues coerces a
+// parameter's text to one of the scalar types; InferSchema also reports the
+// others.
+type FieldType int
+
+const (
+	StringField FieldType = iota
+	IntField
+	FloatField
+	BoolField
+	NullField
+	ArrayField
+	ObjectField
+	OtherField
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case StringField:
+		return "string"
+	case IntField:
+		return "int"
+	case FloatField:
+		return "float"
+	case BoolField:
+		return "bool"
+	case NullField:
+		return "null"
+	case ArrayField:
+		return "array"
+	case ObjectField:
+		return "object"
+	case OtherField:
+		return "other"
+	default:
+		return fmt.Sprintf("FieldType(%d)", int(t))
+	}
+}
+
+// FieldTypes lists the fields a query string may filter on and their types.
+// Field names may be dotted paths into nested documents, as FromURLValues
+// reads them.
+type FieldTypes map[string]FieldType
@@ -0,0 +1,205 @@
+package mongory
+
+import (
+	"context"
+	"iter"
+)
+
+// FilterFunc matches every record in order and calls onMatch for each hit
+// with its index. Returning false from onMatch stops the scan early, so
+// callers can stop after N results without building a result slice. An
+// optional ErrorPolicy controls how failing documents are handled.
+func (m *matcher) FilterFunc(records []any, onMatch func(i int, doc any) bool, policy ...ErrorPolicy) error {
+	match, done := batchMatch(m)
+	defer done()
+	return runBatch(resolvePolicy(policy), records, match, func(i int, doc any, matched bool) bool {
+		return !matched || onMatch(i, doc)
+	})
+}
+
+// MatchBatch matches every value and returns the results in input order.
+// Values are converted into one arena, reset only at the limits set by
+// SetArenaLimits, whatever the BatchMode, so the per-document setup is paid
+// once per run rather than for every value. Failing values report false:
+// under SkipAndCollect they are listed in a *MultiError alongside the
+// results, and under Callback they are passed to the callback, whose first
+// error stops the batch and is returned instead of the results.
+func (m *matcher) MatchBatch(values []any, policy ...ErrorPolicy) ([]bool, error) {
+	b := m.Matcher.NewArenaBatch()
+	defer b.Close()
+	results := make([]bool, len(values))
+	err := runBatch(resolvePolicy(policy), values, b.Match, func(i int, _ any, matched bool) bool {
+		results[i] = matched
+		return true
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		return nil, err
+	}
+	return results, err
+}
+
+// FilterContext returns the records that match, in order, checking ctx
+// every few hundred records so filtering a large slice can be cancelled, for
+// example when a request times out. Once ctx is done it returns ctx.Err()
+// and no records, whatever the policy; a Match already running finishes
+// first. Operators taking a context are passed ctx, as by MatchContext.
+// Failing documents are left out: under SkipAndCollect they are reported in
+// a *MultiError alongside the matches, and under Callback they are passed to
+// the callback, whose first error stops the scan and is returned instead of
+// the matches.
+func (m *matcher) FilterContext(ctx context.Context, records []any, policy ...ErrorPolicy) ([]any, error) {
+	match, done := batchMatchContext(ctx, m)
+	defer done()
+	var matched []any
+	err := runBatchContext(ctx, resolvePolicy(policy), records, match, func(_ int, record any, ok bool) bool {
+		if ok {
+			matched = append(matched, record)
+		}
+		return true
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		return nil, err
+	}
+	return matched, err
+}
+
+// MatchAll lazily yields the documents of seq that match, in order, without
+// collecting them; scratch memory is managed as the BatchMode says. A sequence
+// has no error result, so the policy decides what a failing document does:
+// FailFast (the default) ends the sequence, SkipAndCollect skips it, and
+// Callback reports it and ends the sequence if the callback returns an
+// error. Indices passed to the callback count documents read from seq.
+func (m *matcher) MatchAll(seq iter.Seq[any], policy ...ErrorPolicy) iter.Seq[any] {
+	p := resolvePolicy(policy)
+	return func(yield func(any) bool) {
+		b := batch{policy: p}
+		match, done := batchMatch(m)
+		defer done()
+		i := 0
+		for doc := range seq {
+			matched, err := match(doc)
+			if err != nil {
+				if b.fail(i, doc, err) != nil {
+					return
+				}
+			} else if matched && !yield(doc) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Filter returns the records matching condition, in order. The condition is
+// compiled once and its scratch memory reused for every record, as the
+// BatchMode says. Failing documents are left out: under SkipAndCollect they
+// are reported in a *MultiError alongside the matches, and under Callback
+// they are passed to the callback, whose first error stops the scan and is
+// returned instead of the matches.
+func Filter[T any](records []T, condition map[string]any, policy ...ErrorPolicy) ([]T, error) {
+	m, err := NewMatcher(condition)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	match, done := batchMatch(m)
+	defer done()
+	var matched []T
+	err = runBatch(resolvePolicy(policy), records, match, func(_ int, record T, ok bool) bool {
+		if ok {
+			matched = append(matched, record)
+		}
+		return true
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		return nil, err
+	}
+	return matched, err
+}
+
+// Partition splits records into those matching condition and the rest in a
+// single pass, preserving order within each half, so routing records two
+// ways needs neither two matchers nor an inverted condition. Failing
+// documents are left out of both halves: under SkipAndCollect they are
+// reported in a *MultiError alongside them, and under Callback they are
+// passed to the callback, whose first error stops the scan and is returned
+// instead of the halves.
+func Partition[T any](records []T, condition map[string]any, policy ...ErrorPolicy) (matched, rest []T, err error) {
+	m, err := NewMatcher(condition)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer m.Close()
+	match, done := batchMatch(m)
+	defer done()
+	err = runBatch(resolvePolicy(policy), records, match, func(_ int, record T, ok bool) bool {
+		if ok {
+			matched = append(matched, record)
+		} else {
+			rest = append(rest, record)
+		}
+		return true
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		return nil, nil, err
+	}
+	return matched, rest, err
+}
+
+// Count returns how many records match condition. Failing documents are not
+// counted: under SkipAndCollect they are reported in a *MultiError alongside
+// the count, and under Callback they are passed to the callback, whose first
+// error stops the scan and is returned with a count of 0.
+func Count[T any](records []T, condition map[string]any, policy ...ErrorPolicy) (int, error) {
+	m, err := NewMatcher(condition)
+	if err != nil {
+		return 0, err
+	}
+	defer m.Close()
+	match, done := batchMatch(m)
+	defer done()
+	n := 0
+	err = runBatch(resolvePolicy(policy), records, match, func(_ int, _ T, ok bool) bool {
+		if ok {
+			n++
+		}
+		return true
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		return 0, err
+	}
+	return n, err
+}
+
+// First returns the first record matching condition, stopping the scan
+// there; ok is false when none does. Documents that fail before it are
+// skipped: under SkipAndCollect they are reported in a *MultiError alongside
+// the result, and under Callback they are passed to the callback, whose first
+// error stops the scan and is returned instead of the result.
+func First[T any](records []T, condition map[string]any, policy ...ErrorPolicy) (first T, ok bool, err error) {
+	m, err := NewMatcher(condition)
+	if err != nil {
+		return first, false, err
+	}
+	defer m.Close()
+	match, done := batchMatch(m)
+	defer done()
+	err = runBatch(resolvePolicy(policy), records, match, func(_ int, record T, matched bool) bool {
+		if matched {
+			first, ok = record, true
+		}
+		return !matched
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		var zero T
+		return zero, false, err
+	}
+	return first, ok, err
+}
+
+// Exists reports whether any record matches condition, stopping at the first
+// that does. Errors are handled as by First.
+func Exists[T any](records []T, condition map[string]any, policy ...ErrorPolicy) (bool, error) {
+	_, ok, err := First(records, condition, policy...)
+	return ok, err
+}
@@ -0,0 +1,368 @@
+package mongory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func adultRecords() []any {
+	return []any{
+		map[string]any{"name": "a", "age": 30},
+		map[string]any{"name": "b", "age": 10},
+		map[string]any{"name": "c", "age": 40},
+		map[string]any{"name": "d", "age": 50},
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	matcher, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	var indexes []int
+	err = matcher.FilterFunc(adultRecords(), func(i int, doc any) bool {
+		indexes = append(indexes, i)
+		return len(indexes) < 2
+	})
+	if err != nil {
+		t.Fatalf("FilterFunc failed: %v", err)
+	}
+	if len(indexes) != 2 || indexes[0] != 0 || indexes[1] != 2 {
+		t.Fatalf("FilterFunc visited %v, want [0 2]", indexes)
+	}
+}
+
+func TestMatchBatch(t *testing.T) {
+	m, err := NewMatcher(map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	results, err := m.MatchBatch(adultRecords())
+	if err != nil {
+		t.Fatalf("MatchBatch failed: %v", err)
+	}
+	if want := []bool{true, false, true, true}; !slices.Equal(results, want) {
+		t.Fatalf("MatchBatch = %v, want %v", results, want)
+	}
+	if results, err := m.MatchBatch(nil); err != nil || len(results) != 0 {
+		t.Fatalf("MatchBatch(nil) = %v, %v", results, err)
+	}
+
+	SetNilDocumentMode(NilDocumentError)
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	values := append(adultRecords(), nil)
+	if _, err := m.MatchBatch(values); !errors.Is(err, ErrNilDocument) {
+		t.Fatalf("MatchBatch error = %v, want ErrNilDocument", err)
+	}
+	results, err = m.MatchBatch(values, SkipAndCollect)
+	var multi *MultiError
+	if !errors.As(err, &multi) || !slices.Equal(multi.Indices(), []int{4}) {
+		t.Fatalf("MatchBatch error = %v, want a MultiError for index 4", err)
+	}
+	if want := []bool{true, false, true, true, false}; !slices.Equal(results, want) {
+		t.Fatalf("MatchBatch = %v, want %v", results, want)
+	}
+
+	var failed []int
+	skip := Callback(func(index int, _ any, err error) error {
+		failed = append(failed, index)
+		return nil
+	})
+	results, err = m.MatchBatch(values, skip)
+	if err != nil || !slices.Equal(failed, []int{4}) {
+		t.Fatalf("MatchBatch(Callback) error = %v with failures %v; want nil and [4]", err, failed)
+	}
+	if want := []bool{true, false, true, true, false}; !slices.Equal(results, want) {
+		t.Fatalf("MatchBatch(Callback) = %v, want %v", results, want)
+	}
+	stop := errors.New("stop")
+	abort := Callback(func(int, any, error) error { return stop })
+	if results, err := m.MatchBatch(values, abort); results != nil || err != stop {
+		t.Fatalf("MatchBatch(Callback) = %v, %v; want no results and the callback's error", results, err)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	matched, rest, err := Partition(adultRecords(), map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil {
+		t.Fatalf("Partition failed: %v", err)
+	}
+	if len(matched) != 3 || len(rest) != 1 {
+		t.Fatalf("Partition = %d matched, %d rest; want 3 and 1", len(matched), len(rest))
+	}
+	if rest[0].(map[string]any)["name"] != "b" {
+		t.Fatalf("unexpected rest: %v", rest)
+	}
+	if _, _, err := Partition(adultRecords(), map[string]any{"$and": "hello"}); err == nil {
+		t.Fatalf("Partition with an invalid condition succeeded")
+	}
+	if matched, rest, err := Partition([]any{}, map[string]any{"age": 1}); err != nil || len(matched)+len(rest) != 0 {
+		t.Fatalf("Partition(empty) = %v, %v, %v", matched, rest, err)
+	}
+
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	SetNilDocumentMode(NilDocumentError)
+	records := append(adultRecords(), nil)
+	adult := map[string]any{"age": map[string]any{"$gte": 18}}
+	if _, _, err := Partition(records, adult); !errors.Is(err, ErrNilDocument) {
+		t.Fatalf("Partition error = %v, want ErrNilDocument", err)
+	}
+	matched, rest, err = Partition(records, adult, SkipAndCollect)
+	var multi *MultiError
+	if len(matched) != 3 || len(rest) != 1 || !errors.As(err, &multi) || !slices.Equal(multi.Indices(), []int{4}) {
+		t.Fatalf("Partition(SkipAndCollect) = %d matched, %d rest, %v", len(matched), len(rest), err)
+	}
+	var failed []int
+	skip := Callback(func(index int, _ any, err error) error {
+		failed = append(failed, index)
+		return nil
+	})
+	matched, rest, err = Partition(records, adult, skip)
+	if len(matched) != 3 || len(rest) != 1 || err != nil || !slices.Equal(failed, []int{4}) {
+		t.Fatalf("Partition(Callback) = %d matched, %d rest, %v with failures %v", len(matched), len(rest), err, failed)
+	}
+	stop := errors.New("stop")
+	abort := Callback(func(int, any, error) error { return stop })
+	if matched, rest, err := Partition(records, adult, abort); matched != nil || rest != nil || err != stop {
+		t.Fatalf("Partition(Callback) = %v, %v, %v; want no halves and the callback's error", matched, rest, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	users := []map[string]any{
+		{"name": "a", "age": 30},
+		{"name": "b", "age": 10},
+		{"name": "c", "age": 40},
+	}
+	adults, err := Filter(users, map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil {
+		t.Fatalf("Filter failed: %v", err)
+	}
+	if len(adults) != 2 || adults[0]["name"] != "a" || adults[1]["name"] != "c" {
+		t.Fatalf("Filter = %v, want a and c", adults)
+	}
+	if _, err := Filter(users, map[string]any{"$and": "hello"}); err == nil {
+		t.Fatalf("Filter with an invalid condition succeeded")
+	}
+	none, err := Filter([]map[string]any{}, map[string]any{"age": 1})
+	if err != nil || len(none) != 0 {
+		t.Fatalf("Filter(empty) = %v, %v", none, err)
+	}
+
+	// Under Callback a failing document goes to the callback, not into a
+	// MultiError, and an error the callback returns is returned instead.
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	SetNilDocumentMode(NilDocumentError)
+	records := []any{nil, map[string]any{"age": 20}}
+	var failed []int
+	skip := Callback(func(index int, _ any, err error) error {
+		if !errors.Is(err, ErrNilDocument) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		failed = append(failed, index)
+		return nil
+	})
+	matched, err := Filter(records, map[string]any{"age": map[string]any{"$gte": 18}}, skip)
+	if err != nil || len(matched) != 1 || !slices.Equal(failed, []int{0}) {
+		t.Fatalf("Filter(Callback) = %v, %v with failures %v; want 1 match, no error and [0]", matched, err, failed)
+	}
+	stop := errors.New("stop")
+	abort := Callback(func(int, any, error) error { return stop })
+	if matched, err := Filter(records, map[string]any{"age": map[string]any{"$gte": 18}}, abort); matched != nil || err != stop {
+		t.Fatalf("Filter(Callback) = %v, %v; want no matches and the callback's error", matched, err)
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	matcher, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	var names []any
+	for doc := range matcher.MatchAll(slices.Values(adultRecords())) {
+		names = append(names, doc.(map[string]any)["name"])
+		if len(names) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(names, []any{"a", "c"}) {
+		t.Fatalf("MatchAll yielded %v, want [a c]", names)
+	}
+
+	// An endless source is consumed lazily.
+	endless := func(yield func(any) bool) {
+		for i := 0; ; i++ {
+			if !yield(map[string]any{"age": i}) {
+				return
+			}
+		}
+	}
+	count := 0
+	for range matcher.MatchAll(endless) {
+		if count++; count == 3 {
+			break
+		}
+	}
+
+	records := []any{map[string]any{"age": 30}, map[int]any{1: 1}, map[string]any{"age": 40}}
+	if got := slices.Collect(matcher.MatchAll(slices.Values(records))); len(got) != 1 {
+		t.Fatalf("MatchAll with FailFast yielded %d documents, want 1", len(got))
+	}
+	var failed []int
+	onError := Callback(func(index int, doc any, err error) error {
+		failed = append(failed, index)
+		if !errors.Is(err, ErrCallbackPanic) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return nil
+	})
+	if got := slices.Collect(matcher.MatchAll(slices.Values(records), onError)); len(got) != 2 {
+		t.Fatalf("MatchAll with Callback yielded %d documents, want 2", len(got))
+	}
+	if !slices.Equal(failed, []int{1}) {
+		t.Fatalf("failed indices = %v, want [1]", failed)
+	}
+}
+
+func TestCountFirstExists(t *testing.T) {
+	adult := map[string]any{"age": map[string]any{"$gte": 18}}
+	if n, err := Count(adultRecords(), adult); err != nil || n != 3 {
+		t.Fatalf("Count = %d, %v; want 3", n, err)
+	}
+	first, ok, err := First(adultRecords(), map[string]any{"age": map[string]any{"$lt": 18}})
+	if err != nil || !ok || first.(map[string]any)["name"] != "b" {
+		t.Fatalf("First = %v, %v, %v; want b", first, ok, err)
+	}
+	if _, ok, err := First(adultRecords(), map[string]any{"age": 99}); err != nil || ok {
+		t.Fatalf("First(no match) = %v, %v", ok, err)
+	}
+	if ok, err := Exists(adultRecords(), map[string]any{"name": "d"}); err != nil || !ok {
+		t.Fatalf("Exists(d) = %v, %v; want true", ok, err)
+	}
+	if _, err := Count(adultRecords(), map[string]any{"$and": "hello"}); err == nil {
+		t.Fatalf("Count with an invalid condition succeeded")
+	}
+
+	// A nil document fails in NilDocumentError mode, which shows where each
+	// helper stops scanning.
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	SetNilDocumentMode(NilDocumentError)
+	records := append(adultRecords(), nil)
+	if ok, err := Exists(records, adult); err != nil || !ok {
+		t.Fatalf("Exists = %v, %v; want to stop before the nil document", ok, err)
+	}
+	if _, err := Count(records, adult); !errors.Is(err, ErrNilDocument) {
+		t.Fatalf("Count error = %v, want ErrNilDocument", err)
+	}
+	n, err := Count(records, adult, SkipAndCollect)
+	var multi *MultiError
+	if n != 3 || !errors.As(err, &multi) || len(multi.Errors) != 1 {
+		t.Fatalf("Count(SkipAndCollect) = %d, %v", n, err)
+	}
+	first, ok, err = First([]any{nil, map[string]any{"age": 20}}, adult, SkipAndCollect)
+	if !ok || first == nil || !errors.As(err, &multi) {
+		t.Fatalf("First(SkipAndCollect) = %v, %v, %v", first, ok, err)
+	}
+
+	// Under Callback the failures go to the callback instead.
+	failures := 0
+	skip := Callback(func(int, any, error) error {
+		failures++
+		return nil
+	})
+	if n, err := Count(records, adult, skip); n != 3 || err != nil || failures != 1 {
+		t.Fatalf("Count(Callback) = %d, %v with %d failures; want 3, nil and 1", n, err, failures)
+	}
+	first, ok, err = First([]any{nil, map[string]any{"age": 20}}, adult, skip)
+	if !ok || first == nil || err != nil || failures != 2 {
+		t.Fatalf("First(Callback) = %v, %v, %v with %d failures", first, ok, err, failures)
+	}
+	stop := errors.New("stop")
+	abort := Callback(func(int, any, error) error { return stop })
+	if n, err := Count(records, adult, abort); n != 0 || err != stop {
+		t.Fatalf("Count(Callback) = %d, %v; want 0 and the callback's error", n, err)
+	}
+	if _, ok, err := First([]any{nil, map[string]any{"age": 20}}, adult, abort); ok || err != stop {
+		t.Fatalf("First(Callback) = %v, %v; want false and the callback's error", ok, err)
+	}
+}
+
+func TestFilterContext(t *testing.T) {
+	m, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	records := make([]any, 10*contextCheckInterval)
+	for i := range records {
+		records[i] = map[string]any{"age": i % 40}
+	}
+
+	matched, err := m.FilterContext(context.Background(), records)
+	if err != nil || len(matched) != len(records)*22/40 {
+		t.Fatalf("FilterContext = %d, %v; want %d", len(matched), err, len(records)*22/40)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if matched, err := m.FilterContext(ctx, records); matched != nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("FilterContext(cancelled) = %d, %v; want context.Canceled", len(matched), err)
+	}
+
+	// Cancelling midway stops the scan at the next check.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	seen := 0
+	counting := func(doc any) (bool, error) {
+		if seen++; seen == contextCheckInterval+1 {
+			cancel()
+		}
+		return m.Match(doc)
+	}
+	err = runBatchContext(ctx, FailFast, records, counting, func(int, any, bool) bool { return true })
+	if !errors.Is(err, context.Canceled) || seen != 2*contextCheckInterval {
+		t.Fatalf("runBatchContext stopped after %d records with %v; want %d and context.Canceled", seen, err, 2*contextCheckInterval)
+	}
+
+	// Records matched with the cancelled context fail with ctx.Err(), which
+	// ends the scan rather than going to the policy.
+	cancelling := func(doc any) (bool, error) {
+		if seen++; seen == 2 {
+			cancel()
+		}
+		return m.MatchContext(ctx, doc)
+	}
+	onError := Callback(func(int, any, error) error {
+		t.Fatal("the callback was handed a cancelled record")
+		return nil
+	})
+	for _, policy := range []ErrorPolicy{SkipAndCollect, onError} {
+		seen = 0
+		ctx, cancel = context.WithCancel(context.Background())
+		err = runBatchContext(ctx, policy, records, cancelling, func(int, any, bool) bool { return true })
+		cancel()
+		if !errors.Is(err, context.Canceled) || seen != 2 {
+			t.Fatalf("runBatchContext stopped after %d records with %v; want 2 and context.Canceled", seen, err)
+		}
+	}
+
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	SetNilDocumentMode(NilDocumentError)
+	matched, err = m.FilterContext(context.Background(), []any{nil, map[string]any{"age": 20}}, SkipAndCollect)
+	var multi *MultiError
+	if len(matched) != 1 || !errors.As(err, &multi) {
+		t.Fatalf("FilterContext(SkipAndCollect) = %v, %v", matched, err)
+	}
+	var failed []int
+	skip := Callback(func(index int, _ any, err error) error {
+		failed = append(failed, index)
+		return nil
+	})
+	matched, err = m.FilterContext(context.Background(), []any{nil, map[string]any{"age": 20}}, skip)
+	if len(matched) != 1 || err != nil || !slices.Equal(failed, []int{0}) {
+		t.Fatalf("FilterContext(Callback) = %v, %v with failures %v; want 1 match, no error and [0]", matched, err, failed)
+	}
+}
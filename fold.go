@@ -0,0 +1,99 @@
+package mongory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// AgoKey is the operand expression for an instant relative to when the
+// condition is compiled: {"created": {"$gte": {"$ago": "24h"}}} matches
+// documents created in the last day. The duration is in time.ParseDuration
+// form, and the instant is written the way time.Time values convert, so it
+// compares with them and, under MatcherOptions.ParseTimes, with timestamp
+// strings.
+const AgoKey = "$ago"
+
+// foldConstants replaces the expressions in condition that compiling can
+// evaluate once with their values, taken at now. Macros are expanded before
+// folding, so expressions in their fragments are folded too. The folded
+// condition is the one the matcher keeps, so Explain and Condition show the
+// values that are compared rather than the expressions; a matcher compiled
+// with {"$ago": "1h"} keeps the instant of its compilation for its lifetime.
+func foldConstants(condition map[string]any, now time.Time) (map[string]any, error) {
+	folded, err := foldValue(condition, now)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := folded.(map[string]any)
+	return result, nil
+}
+
+func foldValue(value any, now time.Time) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if operand, ok := v[AgoKey]; ok && len(v) == 1 {
+			return foldAgo(operand, now)
+		}
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			folded, err := foldValue(item, now)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = folded
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			folded, err := foldValue(item, now)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = folded
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// hasFoldable reports whether value holds an expression foldConstants
+// evaluates, whose value depends on when it is compiled.
+func hasFoldable(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		if _, ok := v[AgoKey]; ok && len(v) == 1 {
+			return true
+		}
+		for _, item := range v {
+			if hasFoldable(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if hasFoldable(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func foldAgo(operand any, now time.Time) (string, error) {
+	s, ok := operand.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s needs a duration string, got %T", ErrInvalidCondition, AgoKey, operand)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidCondition, AgoKey, err)
+	}
+	return cgo.FormatTime(now.Add(-d)), nil
+}
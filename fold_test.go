@@ -0,0 +1,93 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+func TestFoldConstants(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	got, err := foldConstants(map[string]any{
+		"created": map[string]any{"$gte": map[string]any{"$ago": "24h"}},
+		"$or": []any{
+			map[string]any{"seen": map[string]any{"$lt": map[string]any{"$ago": "1h30m"}}},
+			map[string]any{"note": map[string]any{"$ago": "1h", "other": 1}},
+		},
+	}, now)
+	if err != nil {
+		t.Fatalf("foldConstants failed: %v", err)
+	}
+	want := map[string]any{
+		"created": map[string]any{"$gte": "2026-02-28T12:00:00.000000000Z"},
+		"$or": []any{
+			map[string]any{"seen": map[string]any{"$lt": "2026-03-01T10:30:00.000000000Z"}},
+			// Only a map holding nothing but $ago is an expression.
+			map[string]any{"note": map[string]any{"$ago": "1h", "other": 1}},
+		},
+	}
+	if !EquivalentConditions(got, want) {
+		t.Fatalf("foldConstants = %s; want %s", CanonicalJSON(got), CanonicalJSON(want))
+	}
+
+	for _, operand := range []any{"yesterday", 24, nil} {
+		_, err := foldConstants(map[string]any{"a": map[string]any{"$gt": map[string]any{"$ago": operand}}}, now)
+		if !errors.Is(err, ErrInvalidCondition) {
+			t.Fatalf("foldConstants($ago: %v) err = %v, want ErrInvalidCondition", operand, err)
+		}
+	}
+}
+
+func TestAgo(t *testing.T) {
+	defer UndefineMacro("recent")
+	if err := DefineMacro("recent", map[string]any{"created": map[string]any{"$gte": map[string]any{"$ago": "1h"}}}); err != nil {
+		t.Fatalf("DefineMacro failed: %v", err)
+	}
+	before := time.Now()
+	m, err := NewMatcherWithOptions(map[string]any{"$macro": "recent"}, MatcherOptions{StrictTypes: true, ParseTimes: true})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer m.Close()
+	for _, tc := range []struct {
+		created any
+		want    bool
+	}{
+		{time.Now().Add(-time.Minute), true},
+		{time.Now().Add(-2 * time.Hour), false},
+		{time.Now().Add(-time.Minute).Format(time.RFC3339), true},
+		{time.Now().Add(-2 * time.Hour).Format(time.RFC3339), false},
+	} {
+		if got, err := m.Match(map[string]any{"created": tc.created}); err != nil || got != tc.want {
+			t.Fatalf("Match(created: %v) = %v, %v; want %v", tc.created, got, err, tc.want)
+		}
+	}
+
+	// What is shown is the instant compared against, not the expression.
+	plan, err := m.ExplainPlan()
+	if err != nil {
+		t.Fatalf("ExplainPlan failed: %v", err)
+	}
+	cutoff, ok := plan.Operand.(map[string]any)["created"].(map[string]any)["$gte"].(string)
+	if !ok {
+		t.Fatalf("ExplainPlan root operand = %v; want a folded $gte", plan.Operand)
+	}
+	if at, err := time.Parse(cgo.TimeLayout, cutoff); err != nil || at.Before(before.Add(-time.Hour-time.Second)) || at.After(time.Now().Add(-time.Hour)) {
+		t.Fatalf("folded cutoff %q, %v; want an hour before compiling", cutoff, err)
+	}
+	if got := m.Condition().String(); got != `{"created":{"$gte":"`+cutoff+`"}}` {
+		t.Fatalf("Condition = %s; want the folded cutoff", got)
+	}
+
+	if err := ValidateCondition(map[string]any{"a": map[string]any{"$lt": map[string]any{"$ago": "15m"}}}); err != nil {
+		t.Fatalf("ValidateCondition rejected $ago: %v", err)
+	}
+	if err := ValidateCondition(map[string]any{"a": map[string]any{"$lt": map[string]any{"$ago": "soon"}}}); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("ValidateCondition accepted a bad duration: %v", err)
+	}
+	if _, err := NewMatcher(map[string]any{"a": map[string]any{"$lt": map[string]any{"$ago": 5}}}); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("NewMatcher accepted a non-string duration: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+//go:build !mongory_noformats
+
+package mongory
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// graphQLOperators maps the operator names common GraphQL filter inputs use
+// (Prisma, Hasura with its leading underscore stripped, and similar) to
+// condition operators that take the operand unchanged.
+var graphQLOperators = map[string]string{
+	"eq":     "$eq",
+	"equals": "$eq",
+	"ne":     "$ne",
+	"neq":    "$ne",
+	"gt":     "$gt",
+	"gte":    "$gte",
+	"lt":     "$lt",
+	"lte":    "$lte",
+	"in":     "$in",
+	"nin":    "$nin",
+	"notIn":  "$nin",
+	"not_in": "$nin",
+	"exists": "$exists",
+	"regex":  "$regex",
+}
+
+// FromGraphQLFilter converts a GraphQL filter argument, as decoded by the
+// resolver, into a condition:
+//
+//	{age: {gte: 18}, OR: [{role: "admin"}, {name: {startsWith: "a"}}]}
+//
+// becomes
+//
+//	{"age": {"$gte": 18}, "$or": [{"role": "admin"}, {"name": {"$regex": "^a"}}]}
+//
+// AND, OR and NOT combine filters and may also be written in lower case or
+// with a leading underscore. Field filters accept eq/equals, ne/neq, gt, gte,
+// lt, lte, in, nin/notIn, exists, regex, contains, startsWith, endsWith,
+// isNull and not, again optionally with a leading underscore. A field filter
+// made of other keys filters a nested object, and a plain value means
+// equality.
+func FromGraphQLFilter(filter map[string]any) (map[string]any, error) {
+	return graphQLCondition(filter)
+}
+
+func graphQLCondition(filter map[string]any) (map[string]any, error) {
+	condition := map[string]any{}
+	var and []any
+	for key, value := range filter {
+		switch logical := strings.ToUpper(strings.TrimPrefix(key, "_")); logical {
+		case "AND", "OR":
+			branches, err := graphQLBranches(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if logical == "OR" {
+				and = append(and, map[string]any{"$or": branches})
+			} else {
+				and = append(and, branches...)
+			}
+		case "NOT":
+			sub, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("mongory: graphql %s must be an object, got %T", key, value)
+			}
+			negated, err := graphQLCondition(sub)
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, map[string]any{"$not": negated})
+		default:
+			sub, ok := value.(map[string]any)
+			if !ok {
+				condition[key] = value
+				continue
+			}
+			if !isGraphQLFieldFilter(sub) {
+				// A condition on the nested object's fields: the core reads
+				// dotted keys as field names, not paths.
+				nested, err := graphQLCondition(sub)
+				if err != nil {
+					return nil, err
+				}
+				condition[key] = nested
+				continue
+			}
+			ops, err := graphQLFieldFilter(key, sub)
+			if err != nil {
+				return nil, err
+			}
+			condition[key] = ops
+		}
+	}
+	if len(and) == 0 {
+		return condition, nil
+	}
+	if len(condition) > 0 {
+		and = append([]any{condition}, and...)
+	}
+	if len(and) == 1 {
+		return and[0].(map[string]any), nil
+	}
+	return map[string]any{"$and": and}, nil
+}
+
+func graphQLBranches(key string, value any) ([]any, error) {
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	case []map[string]any:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case map[string]any:
+		items = []any{v}
+	default:
+		return nil, fmt.Errorf("mongory: graphql %s must be a list of filters, got %T", key, value)
+	}
+	branches := make([]any, 0, len(items))
+	for _, item := range items {
+		sub, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("mongory: graphql %s entries must be objects, got %T", key, item)
+		}
+		branch, err := graphQLCondition(sub)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, nil
+}
+
+// isGraphQLFieldFilter reports whether every key of sub is a field operator,
+// as opposed to a filter on a nested object's fields.
+func isGraphQLFieldFilter(sub map[string]any) bool {
+	if len(sub) == 0 {
+		return false
+	}
+	for key := range sub {
+		if _, ok := graphQLFieldOperator(key); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func graphQLFieldOperator(key string) (string, bool) {
+	name := strings.TrimPrefix(key, "_")
+	if op, ok := graphQLOperators[name]; ok {
+		return op, true
+	}
+	switch name {
+	case "contains", "startsWith", "starts_with", "endsWith", "ends_with", "isNull", "is_null", "not":
+		return name, true
+	}
+	return "", false
+}
+
+func graphQLFieldFilter(field string, sub map[string]any) (map[string]any, error) {
+	ops := map[string]any{}
+	set := func(op string, operand any) error {
+		if _, exists := ops[op]; exists {
+			return fmt.Errorf("mongory: graphql filter on %q sets %s twice", field, op)
+		}
+		ops[op] = operand
+		return nil
+	}
+	// Patterns from contains, startsWith, endsWith and regex, which may be
+	// combined on one field.
+	var patterns []string
+	for key, operand := range sub {
+		name, _ := graphQLFieldOperator(key)
+		var err error
+		switch name {
+		case "contains", "startsWith", "starts_with", "endsWith", "ends_with":
+			text, ok := operand.(string)
+			if !ok {
+				return nil, fmt.Errorf("mongory: graphql %s on %q must be a string, got %T", key, field, operand)
+			}
+			pattern := regexp.QuoteMeta(text)
+			switch name {
+			case "startsWith", "starts_with":
+				pattern = "^" + pattern
+			case "endsWith", "ends_with":
+				pattern += "$"
+			}
+			patterns = append(patterns, pattern)
+		case "$regex":
+			if pattern, ok := operand.(string); ok {
+				patterns = append(patterns, pattern)
+			} else {
+				err = set(name, operand)
+			}
+		case "isNull", "is_null":
+			isNull, ok := operand.(bool)
+			if !ok {
+				return nil, fmt.Errorf("mongory: graphql %s on %q must be a boolean, got %T", key, field, operand)
+			}
+			if isNull {
+				err = set("$eq", nil)
+			} else {
+				err = set("$ne", nil)
+			}
+		case "not":
+			inner, ok := operand.(map[string]any)
+			if !ok {
+				err = set("$ne", operand)
+				break
+			}
+			if !isGraphQLFieldFilter(inner) {
+				return nil, fmt.Errorf("mongory: graphql not on %q must hold field operators", field)
+			}
+			negated, nerr := graphQLFieldFilter(field, inner)
+			if nerr != nil {
+				return nil, nerr
+			}
+			err = set("$not", negated)
+		default:
+			err = set(name, operand)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(patterns)
+	if _, regex := ops["$regex"]; len(patterns) == 1 && !regex {
+		ops["$regex"] = patterns[0]
+	} else if len(patterns) > 0 {
+		// The value must match each. Go's regexp has no lookahead to join
+		// them into one pattern, and "^a.*b$" would not match "ab" for
+		// startsWith "ab" and endsWith "b".
+		each := make([]any, len(patterns))
+		for i, pattern := range patterns {
+			each[i] = map[string]any{"$regex": pattern}
+		}
+		if err := set("$and", each); err != nil {
+			return nil, err
+		}
+	}
+	return ops, nil
+}
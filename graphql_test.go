@@ -0,0 +1,133 @@
+//go:build !mongory_noformats
+
+package mongory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromGraphQLFilter(t *testing.T) {
+	cases := []struct {
+		filter map[string]any
+		want   map[string]any
+	}{
+		{
+			map[string]any{"age": map[string]any{"gte": 18, "lt": 65}, "role": "admin"},
+			map[string]any{"age": map[string]any{"$gte": 18, "$lt": 65}, "role": "admin"},
+		},
+		{
+			map[string]any{"OR": []any{map[string]any{"role": "admin"}, map[string]any{"age": map[string]any{"_gt": 30}}}},
+			map[string]any{"$or": []any{map[string]any{"role": "admin"}, map[string]any{"age": map[string]any{"$gt": 30}}}},
+		},
+		{
+			map[string]any{"name": map[string]any{"startsWith": "a.b"}, "NOT": map[string]any{"banned": true}},
+			map[string]any{"$and": []any{
+				map[string]any{"name": map[string]any{"$regex": `^a\.b`}},
+				map[string]any{"$not": map[string]any{"banned": true}},
+			}},
+		},
+		{
+			map[string]any{"address": map[string]any{"city": map[string]any{"in": []any{"Taipei", "Tokyo"}}}},
+			map[string]any{"address": map[string]any{"city": map[string]any{"$in": []any{"Taipei", "Tokyo"}}}},
+		},
+		{
+			map[string]any{"user": map[string]any{"age": map[string]any{"gte": 18}, "OR": []any{map[string]any{"role": "admin"}}}},
+			map[string]any{"user": map[string]any{"$and": []any{
+				map[string]any{"age": map[string]any{"$gte": 18}},
+				map[string]any{"$or": []any{map[string]any{"role": "admin"}}},
+			}}},
+		},
+		{
+			map[string]any{"name": map[string]any{"startsWith": "ab", "endsWith": "b", "gt": "a"}},
+			map[string]any{"name": map[string]any{"$gt": "a", "$and": []any{
+				map[string]any{"$regex": "^ab"},
+				map[string]any{"$regex": "b$"},
+			}}},
+		},
+		{
+			map[string]any{"deletedAt": map[string]any{"isNull": true}, "age": map[string]any{"not": map[string]any{"lt": 18}}},
+			map[string]any{"deletedAt": map[string]any{"$eq": nil}, "age": map[string]any{"$not": map[string]any{"$lt": 18}}},
+		},
+	}
+	for _, tc := range cases {
+		got, err := FromGraphQLFilter(tc.filter)
+		if err != nil {
+			t.Fatalf("FromGraphQLFilter(%v) failed: %v", tc.filter, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("FromGraphQLFilter(%v) = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	for _, filter := range []map[string]any{
+		{"OR": "x"},
+		{"NOT": []any{}},
+		{"name": map[string]any{"contains": 5}},
+	} {
+		if _, err := FromGraphQLFilter(filter); err == nil {
+			t.Fatalf("FromGraphQLFilter(%v) succeeded, want an error", filter)
+		}
+	}
+
+	condition, err := FromGraphQLFilter(map[string]any{
+		"age": map[string]any{"gte": 18},
+		"OR":  []any{map[string]any{"name": map[string]any{"contains": "an"}}, map[string]any{"role": "admin"}},
+	})
+	if err != nil {
+		t.Fatalf("FromGraphQLFilter failed: %v", err)
+	}
+	matcher, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	for _, tc := range []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"age": 20, "name": "Joanna"}, true},
+		{map[string]any{"age": 20, "name": "Bob", "role": "admin"}, true},
+		{map[string]any{"age": 20, "name": "Bob"}, false},
+		{map[string]any{"age": 10, "name": "Ann"}, false},
+	} {
+		if got, err := matcher.Match(tc.doc); err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, got, err, tc.want)
+		}
+	}
+}
+
+func TestFromGraphQLFilterMatches(t *testing.T) {
+	cases := []struct {
+		filter map[string]any
+		doc    map[string]any
+		want   bool
+	}{
+		{map[string]any{"user": map[string]any{"age": map[string]any{"gte": 18}}}, map[string]any{"user": map[string]any{"age": 20}}, true},
+		{map[string]any{"user": map[string]any{"age": map[string]any{"gte": 18}}}, map[string]any{"user": map[string]any{"age": 10}}, false},
+		{map[string]any{"user": map[string]any{"age": map[string]any{"gte": 18}}}, map[string]any{"user.age": 20}, false},
+		{map[string]any{"user": map[string]any{"address": map[string]any{"city": "Taipei"}}}, map[string]any{"user": map[string]any{"address": map[string]any{"city": "Taipei"}}}, true},
+		{map[string]any{"user": map[string]any{"NOT": map[string]any{"role": "guest"}}}, map[string]any{"user": map[string]any{"role": "admin"}}, true},
+		{map[string]any{"user": map[string]any{"NOT": map[string]any{"role": "guest"}}}, map[string]any{"user": map[string]any{"role": "guest"}}, false},
+		{map[string]any{"name": map[string]any{"startsWith": "ab", "endsWith": "b"}}, map[string]any{"name": "ab"}, true},
+		{map[string]any{"name": map[string]any{"startsWith": "ab", "endsWith": "b"}}, map[string]any{"name": "abxb"}, true},
+		{map[string]any{"name": map[string]any{"startsWith": "ab", "endsWith": "b"}}, map[string]any{"name": "abc"}, false},
+		{map[string]any{"name": map[string]any{"startsWith": "ab", "endsWith": "b"}}, map[string]any{"name": "xab"}, false},
+		{map[string]any{"name": map[string]any{"contains": ".", "regex": "^[a-z.]+$"}}, map[string]any{"name": "a.b"}, true},
+		{map[string]any{"name": map[string]any{"contains": ".", "regex": "^[a-z.]+$"}}, map[string]any{"name": "ab"}, false},
+	}
+	for _, tc := range cases {
+		condition, err := FromGraphQLFilter(tc.filter)
+		if err != nil {
+			t.Fatalf("FromGraphQLFilter(%v) failed: %v", tc.filter, err)
+		}
+		matcher, err := NewMatcher(condition)
+		if err != nil {
+			t.Fatalf("NewMatcher(%v) failed: %v", condition, err)
+		}
+		got, err := matcher.Match(tc.doc)
+		matcher.Close()
+		if err != nil || got != tc.want {
+			t.Fatalf("%v against %v = %v, %v; want %v", tc.filter, tc.doc, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package mongory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// HealthLimits bounds the resources Healthy tolerates. Zero fields are not
+// checked.
+type HealthLimits struct {
+	MaxLivePools int64
+}
+
+// DefaultHealthLimits is used by Healthy and HealthHandler.
+var DefaultHealthLimits = HealthLimits{}
+
+var errNotInitialized = errors.New("mongory: native runtime is not initialized")
+
+// Warmup compiles and matches every condition once so that invalid rules
+// and first-use costs, such as loading the native runtime and the conversion
+// paths, surface at startup rather than on the first request. It keeps none
+// of the matchers it compiles; cache them with a MatcherCache or Registry to
+// keep compiled conditions warm. All failures are reported together.
+func Warmup(conditions []map[string]any) error {
+	var errs []error
+	for i, condition := range conditions {
+		if err := warmup(condition); err != nil {
+			errs = append(errs, fmt.Errorf("condition %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func warmup(condition map[string]any) error {
+	matcher, err := NewMatcher(condition)
+	if err != nil {
+		return err
+	}
+	defer matcher.Close()
+	_, err = matcher.Match(map[string]any{})
+	return err
+}
+
+// Healthy verifies the native runtime is initialized, that a trivial match
+// succeeds, and that native pool usage is within DefaultHealthLimits.
+func Healthy() error {
+	return CheckHealth(DefaultHealthLimits)
+}
+
+// CheckHealth is Healthy with explicit limits.
+func CheckHealth(limits HealthLimits) error {
+	if err := InitE(); err != nil {
+		return err
+	}
+	if !initialized.Load() {
+		return errNotInitialized
+	}
+	if err := selfTest(); err != nil {
+		return err
+	}
+	if limits.MaxLivePools > 0 {
+		if live := cgo.LivePools(); live > limits.MaxLivePools {
+			return fmt.Errorf("mongory: %d live native pools exceeds limit %d", live, limits.MaxLivePools)
+		}
+	}
+	return nil
+}
+
+// selfTest compiles and matches a trivial condition, closing the matcher so
+// that health probes do not count their own pools.
+func selfTest() error {
+	matcher, err := NewMatcher(map[string]any{"ok": true})
+	if err != nil {
+		return fmt.Errorf("mongory: self-test compile failed: %w", err)
+	}
+	defer matcher.Close()
+	matched, err := matcher.Match(map[string]any{"ok": true})
+	if err != nil {
+		return fmt.Errorf("mongory: self-test match failed: %w", err)
+	}
+	if !matched {
+		return errors.New("mongory: self-test match returned false")
+	}
+	return nil
+}
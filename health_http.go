@@ -0,0 +1,22 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HealthHandler serves Healthy as an HTTP endpoint: 200 when healthy, 503
+// with the failure reason otherwise.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := Healthy(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,17 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("HealthHandler status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package mongory
+
+import (
+	"testing"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+func TestWarmup(t *testing.T) {
+	if err := Warmup([]map[string]any{{"age": map[string]any{"$gte": 18}}}); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+	if err := Warmup([]map[string]any{{"$and": "bad"}}); err == nil {
+		t.Fatalf("Warmup should report invalid conditions")
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	if err := Healthy(); err != nil {
+		t.Fatalf("Healthy failed: %v", err)
+	}
+	if err := CheckHealth(HealthLimits{MaxLivePools: -1}); err != nil {
+		t.Fatalf("negative limits should be ignored: %v", err)
+	}
+
+	// Probes and warmups close what they compile, so repeating them does
+	// not run into the pool limit.
+	limits := HealthLimits{MaxLivePools: cgo.LivePools()}
+	for range 50 {
+		if err := CheckHealth(limits); err != nil {
+			t.Fatalf("repeated CheckHealth failed: %v", err)
+		}
+		if err := Warmup([]map[string]any{{"a": 1}}); err != nil {
+			t.Fatalf("Warmup failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package mongory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInSet(t *testing.T) {
+	ints := make([]any, 100)
+	strs := make([]any, 100)
+	for i := range ints {
+		ints[i] = i * 3
+		strs[i] = fmt.Sprintf("id-%d", i)
+	}
+	mixed := append([]any{"x"}, ints...)
+	numbers := append([]any{2.5}, ints...)
+
+	cases := []struct {
+		operand []any
+		value   any
+		want    bool
+	}{
+		{ints, 297, true},
+		{ints, 298, false},
+		{ints, 30.0, true},
+		{ints, 30.5, false},
+		{ints, "30", false},
+		{ints, []any{1, 2, 6}, true},
+		{ints, []any{1, 2}, false},
+		{strs, "id-42", true},
+		{strs, "id-420", false},
+		{strs, 42, false},
+		{mixed, "x", true},
+		{mixed, 3, true},
+		{mixed, 4, false},
+		{numbers, 2.5, true},
+		{numbers, 6, true},
+		{numbers, 6.0, true},
+		{numbers, 7, false},
+	}
+	for _, threshold := range []int{0, 16} {
+		SetInSetThreshold(threshold)
+		for _, tc := range cases {
+			matcher, err := NewCMatcher(map[string]any{"v": map[string]any{"$in": tc.operand}}, nil)
+			if err != nil {
+				t.Fatalf("NewMatcher failed: %v", err)
+			}
+			got, err := matcher.Match(map[string]any{"v": tc.value})
+			if err != nil || got != tc.want {
+				t.Fatalf("threshold %d: $in match of %v = %v, %v; want %v", threshold, tc.value, got, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestInSetNumericTypes(t *testing.T) {
+	defer SetInSetThreshold(16)
+	const big = 1 << 53
+	pad := func(operand ...any) []any {
+		for i := 0; len(operand) < 20; i++ {
+			operand = append(operand, 1000+i)
+		}
+		return operand
+	}
+	cases := []struct {
+		operand []any
+		value   any
+		float   bool // under NumericFloat, the default
+		exact   bool // under NumericExact
+	}{
+		{pad(1, 2.0), 2, true, true},
+		{pad(1, 2), 2.0, true, true},
+		{pad(1, 2.5), 2.0, false, false},
+		{pad(1, 2.5), 2.5, true, true},
+		{pad(1, 2.5), 3, false, false},
+		{pad(int64(big+1), 0.5), int64(big), false, false},
+		{pad(int64(big+1), 0.5), float64(big), true, false},
+		{pad(float64(big)), int64(big + 1), true, false},
+		{pad(float64(big)), int64(big), true, true},
+		{pad(1.0, 2.0), []any{5, 2}, true, true},
+		{pad(-0.0), 0, true, true},
+		{pad(1e300), 1e300, true, true},
+	}
+	for _, threshold := range []int{0, 16} {
+		SetInSetThreshold(threshold)
+		for _, mode := range []NumericMode{NumericFloat, NumericExact} {
+			for _, tc := range cases {
+				want := tc.float
+				if mode == NumericExact {
+					want = tc.exact
+				}
+				for op, opWant := range map[string]bool{"$in": want, "$nin": !want} {
+					m, err := NewMatcherWithOptions(map[string]any{"v": map[string]any{op: tc.operand}}, MatcherOptions{Numeric: mode})
+					if err != nil {
+						t.Fatalf("NewMatcherWithOptions failed: %v", err)
+					}
+					got, err := m.Match(map[string]any{"v": tc.value})
+					m.Close()
+					if err != nil || got != opWant {
+						t.Errorf("threshold %d, %s: %s %v of %v = %v, %v; want %v", threshold, mode, op, tc.operand[:2], tc.value, got, err, opWant)
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkInLarge(b *testing.B) {
+	ids := make([]any, 10000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("user-%d", i)
+	}
+	doc := map[string]any{"id": "user-9999"}
+	for _, threshold := range []int{0, 16} {
+		b.Run(fmt.Sprintf("threshold=%d", threshold), func(b *testing.B) {
+			SetInSetThreshold(threshold)
+			defer SetInSetThreshold(16)
+			matcher, err := NewCMatcher(map[string]any{"id": map[string]any{"$in": ids}}, nil)
+			if err != nil {
+				b.Fatalf("NewMatcher failed: %v", err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := matcher.Match(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package mongory
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+const (
+	maxSchemaExamples = 3
+	maxSchemaDepth    = 32
+)
+
+// Schema describes the fields seen in a sample of documents.
+type Schema struct {
+	Documents int           // documents sampled
+	Fields    []SchemaField // sorted by path
+}
+
+// SchemaField describes one field of a Schema. Fields of documents nested in
+// arrays are listed under the array's path, as "items.sku", since that is
+// how conditions reach them.
+type SchemaField struct {
+	Path         string
+	Types        []FieldType // types the field held, sorted
+	ElementTypes []FieldType // for arrays, the types their elements held
+	Count        int         // documents that have the field
+	Examples     []any       // up to three distinct scalar values
+}
+
+// InferSchema reports the fields, types and example values found in docs,
+// for query editors to autocomplete against. Documents that are not maps
+// count towards Documents but contribute no fields.
+func InferSchema(docs []any) Schema {
+	fields := map[string]*SchemaField{}
+	for _, doc := range docs {
+		seen := map[string]bool{}
+		inferDocument(fields, seen, reflect.ValueOf(doc), "", 0)
+	}
+	schema := Schema{Documents: len(docs), Fields: make([]SchemaField, 0, len(fields))}
+	for _, field := range fields {
+		slices.Sort(field.Types)
+		slices.Sort(field.ElementTypes)
+		schema.Fields = append(schema.Fields, *field)
+	}
+	sort.Slice(schema.Fields, func(i, j int) bool { return schema.Fields[i].Path < schema.Fields[j].Path })
+	return schema
+}
+
+// Field returns the field at path.
+func (s Schema) Field(path string) (SchemaField, bool) {
+	i := sort.Search(len(s.Fields), func(i int) bool { return s.Fields[i].Path >= path })
+	if i < len(s.Fields) && s.Fields[i].Path == path {
+		return s.Fields[i], true
+	}
+	return SchemaField{}, false
+}
+
+// FieldTypes returns the fields that only ever held one kind of scalar,
+// ignoring nulls, in the form FromURLValues takes.
+func (s Schema) FieldTypes() FieldTypes {
+	types := FieldTypes{}
+	for _, field := range s.Fields {
+		kinds := slices.DeleteFunc(slices.Clone(field.Types), func(t FieldType) bool { return t == NullField })
+		if len(kinds) == 1 && kinds[0] <= BoolField {
+			types[field.Path] = kinds[0]
+		}
+	}
+	return types
+}
+
+func inferDocument(fields map[string]*SchemaField, seen map[string]bool, rv reflect.Value, prefix string, depth int) {
+	rv = inferElem(rv)
+	if !rv.IsValid() || rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String || depth > maxSchemaDepth {
+		return
+	}
+	iter := rv.MapRange()
+	for iter.Next() {
+		path := prefix + iter.Key().String()
+		field := fields[path]
+		if field == nil {
+			field = &SchemaField{Path: path}
+			fields[path] = field
+		}
+		if !seen[path] {
+			seen[path] = true
+			field.Count++
+		}
+		value := inferElem(iter.Value())
+		kind := inferType(value)
+		field.Types = addFieldType(field.Types, kind)
+		switch kind {
+		case ObjectField:
+			inferDocument(fields, seen, value, path+".", depth+1)
+		case ArrayField:
+			for i := 0; i < value.Len(); i++ {
+				element := inferElem(value.Index(i))
+				elementKind := inferType(element)
+				field.ElementTypes = addFieldType(field.ElementTypes, elementKind)
+				switch elementKind {
+				case ObjectField:
+					inferDocument(fields, seen, element, path+".", depth+1)
+				case ArrayField, NullField, OtherField:
+				default:
+					field.addExample(element.Interface())
+				}
+			}
+		case NullField, OtherField:
+		default:
+			field.addExample(value.Interface())
+		}
+	}
+}
+
+func (f *SchemaField) addExample(value any) {
+	if len(f.Examples) >= maxSchemaExamples {
+		return
+	}
+	for _, example := range f.Examples {
+		if example == value {
+			return
+		}
+	}
+	f.Examples = append(f.Examples, value)
+}
+
+func inferElem(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Interface || rv.Kind() == reflect.Pointer) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func inferType(rv reflect.Value) FieldType {
+	if !rv.IsValid() {
+		return NullField
+	}
+	if v, ok := cgo.SQLValue(operandInterface(rv)); ok {
+		return inferType(reflect.ValueOf(v))
+	}
+	if n, ok := operandInterface(rv).(json.Number); ok {
+		if number, ok := cgo.JSONNumber(n); ok {
+			return inferType(reflect.ValueOf(number))
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return NullField
+	case reflect.String:
+		return StringField
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntField
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			// Too large for an int64, so matched as a double.
+			return FloatField
+		}
+		return IntField
+	case reflect.Float32, reflect.Float64:
+		return FloatField
+	case reflect.Bool:
+		return BoolField
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return NullField
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Bytes match as a string holding them.
+			return StringField
+		}
+		return ArrayField
+	case reflect.Map:
+		if rv.IsNil() {
+			return NullField
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return OtherField
+		}
+		return ObjectField
+	default:
+		return OtherField
+	}
+}
+
+func addFieldType(types []FieldType, kind FieldType) []FieldType {
+	if slices.Contains(types, kind) {
+		return types
+	}
+	return append(types, kind)
+}
@@ -0,0 +1,52 @@
+package mongory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	schema := InferSchema([]any{
+		map[string]any{"name": "ann", "age": 30, "tags": []any{"a", "b"}, "address": map[string]any{"city": "Taipei"}},
+		map[string]any{"name": "bob", "age": 31.5, "items": []any{map[string]any{"sku": "x"}, map[string]any{"sku": "y"}}},
+		map[string]any{"name": "ann", "age": nil, "address": map[string]any{"city": "Tokyo", "zip": uint16(100)}},
+		"not a document",
+	})
+	if schema.Documents != 4 {
+		t.Fatalf("Documents = %d, want 4", schema.Documents)
+	}
+	var paths []string
+	for _, field := range schema.Fields {
+		paths = append(paths, field.Path)
+	}
+	want := []string{"address", "address.city", "address.zip", "age", "items", "items.sku", "name", "tags"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	cases := []struct {
+		path string
+		want SchemaField
+	}{
+		{"name", SchemaField{Path: "name", Types: []FieldType{StringField}, Count: 3, Examples: []any{"ann", "bob"}}},
+		{"age", SchemaField{Path: "age", Types: []FieldType{IntField, FloatField, NullField}, Count: 3, Examples: []any{30, 31.5}}},
+		{"tags", SchemaField{Path: "tags", Types: []FieldType{ArrayField}, ElementTypes: []FieldType{StringField}, Count: 1, Examples: []any{"a", "b"}}},
+		{"items.sku", SchemaField{Path: "items.sku", Types: []FieldType{StringField}, Count: 1, Examples: []any{"x", "y"}}},
+		{"address.city", SchemaField{Path: "address.city", Types: []FieldType{StringField}, Count: 2, Examples: []any{"Taipei", "Tokyo"}}},
+	}
+	for _, tc := range cases {
+		got, ok := schema.Field(tc.path)
+		if !ok || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Field(%q) = %+v, %v; want %+v", tc.path, got, ok, tc.want)
+		}
+	}
+	if _, ok := schema.Field("missing"); ok {
+		t.Fatalf("Field(missing) found a field")
+	}
+
+	types := schema.FieldTypes()
+	wantTypes := FieldTypes{"name": StringField, "address.city": StringField, "address.zip": IntField, "items.sku": StringField}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Fatalf("FieldTypes = %v, want %v", types, wantTypes)
+	}
+}
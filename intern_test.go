@@ -0,0 +1,38 @@
+package mongory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStringInterning(t *testing.T) {
+	SetStringInterning(4)
+	defer SetStringInterning(0)
+	matcher, err := NewCMatcher(map[string]any{
+		"status": "active",
+		"tags":   map[string]any{"$in": []any{"host-7"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	// More distinct strings than the cache holds, so entries are evicted
+	// while documents referencing them are still being matched.
+	for i := 0; i < 50; i++ {
+		tags := make([]any, 10)
+		for j := range tags {
+			tags[j] = fmt.Sprintf("host-%d", (i+j)%20)
+		}
+		doc := map[string]any{"status": "active", "tags": tags}
+		want := false
+		for _, tag := range tags {
+			want = want || tag == "host-7"
+		}
+		got, err := matcher.Match(doc)
+		if err != nil || got != want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", doc, got, err, want)
+		}
+	}
+	if got, err := matcher.Match(map[string]any{"status": "inactive", "tags": []any{"host-7"}}); err != nil || got {
+		t.Fatalf("Match(inactive) = %v, %v; want false", got, err)
+	}
+}
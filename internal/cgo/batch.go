@@ -0,0 +1,155 @@
+package cgo
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	arenaBatching  atomic.Bool
+	arenaDocuments atomic.Int64
+	arenaBytes     atomic.Int64
+)
+
+func init() {
+	arenaDocuments.Store(256)
+	arenaBytes.Store(4 << 20)
+}
+
+// SetArenaBatching switches batches between resetting their scratch pool
+// after every document (the default) and converting a run of documents into
+// one pool, reset only once it reaches the limits set by SetArenaLimits.
+func SetArenaBatching(enabled bool) {
+	arenaBatching.Store(enabled)
+}
+
+// SetArenaLimits sets how far an arena may grow before a batch resets it:
+// after documents documents or once it holds bytes of native memory,
+// whichever comes first. Zero or less leaves that limit unchanged.
+func SetArenaLimits(documents int, bytes int64) {
+	if documents > 0 {
+		arenaDocuments.Store(int64(documents))
+	}
+	if bytes > 0 {
+		arenaBytes.Store(bytes)
+	}
+}
+
+// ArenaLimits returns the limits set by SetArenaLimits.
+func ArenaLimits() (documents int, bytes int64) {
+	return int(arenaDocuments.Load()), arenaBytes.Load()
+}
+
+// Batch matches a run of documents against one Matcher with a scratch pool
+// held for the whole run, saving the pool hand-off of every Match. Documents
+// matched earlier in the run stay converted until the pool is reset, so
+// their memory must not be relied on after Match returns. A Batch is not
+// safe for concurrent use; Close returns its pool to the matcher.
+type Batch struct {
+	m     *Matcher
+	pool  *MemoryPool
+	fresh bool // pool is not the matcher's, so it is freed, never reset
+	count int64
+	// documents and bytes are the arena limits, fixed when the batch starts.
+	documents int64
+	bytes     int64
+}
+
+// NewBatch starts a batch. The batching mode and arena limits in effect now
+// hold for its whole run.
+func (m *Matcher) NewBatch() *Batch {
+	b := &Batch{m: m, documents: 1}
+	if arenaBatching.Load() {
+		b.documents = arenaDocuments.Load()
+		b.bytes = arenaBytes.Load()
+	}
+	return b
+}
+
+// NewArenaBatch starts a batch that converts documents into one arena, as
+// NewBatch does with arena batching enabled, whatever the batching mode.
+func (m *Matcher) NewArenaBatch() *Batch {
+	return &Batch{m: m, documents: arenaDocuments.Load(), bytes: arenaBytes.Load()}
+}
+
+// Match matches value like Matcher.Match, converting it into the batch's
+// pool.
+func (b *Batch) Match(value any) (bool, error) {
+	return b.MatchContext(context.Background(), value)
+}
+
+// MatchContext is Match in ctx, like Matcher.MatchContext.
+func (b *Batch) MatchContext(ctx context.Context, value any) (matched bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m := b.m
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockShared()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	start := time.Now()
+	defer func() { m.finish(start, matched, err) }()
+	if isNil, err := nilDocument(value); isNil {
+		return false, err
+	}
+	if m.traceEnabled {
+		m.traceMu.Lock()
+		defer m.traceMu.Unlock()
+	}
+	b.prepare()
+	return m.matchIn(ctx, b.pool, value)
+}
+
+// prepare readies the pool for the next document, resetting it once the
+// arena is full.
+func (b *Batch) prepare() {
+	full := b.count >= b.documents || (b.bytes > 0 && b.pool != nil && b.pool.Bytes() >= b.bytes)
+	switch {
+	case b.pool == nil:
+	case !full:
+		b.count++
+		return
+	case b.fresh:
+		b.pool.Free()
+		b.pool = nil
+	default:
+		b.pool.Reset()
+		b.count = 1
+		return
+	}
+	// See Matcher.Match on why deep conversion and sanitizer builds never
+	// reuse a reset pool.
+	if deepConversion.Load() || sanitized {
+		b.pool, b.fresh = NewMemoryPool(), true
+	} else {
+		b.pool, b.fresh = b.m.acquireScratch(), false
+	}
+	b.count = 1
+}
+
+// Close releases the batch's pool. The Batch can be used again afterwards
+// and starts a new run.
+func (b *Batch) Close() {
+	if b.pool == nil {
+		return
+	}
+	pool := b.pool
+	b.pool, b.count = nil, 0
+	if b.fresh {
+		pool.Free()
+		return
+	}
+	unlock, err := b.m.lockShared()
+	if err != nil {
+		// The matcher freed its idle pools already.
+		pool.Free()
+		return
+	}
+	defer unlock()
+	b.m.releaseScratch(pool)
+}
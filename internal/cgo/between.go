@@ -0,0 +1,50 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+*/
+import "C"
+
+// betweenMatcher implements $between: the operand is [low, high], two
+// numbers or two strings, and the field matches {"$gte": low, "$lte": high}
+// as the core compares them.
+type betweenMatcher struct {
+	condition *C.mongory_matcher
+}
+
+func buildBetween(b operatorBuild) (nativeMatcher, string, bool) {
+	operand := &Value{CPoint: b.condition}
+	var bounds *Array
+	if operand.Kind() == MONGORY_TYPE_ARRAY {
+		bounds = operand.GetArray()
+	}
+	if bounds != nil && bounds.Len() == 2 {
+		low, high := bounds.Get(0), bounds.Get(1)
+		if boundKind(low) != MONGORY_TYPE_NULL && boundKind(low) == boundKind(high) {
+			table := NewTable(b.ctx.pool)
+			table.Set("$gte", low)
+			table.Set("$lte", high)
+			if matcher := operandMatcher(b, NewValueTable(b.ctx.pool, table).CPoint); matcher != nil {
+				return &betweenMatcher{condition: matcher}, "Between", true
+			}
+		}
+	}
+	b.fail("$between condition must be [low, high], two numbers or two strings.")
+	return nil, "", false
+}
+
+// boundKind returns the kind of a $between bound, counting integers and
+// doubles as one, or MONGORY_TYPE_NULL for values that cannot be one.
+func boundKind(v *Value) MongoryType {
+	switch kind := v.Kind(); kind {
+	case MONGORY_TYPE_INT, MONGORY_TYPE_DOUBLE:
+		return MONGORY_TYPE_DOUBLE
+	case MONGORY_TYPE_STRING:
+		return kind
+	}
+	return MONGORY_TYPE_NULL
+}
+
+func (m *betweenMatcher) match(value *C.mongory_value) bool {
+	return runMatcher(m.condition, value)
+}
@@ -0,0 +1,49 @@
+package cgo
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+)
+
+var deepConversion atomic.Bool
+
+// SetDeepConversion switches document conversion between bridging Go maps
+// and slices lazily (the default) and copying the whole document into native
+// tables and arrays before matching.
+func SetDeepConversion(enabled bool) {
+	deepConversion.Store(enabled)
+}
+
+var nilDocumentError atomic.Bool
+
+// ErrNilDocument is returned for nil documents when SetNilDocumentError is
+// enabled.
+var ErrNilDocument = errors.New("mongory: nil document")
+
+// SetNilDocumentError switches nil documents (nil, or a nil map, slice or
+// pointer) between matching nothing (the default) and failing with
+// ErrNilDocument.
+func SetNilDocumentError(enabled bool) {
+	nilDocumentError.Store(enabled)
+}
+
+// nilDocument reports whether value is a nil document, with the error the
+// current mode returns for it.
+func nilDocument(value any) (bool, error) {
+	rv := reflect.ValueOf(value)
+	if rv.IsValid() {
+		switch rv.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+			if !rv.IsNil() {
+				return false, nil
+			}
+		default:
+			return false, nil
+		}
+	}
+	if nilDocumentError.Load() {
+		return true, ErrNilDocument
+	}
+	return true, nil
+}
@@ -1,4 +1,4 @@
-// This file aggregates all mongory-core C sources (synced into cgo/binding)
+// This file aggregates all mongory-core C sources (synced into internal/cgo/binding)
 // into a single compilation unit so that cgo can compile automatically.
 
 #include "binding/src/foundations/array.c"
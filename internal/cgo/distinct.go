@@ -0,0 +1,102 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+*/
+import "C"
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// distinctMatcher implements $distinctElems: the field is an array whose
+// elements, or the values at path in its elements, are pairwise different,
+// comparing numbers by value across integers and doubles as the core does.
+// Elements without a value at path are skipped, so {"$distinctElems": "sku"}
+// holds for items of which only one has a SKU.
+type distinctMatcher struct {
+	path []string // nil compares whole elements
+}
+
+func buildDistinct(b operatorBuild) (nativeMatcher, string, bool) {
+	switch operand := recoverValue(b.condition).(type) {
+	case bool:
+		if operand {
+			return &distinctMatcher{}, "DistinctElems", true
+		}
+	case string:
+		if operand != "" {
+			return &distinctMatcher{path: strings.Split(operand, ".")}, "DistinctElems", true
+		}
+	}
+	b.fail("$distinctElems condition must be true or a field path.")
+	return nil, "", false
+}
+
+func (d *distinctMatcher) match(value *C.mongory_value) bool {
+	if value == nil || value._type != C.MONGORY_TYPE_ARRAY {
+		return false
+	}
+	array := (&Value{CPoint: value}).GetArray()
+	if array == nil {
+		return false
+	}
+	seen := make(map[string]struct{}, array.Len())
+	for i := 0; i < array.Len(); i++ {
+		item := d.lookup(array.Get(i))
+		if item == nil {
+			continue
+		}
+		key := distinctKey(item)
+		if _, dup := seen[key]; dup {
+			return false
+		}
+		seen[key] = struct{}{}
+	}
+	return true
+}
+
+// lookup returns the value at the matcher's path in item, or nil if there
+// is none.
+func (d *distinctMatcher) lookup(item *Value) *Value {
+	for _, key := range d.path {
+		if item == nil || item.Kind() != MONGORY_TYPE_TABLE {
+			return nil
+		}
+		table := item.GetTable()
+		if table == nil {
+			return nil
+		}
+		item = table.Get(key)
+	}
+	return item
+}
+
+// distinctKey returns a key equal for values the core compares as equal:
+// numbers by value, strings and booleans by content, and documents and
+// arrays by their JSON.
+func distinctKey(v *Value) string {
+	switch v.Kind() {
+	case MONGORY_TYPE_NULL:
+		return "z"
+	case MONGORY_TYPE_BOOL:
+		return "b" + strconv.FormatBool(v.GetBool())
+	case MONGORY_TYPE_INT:
+		return "n" + strconv.FormatInt(v.GetInt(), 10)
+	case MONGORY_TYPE_DOUBLE:
+		d := v.GetDouble()
+		if i, ok := integral(d); ok {
+			return "n" + strconv.FormatInt(i, 10)
+		}
+		return "n" + strconv.FormatFloat(d, 'g', -1, 64)
+	case MONGORY_TYPE_STRING:
+		return "s" + v.GetString()
+	}
+	encoded, err := json.Marshal(v.Recover())
+	if err != nil {
+		// Values with no JSON form are told apart by their printed form.
+		return "?" + v.ToString()
+	}
+	return "j" + string(encoded)
+}
@@ -0,0 +1,102 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+*/
+import "C"
+import "errors"
+
+var (
+	// ErrInvalidCondition is wrapped by errors for conditions the core
+	// rejects, such as an operand of the wrong type.
+	ErrInvalidCondition = errors.New("mongory: invalid condition")
+	// ErrUnsupportedOperator is wrapped by errors for operations the core
+	// does not support.
+	ErrUnsupportedOperator = errors.New("mongory: unsupported operator")
+	// ErrAllocation is wrapped by errors for native allocations that failed.
+	ErrAllocation = errors.New("mongory: native allocation failed")
+	// ErrConversion is wrapped by errors for documents that could not be
+	// converted for matching, including panics while reading them.
+	ErrConversion = errors.New("mongory: document conversion failed")
+)
+
+// ErrorCode is the type of an error reported by the core.
+type ErrorCode int
+
+const (
+	CodeMemory               ErrorCode = C.MONGORY_ERROR_MEMORY
+	CodeInvalidType          ErrorCode = C.MONGORY_ERROR_INVALID_TYPE
+	CodeOutOfBounds          ErrorCode = C.MONGORY_ERROR_OUT_OF_BOUNDS
+	CodeUnsupportedOperation ErrorCode = C.MONGORY_ERROR_UNSUPPORTED_OPERATION
+	CodeInvalidArgument      ErrorCode = C.MONGORY_ERROR_INVALID_ARGUMENT
+	CodeIO                   ErrorCode = C.MONGORY_ERROR_IO
+	CodeParse                ErrorCode = C.MONGORY_ERROR_PARSE
+	CodeUnknown              ErrorCode = C.MONGORY_ERROR_UNKNOWN
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeMemory:
+		return "memory"
+	case CodeInvalidType:
+		return "invalid type"
+	case CodeOutOfBounds:
+		return "out of bounds"
+	case CodeUnsupportedOperation:
+		return "unsupported operation"
+	case CodeInvalidArgument:
+		return "invalid argument"
+	case CodeIO:
+		return "io"
+	case CodeParse:
+		return "parse"
+	default:
+		return "unknown"
+	}
+}
+
+// NativeError is an error reported by the core. Its text is the core's
+// message; errors.Is matches it against the sentinel for its kind.
+type NativeError struct {
+	Code    ErrorCode
+	Message string
+	kind    error
+}
+
+func (e *NativeError) Error() string {
+	return e.Message
+}
+
+func (e *NativeError) Unwrap() error {
+	return e.kind
+}
+
+// nativeError returns the error recorded on the pool as a *NativeError.
+// Allocation failures and unsupported operations wrap their own sentinel;
+// any other error wraps kind, the sentinel for what the caller was doing.
+func (m *MemoryPool) nativeError(kind error) error {
+	err := &NativeError{Code: CodeUnknown, Message: "mongory: native call failed", kind: kind}
+	if cerr := m.CPoint.error; cerr != nil {
+		err.Code = ErrorCode(cerr._type)
+		if cerr.message != nil {
+			err.Message = C.GoString(cerr.message)
+		}
+	}
+	switch err.Code {
+	case CodeMemory:
+		err.kind = ErrAllocation
+	case CodeUnsupportedOperation:
+		err.kind = ErrUnsupportedOperator
+	}
+	return err
+}
+
+// kindError is an error that keeps its own text but also matches kind.
+type kindError struct {
+	error
+	kind error
+}
+
+func (e kindError) Unwrap() []error {
+	return []error{e.error, e.kind}
+}
@@ -0,0 +1,224 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <string.h>
+#include <mongory-core.h>
+#include "matchers/base_matcher.h"
+#include "matchers/literal_matcher.h"
+
+typedef struct go_mongory_node {
+	int level;
+	char *name;
+	char *field;
+	char *condition;
+	char *record;
+	bool matched;
+} go_mongory_node;
+
+static go_mongory_node *go_mongory_node_new(mongory_memory_pool *pool, mongory_matcher *matcher, int level) {
+	go_mongory_node *node = MG_ALLOC_PTR(pool, go_mongory_node);
+	if (node == NULL) {
+		return NULL;
+	}
+	node->level = level;
+	node->name = matcher->name;
+	node->field = NULL;
+	if (matcher->name != NULL && strcmp(matcher->name, "Field") == 0) {
+		node->field = ((mongory_field_matcher *)matcher)->field;
+	}
+	node->condition = NULL;
+	if (matcher->condition != NULL && matcher->condition->to_str != NULL) {
+		node->condition = matcher->condition->to_str(matcher->condition, pool);
+	}
+	node->record = NULL;
+	node->matched = false;
+	return node;
+}
+
+static bool go_mongory_explain_cb(mongory_matcher *matcher, mongory_matcher_traverse_context *ctx) {
+	mongory_array *nodes = (mongory_array *)ctx->acc;
+	go_mongory_node *node = go_mongory_node_new(ctx->pool, matcher, ctx->level);
+	if (node == NULL) {
+		return false;
+	}
+	nodes->push(nodes, mongory_value_wrap_ptr(ctx->pool, node));
+	return true;
+}
+
+static mongory_array *go_mongory_explain_nodes(mongory_matcher *matcher, mongory_memory_pool *pool) {
+	mongory_array *nodes = mongory_array_new(pool);
+	mongory_matcher_traverse_context ctx = {
+		.pool = pool,
+		.level = 0,
+		.count = 0,
+		.total = 0,
+		.acc = nodes,
+		.callback = go_mongory_explain_cb,
+	};
+	matcher->traverse(matcher, &ctx);
+	return nodes;
+}
+
+static bool go_mongory_traced_match(mongory_matcher *matcher, mongory_value *value) {
+	bool matched = matcher->original_match(matcher, value);
+	mongory_array *nodes = matcher->trace_stack;
+	mongory_memory_pool *pool = nodes->pool;
+	go_mongory_node *node = go_mongory_node_new(pool, matcher, matcher->trace_level);
+	if (node == NULL) {
+		return matched;
+	}
+	if (value != NULL && value->to_str != NULL) {
+		node->record = value->to_str(value, pool);
+	}
+	node->matched = matched;
+	nodes->push(nodes, mongory_value_wrap_ptr(pool, node));
+	return matched;
+}
+
+static bool go_mongory_enable_trace_cb(mongory_matcher *matcher, mongory_matcher_traverse_context *ctx) {
+	matcher->trace_stack = (mongory_array *)ctx->acc;
+	matcher->trace_level = ctx->level;
+	matcher->match = go_mongory_traced_match;
+	return true;
+}
+
+static mongory_array *go_mongory_trace_nodes(mongory_matcher *matcher, mongory_memory_pool *pool, mongory_value *value, bool *matched) {
+	mongory_array *nodes = mongory_array_new(pool);
+	mongory_matcher_traverse_context ctx = {
+		.pool = pool,
+		.level = 0,
+		.count = 0,
+		.total = 0,
+		.acc = nodes,
+		.callback = go_mongory_enable_trace_cb,
+	};
+	matcher->traverse(matcher, &ctx);
+	*matched = matcher->match(matcher, value);
+	mongory_matcher_disable_trace(matcher);
+	return nodes;
+}
+
+static go_mongory_node *go_mongory_node_at(mongory_array *nodes, size_t index) {
+	mongory_value *value = nodes->get(nodes, index);
+	return value == NULL ? NULL : (go_mongory_node *)value->data.ptr;
+}
+*/
+import "C"
+import "runtime"
+
+// ExplainEntry is one matcher node of a compiled condition, listed in
+// depth-first order with its nesting level.
+type ExplainEntry struct {
+	Level     int
+	Name      string
+	Field     string
+	Condition string
+}
+
+// TraceEntry is one evaluated matcher node. Entries are listed parent first,
+// like PrintTrace output.
+type TraceEntry struct {
+	ExplainEntry
+	Record  string
+	Matched bool
+}
+
+func (m *Matcher) ExplainEntries() ([]ExplainEntry, error) {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	pool := NewMemoryPool()
+	defer pool.Free()
+	nodes := C.go_mongory_explain_nodes(m.CPoint, pool.CPoint)
+	if pool.GetError() != "" {
+		return nil, pool.nativeError(nil)
+	}
+	entries := make([]ExplainEntry, 0, int(nodes.count))
+	for i := 0; i < int(nodes.count); i++ {
+		node := C.go_mongory_node_at(nodes, C.size_t(i))
+		if node == nil {
+			continue
+		}
+		entries = append(entries, explainEntry(node))
+	}
+	return entries, nil
+}
+
+func (m *Matcher) TraceEntries(value any) (bool, []TraceEntry, error) {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockExclusive()
+	if err != nil {
+		return false, nil, err
+	}
+	defer unlock()
+	if isNil, err := nilDocument(value); isNil {
+		return false, nil, err
+	}
+	pool := NewMemoryPool()
+	defer pool.Free()
+	convertedValue := m.convertDocument(pool, value)
+	if convertedValue == nil {
+		return false, nil, pool.Err()
+	}
+	var matched C.bool
+	nodes := C.go_mongory_trace_nodes(m.CPoint, pool.CPoint, convertedValue.CPoint, &matched)
+	if m.traceEnabled {
+		C.mongory_matcher_enable_trace(m.CPoint, m.tracePool.CPoint)
+	}
+	if err := pool.MatchError(); err != nil {
+		return false, nil, err
+	}
+	if pool.GetError() != "" {
+		return false, nil, pool.nativeError(nil)
+	}
+	entries := make([]TraceEntry, 0, int(nodes.count))
+	for i := 0; i < int(nodes.count); i++ {
+		node := C.go_mongory_node_at(nodes, C.size_t(i))
+		if node == nil {
+			continue
+		}
+		entries = append(entries, TraceEntry{
+			ExplainEntry: explainEntry(node),
+			Record:       goStringOrEmpty(node.record),
+			Matched:      bool(node.matched),
+		})
+	}
+	return bool(matched), parentFirst(entries, 0), nil
+}
+
+// parentFirst reorders trace entries, which the core records as each node
+// finishes (children before their parent), into parent-first order.
+func parentFirst(entries []TraceEntry, level int) []TraceEntry {
+	sorted := make([]TraceEntry, 0, len(entries))
+	var group []TraceEntry
+	for _, entry := range entries {
+		if entry.Level != level {
+			group = append(group, entry)
+			continue
+		}
+		sorted = append(sorted, entry)
+		sorted = append(sorted, parentFirst(group, level+1)...)
+		group = nil
+	}
+	return sorted
+}
+
+func explainEntry(node *C.go_mongory_node) ExplainEntry {
+	return ExplainEntry{
+		Level:     int(node.level),
+		Name:      goStringOrEmpty(node.name),
+		Field:     goStringOrEmpty(node.field),
+		Condition: goStringOrEmpty(node.condition),
+	}
+}
+
+func goStringOrEmpty(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	return C.GoString(s)
+}
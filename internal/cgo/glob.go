@@ -0,0 +1,102 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+
+static char *go_mongory_glob_s(mongory_value *v) { return v->data.s; }
+*/
+import "C"
+import "strings"
+
+// globMatcher implements $glob: "*" matches any run of characters, "?"
+// matches exactly one, and "\" escapes the next character. Unlike path.Match,
+// "*" also matches "/", so "/api/*" covers every path below /api/.
+type globMatcher struct {
+	pattern []rune
+}
+
+func buildGlob(b operatorBuild) (nativeMatcher, string, bool) {
+	pattern, ok := recoverValue(b.condition).(string)
+	if !ok {
+		b.fail("$glob condition must be a string.")
+		return nil, "", false
+	}
+	if strings.HasSuffix(pattern, `\`) && !strings.HasSuffix(pattern, `\\`) {
+		b.fail("$glob pattern must not end with an unescaped backslash.")
+		return nil, "", false
+	}
+	return &globMatcher{pattern: []rune(pattern)}, "Glob", true
+}
+
+func (g *globMatcher) match(value *C.mongory_value) bool {
+	if value == nil || value._type != C.MONGORY_TYPE_STRING {
+		return false
+	}
+	return globMatch(g.pattern, []rune(C.GoString(C.go_mongory_glob_s(value))))
+}
+
+// globMatch matches with single-star backtracking, which is linear in
+// practice and never exponential.
+func globMatch(pattern, s []rune) bool {
+	p, i := 0, 0
+	starP, starI := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch c := pattern[p]; {
+			case c == '*':
+				starP, starI = p, i
+				p++
+				continue
+			case c == '?':
+				p++
+				i++
+				continue
+			case c == '\\' && p+1 < len(pattern):
+				if pattern[p+1] == s[i] {
+					p += 2
+					i++
+					continue
+				}
+			case c == s[i]:
+				p++
+				i++
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		p = starP + 1
+		starI++
+		i = starI
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// globPrefix returns the literal prefix of a pattern of the form "prefix*".
+func globPrefix(pattern string) (string, bool) {
+	body, ok := strings.CutSuffix(pattern, "*")
+	if !ok {
+		return "", false
+	}
+	var prefix strings.Builder
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*', '?':
+			return "", false
+		case '\\':
+			if i+1 >= len(runes) {
+				return "", false
+			}
+			i++
+			prefix.WriteRune(runes[i])
+		default:
+			prefix.WriteRune(r)
+		}
+	}
+	return prefix.String(), true
+}
@@ -0,0 +1,73 @@
+package cgo
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrMatcherFreed is returned by a Matcher used after Free. The core would
+// dereference released memory instead.
+var ErrMatcherFreed = errors.New("mongory: matcher used after Free")
+
+// ErrCallbackPanic is wrapped by the error Match returns when Go code called
+// from the core, such as a custom operator, panics. A panic cannot unwind
+// through C frames, so it is recovered at the boundary and reported here.
+var ErrCallbackPanic = errors.New("mongory: callback panicked")
+
+// lockShared holds m for a call that only reads the compiled structure,
+// keeping Free and trace changes out until unlock is called.
+func (m *Matcher) lockShared() (unlock func(), err error) {
+	if m == nil || m.matcherState == nil {
+		return nil, ErrMatcherFreed
+	}
+	m.mu.RLock()
+	if m.freed {
+		m.mu.RUnlock()
+		return nil, ErrMatcherFreed
+	}
+	return m.mu.RUnlock, nil
+}
+
+// lockExclusive holds m for a call that rewires the compiled structure, such
+// as switching trace mode, waiting for every other call to finish.
+func (m *Matcher) lockExclusive() (unlock func(), err error) {
+	if m == nil || m.matcherState == nil {
+		return nil, ErrMatcherFreed
+	}
+	m.mu.Lock()
+	if m.freed {
+		m.mu.Unlock()
+		return nil, ErrMatcherFreed
+	}
+	return m.mu.Unlock, nil
+}
+
+// recoverCallback is deferred by exported callbacks. It turns a panic into
+// an error recorded on the pool being matched, if one is known, and reports
+// whether a panic was recovered.
+func recoverCallback(cpool unsafe.Pointer, where string) bool {
+	r := recover()
+	if r == nil {
+		return false
+	}
+	var err error = fmt.Errorf("%w in %s: %v", ErrCallbackPanic, where, r)
+	if where == "document conversion" {
+		err = kindError{err, ErrConversion}
+	}
+	if pool := lookupPool(cpool); pool != nil && pool.callbackErr == nil {
+		pool.callbackErr = err
+	}
+	return true
+}
+
+// MatchError returns the limit violation or callback panic recorded since the
+// last reset, if any. The byte limit is checked once more here, since the
+// core also allocates while matching.
+func (m *MemoryPool) MatchError() error {
+	m.checkByteLimit()
+	if m.limitErr != nil {
+		return m.limitErr
+	}
+	return m.callbackErr
+}
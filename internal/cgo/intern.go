@@ -0,0 +1,126 @@
+package cgo
+
+/*
+#include <stdlib.h>
+#include <mongory-core.h>
+
+static mongory_value *go_mongory_value_wrap_interned(mongory_memory_pool *pool, char *s) {
+	mongory_value *value = mongory_value_wrap_s(pool, NULL);
+	if (value != NULL) {
+		value->data.s = s;
+	}
+	return value;
+}
+*/
+import "C"
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// maxInternLength is the longest document string that is interned. Longer
+// strings are rarely repeated and would crowd out the ones that are.
+const maxInternLength = 128
+
+// internedString is a native copy of a document string shared by every
+// match that converts the same string. The table holds one reference while
+// the string is cached and each pool using it holds another until it is
+// reset, so an evicted string stays valid for matches still reading it.
+type internedString struct {
+	key   string
+	cstr  *C.char
+	refs  atomic.Int32
+	entry *list.Element
+}
+
+func (s *internedString) release() {
+	if s.refs.Add(-1) == 0 {
+		C.free(unsafe.Pointer(s.cstr))
+	}
+}
+
+// internTable is a bounded LRU of interned strings.
+type internTable struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*internedString
+	order    *list.List
+}
+
+var (
+	interning   atomic.Bool
+	internCache = &internTable{entries: map[string]*internedString{}, order: list.New()}
+)
+
+// SetStringInterning caches up to capacity document strings natively so that
+// converting a repeated string reuses one copy across Match calls instead of
+// allocating it in every match. Zero or less disables interning and drops
+// the cache.
+func SetStringInterning(capacity int) {
+	internCache.resize(capacity)
+	interning.Store(capacity > 0)
+}
+
+func (t *internTable) resize(capacity int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.capacity = max(capacity, 0)
+	t.evict()
+}
+
+// evict drops least recently used strings until the table fits.
+func (t *internTable) evict() {
+	for len(t.entries) > t.capacity {
+		s := t.order.Remove(t.order.Back()).(*internedString)
+		delete(t.entries, s.key)
+		s.release()
+	}
+}
+
+// acquire returns the interned copy of key with a reference held for the
+// caller, adding it to the table if needed.
+func (t *internTable) acquire(key string) *internedString {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.entries[key]; ok {
+		t.order.MoveToFront(s.entry)
+		s.refs.Add(1)
+		return s
+	}
+	if t.capacity == 0 {
+		return nil
+	}
+	s := &internedString{key: key, cstr: C.CString(key)}
+	s.refs.Store(2)
+	s.entry = t.order.PushFront(s)
+	t.entries[key] = s
+	t.evict()
+	return s
+}
+
+// internString converts a document string through the intern table, or
+// returns nil if it is not eligible.
+func (m *MemoryPool) internString(s string) *Value {
+	if len(s) > maxInternLength || !interning.Load() {
+		return nil
+	}
+	interned := internCache.acquire(s)
+	if interned == nil {
+		return nil
+	}
+	m.interned = append(m.interned, interned)
+	return &Value{
+		CPoint: C.go_mongory_value_wrap_interned(m.CPoint, interned.cstr),
+		Type:   MONGORY_TYPE_STRING,
+		pool:   m,
+	}
+}
+
+func (m *MemoryPool) releaseInterned() {
+	for _, s := range m.interned {
+		s.release()
+	}
+	m.interned = m.interned[:0]
+}
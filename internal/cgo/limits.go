@@ -0,0 +1,124 @@
+package cgo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ErrDocumentLimit is wrapped by the error Match returns when a document
+// exceeds the configured DocumentLimits.
+var ErrDocumentLimit = errors.New("mongory: document exceeds limits")
+
+// ErrPoolLimit is wrapped by the error Match returns when matching a
+// document needs more native memory than the matcher's memory limit.
+var ErrPoolLimit = errors.New("mongory: native memory limit exceeded")
+
+// DocumentLimits caps what a single matched document may expand into on the
+// native side. Zero fields are not checked.
+type DocumentLimits struct {
+	// MaxDepth is the deepest container nesting allowed; the top-level
+	// document counts as depth 1.
+	MaxDepth int
+	// MaxArrayLength is the longest array allowed anywhere in the document.
+	MaxArrayLength int
+	// MaxNodes is the number of values that may be converted while matching
+	// one document. Conversion is lazy, so only visited values count.
+	MaxNodes int
+}
+
+var documentLimits atomic.Pointer[DocumentLimits]
+
+// SetDocumentLimits sets the limits applied to every document matched from
+// now on.
+func SetDocumentLimits(limits DocumentLimits) {
+	documentLimits.Store(&limits)
+}
+
+// GetDocumentLimits returns the limits currently in effect.
+func GetDocumentLimits() DocumentLimits {
+	if limits := documentLimits.Load(); limits != nil {
+		return *limits
+	}
+	return DocumentLimits{}
+}
+
+// pools maps native pool pointers back to their Go wrapper, so callbacks from
+// the core allocate through the same MemoryPool (and its handle list and
+// limit counters) that owns the document.
+var pools sync.Map
+
+func registerPool(pool *MemoryPool) {
+	pools.Store(uintptr(unsafe.Pointer(pool.CPoint)), pool)
+}
+
+func unregisterPool(pool *MemoryPool) {
+	pools.Delete(uintptr(unsafe.Pointer(pool.CPoint)))
+}
+
+func lookupPool(cpoint unsafe.Pointer) *MemoryPool {
+	if pool, ok := pools.Load(uintptr(cpoint)); ok {
+		return pool.(*MemoryPool)
+	}
+	return nil
+}
+
+// ConvertDocument converts a document to be matched in the current
+// conversion mode, enforcing the current DocumentLimits for the whole
+// (possibly lazy) conversion until the pool is reset.
+func (m *MemoryPool) ConvertDocument(value any) *Value {
+	m.limits = GetDocumentLimits()
+	m.deep = deepConversion.Load()
+	m.nodes = 0
+	m.limitErr = nil
+	m.byteBase = m.Bytes()
+	return m.valueConvert(value, 1)
+}
+
+// LimitError returns the limit violation recorded since the last reset, if
+// any.
+func (m *MemoryPool) LimitError() error {
+	return m.limitErr
+}
+
+// Err returns the pool's limit violation, callback panic or native error,
+// for a document that failed to convert.
+func (m *MemoryPool) Err() error {
+	if err := m.MatchError(); err != nil {
+		return err
+	}
+	return m.nativeError(ErrConversion)
+}
+
+// checkLimits counts one converted value at depth with length elements and
+// records the first limit it breaks.
+func (m *MemoryPool) checkLimits(depth, length int) bool {
+	if m.limitErr != nil {
+		return false
+	}
+	m.nodes++
+	switch {
+	case m.limits.MaxNodes > 0 && m.nodes > m.limits.MaxNodes:
+		m.limitErr = fmt.Errorf("%w: more than %d values", ErrDocumentLimit, m.limits.MaxNodes)
+	case m.limits.MaxDepth > 0 && depth > m.limits.MaxDepth:
+		m.limitErr = fmt.Errorf("%w: nesting depth %d exceeds %d", ErrDocumentLimit, depth, m.limits.MaxDepth)
+	case m.limits.MaxArrayLength > 0 && length > m.limits.MaxArrayLength:
+		m.limitErr = fmt.Errorf("%w: array length %d exceeds %d", ErrDocumentLimit, length, m.limits.MaxArrayLength)
+	default:
+		m.checkByteLimit()
+	}
+	return m.limitErr == nil
+}
+
+// checkByteLimit records ErrPoolLimit once the current document holds more
+// than the pool's byte limit. Allocation itself never fails, as the core does
+// not expect it to; instead conversion stops and Match reports the error.
+func (m *MemoryPool) checkByteLimit() {
+	if m.limitErr == nil && m.byteLimit > 0 {
+		if bytes := m.Bytes() - m.byteBase; bytes > m.byteLimit {
+			m.limitErr = fmt.Errorf("%w: %d bytes exceeds %d", ErrPoolLimit, bytes, m.byteLimit)
+		}
+	}
+}
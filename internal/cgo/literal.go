@@ -0,0 +1,130 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <mongory-core.h>
+#include "matchers/literal_matcher.h"
+#include "matchers/array_record_matcher.h"
+#include "matchers/matcher_traversable.h"
+#include "matchers/composite_matcher.h"
+
+#include <stdio.h>
+
+// go_mongory_prepare_state is the accumulator of the prepare pass: the
+// compiling matcher's context and the array matchers built so far, keyed by
+// the address of their condition.
+typedef struct go_mongory_prepare_state {
+	void *extern_ctx;
+	mongory_table *arrays;
+} go_mongory_prepare_state;
+
+// go_mongory_literal_traverse walks a literal matcher the way the core does
+// before it has matched an array, so explain and trace output does not
+// depend on the array matcher being built.
+static bool go_mongory_literal_traverse(mongory_matcher *matcher, mongory_matcher_traverse_context *ctx) {
+	void *prev_acc = ctx->acc;
+	if (!mongory_matcher_leaf_traverse(matcher, ctx)) {
+		return false;
+	}
+	mongory_matcher *delegate = ((mongory_literal_matcher *)matcher)->delegate_matcher;
+	mongory_matcher_traverse_context child_ctx = {
+		.pool = ctx->pool,
+		.level = ctx->level + 1,
+		.count = 0,
+		.total = 1,
+		.acc = ctx->acc,
+		.callback = ctx->callback,
+	};
+	bool result = delegate->traverse(delegate, &child_ctx);
+	ctx->acc = prev_acc;
+	return result;
+}
+
+// go_mongory_prepare_literals walks the matchers under matcher once,
+// preparing each literal it has not prepared yet. It walks the tree itself
+// rather than through traverse, which would descend again into the array
+// matchers shared below.
+static bool go_mongory_prepare_literals(mongory_matcher *matcher, go_mongory_prepare_state *state) {
+	if (matcher->traverse == mongory_matcher_composite_traverse) {
+		mongory_array *children = ((mongory_composite_matcher *)matcher)->children;
+		for (size_t i = 0; i < children->count; i++) {
+			if (!go_mongory_prepare_literals((mongory_matcher *)children->get(children, (int)i), state)) {
+				return false;
+			}
+		}
+		return true;
+	}
+	if (matcher->traverse != mongory_matcher_literal_traverse) {
+		// A leaf, or a literal prepared already.
+		return true;
+	}
+	mongory_literal_matcher *literal = (mongory_literal_matcher *)matcher;
+	matcher->traverse = go_mongory_literal_traverse;
+	if (matcher->extern_ctx == NULL) {
+		// Field matchers are built without one, which custom operators in
+		// the array matcher need.
+		matcher->extern_ctx = state->extern_ctx;
+	}
+	if (!go_mongory_prepare_literals(literal->delegate_matcher, state)) {
+		return false;
+	}
+	if (literal->array_record_matcher != NULL) {
+		return go_mongory_prepare_literals(literal->array_record_matcher, state);
+	}
+	// The array matcher of a condition holds literals for its subconditions,
+	// which need array matchers of their own: built for each literal, a
+	// condition nesting n fields deep would build 2^n. Literals on the same
+	// condition share one instead; it depends only on the condition and the
+	// context, and is only read while matching.
+	char key[2 * sizeof(void *) + 3];
+	snprintf(key, sizeof(key), "%p", (void *)matcher->condition);
+	mongory_value *built = state->arrays->get(state->arrays, key);
+	if (built != NULL) {
+		literal->array_record_matcher = (mongory_matcher *)built->data.ptr;
+		return true;
+	}
+	// The core parses a table condition into new tables in the condition's
+	// pool, which clones share and may be compiling from concurrently. Hand
+	// it the table viewed from the matcher's pool, so they are allocated
+	// there and freed with the matcher.
+	mongory_value *condition = matcher->condition;
+	if (condition != NULL && condition->type == MONGORY_TYPE_TABLE) {
+		condition = mongory_value_wrap_t(matcher->pool, condition->data.t);
+		if (condition == NULL) {
+			return false;
+		}
+	}
+	literal->array_record_matcher = mongory_matcher_array_record_new(matcher->pool, condition, matcher->extern_ctx);
+	if (literal->array_record_matcher == NULL) {
+		return false;
+	}
+	state->arrays->set(state->arrays, key, mongory_value_wrap_ptr(state->arrays->pool, literal->array_record_matcher));
+	return go_mongory_prepare_literals(literal->array_record_matcher, state);
+}
+
+static bool go_mongory_prepare_literals_handle(mongory_matcher *matcher, uintptr_t extern_ctx) {
+	mongory_memory_pool *temp = mongory_memory_pool_new();
+	if (temp == NULL) {
+		return false;
+	}
+	go_mongory_prepare_state state = {
+		.extern_ctx = (void *)extern_ctx,
+		.arrays = mongory_table_new(temp),
+	};
+	bool ok = state.arrays != NULL && go_mongory_prepare_literals(matcher, &state);
+	temp->free(temp);
+	return ok;
+}
+*/
+import "C"
+import rcgo "runtime/cgo"
+
+// prepareLiterals builds the array matchers of every literal matcher under m,
+// which the core otherwise builds in the matcher's pool on the first array
+// value it meets. Doing that during a match would race between concurrent
+// matches. externCtx is the handle of the compiling matcher's context. It
+// reports false when the core fails to build one.
+func prepareLiterals(m *C.mongory_matcher, externCtx rcgo.Handle) bool {
+	return bool(C.go_mongory_prepare_literals_handle(m, handleArg(externCtx)))
+}
@@ -0,0 +1,457 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <mongory-core.h>
+
+static mongory_matcher *go_mongory_matcher_new(mongory_memory_pool *pool, mongory_value *condition, uintptr_t extern_ctx) {
+	return mongory_matcher_new(pool, condition, (void *)extern_ctx);
+}
+
+// go_mongory_match_pool is the pool of the document being matched on this
+// thread, for Go operators to find the context of the match; values they
+// are called with do not lead there, missing fields being NULL.
+__thread mongory_memory_pool *go_mongory_match_pool = NULL;
+
+static bool go_mongory_matcher_match_in(mongory_matcher *matcher, mongory_value *value, mongory_memory_pool *pool) {
+	mongory_memory_pool *outer = go_mongory_match_pool;
+	go_mongory_match_pool = pool;
+	bool matched = mongory_matcher_match(matcher, value);
+	go_mongory_match_pool = outer;
+	return matched;
+}
+
+// The core prints explain and trace output with printf; flush so it is not
+// lost or reordered when Go output shares the same stream.
+static void go_mongory_flush_stdout() {
+	fflush(stdout);
+}
+*/
+import "C"
+import (
+	"context"
+	"runtime"
+	rcgo "runtime/cgo"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Matcher is a compiled condition. Its native memory is released by Free or,
+// if Free is never called, by a cleanup once the Matcher is unreachable.
+// Every method that passes native pointers to the core keeps the Matcher
+// alive until the call returns.
+//
+// A Matcher is safe for concurrent use. The compiled structure is only read
+// while matching, and each Match converts its document in a scratch pool of
+// its own. Tracing rewires the compiled structure, so the trace methods wait
+// for matches in progress, and matches made while trace is enabled run one
+// at a time.
+type Matcher struct {
+	*matcherState
+	cleanup runtime.Cleanup
+}
+
+// matcherState holds everything a Matcher owns. It is the cleanup argument,
+// so it must never point back to the Matcher.
+type matcherState struct {
+	// mu is held shared by methods that only read the compiled structure
+	// and exclusively by Free and the methods that change trace mode.
+	mu           sync.RWMutex
+	traceMu      sync.Mutex // serializes matches while trace is enabled
+	CPoint       *C.mongory_matcher
+	shared       *sharedCondition
+	condition    *map[string]any
+	context      any
+	options      Options
+	pool         *MemoryPool
+	scratchMu    sync.Mutex
+	scratch      []*MemoryPool // idle scratch pools
+	tracePool    *MemoryPool
+	traceEnabled bool
+	freed        bool
+	memoryLimit  atomic.Int64
+	peakBytes    atomic.Int64
+	stats        matchStats
+	observer     atomic.Pointer[func(MatchEvent)]
+}
+
+// sharedCondition is a converted condition that a matcher and its clones
+// compile from. The core never mutates a condition value after conversion,
+// and compiling allocates only in the new matcher's pool, so clones compile
+// from it concurrently under a shared lock. It is freed only when the last
+// matcher referencing it is freed.
+type sharedCondition struct {
+	pool  *MemoryPool
+	value *Value
+	refs  atomic.Int32
+}
+
+func (c *sharedCondition) retain() {
+	c.refs.Add(1)
+}
+
+func (c *sharedCondition) release() {
+	if c.refs.Add(-1) == 0 {
+		c.pool.Free()
+	}
+}
+
+// NewMatcher compiles condition. context is handed to Go-side operators as
+// they are compiled and is returned by Context.
+func NewMatcher(condition map[string]any, context any) (*Matcher, error) {
+	return NewMatcherWithOptions(condition, context, Options{})
+}
+
+// NewMatcherWithOptions compiles condition like NewMatcher, configured by
+// options.
+func NewMatcherWithOptions(condition map[string]any, context any, options Options) (*Matcher, error) {
+	conditionPool := NewMemoryPool()
+	conditionPool.exactNumbers = options.ExactNumbers
+	conditionPool.parseTimes = options.ParseTimes
+	conditionValue := conditionPool.ConditionConvert(condition)
+	if conditionValue == nil {
+		defer conditionPool.Free()
+		return nil, conditionPool.nativeError(ErrConversion)
+	}
+	if err := conditionPool.LimitError(); err != nil {
+		conditionPool.Free()
+		return nil, err
+	}
+	shared := &sharedCondition{pool: conditionPool, value: conditionValue}
+	shared.retain()
+	m, err := compileMatcher(shared, &condition, context, options)
+	if err != nil {
+		shared.release()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Clone compiles a new matcher from the same converted condition. The clone
+// keeps the memory limit but has its own pools and trace state, so tracing
+// one does not hold up matches on the other. Everything the clone allocates
+// is in its own pools, so freeing it gives the memory back even while the
+// condition is still shared.
+func (m *Matcher) Clone() (*Matcher, error) {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	m.shared.retain()
+	clone, err := compileMatcher(m.shared, m.condition, m.context, m.options)
+	if err != nil {
+		m.shared.release()
+		return nil, err
+	}
+	clone.memoryLimit.Store(m.memoryLimit.Load())
+	return clone, nil
+}
+
+func compileMatcher(shared *sharedCondition, condition *map[string]any, context any, options Options) (*Matcher, error) {
+	pool := NewMemoryPool()
+	h := rcgo.NewHandle(&matcherContext{context: context, pool: pool, options: options})
+	pool.trackHandle(h)
+	cpoint := C.go_mongory_matcher_new(pool.CPoint, shared.value.CPoint, handleArg(h))
+	if cpoint == nil || !prepareLiterals(cpoint, h) {
+		defer pool.Free()
+		return nil, pool.nativeError(ErrInvalidCondition)
+	}
+	state := &matcherState{
+		CPoint:       cpoint,
+		shared:       shared,
+		condition:    condition,
+		context:      context,
+		options:      options,
+		pool:         pool,
+		tracePool:    nil,
+		traceEnabled: false,
+	}
+	m := &Matcher{matcherState: state}
+	m.cleanup = runtime.AddCleanup(m, (*matcherState).free, state)
+	return m, nil
+}
+
+func (m *Matcher) Match(value any) (bool, error) {
+	return m.MatchContext(context.Background(), value)
+}
+
+// MatchContext is Match passing ctx to the operators and $func predicates
+// that take a context. It fails with ctx.Err() without matching if ctx is
+// already done; a match in progress is not interrupted.
+func (m *Matcher) MatchContext(ctx context.Context, value any) (matched bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockShared()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	start := time.Now()
+	defer func() { m.finish(start, matched, err) }()
+	if isNil, err := nilDocument(value); isNil {
+		return false, err
+	}
+	if m.traceEnabled {
+		// Traced matches record into the matcher's shared trace pool.
+		m.traceMu.Lock()
+		defer m.traceMu.Unlock()
+	}
+	var pool *MemoryPool
+	if deepConversion.Load() || sanitized {
+		// A reset pool reuses its chunks without checking they are large
+		// enough for the next request, which the bucket arrays of big
+		// deep-converted tables can overrun; use a fresh pool instead.
+		// Sanitizer builds always do, so stale reads are caught.
+		pool = NewMemoryPool()
+		defer pool.Free()
+	} else {
+		pool = m.acquireScratch()
+		defer m.releaseScratch(pool)
+	}
+	return m.matchIn(ctx, pool, value)
+}
+
+// matchIn converts value into pool and matches it in ctx. The caller holds
+// m.
+func (m *Matcher) matchIn(ctx context.Context, pool *MemoryPool, value any) (bool, error) {
+	pool.ctx = ctx
+	defer func() { pool.ctx = nil }()
+	pool.byteLimit = m.memoryLimit.Load()
+	convertedValue := m.convertDocument(pool, value)
+	if convertedValue == nil {
+		m.notePeak(pool)
+		return false, pool.Err()
+	}
+	result := bool(C.go_mongory_matcher_match_in(m.CPoint, convertedValue.CPoint, pool.CPoint))
+	m.notePeak(pool)
+	if err := pool.MatchError(); err != nil {
+		return false, err
+	}
+
+	return result, nil
+}
+
+// convertDocument converts value into pool as the matcher's options ask.
+func (m *Matcher) convertDocument(pool *MemoryPool, value any) *Value {
+	pool.exactNumbers = m.options.ExactNumbers
+	pool.parseTimes = m.options.ParseTimes
+	return pool.ConvertDocument(value)
+}
+
+// SetMemoryLimit caps the native memory one Match may use to convert and
+// match a document, on top of the compiled condition. A document that needs
+// more fails with ErrPoolLimit. Zero or less removes the limit.
+func (m *Matcher) SetMemoryLimit(bytes int64) {
+	m.memoryLimit.Store(max(bytes, 0))
+}
+
+// PeakNativeBytes reports the most native memory the matcher has held during
+// a Match: its compiled condition plus the largest document conversion. The
+// converted condition shared with clones is not included.
+func (m *Matcher) PeakNativeBytes() int64 {
+	return m.peakBytes.Load()
+}
+
+// acquireScratch takes an idle scratch pool, or makes one when every pool is
+// in use by another Match.
+func (m *Matcher) acquireScratch() *MemoryPool {
+	m.scratchMu.Lock()
+	defer m.scratchMu.Unlock()
+	if n := len(m.scratch); n > 0 {
+		pool := m.scratch[n-1]
+		m.scratch = m.scratch[:n-1]
+		return pool
+	}
+	return NewMemoryPool()
+}
+
+// releaseScratch resets pool and keeps it for the next Match, up to one idle
+// pool per processor.
+func (m *Matcher) releaseScratch(pool *MemoryPool) {
+	pool.Reset()
+	m.scratchMu.Lock()
+	defer m.scratchMu.Unlock()
+	if len(m.scratch) >= runtime.GOMAXPROCS(0) {
+		pool.Free()
+		return
+	}
+	m.scratch = append(m.scratch, pool)
+}
+
+func (m *Matcher) notePeak(scratch *MemoryPool) {
+	bytes := m.pool.Bytes() + scratch.Bytes()
+	for {
+		peak := m.peakBytes.Load()
+		if bytes <= peak || m.peakBytes.CompareAndSwap(peak, bytes) {
+			return
+		}
+	}
+}
+
+func (m *Matcher) Explain() error {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockShared()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	pool := m.acquireScratch()
+	defer m.releaseScratch(pool)
+	C.mongory_matcher_explain(m.CPoint, pool.CPoint)
+	C.go_mongory_flush_stdout()
+	if pool.GetError() != "" {
+		return pool.nativeError(nil)
+	}
+	return nil
+}
+
+func (m *Matcher) Trace(value any) (bool, error) {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockExclusive()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if isNil, err := nilDocument(value); isNil {
+		return false, err
+	}
+	tracePool := NewMemoryPool()
+	defer tracePool.Free()
+	convertedValue := m.convertDocument(tracePool, value)
+	if convertedValue == nil {
+		return false, tracePool.Err()
+	}
+	result := bool(C.mongory_matcher_trace(m.CPoint, convertedValue.CPoint))
+	C.go_mongory_flush_stdout()
+	if err := tracePool.MatchError(); err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+func (m *Matcher) EnableTrace() error {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	m.traceEnabled = true
+	if m.tracePool == nil {
+		m.tracePool = NewMemoryPool()
+	}
+	C.mongory_matcher_enable_trace(m.CPoint, m.tracePool.CPoint)
+	if m.tracePool.GetError() != "" {
+		return m.tracePool.nativeError(nil)
+	}
+	return nil
+}
+
+// TraceEnabled reports whether trace mode is on.
+func (m *Matcher) TraceEnabled() bool {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockShared()
+	if err != nil {
+		return false
+	}
+	defer unlock()
+	return m.traceEnabled
+}
+
+func (m *Matcher) DisableTrace() error {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockExclusive()
+	if err != nil {
+		return nil
+	}
+	defer unlock()
+	if !m.traceEnabled {
+		return nil
+	}
+	C.mongory_matcher_disable_trace(m.CPoint)
+	m.tracePool.Free()
+	m.tracePool = nil
+	m.traceEnabled = false
+	return nil
+}
+
+func (m *Matcher) PrintTrace() error {
+	defer runtime.KeepAlive(m)
+	unlock, err := m.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if !m.traceEnabled {
+		return nil
+	}
+	C.mongory_matcher_print_trace(m.CPoint)
+	C.go_mongory_flush_stdout()
+	if m.tracePool.GetError() != "" {
+		return m.tracePool.nativeError(nil)
+	}
+
+	return nil
+}
+
+// GetCondition returns the condition the matcher was compiled from.
+//
+// Deprecated: the map is shared with the caller that built the matcher.
+// Use the root package's Matcher.Condition for a read-only snapshot.
+func (m *Matcher) GetCondition() *map[string]any {
+	return m.condition
+}
+
+// Context returns the context the matcher was compiled with.
+func (m *Matcher) Context() any {
+	return m.context
+}
+
+// GetContext returns a pointer to a copy of the matcher's context, or nil
+// when it has none.
+//
+// Deprecated: use Context, or the root package's MatcherContext for a typed
+// context.
+func (m *Matcher) GetContext() *any {
+	if m.context == nil {
+		return nil
+	}
+	context := m.context
+	return &context
+}
+
+// Free releases the matcher's native memory once calls in progress return.
+// It is safe to call more than once, and later calls to other methods
+// return ErrMatcherFreed.
+func (m *Matcher) Free() {
+	if m == nil || m.matcherState == nil {
+		return
+	}
+	m.cleanup.Stop()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matcherState.free()
+}
+
+func (s *matcherState) free() {
+	if s == nil || s.freed {
+		return
+	}
+	s.freed = true
+	for _, pool := range s.scratch {
+		pool.Free()
+	}
+	s.scratch = nil
+	s.pool.Free()
+	s.shared.release()
+	if s.tracePool != nil {
+		s.tracePool.Free()
+	}
+}
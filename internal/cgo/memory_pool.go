@@ -0,0 +1,440 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <mongory-core.h>
+#include <stdlib.h>
+
+void *go_mongory_memory_pool_alloc(mongory_memory_pool* pool, size_t size) {
+	return pool->alloc(pool, size);
+}
+
+void go_mongory_memory_pool_reset(mongory_memory_pool* pool) {
+	pool->reset(pool);
+}
+
+void go_mongory_memory_pool_free(mongory_memory_pool* pool) {
+	pool->free(pool);
+}
+
+// go_mongory_counted_pool forwards to a core pool and counts the bytes handed
+// out since the last reset, plus traced memory, and their high-water mark.
+typedef struct go_mongory_counted_pool {
+	mongory_memory_pool base;
+	mongory_memory_pool *inner;
+	size_t allocated;
+	size_t traced;
+	size_t peak;
+} go_mongory_counted_pool;
+
+static void go_mongory_counted_note(go_mongory_counted_pool *c) {
+	size_t bytes = c->allocated + c->traced;
+	if (bytes > c->peak) {
+		c->peak = bytes;
+	}
+}
+
+static void *go_mongory_counted_alloc(mongory_memory_pool *pool, size_t size) {
+	go_mongory_counted_pool *c = (go_mongory_counted_pool *)pool;
+	void *ptr = c->inner->alloc(c->inner, size);
+	if (ptr != NULL) {
+		c->allocated += (size + 7) & ~((size_t)7);
+		go_mongory_counted_note(c);
+	}
+	return ptr;
+}
+
+static void go_mongory_counted_trace(mongory_memory_pool *pool, void *ptr, size_t size) {
+	go_mongory_counted_pool *c = (go_mongory_counted_pool *)pool;
+	c->inner->trace(c->inner, ptr, size);
+	c->traced += size;
+	go_mongory_counted_note(c);
+}
+
+static void go_mongory_counted_reset(mongory_memory_pool *pool) {
+	go_mongory_counted_pool *c = (go_mongory_counted_pool *)pool;
+	c->inner->reset(c->inner);
+	c->allocated = 0;
+}
+
+static void go_mongory_counted_free(mongory_memory_pool *pool) {
+	go_mongory_counted_pool *c = (go_mongory_counted_pool *)pool;
+	c->inner->free(c->inner);
+	free(c);
+}
+
+static mongory_memory_pool *go_mongory_counted_pool_new(void) {
+	mongory_memory_pool *inner = mongory_memory_pool_new();
+	if (inner == NULL) {
+		return NULL;
+	}
+	go_mongory_counted_pool *c = calloc(1, sizeof(go_mongory_counted_pool));
+	if (c == NULL) {
+		inner->free(inner);
+		return NULL;
+	}
+	c->inner = inner;
+	c->base.ctx = inner->ctx;
+	c->base.alloc = go_mongory_counted_alloc;
+	c->base.trace = go_mongory_counted_trace;
+	c->base.reset = go_mongory_counted_reset;
+	c->base.free = go_mongory_counted_free;
+	c->base.error = NULL;
+	return &c->base;
+}
+
+static size_t go_mongory_pool_bytes(mongory_memory_pool *pool) {
+	go_mongory_counted_pool *c = (go_mongory_counted_pool *)pool;
+	return c->allocated + c->traced;
+}
+
+static size_t go_mongory_pool_peak(mongory_memory_pool *pool) {
+	go_mongory_counted_pool *c = (go_mongory_counted_pool *)pool;
+	return c->peak;
+}
+*/
+import "C"
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"regexp"
+	rcgo "runtime/cgo"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+type MemoryPool struct {
+	CPoint   *C.mongory_memory_pool
+	handles  []rcgo.Handle
+	limits   DocumentLimits
+	nodes    int
+	limitErr error
+	// byteLimit is the most native memory a document conversion may use;
+	// zero means unlimited.
+	byteLimit int64
+	// byteBase is what the pool held before the current document, which
+	// pools shared by a batch of documents count from.
+	byteBase int64
+	// callbackErr is a panic recovered from a Go callback during a match.
+	callbackErr error
+	// ctx is the context of the match in progress, for the operators that
+	// take one.
+	ctx  context.Context
+	deep bool
+	// exactNumbers makes numbers converted into the pool compare exactly;
+	// see Options.
+	exactNumbers bool
+	// parseTimes converts strings holding RFC 3339 timestamps into the pool
+	// as times; see Options.
+	parseTimes bool
+	shared     []*sharedValueState
+	// interned are the document strings the pool's values point to.
+	interned []*internedString
+}
+
+var livePools atomic.Int64
+
+var sortConditionKeys = func() *atomic.Bool {
+	b := &atomic.Bool{}
+	b.Store(true)
+	return b
+}()
+
+// SetSortConditionKeys controls whether ConditionConvert inserts map keys in
+// sorted order. Sorting makes the compiled matcher, explain output and
+// evaluation order identical across runs; it is on by default.
+func SetSortConditionKeys(enabled bool) {
+	sortConditionKeys.Store(enabled)
+}
+
+// LivePools reports how many native memory pools are currently allocated.
+func LivePools() int64 {
+	return livePools.Load()
+}
+
+// PoolStats counts the native memory pools currently allocated and the bytes
+// they have handed out since they were last reset.
+type PoolStats struct {
+	Pools int64
+	Bytes int64
+}
+
+// ReadPoolStats sums the pools currently allocated. Pools in use by a match
+// in progress are read without waiting for it, so take it while matchers are
+// idle.
+func ReadPoolStats() PoolStats {
+	var stats PoolStats
+	pools.Range(func(_, pool any) bool {
+		stats.Pools++
+		stats.Bytes += pool.(*MemoryPool).Bytes()
+		return true
+	})
+	return stats
+}
+
+func NewMemoryPool() *MemoryPool {
+	pool := C.go_mongory_counted_pool_new()
+	livePools.Add(1)
+	m := &MemoryPool{CPoint: pool, handles: make([]rcgo.Handle, 0)}
+	registerPool(m)
+	return m
+}
+
+func (m *MemoryPool) trackHandle(h rcgo.Handle) {
+	m.handles = append(m.handles, h)
+}
+
+func (m *MemoryPool) Reset() {
+	C.go_mongory_memory_pool_reset(m.CPoint)
+	for _, h := range m.handles {
+		h.Delete()
+	}
+	m.handles = m.handles[:0]
+	m.nodes = 0
+	m.limitErr = nil
+	m.callbackErr = nil
+	m.releaseInterned()
+}
+
+func (m *MemoryPool) Free() {
+	unregisterPool(m)
+	C.go_mongory_memory_pool_free(m.CPoint)
+	livePools.Add(-1)
+	for _, h := range m.handles {
+		h.Delete()
+	}
+	m.handles = nil
+	for _, shared := range m.shared {
+		shared.release()
+	}
+	m.shared = nil
+	m.releaseInterned()
+}
+
+// Bytes reports how much native memory the pool has handed out since it was
+// last reset.
+func (m *MemoryPool) Bytes() int64 {
+	return int64(C.go_mongory_pool_bytes(m.CPoint))
+}
+
+// PeakBytes reports the most native memory the pool has held at once.
+func (m *MemoryPool) PeakBytes() int64 {
+	return int64(C.go_mongory_pool_peak(m.CPoint))
+}
+
+func (m *MemoryPool) GetError() string {
+	err := m.CPoint.error
+	if err == nil {
+		return ""
+	}
+	return C.GoString(err.message)
+}
+
+func (m *MemoryPool) ConditionConvert(value any) *Value {
+	switch v := value.(type) {
+	case *SharedValue:
+		if v != nil {
+			return m.useShared(v)
+		}
+	case *regexp.Regexp:
+		if v != nil {
+			return NewValueRegex(m, v)
+		}
+	case time.Time:
+		return NewValueString(m, FormatTime(v))
+	case json.Number:
+		if number := m.numberValue(v); number != nil {
+			return number
+		}
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return NewValueNull(m)
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice:
+		if isBytes(rv) {
+			return m.bytesValue(rv)
+		}
+		array := NewArray(m)
+		for i := 0; i < rv.Len(); i++ {
+			array.Push(m.ConditionConvert(rv.Index(i).Interface()))
+		}
+		return NewValueArray(m, array)
+	case reflect.Map:
+		table := NewTable(m)
+		if !sortConditionKeys.Load() {
+			iter := rv.MapRange()
+			for iter.Next() {
+				key := iter.Key().String()
+				table.Set(key, m.ConditionConvert(iter.Value().Interface()))
+			}
+			return NewValueTable(m, table)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			table.Set(key.String(), m.ConditionConvert(rv.MapIndex(key).Interface()))
+		}
+		return NewValueTable(m, table)
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return NewValueNull(m)
+		}
+		return m.ConditionConvert(rv.Elem().Interface())
+	case reflect.String:
+		return m.conditionString(rv.String())
+	case reflect.Struct:
+		if v, ok := SQLValue(value); ok {
+			return m.ConditionConvert(v)
+		}
+		return m.primitiveConvert(value)
+	default:
+		return m.primitiveConvert(value)
+	}
+}
+
+func (m *MemoryPool) ValueConvert(value any) *Value {
+	return m.valueConvert(value, 1)
+}
+
+// valueConvert converts a document value found at the given container depth.
+// Containers are bridged lazily, their elements converted at depth+1 when
+// the core reads them, unless the pool is in deep conversion mode.
+func (m *MemoryPool) valueConvert(value any, depth int) *Value {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		m.checkLimits(0, 0)
+		return NewValueNull(m)
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice:
+		if isBytes(rv) {
+			if !m.checkLimits(0, 0) {
+				return NewValueNull(m)
+			}
+			return m.bytesValue(rv)
+		}
+		if !m.checkLimits(depth, rv.Len()) {
+			return NewValueNull(m)
+		}
+		if m.deep {
+			array := NewArray(m)
+			for i := 0; i < rv.Len(); i++ {
+				array.Push(m.valueConvert(rv.Index(i).Interface(), depth+1))
+			}
+			return NewValueArray(m, array)
+		}
+		return NewValueShallowArray(m, NewShallowArray(m, value, depth))
+	case reflect.Map:
+		if !m.checkLimits(depth, 0) {
+			return NewValueNull(m)
+		}
+		if m.deep {
+			table := NewTable(m)
+			iter := rv.MapRange()
+			for iter.Next() {
+				if iter.Key().Kind() == reflect.String {
+					table.Set(iter.Key().String(), m.valueConvert(iter.Value().Interface(), depth+1))
+				}
+			}
+			return NewValueTable(m, table)
+		}
+		return NewValueShallowTable(m, NewShallowTable(m, value, depth))
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return m.valueConvert(nil, depth)
+		}
+		if layout := structLayout(rv.Type().Elem()); layout != nil {
+			return m.structConvert(layout, rv.UnsafePointer(), depth)
+		}
+		return m.valueConvert(rv.Elem().Interface(), depth)
+	case reflect.String:
+		if !m.checkLimits(0, 0) {
+			return NewValueNull(m)
+		}
+		if n, ok := value.(json.Number); ok {
+			if number := m.numberValue(n); number != nil {
+				return number
+			}
+		}
+		return m.documentString(rv.String())
+	case reflect.Struct:
+		if layout := structLayout(rv.Type()); layout != nil {
+			return m.structConvert(layout, structPointer(rv), depth)
+		}
+		if t, ok := value.(time.Time); ok {
+			if !m.checkLimits(0, 0) {
+				return NewValueNull(m)
+			}
+			return NewValueString(m, FormatTime(t))
+		}
+		if v, ok := SQLValue(value); ok {
+			return m.valueConvert(v, depth)
+		}
+		fallthrough
+	default:
+		if !m.checkLimits(0, 0) {
+			return NewValueNull(m)
+		}
+		return m.primitiveConvert(value)
+	}
+}
+
+// isBytes reports whether rv is a []byte or [N]byte, which convert as
+// strings rather than as arrays of numbers.
+func isBytes(rv reflect.Value) bool {
+	return rv.Type().Elem().Kind() == reflect.Uint8
+}
+
+// bytesValue converts the bytes of rv as a string holding them, compared
+// byte by byte whatever the invalid UTF-8 mode, as binary data need not be
+// text. A nil slice is null, as database/sql scans NULL into a []byte. The
+// core reads strings up to a NUL, so bytes after one are not compared.
+func (m *MemoryPool) bytesValue(rv reflect.Value) *Value {
+	if rv.Kind() == reflect.Slice {
+		if rv.IsNil() {
+			return NewValueNull(m)
+		}
+		return NewValueString(m, string(rv.Bytes()))
+	}
+	b := make([]byte, rv.Len())
+	for i := range b {
+		b[i] = byte(rv.Index(i).Uint())
+	}
+	return NewValueString(m, string(b))
+}
+
+func (m *MemoryPool) primitiveConvert(value any) *Value {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return NewValueUnsupported(m, value)
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewValueInt(m, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return newValueUint(m, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return NewValueDouble(m, rv.Float())
+	case reflect.String:
+		return NewValueString(m, rv.String())
+	case reflect.Bool:
+		return NewValueBool(m, rv.Bool())
+	default:
+		return NewValueUnsupported(m, value)
+	}
+}
+
+// newValueUint converts an unsigned integer: as an integer when it fits in
+// an int64, else as the nearest double, which is how the core compares
+// integers with doubles anyway.
+func newValueUint(m *MemoryPool, u uint64) *Value {
+	if u > math.MaxInt64 {
+		return NewValueDouble(m, float64(u))
+	}
+	return NewValueInt(m, int64(u))
+}
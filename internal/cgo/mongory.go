@@ -0,0 +1,57 @@
+// Package cgo binds mongory-core. Its types mirror the core's, C pointers
+// included, and change with it; use the native package for a stable
+// low-level API.
+package cgo
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/binding/include -I${SRCDIR}/binding/src -I${SRCDIR}/../../mongory-core/include
+#cgo LDFLAGS: -lm
+#include <stdbool.h>
+#include <mongory-core.h>
+#include <stdlib.h>
+
+static bool go_mongory_pool_probe() {
+	mongory_memory_pool *pool = mongory_memory_pool_new();
+	if (pool == NULL) {
+		return false;
+	}
+	pool->free(pool);
+	return true;
+}
+*/
+import "C"
+import "errors"
+
+// Init initializes the core library and verifies it can build and run a
+// trivial matcher, so an unusable native runtime is reported instead of
+// failing later on the first match.
+func Init() error {
+	C.mongory_init()
+	installOperators()
+	installRegex()
+	return selfTest()
+}
+
+func Cleanup() {
+	C.mongory_cleanup()
+}
+
+func selfTest() error {
+	if !C.go_mongory_pool_probe() {
+		return errors.New("mongory: cannot allocate native memory pool")
+	}
+
+	matcher, err := NewMatcher(map[string]any{"probe": 1}, nil)
+	if err != nil {
+		return errors.New("mongory: native self-test failed to compile: " + err.Error())
+	}
+	defer matcher.Free()
+	matched, err := matcher.Match(map[string]any{"probe": 1})
+	if err != nil {
+		return errors.New("mongory: native self-test failed to match: " + err.Error())
+	}
+	if !matched {
+		return errors.New("mongory: native self-test returned a wrong result")
+	}
+	return nil
+}
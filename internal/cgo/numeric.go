@@ -0,0 +1,126 @@
+package cgo
+
+/*
+#include <stdint.h>
+#include <math.h>
+#include <mongory-core.h>
+
+// go_mongory_int_double_compare compares i with d exactly, where the core
+// converts i to a double first and so rounds integers beyond 2^53.
+static int go_mongory_int_double_compare(int64_t i, double d) {
+	if (isnan(d)) {
+		return mongory_value_compare_fail;
+	}
+	if (d >= 9223372036854775808.0) {
+		return -1;
+	}
+	if (d < -9223372036854775808.0) {
+		return 1;
+	}
+	int64_t t = (int64_t)d;
+	if (i != t) {
+		return (i > t) - (i < t);
+	}
+	double frac = d - (double)t;
+	return (frac < 0) - (frac > 0);
+}
+
+static int go_mongory_exact_compare(mongory_value *a, mongory_value *b) {
+	switch (a->type) {
+	case MONGORY_TYPE_INT:
+		if (b->type == MONGORY_TYPE_INT) {
+			return (a->data.i > b->data.i) - (a->data.i < b->data.i);
+		}
+		if (b->type == MONGORY_TYPE_DOUBLE) {
+			return go_mongory_int_double_compare(a->data.i, b->data.d);
+		}
+		break;
+	case MONGORY_TYPE_DOUBLE:
+		if (b->type == MONGORY_TYPE_DOUBLE) {
+			return (a->data.d > b->data.d) - (a->data.d < b->data.d);
+		}
+		if (b->type == MONGORY_TYPE_INT) {
+			int result = go_mongory_int_double_compare(b->data.i, a->data.d);
+			return result == mongory_value_compare_fail ? result : -result;
+		}
+		break;
+	default:
+		break;
+	}
+	return mongory_value_compare_fail;
+}
+
+static void go_mongory_set_exact_compare(mongory_value *v) {
+	if (v != NULL) {
+		v->comp = go_mongory_exact_compare;
+	}
+}
+*/
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Options configure how a Matcher compiles and matches.
+type Options struct {
+	// ExactNumbers compares integers with doubles by their exact values, as
+	// MongoDB does. The core converts the integer to a double instead, which
+	// rounds integers beyond 2^53, so 2^53+1 equals 2^53 as a double.
+	ExactNumbers bool
+	// ParseTimes compares strings holding RFC 3339 timestamps as times, by
+	// converting them to TimeLayout as time.Time values are, so that
+	// "2024-05-01T12:00:00+02:00" equals a time.Time at 10:00 UTC and
+	// orders with other times.
+	ParseTimes bool
+}
+
+// exactNumber makes v, an integer or double, compare exactly when the pool
+// converts for a Matcher with ExactNumbers.
+func (m *MemoryPool) exactNumber(v *Value) *Value {
+	if m.exactNumbers {
+		C.go_mongory_set_exact_compare(v.CPoint)
+	}
+	return v
+}
+
+// Options returns the options the matcher was compiled with.
+func (m *Matcher) Options() Options {
+	return m.options
+}
+
+// JSONNumber returns n, as decoded by a json.Decoder with UseNumber, as an
+// int64 when it is whole and fits, so that large integer IDs keep their
+// exact value, and as a float64 otherwise. ok is false if n is not a number.
+func JSONNumber(n json.Number) (value any, ok bool) {
+	if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+		return i, true
+	}
+	f, err := strconv.ParseFloat(n.String(), 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil, false
+	}
+	if f == math.Trunc(f) && math.Abs(f) <= 1<<63 {
+		// Whole numbers written with a fraction or exponent, such as
+		// 9007199254740993.0, are read exactly rather than through f.
+		if r, ok := new(big.Rat).SetString(n.String()); ok && r.IsInt() && r.Num().IsInt64() {
+			return r.Num().Int64(), true
+		}
+	}
+	return f, true
+}
+
+// numberValue converts n as JSONNumber reads it, or returns nil if it is
+// not a number.
+func (m *MemoryPool) numberValue(n json.Number) *Value {
+	switch v, _ := JSONNumber(n); v := v.(type) {
+	case int64:
+		return NewValueInt(m, v)
+	case float64:
+		return NewValueDouble(m, v)
+	}
+	return nil
+}
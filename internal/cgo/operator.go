@@ -0,0 +1,504 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <mongory-core.h>
+#include "foundations/config_private.h"
+#include "foundations/utils.h"
+#include "matchers/composite_matcher.h"
+#include "matchers/external_matcher.h"
+#include "matchers/inclusion_matcher.h"
+
+extern bool go_mongory_custom_lookup(char *key);
+extern mongory_matcher_custom_context *go_mongory_custom_build(char *key, mongory_value *condition, void *extern_ctx);
+extern bool go_mongory_custom_match(void *external_matcher, mongory_value *value);
+extern bool go_mongory_recover_pair(char *key, mongory_value *value, void *acc);
+
+static mongory_matcher_custom_context *go_mongory_custom_context_new(mongory_memory_pool *pool, char *name, void *external) {
+	mongory_matcher_custom_context *ctx = MG_ALLOC_PTR(pool, mongory_matcher_custom_context);
+	if (ctx == NULL) {
+		return NULL;
+	}
+	ctx->name = mongory_string_cpy(pool, name);
+	ctx->external_matcher = external;
+	return ctx;
+}
+
+// $in is built by the core before custom operators are consulted, so the Go
+// side gets a chance at it through this wrapper and falls back to the linear
+// core matcher when it declines.
+static mongory_matcher *go_mongory_in_new(mongory_memory_pool *pool, mongory_value *condition, void *extern_ctx) {
+	if (condition != NULL && condition->type == MONGORY_TYPE_ARRAY && condition->data.a != NULL && extern_ctx != NULL) {
+		mongory_matcher *matcher = mongory_matcher_custom_new(pool, "$in", condition, extern_ctx);
+		if (matcher != NULL) {
+			return matcher;
+		}
+	}
+	return mongory_matcher_in_new(pool, condition, extern_ctx);
+}
+
+// Likewise for $or, so that disjunctions of numeric ranges on one field can
+// be compiled to a sorted interval search.
+static mongory_matcher *go_mongory_or_new(mongory_memory_pool *pool, mongory_value *condition, void *extern_ctx) {
+	if (condition != NULL && condition->type == MONGORY_TYPE_ARRAY && condition->data.a != NULL && extern_ctx != NULL) {
+		mongory_matcher *matcher = mongory_matcher_custom_new(pool, "$or", condition, extern_ctx);
+		if (matcher != NULL) {
+			return matcher;
+		}
+	}
+	return mongory_matcher_or_new(pool, condition, extern_ctx);
+}
+
+static void go_mongory_pool_set_error(mongory_memory_pool *pool, char *message) {
+	mongory_error *error = MG_ALLOC_PTR(pool, mongory_error);
+	if (error == NULL) {
+		return;
+	}
+	error->type = MONGORY_ERROR_INVALID_ARGUMENT;
+	error->message = mongory_string_cpy(pool, message);
+	pool->error = error;
+}
+
+static void go_mongory_operators_install() {
+	mongory_custom_matcher_lookup_func_set(go_mongory_custom_lookup);
+	mongory_custom_matcher_build_func_set(go_mongory_custom_build);
+	mongory_custom_matcher_match_func_set(go_mongory_custom_match);
+	mongory_matcher_register("$in", go_mongory_in_new);
+	mongory_matcher_register("$or", go_mongory_or_new);
+}
+
+// go_mongory_core_operators adds the names in the core's matcher table to
+// the map behind acc.
+static bool go_mongory_core_operators(uintptr_t acc) {
+	if (mongory_matcher_mapping == NULL || mongory_matcher_mapping->each == NULL) {
+		return false;
+	}
+	return mongory_matcher_mapping->each(mongory_matcher_mapping, (void *)acc, go_mongory_recover_pair);
+}
+
+extern __thread mongory_memory_pool *go_mongory_match_pool;
+
+static mongory_memory_pool *go_mongory_current_match_pool() { return go_mongory_match_pool; }
+
+static int64_t go_mongory_value_i(mongory_value *v) { return v->data.i; }
+static double go_mongory_value_d(mongory_value *v) { return v->data.d; }
+static char *go_mongory_value_s(mongory_value *v) { return v->data.s; }
+static mongory_array *go_mongory_value_a(mongory_value *v) { return v->data.a; }
+
+static mongory_value *go_mongory_array_at(mongory_array *a, size_t index) {
+	return a->get(a, index);
+}
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"math"
+	rcgo "runtime/cgo"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// nativeMatcher is a Go implementation of one operator in a compiled
+// condition, called by the core through the custom matcher adapter.
+type nativeMatcher interface {
+	match(value *C.mongory_value) bool
+}
+
+// operatorBuild is what an operatorBuilder receives: the native operand and
+// the compiling matcher's context.
+type operatorBuild struct {
+	condition *C.mongory_value
+	ctx       *matcherContext
+	externCtx rcgo.Handle
+}
+
+// fail reports an invalid operand as the compile error of the matcher.
+func (b operatorBuild) fail(message string) {
+	cmessage := C.CString(message)
+	defer C.free(unsafe.Pointer(cmessage))
+	C.go_mongory_pool_set_error(b.ctx.pool.CPoint, cmessage)
+}
+
+// operatorBuilder compiles an operator operand. Returning ok == false
+// declines, which for overridden built-ins means the core implementation is
+// used instead.
+type operatorBuilder func(b operatorBuild) (m nativeMatcher, name string, ok bool)
+
+var (
+	operatorsMu sync.RWMutex
+	operators   = map[string]operatorBuilder{
+		"$in":            buildInSet,
+		"$or":            buildOr,
+		"$glob":          buildGlob,
+		"$rollout":       buildRollout,
+		"$func":          buildFunc,
+		"$distinctElems": buildDistinct,
+		"$sumOf":         buildReducer(reduceSum, "$sumOf", "SumOf"),
+		"$minOf":         buildReducer(reduceMin, "$minOf", "MinOf"),
+		"$maxOf":         buildReducer(reduceMax, "$maxOf", "MaxOf"),
+		"$avgOf":         buildReducer(reduceAvg, "$avgOf", "AvgOf"),
+		"$strLen":        buildStrLen,
+		"$between":       buildBetween,
+	}
+	// customOperators are the names added with RegisterOperator.
+	customOperators = map[string]bool{}
+)
+
+// RegisterOperator makes name compile to fn, which is called with the
+// matched value and the operand as Go values. It fails if name already has a
+// Go implementation.
+func RegisterOperator(name string, fn func(value, operand any) bool) error {
+	return registerOperator(name, func(b operatorBuild) (nativeMatcher, string, bool) {
+		return &funcMatcher{fn: fn, operand: recoverValue(b.condition)}, name, true
+	})
+}
+
+// RegisterOperatorContext is RegisterOperator for functions that take the
+// context of the match, as given to MatchContext.
+func RegisterOperatorContext(name string, fn func(ctx context.Context, value, operand any) bool) error {
+	return registerOperator(name, func(b operatorBuild) (nativeMatcher, string, bool) {
+		return &contextFuncMatcher{fn: fn, operand: recoverValue(b.condition)}, name, true
+	})
+}
+
+// RegisterValueOperator is RegisterOperator for functions that read the
+// matched value in place instead of having it converted to a Go value.
+func RegisterValueOperator(name string, fn func(value *Value, operand any) bool) error {
+	return registerOperator(name, func(b operatorBuild) (nativeMatcher, string, bool) {
+		return &valueFuncMatcher{fn: fn, operand: recoverValue(b.condition)}, name, true
+	})
+}
+
+func registerOperator(name string, build operatorBuilder) error {
+	operatorsMu.Lock()
+	defer operatorsMu.Unlock()
+	if _, exists := operators[name]; exists {
+		return fmt.Errorf("mongory: operator %s is already registered", name)
+	}
+	operators[name] = build
+	customOperators[name] = true
+	return nil
+}
+
+// CustomOperator reports whether name was added with RegisterOperator,
+// RegisterOperatorContext or RegisterValueOperator.
+func CustomOperator(name string) bool {
+	operatorsMu.RLock()
+	defer operatorsMu.RUnlock()
+	return customOperators[name]
+}
+
+// CustomOperators returns the names added with RegisterOperator,
+// RegisterOperatorContext or RegisterValueOperator, sorted.
+func CustomOperators() []string {
+	operatorsMu.RLock()
+	names := make([]string, 0, len(customOperators))
+	for name := range customOperators {
+		names = append(names, name)
+	}
+	operatorsMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// CoreOperators returns the operators registered in the linked core's
+// matcher table, sorted, or nil before Init. They include the built-ins this
+// package overrides there, such as $in and $or, but not the operators it
+// implements in Go.
+func CoreOperators() []string {
+	table := map[string]any{}
+	h := rcgo.NewHandle(table)
+	defer h.Delete()
+	if !C.go_mongory_core_operators(handleArg(h)) {
+		return nil
+	}
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnregisterOperator removes an operator added with RegisterOperator,
+// RegisterOperatorContext or RegisterValueOperator, reporting whether there
+// was one. Matchers compiled with it keep using it.
+func UnregisterOperator(name string) bool {
+	operatorsMu.Lock()
+	defer operatorsMu.Unlock()
+	if _, ok := customOperators[name]; !ok {
+		return false
+	}
+	delete(operators, name)
+	delete(customOperators, name)
+	return true
+}
+
+// funcMatcher implements an operator registered with RegisterOperator.
+type funcMatcher struct {
+	fn      func(value, operand any) bool
+	operand any
+}
+
+func (f *funcMatcher) match(value *C.mongory_value) bool {
+	return f.fn(recoverValue(value), f.operand)
+}
+
+// contextFuncMatcher implements an operator registered with
+// RegisterOperatorContext.
+type contextFuncMatcher struct {
+	fn      func(ctx context.Context, value, operand any) bool
+	operand any
+}
+
+func (f *contextFuncMatcher) match(value *C.mongory_value) bool {
+	return f.fn(matchContext(), recoverValue(value), f.operand)
+}
+
+// matchContext returns the context of the match in progress on this thread,
+// or the background context outside MatchContext.
+func matchContext() context.Context {
+	if pool := lookupPool(unsafe.Pointer(C.go_mongory_current_match_pool())); pool != nil && pool.ctx != nil {
+		return pool.ctx
+	}
+	return context.Background()
+}
+
+// valueFuncMatcher implements an operator registered with
+// RegisterValueOperator.
+type valueFuncMatcher struct {
+	fn      func(value *Value, operand any) bool
+	operand any
+}
+
+func (f *valueFuncMatcher) match(value *C.mongory_value) bool {
+	return f.fn(&Value{CPoint: value}, f.operand)
+}
+
+// matcherContext is what a compiled matcher passes to the core as its
+// extern_ctx: the caller's context and the pool Go-side operator state is
+// tied to.
+type matcherContext struct {
+	context any
+	pool    *MemoryPool
+	options Options
+}
+
+func installOperators() {
+	C.go_mongory_operators_install()
+}
+
+//export go_mongory_custom_lookup
+func go_mongory_custom_lookup(key *C.char) C.bool {
+	operatorsMu.RLock()
+	defer operatorsMu.RUnlock()
+	_, ok := operators[C.GoString(key)]
+	return C.bool(ok)
+}
+
+//export go_mongory_custom_build
+func go_mongory_custom_build(key *C.char, condition *C.mongory_value, externCtx unsafe.Pointer) (custom *C.mongory_matcher_custom_context) {
+	if externCtx == nil {
+		return nil
+	}
+	h := ptrToHandle(externCtx)
+	ctx, ok := h.Value().(*matcherContext)
+	if !ok {
+		return nil
+	}
+	operatorName := C.GoString(key)
+	operatorsMu.RLock()
+	build := operators[operatorName]
+	operatorsMu.RUnlock()
+	if build == nil {
+		return nil
+	}
+	b := operatorBuild{condition: condition, ctx: ctx, externCtx: h}
+	defer func() {
+		if r := recover(); r != nil {
+			b.fail(fmt.Sprintf("%v in %s: %v", ErrCallbackPanic, operatorName, r))
+			custom = nil
+		}
+	}()
+	m, name, ok := build(b)
+	if !ok {
+		return nil
+	}
+	mh := rcgo.NewHandle(m)
+	ctx.pool.trackHandle(mh)
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.go_mongory_custom_context_new(ctx.pool.CPoint, cname, handleToPtr(mh))
+}
+
+//export go_mongory_custom_match
+func go_mongory_custom_match(external unsafe.Pointer, value *C.mongory_value) (matched C.bool) {
+	m, ok := ptrToHandle(external).Value().(nativeMatcher)
+	if !ok {
+		return false
+	}
+	var cpool unsafe.Pointer
+	if value != nil {
+		cpool = unsafe.Pointer(value.pool)
+	}
+	defer recoverCallback(cpool, "custom operator")
+	return C.bool(m.match(value))
+}
+
+// ----- Set-backed $in -----
+
+var inSetThreshold atomic.Int64
+
+func init() {
+	inSetThreshold.Store(16)
+}
+
+// SetInSetThreshold sets the operand length from which $in lists of only
+// strings or only numbers are compiled to hash sets instead of being scanned
+// linearly. Zero or less disables the optimization.
+func SetInSetThreshold(n int) {
+	inSetThreshold.Store(int64(n))
+}
+
+type inSet struct {
+	strings map[string]struct{}
+	// Numbers are kept in two buckets, ints for integers and doubles for
+	// doubles, so that operands of either type match documents of the other
+	// as the core compares them. With ExactNumbers, doubles with an
+	// integral value in int64 range go to ints; otherwise rounded holds
+	// every integer converted to a double, which is how the core compares
+	// an integer with a double.
+	exact   bool
+	ints    map[int64]struct{}
+	doubles map[float64]struct{}
+	rounded map[float64]struct{}
+}
+
+func buildInSet(b operatorBuild) (nativeMatcher, string, bool) {
+	threshold := inSetThreshold.Load()
+	array := C.go_mongory_value_a(b.condition)
+	count := int(array.count)
+	if threshold <= 0 || int64(count) < threshold {
+		return nil, "", false
+	}
+	set := &inSet{exact: b.ctx.options.ExactNumbers}
+	for i := 0; i < count; i++ {
+		item := C.go_mongory_array_at(array, C.size_t(i))
+		if item == nil {
+			return nil, "", false
+		}
+		switch item._type {
+		case C.MONGORY_TYPE_STRING:
+			if set.strings == nil {
+				set.strings = make(map[string]struct{}, count)
+			}
+			set.strings[C.GoString(C.go_mongory_value_s(item))] = struct{}{}
+		case C.MONGORY_TYPE_INT:
+			set.addInt(int64(C.go_mongory_value_i(item)), count)
+		case C.MONGORY_TYPE_DOUBLE:
+			d := float64(C.go_mongory_value_d(item))
+			if math.IsNaN(d) {
+				return nil, "", false
+			}
+			if i, ok := integral(d); ok && set.exact {
+				set.addInt(i, count)
+				continue
+			}
+			if set.doubles == nil {
+				set.doubles = map[float64]struct{}{}
+			}
+			set.doubles[d] = struct{}{}
+		default:
+			return nil, "", false
+		}
+	}
+	if set.strings != nil && len(set.ints)+len(set.doubles) > 0 {
+		return nil, "", false
+	}
+	if !set.exact && len(set.ints) > 0 {
+		set.rounded = make(map[float64]struct{}, len(set.ints))
+		for i := range set.ints {
+			set.rounded[float64(i)] = struct{}{}
+		}
+	}
+	return set, "In", true
+}
+
+func (s *inSet) addInt(i int64, count int) {
+	if s.ints == nil {
+		s.ints = make(map[int64]struct{}, count)
+	}
+	s.ints[i] = struct{}{}
+}
+
+// integral returns d as an int64 if it has an integral value in range.
+func integral(d float64) (int64, bool) {
+	if d != math.Trunc(d) || d < -(1<<63) || d >= 1<<63 {
+		return 0, false
+	}
+	return int64(d), true
+}
+
+func (s *inSet) match(value *C.mongory_value) bool {
+	if value == nil {
+		return false
+	}
+	if value._type != C.MONGORY_TYPE_ARRAY {
+		return s.has(value)
+	}
+	array := C.go_mongory_value_a(value)
+	if array == nil {
+		return false
+	}
+	for i := 0; i < int(array.count); i++ {
+		if s.has(C.go_mongory_array_at(array, C.size_t(i))) {
+			return true
+		}
+	}
+	return false
+}
+
+// has mirrors the core's value comparison: strings compare by content and
+// numbers compare by value across integers and doubles.
+func (s *inSet) has(value *C.mongory_value) bool {
+	if value == nil {
+		return false
+	}
+	switch value._type {
+	case C.MONGORY_TYPE_STRING:
+		_, ok := s.strings[C.GoString(C.go_mongory_value_s(value))]
+		return ok
+	case C.MONGORY_TYPE_INT:
+		i := int64(C.go_mongory_value_i(value))
+		if _, ok := s.ints[i]; ok {
+			return true
+		}
+		_, ok := s.doubles[float64(i)]
+		return ok && !s.exact
+	case C.MONGORY_TYPE_DOUBLE:
+		d := float64(C.go_mongory_value_d(value))
+		if math.IsNaN(d) {
+			// The core compares NaN as equal to every number.
+			return len(s.ints)+len(s.doubles) > 0
+		}
+		if _, ok := s.doubles[d]; ok {
+			return true
+		}
+		if !s.exact {
+			_, ok := s.rounded[d]
+			return ok
+		}
+		i, ok := integral(d)
+		if ok {
+			_, ok = s.ints[i]
+		}
+		return ok
+	default:
+		return false
+	}
+}
@@ -0,0 +1,39 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+*/
+import "C"
+import "context"
+
+// predicateMatcher implements $func: the operand is a Go predicate, called
+// with the matched value as a Go value. At the top of a condition that is
+// the whole document, so it serves as a $where that runs Go code. A
+// func(context.Context, any) bool predicate is also passed the context of
+// the match.
+type predicateMatcher struct {
+	fn    func(any) bool
+	ctxFn func(context.Context, any) bool
+}
+
+func buildFunc(b operatorBuild) (nativeMatcher, string, bool) {
+	switch fn := recoverValue(b.condition).(type) {
+	case func(any) bool:
+		if fn != nil {
+			return &predicateMatcher{fn: fn}, "Func", true
+		}
+	case func(context.Context, any) bool:
+		if fn != nil {
+			return &predicateMatcher{ctxFn: fn}, "Func", true
+		}
+	}
+	b.fail("$func condition must be a func(any) bool or a func(context.Context, any) bool.")
+	return nil, "", false
+}
+
+func (p *predicateMatcher) match(value *C.mongory_value) bool {
+	if p.ctxFn != nil {
+		return p.ctxFn(matchContext(), recoverValue(value))
+	}
+	return p.fn(recoverValue(value))
+}
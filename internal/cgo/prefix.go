@@ -0,0 +1,202 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+
+static char *go_mongory_prefix_s(mongory_value *v) { return v->data.s; }
+*/
+import "C"
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync/atomic"
+)
+
+var prefixThreshold atomic.Int64
+
+func init() {
+	prefixThreshold.Store(4)
+}
+
+// SetPrefixThreshold sets how many branches an $or of "^prefix" regexes or
+// "prefix*" globs on a single field needs before it is compiled to a prefix
+// trie. Zero or less disables the optimization.
+func SetPrefixThreshold(n int) {
+	prefixThreshold.Store(int64(n))
+}
+
+// buildOr offers an $or to each specialized compilation in turn.
+func buildOr(b operatorBuild) (nativeMatcher, string, bool) {
+	if m, name, ok := buildRangeOr(b); ok {
+		return m, name, true
+	}
+	return buildPrefixOr(b)
+}
+
+// prefixTrie is a byte trie of literal prefixes.
+type prefixTrie struct {
+	children map[byte]*prefixTrie
+	terminal bool
+}
+
+func (t *prefixTrie) insert(prefix string) {
+	node := t
+	for i := 0; i < len(prefix); i++ {
+		child := node.children[prefix[i]]
+		if child == nil {
+			if node.children == nil {
+				node.children = map[byte]*prefixTrie{}
+			}
+			child = &prefixTrie{}
+			node.children[prefix[i]] = child
+		}
+		node = child
+	}
+	node.terminal = true
+}
+
+// hasPrefixOf reports whether any inserted prefix is a prefix of s.
+func (t *prefixTrie) hasPrefixOf(s string) bool {
+	node := t
+	for i := 0; ; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == len(s) {
+			return false
+		}
+		if node = node.children[s[i]]; node == nil {
+			return false
+		}
+	}
+}
+
+// prefixOr matches a string field against many literal prefixes at once.
+// Other values go to the regular $or matcher, as with rangeOr.
+type prefixOr struct {
+	field    *C.char
+	trie     *prefixTrie
+	fallback *C.mongory_matcher
+}
+
+func buildPrefixOr(b operatorBuild) (nativeMatcher, string, bool) {
+	threshold := prefixThreshold.Load()
+	if threshold <= 0 || int64(arrayLen(b.condition)) < threshold {
+		return nil, "", false
+	}
+	branches, ok := recoverValue(b.condition).([]any)
+	if !ok {
+		return nil, "", false
+	}
+	field, prefixes, ok := prefixBranches(branches)
+	if !ok {
+		return nil, "", false
+	}
+	fallback := orFallback(b)
+	if fallback == nil {
+		return nil, "", false
+	}
+	trie := &prefixTrie{}
+	for _, prefix := range prefixes {
+		trie.insert(prefix)
+	}
+	return &prefixOr{
+		field:    poolString(b.ctx.pool, field),
+		trie:     trie,
+		fallback: fallback,
+	}, "Prefixes", true
+}
+
+// prefixBranches reports whether every branch tests the same field with a
+// single anchored literal $regex or trailing-star $glob, and returns the
+// literal prefixes.
+func prefixBranches(branches []any) (string, []string, bool) {
+	var field string
+	prefixes := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		table, ok := branch.(map[string]any)
+		if !ok || len(table) != 1 {
+			return "", nil, false
+		}
+		for key, value := range table {
+			if strings.HasPrefix(key, "$") || field != "" && key != field {
+				return "", nil, false
+			}
+			field = key
+			ops, ok := value.(map[string]any)
+			if !ok || len(ops) != 1 {
+				return "", nil, false
+			}
+			var prefix string
+			switch {
+			case ops["$regex"] != nil:
+				prefix, ok = regexPrefix(ops["$regex"])
+			case ops["$glob"] != nil:
+				var pattern string
+				if pattern, ok = ops["$glob"].(string); ok {
+					prefix, ok = globPrefix(pattern)
+				}
+			default:
+				ok = false
+			}
+			if !ok {
+				return "", nil, false
+			}
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return field, prefixes, field != ""
+}
+
+// regexPrefix returns the literal of a pattern of the form "^literal",
+// optionally followed by ".*".
+func regexPrefix(operand any) (string, bool) {
+	var source string
+	switch v := operand.(type) {
+	case string:
+		source = v
+	case *regexp.Regexp:
+		source = v.String()
+	default:
+		return "", false
+	}
+	re, err := syntax.Parse(source, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	var subs []*syntax.Regexp
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	} else {
+		subs = []*syntax.Regexp{re}
+	}
+	// A trailing ".*" may match nothing, so it does not change the result of
+	// an unanchored-at-end match.
+	if n := len(subs); n > 0 && subs[n-1].Op == syntax.OpStar {
+		if op := subs[n-1].Sub[0].Op; op == syntax.OpAnyChar || op == syntax.OpAnyCharNotNL {
+			subs = subs[:n-1]
+		}
+	}
+	if len(subs) == 0 || subs[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+	switch len(subs) {
+	case 1:
+		return "", true
+	case 2:
+		if subs[1].Op == syntax.OpLiteral && subs[1].Flags&syntax.FoldCase == 0 {
+			return string(subs[1].Rune), true
+		}
+	}
+	return "", false
+}
+
+func (p *prefixOr) match(value *C.mongory_value) bool {
+	fieldValue := fieldOf(value, p.field)
+	if fieldValue != nil && fieldValue._type == C.MONGORY_TYPE_STRING {
+		return p.trie.hasPrefixOf(C.GoString(C.go_mongory_prefix_s(fieldValue)))
+	}
+	return runMatcher(p.fallback, value)
+}
@@ -0,0 +1,338 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <mongory-core.h>
+#include <stdlib.h>
+#include "foundations/utils.h"
+#include "matchers/composite_matcher.h"
+
+static mongory_matcher *go_mongory_or_fallback(mongory_memory_pool *pool, mongory_value *condition, uintptr_t extern_ctx) {
+	return mongory_matcher_or_new(pool, condition, (void *)extern_ctx);
+}
+
+static bool go_mongory_matcher_run(mongory_matcher *matcher, mongory_value *value) {
+	return matcher->match(matcher, value);
+}
+
+static mongory_value *go_mongory_field_of(mongory_value *record, char *field) {
+	if (record == NULL || record->type != MONGORY_TYPE_TABLE || record->data.t == NULL) {
+		return NULL;
+	}
+	mongory_table *table = record->data.t;
+	return table->get(table, field);
+}
+
+static char *go_mongory_pool_string(mongory_memory_pool *pool, char *s) {
+	return mongory_string_cpy(pool, s);
+}
+
+static size_t go_mongory_range_count(mongory_value *v) { return v->data.a->count; }
+static int64_t go_mongory_range_i(mongory_value *v) { return v->data.i; }
+static double go_mongory_range_d(mongory_value *v) { return v->data.d; }
+*/
+import "C"
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+var rangeThreshold atomic.Int64
+
+func init() {
+	rangeThreshold.Store(4)
+}
+
+// SetRangeThreshold sets how many branches an $or of numeric ranges on a
+// single field needs before it is compiled to a sorted interval search.
+// Zero or less disables the optimization.
+func SetRangeThreshold(n int) {
+	rangeThreshold.Store(int64(n))
+}
+
+// orFallback compiles the core $or matcher for an operand the Go side has
+// taken over, to handle the values a specialized matcher does not cover.
+func orFallback(b operatorBuild) *C.mongory_matcher {
+	fallback := C.go_mongory_or_fallback(b.ctx.pool.CPoint, b.condition, handleArg(b.externCtx))
+	if fallback == nil || !prepareLiterals(fallback, b.externCtx) {
+		return nil
+	}
+	return fallback
+}
+
+func runMatcher(m *C.mongory_matcher, value *C.mongory_value) bool {
+	return bool(C.go_mongory_matcher_run(m, value))
+}
+
+// fieldOf reads field from a table record, returning nil for other values.
+func fieldOf(record *C.mongory_value, field *C.char) *C.mongory_value {
+	return C.go_mongory_field_of(record, field)
+}
+
+// poolString copies s into pool.
+func poolString(pool *MemoryPool, s string) *C.char {
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+	return C.go_mongory_pool_string(pool.CPoint, cs)
+}
+
+func arrayLen(v *C.mongory_value) int {
+	return int(C.go_mongory_range_count(v))
+}
+
+// rangeNumber is an interval end or a matched value, an integer or a double.
+// The core compares integers with each other as integers, so a bound of
+// 2^53+1 excludes 2^53; as doubles the two are equal.
+type rangeNumber struct {
+	i     int64
+	d     float64
+	isInt bool
+}
+
+// maxExactInt is the largest magnitude up to which every integer is also a
+// double, so comparing it as one is exact.
+const maxExactInt = 1 << 53
+
+func intNumber(i int64) rangeNumber      { return rangeNumber{i: i, isInt: true} }
+func doubleNumber(d float64) rangeNumber { return rangeNumber{d: d} }
+
+// exact reports whether the core, which converts an integer to a double to
+// compare it with one, compares n with doubles exactly.
+func (n rangeNumber) exact() bool {
+	return !n.isInt || -maxExactInt <= n.i && n.i <= maxExactInt
+}
+
+// compareNumbers orders a and b by their exact values, neither being NaN.
+func compareNumbers(a, b rangeNumber) int {
+	switch {
+	case a.isInt && b.isInt:
+		return cmpInt(a.i, b.i)
+	case a.isInt:
+		return intDoubleCompare(a.i, b.d)
+	case b.isInt:
+		return -intDoubleCompare(b.i, a.d)
+	case a.d < b.d:
+		return -1
+	case a.d > b.d:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// intDoubleCompare compares i with d exactly, as
+// go_mongory_int_double_compare does.
+func intDoubleCompare(i int64, d float64) int {
+	if d >= 0x1p63 {
+		return -1
+	}
+	if d < -0x1p63 {
+		return 1
+	}
+	t := int64(d)
+	if i != t {
+		return cmpInt(i, t)
+	}
+	frac := d - float64(t)
+	switch {
+	case frac > 0:
+		return -1
+	case frac < 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// interval is a numeric range with optional open ends.
+type interval struct {
+	lo, hi       rangeNumber
+	loInc, hiInc bool
+}
+
+func (iv interval) contains(v rangeNumber) bool {
+	if c := compareNumbers(v, iv.lo); c < 0 || c == 0 && !iv.loInc {
+		return false
+	}
+	c := compareNumbers(v, iv.hi)
+	return c < 0 || c == 0 && iv.hiInc
+}
+
+// rangeOr matches a field against a sorted list of disjoint intervals. Values
+// that are not plain numbers (arrays, missing fields, other types) are handed
+// to the regular $or matcher compiled from the same condition, so semantics
+// do not change. So are the numbers the core compares with some bound by
+// rounding them to doubles first: integers beyond 2^53 when a bound is a
+// finite double, and doubles when an integer bound is beyond 2^53.
+type rangeOr struct {
+	field        *C.char
+	intervals    []interval
+	doubleBounds bool
+	largeInts    bool
+	fallback     *C.mongory_matcher
+}
+
+func buildRangeOr(b operatorBuild) (nativeMatcher, string, bool) {
+	threshold := rangeThreshold.Load()
+	if threshold <= 0 || int64(arrayLen(b.condition)) < threshold || b.ctx.options.ExactNumbers {
+		// Intervals bound doubles, so exact comparison stays with the core.
+		return nil, "", false
+	}
+	branches, ok := recoverValue(b.condition).([]any)
+	if !ok {
+		return nil, "", false
+	}
+	field, intervals, ok := rangeBranches(branches)
+	if !ok {
+		return nil, "", false
+	}
+	fallback := orFallback(b)
+	if fallback == nil {
+		return nil, "", false
+	}
+	r := &rangeOr{field: poolString(b.ctx.pool, field), fallback: fallback}
+	for _, iv := range intervals {
+		for _, end := range []rangeNumber{iv.lo, iv.hi} {
+			r.doubleBounds = r.doubleBounds || !end.isInt && !math.IsInf(end.d, 0)
+			r.largeInts = r.largeInts || !end.exact()
+		}
+	}
+	r.intervals = mergeIntervals(intervals)
+	return r, "Ranges", true
+}
+
+// rangeBranches reports whether every branch constrains the same field with
+// only $gt, $gte, $lt and $lte on numbers, and returns their intervals.
+func rangeBranches(branches []any) (string, []interval, bool) {
+	var field string
+	intervals := make([]interval, 0, len(branches))
+	for _, branch := range branches {
+		table, ok := branch.(map[string]any)
+		if !ok || len(table) != 1 {
+			return "", nil, false
+		}
+		for key, value := range table {
+			if strings.HasPrefix(key, "$") || field != "" && key != field {
+				return "", nil, false
+			}
+			field = key
+			ops, ok := value.(map[string]any)
+			if !ok || len(ops) == 0 {
+				return "", nil, false
+			}
+			iv := interval{lo: doubleNumber(math.Inf(-1)), hi: doubleNumber(math.Inf(1)), loInc: true, hiInc: true}
+			for op, operand := range ops {
+				bound, ok := rangeBound(operand)
+				if !ok {
+					return "", nil, false
+				}
+				switch op {
+				case "$gt":
+					iv.lo, iv.loInc = tighterLo(iv.lo, iv.loInc, bound, false)
+				case "$gte":
+					iv.lo, iv.loInc = tighterLo(iv.lo, iv.loInc, bound, true)
+				case "$lt":
+					iv.hi, iv.hiInc = tighterHi(iv.hi, iv.hiInc, bound, false)
+				case "$lte":
+					iv.hi, iv.hiInc = tighterHi(iv.hi, iv.hiInc, bound, true)
+				default:
+					return "", nil, false
+				}
+			}
+			intervals = append(intervals, iv)
+		}
+	}
+	return field, intervals, field != ""
+}
+
+func rangeBound(operand any) (rangeNumber, bool) {
+	switch v := operand.(type) {
+	case int64:
+		return intNumber(v), true
+	case float64:
+		return doubleNumber(v), !math.IsNaN(v)
+	default:
+		return rangeNumber{}, false
+	}
+}
+
+func tighterLo(lo rangeNumber, loInc bool, bound rangeNumber, inc bool) (rangeNumber, bool) {
+	if c := compareNumbers(bound, lo); c > 0 || c == 0 && !inc {
+		return bound, inc
+	}
+	return lo, loInc
+}
+
+func tighterHi(hi rangeNumber, hiInc bool, bound rangeNumber, inc bool) (rangeNumber, bool) {
+	if c := compareNumbers(bound, hi); c < 0 || c == 0 && !inc {
+		return bound, inc
+	}
+	return hi, hiInc
+}
+
+// mergeIntervals sorts intervals by lower bound and merges those that
+// overlap or touch, dropping empty ones.
+func mergeIntervals(intervals []interval) []interval {
+	nonEmpty := intervals[:0]
+	for _, iv := range intervals {
+		if c := compareNumbers(iv.lo, iv.hi); c < 0 || c == 0 && iv.loInc && iv.hiInc {
+			nonEmpty = append(nonEmpty, iv)
+		}
+	}
+	sort.Slice(nonEmpty, func(i, j int) bool {
+		a, b := nonEmpty[i], nonEmpty[j]
+		c := compareNumbers(a.lo, b.lo)
+		return c < 0 || c == 0 && a.loInc && !b.loInc
+	})
+	merged := make([]interval, 0, len(nonEmpty))
+	for _, iv := range nonEmpty {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if c := compareNumbers(iv.lo, last.hi); c < 0 || c == 0 && (last.hiInc || iv.loInc) {
+				if c := compareNumbers(iv.hi, last.hi); c > 0 || c == 0 && iv.hiInc {
+					last.hi, last.hiInc = iv.hi, iv.hiInc
+				}
+				continue
+			}
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+func (r *rangeOr) match(value *C.mongory_value) bool {
+	fieldValue := fieldOf(value, r.field)
+	if fieldValue != nil {
+		switch fieldValue._type {
+		case C.MONGORY_TYPE_INT:
+			if v := intNumber(int64(C.go_mongory_range_i(fieldValue))); v.exact() || !r.doubleBounds {
+				return r.contains(v)
+			}
+		case C.MONGORY_TYPE_DOUBLE:
+			if v := float64(C.go_mongory_range_d(fieldValue)); !math.IsNaN(v) && !r.largeInts {
+				return r.contains(doubleNumber(v))
+			}
+		}
+	}
+	return runMatcher(r.fallback, value)
+}
+
+func (r *rangeOr) contains(v rangeNumber) bool {
+	i := sort.Search(len(r.intervals), func(i int) bool { return compareNumbers(r.intervals[i].lo, v) > 0 })
+	return i > 0 && r.intervals[i-1].contains(v)
+}
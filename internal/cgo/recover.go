@@ -0,0 +1,93 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <mongory-core.h>
+
+extern bool go_mongory_recover_pair(char *key, mongory_value *value, void *acc);
+
+static bool go_mongory_recover_table(mongory_table *t, uintptr_t acc) {
+	if (t->each == NULL) {
+		return false;
+	}
+	return t->each(t, (void *)acc, go_mongory_recover_pair);
+}
+
+static bool go_mongory_recover_b(mongory_value *v) { return v->data.b; }
+static int64_t go_mongory_recover_i(mongory_value *v) { return v->data.i; }
+static double go_mongory_recover_d(mongory_value *v) { return v->data.d; }
+static char *go_mongory_recover_s(mongory_value *v) { return v->data.s; }
+static mongory_array *go_mongory_recover_a(mongory_value *v) { return v->data.a; }
+static mongory_table *go_mongory_recover_t(mongory_value *v) { return v->data.t; }
+static void *go_mongory_recover_u(mongory_value *v) { return v->data.u; }
+
+static mongory_value *go_mongory_recover_at(mongory_array *a, size_t index) {
+	return a->get(a, index);
+}
+*/
+import "C"
+import (
+	rcgo "runtime/cgo"
+	"unsafe"
+)
+
+// recoverValue turns a native value back into the Go value it represents:
+// bridged documents yield the original Go value, native tables and arrays
+// become map[string]any and []any, scalars become bool, int64, float64 or
+// string, and wrapped Go values (regexps, unsupported types) are unwrapped.
+func recoverValue(v *C.mongory_value) any {
+	if v == nil {
+		return nil
+	}
+	switch v._type {
+	case C.MONGORY_TYPE_BOOL:
+		return bool(C.go_mongory_recover_b(v))
+	case C.MONGORY_TYPE_INT:
+		return int64(C.go_mongory_recover_i(v))
+	case C.MONGORY_TYPE_DOUBLE:
+		return float64(C.go_mongory_recover_d(v))
+	case C.MONGORY_TYPE_STRING:
+		return C.GoString(C.go_mongory_recover_s(v))
+	case C.MONGORY_TYPE_ARRAY:
+		array := C.go_mongory_recover_a(v)
+		if array == nil {
+			return nil
+		}
+		if target, ok := shallowArrayTarget(array); ok {
+			return target
+		}
+		out := make([]any, int(array.count))
+		for i := range out {
+			out[i] = recoverValue(C.go_mongory_recover_at(array, C.size_t(i)))
+		}
+		return out
+	case C.MONGORY_TYPE_TABLE:
+		table := C.go_mongory_recover_t(v)
+		if table == nil {
+			return nil
+		}
+		if target, ok := shallowTableTarget(table); ok {
+			return target
+		}
+		out := make(map[string]any, int(table.count))
+		h := rcgo.NewHandle(out)
+		defer h.Delete()
+		C.go_mongory_recover_table(table, handleArg(h))
+		return out
+	case C.MONGORY_TYPE_UNSUPPORTED, C.MONGORY_TYPE_REGEX:
+		if ptr := C.go_mongory_recover_u(v); ptr != nil {
+			return ptrToHandle(ptr).Value()
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+//export go_mongory_recover_pair
+func go_mongory_recover_pair(key *C.char, value *C.mongory_value, acc unsafe.Pointer) C.bool {
+	out := ptrToHandle(acc).Value().(map[string]any)
+	out[C.GoString(key)] = recoverValue(value)
+	return true
+}
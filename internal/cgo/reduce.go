@@ -0,0 +1,173 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+
+static mongory_matcher *go_mongory_operand_matcher_new(mongory_memory_pool *pool, mongory_value *condition, uintptr_t extern_ctx) {
+	return mongory_matcher_new(pool, condition, (void *)extern_ctx);
+}
+*/
+import "C"
+import (
+	"strings"
+	"unsafe"
+)
+
+// reduction is what $sumOf, $minOf, $maxOf and $avgOf compute.
+type reduction int
+
+const (
+	reduceSum reduction = iota
+	reduceMin
+	reduceMax
+	reduceAvg
+)
+
+// reduceMatcher implements the array reducers: the numbers of the field, an
+// array, or the numbers at path in its elements, are reduced to one value
+// that the operand condition is matched against. A single number counts as
+// an array of one, and values that are not numbers are skipped. The sum of
+// no numbers is 0; the minimum, maximum and average of none are missing, so
+// {"$avgOf": {"$exists": false}} matches empty arrays.
+type reduceMatcher struct {
+	op        reduction
+	path      []string // nil reduces the elements themselves
+	condition *C.mongory_matcher
+}
+
+func buildReducer(op reduction, operator, name string) operatorBuilder {
+	return func(b operatorBuild) (nativeMatcher, string, bool) {
+		condition := b.condition
+		var path []string
+		// A single key that is not an operator is a path into the elements,
+		// as in {"$sumOf": {"price": {"$gt": 100}}}.
+		if operand, ok := recoverValue(b.condition).(map[string]any); ok && len(operand) == 1 {
+			for key := range operand {
+				if !strings.HasPrefix(key, "$") {
+					path = strings.Split(key, ".")
+					condition = (&Value{CPoint: b.condition}).GetTable().Get(key).CPoint
+				}
+			}
+		}
+		matcher := operandMatcher(b, condition)
+		if matcher == nil {
+			b.fail(operator + " condition must be a condition or a value.")
+			return nil, "", false
+		}
+		return &reduceMatcher{op: op, path: path, condition: matcher}, name, true
+	}
+}
+
+// operandMatcher compiles an operand that is matched against a value the
+// operator computes, as the condition of a field holding it: a condition, or
+// any other value to compare for equality. It returns nil if the core fails.
+func operandMatcher(b operatorBuild, condition *C.mongory_value) *C.mongory_matcher {
+	if condition == nil || condition._type != C.MONGORY_TYPE_TABLE {
+		operand := &Value{CPoint: condition}
+		if condition == nil {
+			operand = NewValueNull(b.ctx.pool)
+		}
+		table := NewTable(b.ctx.pool)
+		table.Set("$eq", operand)
+		condition = NewValueTable(b.ctx.pool, table).CPoint
+	}
+	matcher := C.go_mongory_operand_matcher_new(b.ctx.pool.CPoint, condition, handleArg(b.externCtx))
+	if matcher == nil || !prepareLiterals(matcher, b.externCtx) {
+		return nil
+	}
+	return matcher
+}
+
+func (r *reduceMatcher) match(value *C.mongory_value) bool {
+	if value == nil {
+		return false
+	}
+	pool := lookupPool(unsafe.Pointer(value.pool))
+	if pool == nil {
+		return false
+	}
+	var (
+		count      int
+		sum        float64
+		intSum     int64
+		ints       = true
+		best       *Value
+		bestNumber float64
+	)
+	r.collect(&Value{CPoint: value, pool: pool}, r.path, func(item *Value) {
+		var n float64
+		switch item.Kind() {
+		case MONGORY_TYPE_INT:
+			i := item.GetInt()
+			n = float64(i)
+			if ints {
+				if next := intSum + i; (i > 0 && next < intSum) || (i < 0 && next > intSum) {
+					ints = false
+				} else {
+					intSum = next
+				}
+			}
+		case MONGORY_TYPE_DOUBLE:
+			n = item.GetDouble()
+			ints = false
+		default:
+			return
+		}
+		count++
+		sum += n
+		if best == nil || r.op == reduceMin && n < bestNumber || r.op == reduceMax && n > bestNumber {
+			best, bestNumber = item, n
+		}
+	})
+	var reduced *C.mongory_value
+	switch {
+	case r.op == reduceSum && ints:
+		reduced = NewValueInt(pool, intSum).CPoint
+	case r.op == reduceSum:
+		reduced = NewValueDouble(pool, sum).CPoint
+	case count == 0:
+		// Missing, as the field of an empty document is.
+	case r.op == reduceAvg:
+		reduced = NewValueDouble(pool, sum/float64(count)).CPoint
+	default:
+		// The element itself, so integers stay integers.
+		reduced = best.CPoint
+	}
+	return runMatcher(r.condition, reduced)
+}
+
+// collect calls yield with each value to reduce: the elements of v if it is
+// an array, else v, once path is followed through documents and arrays of
+// documents.
+func (r *reduceMatcher) collect(v *Value, path []string, yield func(*Value)) {
+	switch v.Kind() {
+	case MONGORY_TYPE_ARRAY:
+		array := v.GetArray()
+		if array == nil {
+			return
+		}
+		for i := 0; i < array.Len(); i++ {
+			item := array.Get(i)
+			if len(path) == 0 {
+				yield(item)
+			} else if item.Kind() == MONGORY_TYPE_TABLE {
+				r.collect(item, path, yield)
+			}
+		}
+	case MONGORY_TYPE_TABLE:
+		if len(path) == 0 {
+			return
+		}
+		table := v.GetTable()
+		if table == nil {
+			return
+		}
+		if item := table.Get(path[0]); item != nil {
+			r.collect(item, path[1:], yield)
+		}
+	default:
+		if len(path) == 0 {
+			yield(v)
+		}
+	}
+}
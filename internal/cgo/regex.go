@@ -0,0 +1,86 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <stdlib.h>
+#include <mongory-core.h>
+#include "foundations/utils.h"
+
+extern bool go_mongory_regex_match(mongory_memory_pool *pool, mongory_value *pattern, mongory_value *value);
+extern char *go_mongory_regex_source(mongory_value *pattern);
+
+static char *go_mongory_regex_stringify(mongory_memory_pool *pool, mongory_value *pattern) {
+	char *s = go_mongory_regex_source(pattern);
+	char *copy = mongory_string_cpy(pool, s);
+	free(s);
+	return copy;
+}
+
+static void go_mongory_regex_install() {
+	mongory_regex_func_set(go_mongory_regex_match);
+	mongory_regex_stringify_func_set(go_mongory_regex_stringify);
+}
+
+static char *go_mongory_regex_s(mongory_value *v) { return v->data.s; }
+static void *go_mongory_regex_ptr(mongory_value *v) { return v->data.regex; }
+*/
+import "C"
+import (
+	"regexp"
+	"sync"
+)
+
+// regexCache holds compiled $regex patterns by source. Patterns that fail to
+// compile are cached as nil and never match.
+var regexCache sync.Map
+
+func installRegex() {
+	C.go_mongory_regex_install()
+}
+
+func compileRegex(source string) *regexp.Regexp {
+	if cached, ok := regexCache.Load(source); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		re = nil
+	}
+	regexCache.Store(source, re)
+	return re
+}
+
+// patternRegex returns the compiled regexp for a $regex operand, which is
+// either a pattern string or a *regexp.Regexp from the condition.
+func patternRegex(pattern *C.mongory_value) *regexp.Regexp {
+	if pattern == nil {
+		return nil
+	}
+	switch pattern._type {
+	case C.MONGORY_TYPE_STRING:
+		return compileRegex(C.GoString(C.go_mongory_regex_s(pattern)))
+	case C.MONGORY_TYPE_REGEX:
+		if ptr := C.go_mongory_regex_ptr(pattern); ptr != nil {
+			re, _ := ptrToHandle(ptr).Value().(*regexp.Regexp)
+			return re
+		}
+	}
+	return nil
+}
+
+//export go_mongory_regex_match
+func go_mongory_regex_match(pool *C.mongory_memory_pool, pattern *C.mongory_value, value *C.mongory_value) C.bool {
+	re := patternRegex(pattern)
+	if re == nil || value == nil || value._type != C.MONGORY_TYPE_STRING {
+		return false
+	}
+	return C.bool(re.MatchString(C.GoString(C.go_mongory_regex_s(value))))
+}
+
+//export go_mongory_regex_source
+func go_mongory_regex_source(pattern *C.mongory_value) *C.char {
+	if re := patternRegex(pattern); re != nil {
+		return C.CString("/" + re.String() + "/")
+	}
+	return C.CString("//")
+}
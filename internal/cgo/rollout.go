@@ -0,0 +1,91 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+*/
+import "C"
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// rolloutBuckets is the bucket resolution of $rollout, so percentages are
+// honoured to two decimal places.
+const rolloutBuckets = 10000
+
+// rolloutMatcher implements $rollout: the field value is hashed with the
+// salt into a stable bucket, and values whose bucket falls below the
+// percentage match. The same key always lands in the same bucket, so raising
+// the percentage only ever adds keys.
+type rolloutMatcher struct {
+	threshold int
+	salt      string
+}
+
+func buildRollout(b operatorBuild) (nativeMatcher, string, bool) {
+	var percent float64
+	var salt string
+	switch operand := recoverValue(b.condition).(type) {
+	case int64:
+		percent = float64(operand)
+	case float64:
+		percent = operand
+	case map[string]any:
+		var ok bool
+		if percent, ok = rolloutNumber(operand["percent"]); !ok {
+			b.fail("$rollout percent must be a number.")
+			return nil, "", false
+		}
+		if raw, exists := operand["salt"]; exists {
+			if salt, ok = raw.(string); !ok {
+				b.fail("$rollout salt must be a string.")
+				return nil, "", false
+			}
+		}
+	default:
+		b.fail("$rollout condition must be a percentage or {percent, salt}.")
+		return nil, "", false
+	}
+	if math.IsNaN(percent) || percent < 0 || percent > 100 {
+		b.fail("$rollout percent must be between 0 and 100.")
+		return nil, "", false
+	}
+	return &rolloutMatcher{threshold: int(math.Round(percent * rolloutBuckets / 100)), salt: salt}, "Rollout", true
+}
+
+func rolloutNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func (r *rolloutMatcher) match(value *C.mongory_value) bool {
+	var key string
+	switch v := recoverValue(value).(type) {
+	case string:
+		key = v
+	case int64:
+		key = strconv.FormatInt(v, 10)
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return false
+		}
+		key = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return false
+	}
+	return RolloutBucket(r.salt, key) < r.threshold
+}
+
+// RolloutBucket returns the bucket in [0, 10000) that $rollout assigns key
+// under salt.
+func RolloutBucket(salt, key string) int {
+	sum := sha256.Sum256([]byte(salt + ":" + key))
+	return int(binary.BigEndian.Uint64(sum[:8]) % rolloutBuckets)
+}
@@ -0,0 +1,8 @@
+//go:build asan || msan
+
+package cgo
+
+// sanitized is set when built with -asan or -msan. Scratch pools are then
+// never reused between matches, so memory from one match cannot be read by
+// the next without the sanitizer noticing.
+const sanitized = true
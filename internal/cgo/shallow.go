@@ -0,0 +1,288 @@
+package cgo
+
+/*
+#include <stdbool.h>
+#include <mongory-core.h>
+#include <stdlib.h>
+#include <stdint.h>
+#include <string.h>
+
+// ----- Array bridge -----
+
+typedef struct go_mongory_array {
+	mongory_array base;
+	void *go_array;
+} go_mongory_array;
+
+extern mongory_value *go_shallow_array_get(go_mongory_array *a, size_t index);
+
+static mongory_value *cgo_shallow_array_get(mongory_array *a, size_t index) {
+	return go_shallow_array_get((go_mongory_array *)a, index);
+}
+
+static mongory_array *mongory_shallow_array_new(mongory_memory_pool *pool, void *go_array) {
+	go_mongory_array *a = pool->alloc(pool, sizeof(go_mongory_array));
+	a->base.get = cgo_shallow_array_get;
+	a->base.pool = pool;
+	a->go_array = go_array;
+	return &a->base;
+}
+
+static void mongory_shallow_array_set_count(mongory_array *a, size_t count) {
+	a->count = count;
+}
+
+// ----- Table bridge -----
+
+typedef struct go_mongory_table {
+	mongory_table base;
+	void *go_table;
+} go_mongory_table;
+
+extern mongory_value *go_shallow_table_get(go_mongory_table *a, char *key);
+
+static mongory_value *cgo_shallow_table_get(mongory_table *a, char *key) {
+	return go_shallow_table_get((go_mongory_table *)a, key);
+}
+
+static mongory_table *mongory_shallow_table_new(mongory_memory_pool *pool, void *go_table) {
+	go_mongory_table *a = pool->alloc(pool, sizeof(go_mongory_table));
+	a->base.pool = pool;
+	a->go_table = go_table;
+	a->base.get = cgo_shallow_table_get;
+	return &a->base;
+}
+
+static void mongory_shallow_table_set_count(mongory_table *a, size_t count) {
+	a->count = count;
+}
+
+static void *mongory_shallow_array_target(mongory_array *a) {
+	return a->get == cgo_shallow_array_get ? ((go_mongory_array *)a)->go_array : NULL;
+}
+
+static void *mongory_shallow_table_target(mongory_table *t) {
+	return t->get == cgo_shallow_table_get ? ((go_mongory_table *)t)->go_table : NULL;
+}
+
+*/
+import "C"
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	rcgo "runtime/cgo"
+	"unsafe"
+)
+
+// shallowTarget is what a bridged container's handle refers to: the Go value
+// and the depth it was found at. A struct with a compiled layout is held as
+// the layout and a pointer to the struct instead.
+type shallowTarget struct {
+	value  any
+	depth  int
+	layout *StructLayout
+	base   unsafe.Pointer
+}
+
+// goValue returns the bridged Go value; a pointer for structs.
+func (t *shallowTarget) goValue() any {
+	if t.layout != nil {
+		return reflect.NewAt(t.layout.typ, t.base).Interface()
+	}
+	return t.value
+}
+
+// shallowPool returns the MemoryPool that owns a native pool, or a temporary
+// wrapper if the pool is not registered.
+func shallowPool(cpoint *C.mongory_memory_pool) *MemoryPool {
+	if pool := lookupPool(unsafe.Pointer(cpoint)); pool != nil {
+		return pool
+	}
+	return &MemoryPool{CPoint: cpoint}
+}
+
+// ----- Go side: Shallow Array -----
+
+type ShallowArray struct {
+	CPoint *C.mongory_array
+	target any
+	depth  int
+	pool   *MemoryPool
+}
+
+func NewShallowArray(pool *MemoryPool, values any, depth int) *ShallowArray {
+	h := rcgo.NewHandle(&shallowTarget{value: values, depth: depth})
+	pool.trackHandle(h)
+	arr := &ShallowArray{
+		CPoint: C.mongory_shallow_array_new(pool.CPoint, handleToPtr(h)),
+		target: values,
+		depth:  depth,
+		pool:   pool,
+	}
+	rv := reflect.ValueOf(values)
+	var count int
+	if rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) {
+		count = rv.Len()
+	}
+	C.mongory_shallow_array_set_count(arr.CPoint, C.size_t(count))
+	return arr
+}
+
+func (a *ShallowArray) Get(index int) *Value {
+	rv := reflect.ValueOf(a.target)
+	if !rv.IsValid() || rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return a.pool.valueConvert(nil, a.depth+1)
+	}
+	return a.pool.valueConvert(rv.Index(index).Interface(), a.depth+1)
+}
+
+//export go_shallow_array_get
+func go_shallow_array_get(a *C.go_mongory_array, index C.size_t) (value *C.mongory_value) {
+	defer recoverCallback(unsafe.Pointer(a.base.pool), "document conversion")
+	pool := shallowPool(a.base.pool)
+	target := ptrToHandle(a.go_array).Value().(*shallowTarget)
+	rv := reflect.ValueOf(target.value)
+	var iv any
+	if rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) {
+		iv = rv.Index(int(index)).Interface()
+	}
+	return pool.valueConvert(iv, target.depth+1).CPoint
+}
+
+//export go_shallow_array_to_string
+func go_shallow_array_to_string(a *C.go_mongory_array) *C.char {
+	target := ptrToHandle(a.go_array).Value().(*shallowTarget)
+	return C.CString(formatTarget(target.value))
+}
+
+// ----- Go side: Shallow Table -----
+
+type ShallowTable struct {
+	CPoint *C.mongory_table
+	target any
+	depth  int
+	pool   *MemoryPool
+	layout *StructLayout
+	base   unsafe.Pointer
+}
+
+func NewShallowTable(pool *MemoryPool, values any, depth int) *ShallowTable {
+	h := rcgo.NewHandle(&shallowTarget{value: values, depth: depth})
+	pool.trackHandle(h)
+	t := &ShallowTable{
+		CPoint: C.mongory_shallow_table_new(pool.CPoint, handleToPtr(h)),
+		target: values,
+		depth:  depth,
+		pool:   pool,
+	}
+	// 設定項目數量（僅支援 map）
+	rv := reflect.ValueOf(values)
+	var count int
+	if rv.IsValid() && rv.Kind() == reflect.Map {
+		count = rv.Len()
+	}
+	C.mongory_shallow_table_set_count(t.CPoint, C.size_t(count))
+	return t
+}
+
+// newStructTable bridges the struct at base, reading its fields through
+// layout.
+func newStructTable(pool *MemoryPool, layout *StructLayout, base unsafe.Pointer, depth int) *ShallowTable {
+	h := rcgo.NewHandle(&shallowTarget{depth: depth, layout: layout, base: base})
+	pool.trackHandle(h)
+	t := &ShallowTable{
+		CPoint: C.mongory_shallow_table_new(pool.CPoint, handleToPtr(h)),
+		depth:  depth,
+		pool:   pool,
+		layout: layout,
+		base:   base,
+	}
+	C.mongory_shallow_table_set_count(t.CPoint, C.size_t(len(layout.order)))
+	return t
+}
+
+// Get converts the value under key, or returns nil when key is missing.
+func (t *ShallowTable) Get(key string) *Value {
+	if t.layout != nil {
+		f := t.layout.fields[key]
+		if f == nil {
+			return nil
+		}
+		return f.convert(t.pool, t.base, t.depth+1)
+	}
+	v := mapIndex(reflect.ValueOf(t.target), key)
+	if !v.IsValid() {
+		return nil
+	}
+	return t.pool.valueConvert(v.Interface(), t.depth+1)
+}
+
+//export go_shallow_table_get
+func go_shallow_table_get(a *C.go_mongory_table, key *C.char) (value *C.mongory_value) {
+	defer recoverCallback(unsafe.Pointer(a.base.pool), "document conversion")
+	pool := shallowPool(a.base.pool)
+	target := ptrToHandle(a.go_table).Value().(*shallowTarget)
+	if target.layout != nil {
+		// The lookup does not keep the key, so it can view the C string.
+		f := target.layout.fields[unsafe.String((*byte)(unsafe.Pointer(key)), int(C.strlen(key)))]
+		if f == nil {
+			return nil
+		}
+		return f.convert(pool, target.base, target.depth+1).CPoint
+	}
+	v := mapIndex(reflect.ValueOf(target.value), C.GoString(key))
+	if !v.IsValid() {
+		// A missing key is NULL to the core, unlike a key holding nil.
+		return nil
+	}
+	return pool.valueConvert(v.Interface(), target.depth+1).CPoint
+}
+
+// mapIndex looks key up in the map rv, converting it to a named string key
+// type such as that of map[Field]any. Keys of other kinds cannot name
+// fields; looking one up panics, which is reported as a conversion error.
+// It returns the zero Value when rv is not a map or key is missing.
+func mapIndex(rv reflect.Value, key string) reflect.Value {
+	if !rv.IsValid() || rv.Kind() != reflect.Map {
+		return reflect.Value{}
+	}
+	k := reflect.ValueOf(key)
+	if keyType := rv.Type().Key(); keyType.Kind() == reflect.String {
+		k = k.Convert(keyType)
+	}
+	return rv.MapIndex(k)
+}
+
+//export go_shallow_table_to_string
+func go_shallow_table_to_string(t *C.go_mongory_table) *C.char {
+	target := ptrToHandle(t.go_table).Value().(*shallowTarget)
+	return C.CString(formatTarget(target.goValue()))
+}
+
+// shallowArrayTarget returns the Go value behind a bridged array.
+func shallowArrayTarget(a *C.mongory_array) (any, bool) {
+	ptr := C.mongory_shallow_array_target(a)
+	if ptr == nil {
+		return nil, false
+	}
+	return ptrToHandle(ptr).Value().(*shallowTarget).value, true
+}
+
+// shallowTableTarget returns the Go value behind a bridged table.
+func shallowTableTarget(t *C.mongory_table) (any, bool) {
+	ptr := C.mongory_shallow_table_target(t)
+	if ptr == nil {
+		return nil, false
+	}
+	return ptrToHandle(ptr).Value().(*shallowTarget).goValue(), true
+}
+
+// formatTarget renders a bridged Go value the way the core renders its own
+// tables and arrays, falling back to fmt for values JSON cannot encode.
+func formatTarget(target any) string {
+	if encoded, err := json.Marshal(target); err == nil {
+		return string(encoded)
+	}
+	return fmt.Sprintf("%v", target)
+}
@@ -0,0 +1,114 @@
+package cgo
+
+import (
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrSharedValueReleased is wrapped, along with ErrInvalidCondition, by the
+// error for a condition using a SharedValue after its Release.
+var ErrSharedValueReleased = errors.New("mongory: shared value used after Release")
+
+// SharedValue is a condition operand converted once into its own native pool
+// and referenced, not copied, by every matcher whose condition contains it.
+// The pool is freed when the owner has called Release and every matcher using
+// it has been freed.
+type SharedValue struct {
+	*sharedValueState
+	source  any
+	cleanup runtime.Cleanup
+}
+
+// sharedValueState is the part of a SharedValue that pools reference. It is
+// the cleanup argument, so it must never point back to the SharedValue.
+type sharedValueState struct {
+	pool  *MemoryPool
+	value *Value
+	// mu guards refs and released, so a new condition cannot retain the
+	// value as its last reference is dropped.
+	mu       sync.Mutex
+	refs     int
+	released bool
+}
+
+// NewSharedValue converts value into a new pool. If Release is never called,
+// the owner's reference is dropped once the SharedValue is unreachable.
+func NewSharedValue(value any) (*SharedValue, error) {
+	pool := NewMemoryPool()
+	converted := pool.ConditionConvert(value)
+	if err := pool.LimitError(); err != nil {
+		pool.Free()
+		return nil, err
+	}
+	state := &sharedValueState{pool: pool, value: converted}
+	state.refs = 1
+	s := &SharedValue{sharedValueState: state, source: value}
+	s.cleanup = runtime.AddCleanup(s, (*sharedValueState).releaseOwner, state)
+	return s, nil
+}
+
+// Value returns the Go value the SharedValue was built from.
+func (s *SharedValue) Value() any {
+	return s.source
+}
+
+// Release drops the owner's reference. Matchers already compiled with the
+// value keep it alive; conditions using it afterwards fail to compile with
+// ErrSharedValueReleased.
+func (s *SharedValue) Release() {
+	s.cleanup.Stop()
+	s.releaseOwner()
+}
+
+func (s *SharedValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.source)
+}
+
+func (s *sharedValueState) releaseOwner() {
+	s.mu.Lock()
+	if s.released {
+		s.mu.Unlock()
+		return
+	}
+	s.released = true
+	s.mu.Unlock()
+	s.release()
+}
+
+// retain adds a reference for a new condition, and reports false once the
+// owner has released the value.
+func (s *sharedValueState) retain() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released || s.refs == 0 {
+		return false
+	}
+	s.refs++
+	return true
+}
+
+func (s *sharedValueState) release() {
+	s.mu.Lock()
+	s.refs--
+	last := s.refs == 0
+	s.mu.Unlock()
+	if last {
+		s.pool.Free()
+	}
+}
+
+// useShared makes pool hold a reference to s until the pool is freed and
+// returns the shared native value. After s is released it records
+// ErrSharedValueReleased and returns null.
+func (m *MemoryPool) useShared(s *SharedValue) *Value {
+	if !s.retain() {
+		if m.limitErr == nil {
+			m.limitErr = kindError{ErrSharedValueReleased, ErrInvalidCondition}
+		}
+		return NewValueNull(m)
+	}
+	m.shared = append(m.shared, s.sharedValueState)
+	return s.value
+}
@@ -0,0 +1,24 @@
+package cgo
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+var valuerType = reflect.TypeFor[driver.Valuer]()
+
+// SQLValue returns the value a struct implementing driver.Valuer, such as
+// sql.NullString or sql.Null[T], stands for: nil when it is NULL, so it
+// matches as null, and otherwise the value it holds. ok is false for other
+// values and when Value fails.
+func SQLValue(value any) (v any, ok bool) {
+	valuer, isValuer := value.(driver.Valuer)
+	if !isValuer || reflect.TypeOf(value).Kind() != reflect.Struct {
+		return nil, false
+	}
+	v, err := valuer.Value()
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,111 @@
+package cgo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the cumulative counts of a Matcher's matches since it was
+// compiled or its stats were last reset.
+type Stats struct {
+	// Matches counts documents matched, by Match or a Batch, including those
+	// that failed.
+	Matches int64
+	// Hits counts the matches that returned true.
+	Hits int64
+	// Errors counts the matches that returned an error.
+	Errors int64
+	// Latency is the time spent in those matches.
+	Latency time.Duration
+	// LastError is the most recent match error, and LastErrorTime when it
+	// was returned.
+	LastError     error
+	LastErrorTime time.Time
+}
+
+// matchStats accumulates Stats. Counters are updated without locking, so a
+// snapshot taken during matches may count a match in Matches but not yet in
+// Hits.
+type matchStats struct {
+	matches   atomic.Int64
+	hits      atomic.Int64
+	errors    atomic.Int64
+	nanos     atomic.Int64
+	mu        sync.Mutex // guards lastErr and lastErrAt
+	lastErr   error
+	lastErrAt time.Time
+}
+
+// record counts one match that took latency.
+func (s *matchStats) record(latency time.Duration, matched bool, err error) {
+	s.nanos.Add(int64(latency))
+	s.matches.Add(1)
+	switch {
+	case err != nil:
+		s.errors.Add(1)
+		s.mu.Lock()
+		s.lastErr, s.lastErrAt = err, time.Now()
+		s.mu.Unlock()
+	case matched:
+		s.hits.Add(1)
+	}
+}
+
+// MatchEvent describes one finished match to the observer set with
+// SetObserver.
+type MatchEvent struct {
+	Latency time.Duration
+	Matched bool
+	Err     error
+	// PeakBytes is the matcher's PeakNativeBytes after the match.
+	PeakBytes int64
+}
+
+// SetObserver makes fn be called after every match of the matcher, by Match
+// or a Batch, from the matching goroutine. fn must not keep a reference to
+// the matcher, or it is never cleaned up. Nil removes the observer.
+func (m *Matcher) SetObserver(fn func(MatchEvent)) {
+	if fn == nil {
+		m.observer.Store(nil)
+		return
+	}
+	m.observer.Store(&fn)
+}
+
+// finish counts one match that started at start and reports it to the
+// observer.
+func (m *Matcher) finish(start time.Time, matched bool, err error) {
+	latency := time.Since(start)
+	m.stats.record(latency, matched, err)
+	if fn := m.observer.Load(); fn != nil {
+		(*fn)(MatchEvent{Latency: latency, Matched: matched, Err: err, PeakBytes: m.peakBytes.Load()})
+	}
+}
+
+// Stats returns the matcher's match statistics. Clones count their own.
+func (m *Matcher) Stats() Stats {
+	s := &m.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{
+		Matches:       s.matches.Load(),
+		Hits:          s.hits.Load(),
+		Errors:        s.errors.Load(),
+		Latency:       time.Duration(s.nanos.Load()),
+		LastError:     s.lastErr,
+		LastErrorTime: s.lastErrAt,
+	}
+}
+
+// ResetStats sets the matcher's statistics back to zero.
+func (m *Matcher) ResetStats() {
+	s := &m.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matches.Store(0)
+	s.hits.Store(0)
+	s.errors.Store(0)
+	s.nanos.Store(0)
+	s.lastErr, s.lastErrAt = nil, time.Time{}
+}
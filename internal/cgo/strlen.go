@@ -0,0 +1,38 @@
+package cgo
+
+/*
+#include <mongory-core.h>
+*/
+import "C"
+import (
+	"unicode/utf8"
+	"unsafe"
+)
+
+// strLenMatcher implements $strLen: the field is a string whose length in
+// characters, not bytes, matches the operand, a number or a condition such
+// as {"$between": [3, 20]}. Other values do not match.
+type strLenMatcher struct {
+	condition *C.mongory_matcher
+}
+
+func buildStrLen(b operatorBuild) (nativeMatcher, string, bool) {
+	matcher := operandMatcher(b, b.condition)
+	if matcher == nil {
+		b.fail("$strLen condition must be a condition or a number.")
+		return nil, "", false
+	}
+	return &strLenMatcher{condition: matcher}, "StrLen", true
+}
+
+func (s *strLenMatcher) match(value *C.mongory_value) bool {
+	if value == nil || value._type != C.MONGORY_TYPE_STRING {
+		return false
+	}
+	pool := lookupPool(unsafe.Pointer(value.pool))
+	if pool == nil {
+		return false
+	}
+	n := utf8.RuneCountInString((&Value{CPoint: value}).GetString())
+	return runMatcher(s.condition, NewValueInt(pool, int64(n)).CPoint)
+}
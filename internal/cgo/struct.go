@@ -0,0 +1,266 @@
+package cgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// StructLayout is the compiled field layout of a struct type: the name each
+// field is matched under, its offset, and an accessor reading it without
+// reflection.
+type StructLayout struct {
+	typ    reflect.Type
+	fields map[string]*structField
+	order  []*structField
+}
+
+var jsonNumberType = reflect.TypeFor[json.Number]()
+
+type structField struct {
+	name   string
+	offset uintptr
+	typ    reflect.Type
+	// layout is set for fields holding a struct with exported fields, or a
+	// pointer to one (ptr), which convert as nested tables.
+	layout *StructLayout
+	ptr    bool
+	// scalar reads booleans, numbers and strings; other fields go through
+	// valueConvert.
+	scalar func(m *MemoryPool, p unsafe.Pointer) *Value
+}
+
+var (
+	structLayouts  sync.Map // reflect.Type -> *StructLayout
+	derivedLayouts sync.Map // reflect.Type -> *StructLayout, for unregistered types
+)
+
+// RegisterStruct compiles the layout of struct type t, so values of type t
+// and *t are converted by reading their fields at precomputed offsets
+// instead of reflecting over them. Registering a type again returns the
+// layout compiled first.
+func RegisterStruct(t reflect.Type) (*StructLayout, error) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mongory: cannot register %v: not a struct type", t)
+	}
+	if layout, ok := structLayouts.Load(t); ok {
+		return layout.(*StructLayout), nil
+	}
+	layout := compileStruct(t, map[reflect.Type]*StructLayout{})
+	actual, _ := structLayouts.LoadOrStore(t, layout)
+	return actual.(*StructLayout), nil
+}
+
+// Fields returns the names fields are matched under, in declaration order.
+func (l *StructLayout) Fields() []string {
+	names := make([]string, len(l.order))
+	for i, f := range l.order {
+		names[i] = f.name
+	}
+	return names
+}
+
+// RegisteredStruct returns the layout registered for t, or nil.
+func RegisteredStruct(t reflect.Type) *StructLayout {
+	if layout, ok := structLayouts.Load(t); ok {
+		return layout.(*StructLayout)
+	}
+	return nil
+}
+
+// structLayout returns the layout values of type t convert through: the one
+// registered for t, else one compiled the first time a t is converted. Types
+// that are not structs, and structs without exported fields or implementing
+// driver.Valuer, such as time.Time and sql.NullString, have none.
+func structLayout(t reflect.Type) *StructLayout {
+	if layout := RegisteredStruct(t); layout != nil {
+		return layout
+	}
+	if layout, ok := derivedLayouts.Load(t); ok {
+		return layout.(*StructLayout)
+	}
+	if t.Kind() != reflect.Struct || !hasExportedField(t) || t.Implements(valuerType) {
+		return nil
+	}
+	layout, _ := derivedLayouts.LoadOrStore(t, compileStruct(t, map[reflect.Type]*StructLayout{}))
+	return layout.(*StructLayout)
+}
+
+// compileStruct builds the layout of t. compiling holds the layouts under
+// construction, so recursive types refer back to them.
+func compileStruct(t reflect.Type, compiling map[reflect.Type]*StructLayout) *StructLayout {
+	if layout := compiling[t]; layout != nil {
+		return layout
+	}
+	layout := &StructLayout{typ: t, fields: map[string]*structField{}}
+	compiling[t] = layout
+	layout.addFields(t, 0, compiling)
+	return layout
+}
+
+// addFields adds the exported fields of t, found at offset in the layout's
+// type. Fields of embedded structs are promoted, as encoding/json does;
+// fields declared outside them take precedence.
+func (l *StructLayout) addFields(t reflect.Type, offset uintptr, compiling map[reflect.Type]*StructLayout) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, ok := fieldName(sf)
+		if !ok {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && name == "" {
+			embedded = append(embedded, sf)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if _, taken := l.fields[name]; taken {
+			continue
+		}
+		f := &structField{name: name, offset: offset + sf.Offset, typ: sf.Type}
+		f.compile(compiling)
+		l.fields[name] = f
+		l.order = append(l.order, f)
+	}
+	for _, sf := range embedded {
+		l.addFields(sf.Type, offset+sf.Offset, compiling)
+	}
+}
+
+// fieldName returns the name a struct tag gives sf, from the mongory tag or
+// else the json tag, and false for fields tagged "-".
+func fieldName(sf reflect.StructField) (string, bool) {
+	for _, key := range []string{"mongory", "json"} {
+		if tag, ok := sf.Tag.Lookup(key); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				return "", false
+			}
+			if name != "" {
+				return name, true
+			}
+		}
+	}
+	return "", true
+}
+
+func (f *structField) compile(compiling map[reflect.Type]*StructLayout) {
+	t := f.typ
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		t, f.ptr = t.Elem(), true
+	}
+	if t.Kind() == reflect.Struct {
+		// Structs such as sql.NullString convert as the value they hold.
+		if hasExportedField(t) && !t.Implements(valuerType) {
+			f.layout = compileStruct(t, compiling)
+		}
+		return
+	}
+	if f.typ == jsonNumberType {
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value {
+			if number := m.numberValue(*(*json.Number)(p)); number != nil {
+				return number
+			}
+			return m.documentString(*(*string)(p))
+		}
+		return
+	}
+	switch f.typ.Kind() {
+	case reflect.Bool:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueBool(m, *(*bool)(p)) }
+	case reflect.Int:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*int)(p))) }
+	case reflect.Int8:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*int8)(p))) }
+	case reflect.Int16:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*int16)(p))) }
+	case reflect.Int32:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*int32)(p))) }
+	case reflect.Int64:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, *(*int64)(p)) }
+	case reflect.Uint:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return newValueUint(m, uint64(*(*uint)(p))) }
+	case reflect.Uint8:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*uint8)(p))) }
+	case reflect.Uint16:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*uint16)(p))) }
+	case reflect.Uint32:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueInt(m, int64(*(*uint32)(p))) }
+	case reflect.Uint64:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return newValueUint(m, *(*uint64)(p)) }
+	case reflect.Float32:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueDouble(m, float64(*(*float32)(p))) }
+	case reflect.Float64:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return NewValueDouble(m, *(*float64)(p)) }
+	case reflect.String:
+		f.scalar = func(m *MemoryPool, p unsafe.Pointer) *Value { return m.documentString(*(*string)(p)) }
+	}
+}
+
+// hasExportedField reports whether t has a field a layout would expose.
+// Structs without one, such as time.Time, keep converting as Go values.
+func hasExportedField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.IsExported() || sf.Anonymous && sf.Type.Kind() == reflect.Struct && hasExportedField(sf.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// convert converts the field of the struct at base.
+func (f *structField) convert(m *MemoryPool, base unsafe.Pointer, depth int) *Value {
+	p := unsafe.Add(base, f.offset)
+	switch {
+	case f.layout != nil && f.ptr:
+		if p = *(*unsafe.Pointer)(p); p == nil {
+			return m.valueConvert(nil, depth)
+		}
+		return m.structConvert(f.layout, p, depth)
+	case f.layout != nil:
+		return m.structConvert(f.layout, p, depth)
+	case f.scalar != nil:
+		if !m.checkLimits(0, 0) {
+			return NewValueNull(m)
+		}
+		return f.scalar(m, p)
+	default:
+		return m.valueConvert(reflect.NewAt(f.typ, p).Elem().Interface(), depth)
+	}
+}
+
+// structConvert converts the struct at p, which must stay reachable for as
+// long as the pool's values are read.
+func (m *MemoryPool) structConvert(layout *StructLayout, p unsafe.Pointer, depth int) *Value {
+	if !m.checkLimits(depth, 0) {
+		return NewValueNull(m)
+	}
+	if m.deep {
+		table := NewTable(m)
+		for _, f := range layout.order {
+			table.Set(f.name, f.convert(m, p, depth+1))
+		}
+		return NewValueTable(m, table)
+	}
+	return NewValueShallowTable(m, newStructTable(m, layout, p, depth))
+}
+
+// structPointer returns a pointer to the struct rv holds, copying it when rv
+// is not addressable.
+func structPointer(rv reflect.Value) unsafe.Pointer {
+	if rv.CanAddr() {
+		return rv.Addr().UnsafePointer()
+	}
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	return p.UnsafePointer()
+}
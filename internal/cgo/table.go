@@ -53,3 +53,7 @@ func (t *Table) Delete(key string) bool {
 	result := C.go_mongory_table_delete(t.CPoint, ckey)
 	return bool(result)
 }
+
+func (t *Table) Len() int {
+	return int(t.CPoint.count)
+}
@@ -0,0 +1,28 @@
+package cgo
+
+import "time"
+
+// TimeLayout is how time.Time values are converted for matching: RFC 3339 in
+// UTC with nanoseconds always written, so every time between the years 0 and
+// 9999 has the same width and times compare as strings in chronological
+// order.
+const TimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// FormatTime returns t as it is converted for matching.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+// normalizeTime returns s in TimeLayout if it is an RFC 3339 timestamp,
+// reporting whether it is.
+func normalizeTime(s string) (string, bool) {
+	// Check the shape before parsing, as most strings are not timestamps.
+	if len(s) < len("2006-01-02T15:04:05Z") || s[4] != '-' || s[7] != '-' || (s[10] != 'T' && s[10] != 't') {
+		return s, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s, false
+	}
+	return FormatTime(t), true
+}
@@ -0,0 +1,85 @@
+package cgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// How strings holding invalid UTF-8 are handled; see SetInvalidUTF8Mode.
+const (
+	UTF8Bytewise = iota
+	UTF8Replace
+	UTF8Reject
+)
+
+var utf8Mode atomic.Int32
+
+// ErrInvalidUTF8 is wrapped by errors for strings holding invalid UTF-8 in
+// UTF8Reject mode.
+var ErrInvalidUTF8 = errors.New("mongory: invalid UTF-8")
+
+// SetInvalidUTF8Mode sets how condition and document strings holding
+// invalid UTF-8 are converted: kept as they are and compared byte by byte
+// (UTF8Bytewise, the default), with each invalid sequence replaced by U+FFFD
+// (UTF8Replace), or rejected with ErrInvalidUTF8 (UTF8Reject).
+func SetInvalidUTF8Mode(mode int) {
+	utf8Mode.Store(int32(mode))
+}
+
+// validString applies the invalid UTF-8 mode to s, reporting false when s
+// is rejected.
+func validString(s string) (string, bool) {
+	mode := utf8Mode.Load()
+	if mode == UTF8Bytewise || utf8.ValidString(s) {
+		return s, true
+	}
+	if mode == UTF8Replace {
+		return strings.ToValidUTF8(s, "�"), true
+	}
+	return s, false
+}
+
+// invalidUTF8 records that s was rejected, as a conversion error of a
+// document or, in conditions, an invalid condition.
+func (m *MemoryPool) invalidUTF8(s string, kind error) {
+	if m.limitErr != nil {
+		return
+	}
+	const maxQuoted = 32
+	if len(s) > maxQuoted {
+		s = s[:maxQuoted] + "..."
+	}
+	m.limitErr = kindError{fmt.Errorf("%w in string %q", ErrInvalidUTF8, s), kind}
+}
+
+// documentString converts a document string, interning it when eligible.
+func (m *MemoryPool) documentString(s string) *Value {
+	s, ok := validString(s)
+	if !ok {
+		m.invalidUTF8(s, ErrConversion)
+		return NewValueNull(m)
+	}
+	if m.parseTimes {
+		s, _ = normalizeTime(s)
+	}
+	if interned := m.internString(s); interned != nil {
+		return interned
+	}
+	return NewValueString(m, s)
+}
+
+// conditionString converts a condition string.
+func (m *MemoryPool) conditionString(s string) *Value {
+	s, ok := validString(s)
+	if !ok {
+		m.invalidUTF8(s, ErrInvalidCondition)
+		return NewValueNull(m)
+	}
+	if m.parseTimes {
+		s, _ = normalizeTime(s)
+	}
+	return NewValueString(m, s)
+}
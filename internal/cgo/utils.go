@@ -15,10 +15,18 @@ import (
 	"unsafe"
 )
 
+// Handles are small integers, not pointers. A Go frame must not hold one as
+// an unsafe.Pointer across a C call that may call back into Go, since the
+// stack could grow meanwhile and the runtime rejects such values when it
+// copies the frame; those calls take the handle as a uintptr_t instead.
 func handleToPtr(h rcgo.Handle) unsafe.Pointer {
 	return C.go_handle_to_ptr(C.uintptr_t(uintptr(h)))
 }
 
+func handleArg(h rcgo.Handle) C.uintptr_t {
+	return C.uintptr_t(uintptr(h))
+}
+
 func ptrToHandle(ptr unsafe.Pointer) rcgo.Handle {
 	return rcgo.Handle(C.go_ptr_to_handle(ptr))
 }
@@ -5,6 +5,28 @@ package cgo
 #include <mongory-core.h>
 #include <stdlib.h>
 #include <stdint.h>
+#include <string.h>
+#include "foundations/utils.h"
+
+// go_mongory_value_wrap_sn wraps the n bytes at s, which need not end in a
+// NUL, copying them into the pool once. mongory_value_wrap_s would need a C
+// string made first and measure it again.
+static mongory_value *go_mongory_value_wrap_sn(mongory_memory_pool *pool, const char *s, size_t n) {
+	char *copy = (char *)MG_ALLOC(pool, n + 1);
+	if (copy == NULL) {
+		pool->error = &MONGORY_ALLOC_ERROR;
+		return NULL;
+	}
+	if (n > 0) {
+		memcpy(copy, s, n);
+	}
+	copy[n] = '\0';
+	mongory_value *value = mongory_value_wrap_s(pool, NULL);
+	if (value != NULL) {
+		value->data.s = copy;
+	}
+	return value;
+}
 
 char * go_mongory_value_to_string(mongory_value* v, mongory_memory_pool* pool) {
 	return v->to_str(v, pool);
@@ -14,7 +36,7 @@ bool go_mongory_value_get_bool(mongory_value* v) {
   return v->data.b;
 }
 
-int go_mongory_value_get_int(mongory_value* v) {
+int64_t go_mongory_value_get_int(mongory_value* v) {
   return v->data.i;
 }
 
@@ -54,12 +76,20 @@ void * go_mongory_value_get_null(mongory_value* v) {
 extern char *go_shallow_array_to_string(void *go_array);
 extern char *go_shallow_table_to_string(void *go_table);
 
+// The Go side returns malloc'd strings; copy them into the pool so they live
+// as long as the value and free the original.
 static char *cgo_shallow_array_to_string(mongory_value *v, mongory_memory_pool *pool) {
-	return go_shallow_array_to_string(v->data.a);
+	char *s = go_shallow_array_to_string(v->data.a);
+	char *copy = mongory_string_cpy(pool, s);
+	free(s);
+	return copy;
 }
 
 static char *cgo_shallow_table_to_string(mongory_value *v, mongory_memory_pool *pool) {
-	return go_shallow_table_to_string(v->data.t);
+	char *s = go_shallow_table_to_string(v->data.t);
+	char *copy = mongory_string_cpy(pool, s);
+	free(s);
+	return copy;
 }
 
 static void mongory_value_set_array_to_string(mongory_value *v) {
@@ -99,14 +129,15 @@ type Value struct {
 }
 
 func NewValueInt(pool *MemoryPool, i int64) *Value { // as integer
-	return &Value{CPoint: C.mongory_value_wrap_i(pool.CPoint, C.int64_t(i)), Type: MONGORY_TYPE_INT, pool: pool}
+	return pool.exactNumber(&Value{CPoint: C.mongory_value_wrap_i(pool.CPoint, C.int64_t(i)), Type: MONGORY_TYPE_INT, pool: pool})
 }
 
+// NewValueString copies s into the pool straight from Go memory, which cgo
+// keeps pinned for the call. The core reads strings up to a NUL, so a copy
+// is still needed to terminate them; it is the only one made.
 func NewValueString(pool *MemoryPool, s string) *Value { // as string
-	cs := C.CString(s)
-	v := &Value{CPoint: C.mongory_value_wrap_s(pool.CPoint, cs), Type: MONGORY_TYPE_STRING, pool: pool}
-	C.free(unsafe.Pointer(cs))
-	return v
+	cs := (*C.char)(unsafe.Pointer(unsafe.StringData(s)))
+	return &Value{CPoint: C.go_mongory_value_wrap_sn(pool.CPoint, cs, C.size_t(len(s))), Type: MONGORY_TYPE_STRING, pool: pool}
 }
 
 func NewValueBool(pool *MemoryPool, b bool) *Value { // as boolean
@@ -114,7 +145,7 @@ func NewValueBool(pool *MemoryPool, b bool) *Value { // as boolean
 }
 
 func NewValueDouble(pool *MemoryPool, d float64) *Value { // as double
-	return &Value{CPoint: C.mongory_value_wrap_d(pool.CPoint, C.double(d)), Type: MONGORY_TYPE_DOUBLE, pool: pool}
+	return pool.exactNumber(&Value{CPoint: C.mongory_value_wrap_d(pool.CPoint, C.double(d)), Type: MONGORY_TYPE_DOUBLE, pool: pool})
 }
 
 func NewValueArray(pool *MemoryPool, a *Array) *Value { // as array
@@ -196,6 +227,24 @@ func (v *Value) GetType() string {
 	return ""
 }
 
+// Kind returns the type of the native value, which unlike Type is known for
+// values read out of arrays and tables too.
+func (v *Value) Kind() MongoryType {
+	if v == nil || v.CPoint == nil {
+		return MONGORY_TYPE_NULL
+	}
+	return MongoryType(v.CPoint._type)
+}
+
+// Recover returns the Go value v represents, as the operand and value of an
+// operator registered with RegisterOperator are.
+func (v *Value) Recover() any {
+	if v == nil {
+		return nil
+	}
+	return recoverValue(v.CPoint)
+}
+
 func (v *Value) GetInt() int64 {
 	intValue := C.go_mongory_value_get_int(v.CPoint)
 	if intValue == 0 {
@@ -0,0 +1,110 @@
+package mongory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// ParseConditionJSON decodes a JSON query document into a condition. Numbers
+// that are whole and fit in an int64 become int64, others float64, so that
+// large integer IDs keep their exact value instead of rounding through
+// float64 as plain json.Unmarshal does. Compile with NumericExact to also
+// compare them exactly against floats.
+func ParseConditionJSON(data []byte) (map[string]any, error) {
+	raw, err := decodeJSON(data, "condition")
+	if err != nil {
+		return nil, err
+	}
+	condition, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("mongory: JSON condition must be an object, got %s", jsonKind(raw))
+	}
+	return condition, nil
+}
+
+// ParseDocumentJSON decodes a JSON document for matching, with numbers
+// decoded as ParseConditionJSON decodes them, so an ID above 2^53 matches
+// only its own value.
+func ParseDocumentJSON(data []byte) (any, error) {
+	return decodeJSON(data, "document")
+}
+
+// decodeJSON decodes the single JSON value in data, with numbers normalized.
+// what names the value in errors.
+func decodeJSON(data []byte, what string) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw any
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("mongory: invalid JSON %s: %w", what, err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("mongory: invalid JSON %s: unexpected data after the document", what)
+	}
+	return NormalizeJSONNumbers(raw)
+}
+
+// NewMatcherFromJSON compiles a JSON query document, $ operators included.
+func NewMatcherFromJSON(data []byte) (Matcher, error) {
+	condition, err := ParseConditionJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewMatcher(condition)
+}
+
+// NormalizeJSONNumbers replaces the json.Number values in value, as decoded
+// by a json.Decoder with UseNumber, by int64 when they are whole and fit and
+// by float64 otherwise. Maps and slices are updated in place. Matching
+// converts json.Number values the same way without it; it is for callers
+// that also use the decoded values themselves, as $func predicates do.
+func NormalizeJSONNumbers(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			normalized, err := NormalizeJSONNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = normalized
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			normalized, err := NormalizeJSONNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalized
+		}
+		return v, nil
+	case json.Number:
+		number, ok := cgo.JSONNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("mongory: invalid JSON number %s", v)
+		}
+		return number, nil
+	default:
+		return value, nil
+	}
+}
+
+func jsonKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
@@ -0,0 +1,174 @@
+package mongory
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConditionJSON(t *testing.T) {
+	condition, err := ParseConditionJSON([]byte(`{"id": 9007199254740993, "score": {"$gte": 1.5}, "n": 2e3, "tags": {"$in": [1, "x"]}}`))
+	if err != nil {
+		t.Fatalf("ParseConditionJSON failed: %v", err)
+	}
+	want := map[string]any{
+		"id":    int64(9007199254740993),
+		"score": map[string]any{"$gte": 1.5},
+		"n":     int64(2000),
+		"tags":  map[string]any{"$in": []any{int64(1), "x"}},
+	}
+	if !reflect.DeepEqual(condition, want) {
+		t.Fatalf("ParseConditionJSON = %#v, want %#v", condition, want)
+	}
+	for _, input := range []string{`[1]`, `{"a": 1} {}`, `{"a":`, `null`} {
+		if _, err := ParseConditionJSON([]byte(input)); err == nil {
+			t.Fatalf("ParseConditionJSON(%s) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestNewMatcherFromJSON(t *testing.T) {
+	matcher, err := NewMatcherFromJSON([]byte(`{"id": 9007199254740993, "age": {"$gte": 18}}`))
+	if err != nil {
+		t.Fatalf("NewMatcherFromJSON failed: %v", err)
+	}
+	if matched, err := matcher.Match(map[string]any{"id": int64(9007199254740993), "age": 30}); err != nil || !matched {
+		t.Fatalf("Match = %v, %v; want true", matched, err)
+	}
+	if matched, err := matcher.Match(map[string]any{"id": int64(9007199254740992), "age": 30}); err != nil || matched {
+		t.Fatalf("Match(neighbouring id) = %v, %v; want false", matched, err)
+	}
+}
+
+func TestParseDocumentJSON(t *testing.T) {
+	doc, err := ParseDocumentJSON([]byte(`{"id": 9007199254740993, "big": 9007199254740993.0, "max": 9223372036854775807, "min": -9223372036854775808, "huge": 1e300, "ratio": 0.5, "ids": [9007199254740992, 9007199254740993]}`))
+	if err != nil {
+		t.Fatalf("ParseDocumentJSON failed: %v", err)
+	}
+	want := map[string]any{
+		"id":    int64(9007199254740993),
+		"big":   int64(9007199254740993),
+		"max":   int64(math.MaxInt64),
+		"min":   int64(math.MinInt64),
+		"huge":  1e300,
+		"ratio": 0.5,
+		"ids":   []any{int64(9007199254740992), int64(9007199254740993)},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("ParseDocumentJSON = %#v, want %#v", doc, want)
+	}
+	if scalar, err := ParseDocumentJSON([]byte(`9007199254740993`)); err != nil || scalar != int64(9007199254740993) {
+		t.Fatalf("ParseDocumentJSON(scalar) = %#v, %v", scalar, err)
+	}
+	for _, input := range []string{`{"a": 1} {}`, `{"a":`, ``} {
+		if _, err := ParseDocumentJSON([]byte(input)); err == nil {
+			t.Fatalf("ParseDocumentJSON(%s) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestJSONIDPrecision(t *testing.T) {
+	// 2^53 + 1 is the first integer a float64 cannot hold.
+	doc, err := ParseDocumentJSON([]byte(`{"id": 9007199254740993}`))
+	if err != nil {
+		t.Fatalf("ParseDocumentJSON failed: %v", err)
+	}
+	exact, err := NewMatcherFromJSON([]byte(`{"id": 9007199254740993}`))
+	if err != nil {
+		t.Fatalf("NewMatcherFromJSON failed: %v", err)
+	}
+	defer exact.Close()
+	if ok, err := exact.Match(doc); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+	neighbour, err := NewMatcherFromJSON([]byte(`{"id": 9007199254740992}`))
+	if err != nil {
+		t.Fatalf("NewMatcherFromJSON failed: %v", err)
+	}
+	defer neighbour.Close()
+	if ok, err := neighbour.Match(doc); err != nil || ok {
+		t.Fatalf("Match(2^53) = %v, %v; want false", ok, err)
+	}
+
+	// A float operand rounds to 2^53; NumericExact keeps it from matching.
+	condition, err := ParseConditionJSON([]byte(`{"id": {"$eq": 9007199254740992.5}}`))
+	if err != nil {
+		t.Fatalf("ParseConditionJSON failed: %v", err)
+	}
+	m, err := NewMatcherWithOptions(condition, MatcherOptions{Numeric: NumericExact})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer m.Close()
+	if ok, err := m.Match(doc); err != nil || ok {
+		t.Fatalf("exact Match = %v, %v; want false", ok, err)
+	}
+
+	var decoded any
+	decoder := json.NewDecoder(strings.NewReader(`{"id": 9007199254740993}`))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded, err = NormalizeJSONNumbers(decoded); err != nil {
+		t.Fatalf("NormalizeJSONNumbers failed: %v", err)
+	}
+	if ok, err := exact.Match(decoded); err != nil || !ok {
+		t.Fatalf("Match(normalized) = %v, %v; want true", ok, err)
+	}
+}
+
+func TestJSONNumberConversion(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	type order struct {
+		ID    json.Number `json:"id"`
+		Total json.Number `json:"total"`
+	}
+	if err := RegisterStruct[order](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	var doc any
+	decoder := json.NewDecoder(strings.NewReader(`{"id": 9007199254740993, "total": 19.99, "qty": 2.0, "code": "abc"}`))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	var typed order
+	if err := json.Unmarshal([]byte(`{"id": 9007199254740993, "total": 19.99}`), &typed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	cases := []struct {
+		condition map[string]any
+		doc       any
+		want      bool
+	}{
+		{map[string]any{"id": 9007199254740993}, doc, true},
+		{map[string]any{"id": 9007199254740992}, doc, false},
+		{map[string]any{"total": map[string]any{"$lt": 20}}, doc, true},
+		{map[string]any{"qty": map[string]any{"$in": []any{1, 2}}}, doc, true},
+		{map[string]any{"id": json.Number("9007199254740993")}, map[string]any{"id": int64(9007199254740993)}, true},
+		{map[string]any{"total": map[string]any{"$between": []any{json.Number("10"), json.Number("20.5")}}}, doc, true},
+		{map[string]any{"code": json.Number("abc")}, map[string]any{"code": "abc"}, true},
+		{map[string]any{"id": 9007199254740993, "total": map[string]any{"$gt": 19.9}}, typed, true},
+		{map[string]any{"id": 9007199254740992}, &typed, false},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewMatcherWithOptions(tc.condition, MatcherOptions{StrictTypes: true})
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			got, err := m.Match(tc.doc)
+			m.Close()
+			if err != nil || got != tc.want {
+				t.Fatalf("%v: Match(%v) with %v = %v, %v; want %v", mode, tc.doc, tc.condition, got, err, tc.want)
+			}
+		}
+	}
+	if !EquivalentConditions(map[string]any{"a": json.Number("2.0")}, map[string]any{"a": 2}) {
+		t.Fatalf("json.Number 2.0 and 2 should canonicalize alike")
+	}
+}
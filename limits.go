@@ -0,0 +1,26 @@
+package mongory
+
+import "github.com/mongoryhq/mongory-go/internal/cgo"
+
+// DocumentLimits caps the nesting depth, array length and number of values a
+// matched document may expand into natively. Zero fields are not checked.
+type DocumentLimits = cgo.DocumentLimits
+
+// ErrDocumentLimit is wrapped by errors from Match, Trace and the batch
+// helpers when a document breaks the configured limits.
+var ErrDocumentLimit = cgo.ErrDocumentLimit
+
+// ErrPoolLimit is wrapped by errors from Match when a document needs more
+// native memory than the matcher's SetMemoryLimit allows.
+var ErrPoolLimit = cgo.ErrPoolLimit
+
+// SetDocumentLimits applies limits to every document matched from now on, so
+// services matching untrusted payloads can bound native memory per document.
+func SetDocumentLimits(limits DocumentLimits) {
+	cgo.SetDocumentLimits(limits)
+}
+
+// GetDocumentLimits returns the limits currently in effect.
+func GetDocumentLimits() DocumentLimits {
+	return cgo.GetDocumentLimits()
+}
@@ -0,0 +1,87 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDocumentLimits(t *testing.T) {
+	defer SetDocumentLimits(GetDocumentLimits())
+	matcher, err := NewCMatcher(map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	nested := map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}}
+
+	cases := []struct {
+		name   string
+		limits DocumentLimits
+		doc    any
+		fail   bool
+	}{
+		{"unlimited", DocumentLimits{}, nested, false},
+		{"depth ok", DocumentLimits{MaxDepth: 3}, nested, false},
+		{"depth exceeded", DocumentLimits{MaxDepth: 2}, nested, true},
+		{"nodes exceeded", DocumentLimits{MaxNodes: 3}, nested, true},
+		{"array ok", DocumentLimits{MaxArrayLength: 3}, map[string]any{"a": []any{1, 2, 3}}, false},
+		{"array exceeded", DocumentLimits{MaxArrayLength: 2}, map[string]any{"a": []any{1, 2, 3}}, true},
+	}
+	for _, tc := range cases {
+		SetDocumentLimits(tc.limits)
+		matched, err := matcher.Match(tc.doc)
+		if tc.fail {
+			if !errors.Is(err, ErrDocumentLimit) {
+				t.Fatalf("%s: Match error = %v, want ErrDocumentLimit", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Match failed: %v", tc.name, err)
+		}
+		if want := tc.name != "array ok"; matched != want {
+			t.Fatalf("%s: Match = %v, want %v", tc.name, matched, want)
+		}
+	}
+
+	SetDocumentLimits(DocumentLimits{})
+	if matched, err := matcher.Match(nested); err != nil || !matched {
+		t.Fatalf("Match after clearing limits = %v, %v", matched, err)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	matcher, err := NewCMatcher(map[string]any{"tags": map[string]any{"$in": []any{"a"}}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	small := map[string]any{"tags": "a"}
+	big := map[string]any{"tags": make([]any, 10000)}
+	if _, err := matcher.Match(small); err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	base := matcher.PeakNativeBytes()
+	if base <= 0 {
+		t.Fatalf("PeakNativeBytes = %d, want > 0", base)
+	}
+	if _, err := matcher.Match(big); err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if peak := matcher.PeakNativeBytes(); peak <= base {
+		t.Fatalf("PeakNativeBytes = %d after a large document, want > %d", peak, base)
+	}
+
+	matcher.SetMemoryLimit(4096)
+	if _, err := matcher.Match(big); !errors.Is(err, ErrPoolLimit) {
+		t.Fatalf("Match(big) err = %v, want ErrPoolLimit", err)
+	}
+	if matched, err := matcher.Match(small); err != nil || !matched {
+		t.Fatalf("Match(small) = %v, %v; want true", matched, err)
+	}
+	clone, err := matcher.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if _, err := clone.Match(big); !errors.Is(err, ErrPoolLimit) {
+		t.Fatalf("clone.Match(big) err = %v, want ErrPoolLimit", err)
+	}
+}
@@ -0,0 +1,102 @@
+package mongory
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+	"weak"
+)
+
+// LiveMatcher describes a matcher that has been compiled and not closed.
+type LiveMatcher struct {
+	// ID numbers matchers in the order they were compiled.
+	ID uint64 `json:"id"`
+	// Name is MatcherOptions.Name, if one was given.
+	Name      string    `json:"name,omitempty"`
+	Hash      string    `json:"hash"`
+	Condition string    `json:"condition"`
+	Created   time.Time `json:"created"`
+	Matches   int64     `json:"matches"`
+	Hits      int64     `json:"hits"`
+	Errors    int64     `json:"errors"`
+	// LatencyNanos is the total time spent matching.
+	LatencyNanos int64  `json:"latencyNanos"`
+	LastError    string `json:"lastError,omitempty"`
+	// PeakNativeBytes is the most native memory the matcher has held during
+	// a match, as Matcher.PeakNativeBytes reports it.
+	PeakNativeBytes int64 `json:"peakNativeBytes"`
+}
+
+// liveMatchers tracks every matcher until it is closed or collected. It holds
+// them weakly, so listing matchers never keeps one alive.
+var liveMatchers struct {
+	mu      sync.Mutex
+	next    uint64
+	entries map[uint64]liveEntry
+}
+
+type liveEntry struct {
+	matcher weak.Pointer[matcher]
+	created time.Time
+}
+
+func trackMatcher(m *matcher) {
+	liveMatchers.mu.Lock()
+	liveMatchers.next++
+	m.id = liveMatchers.next
+	if liveMatchers.entries == nil {
+		liveMatchers.entries = map[uint64]liveEntry{}
+	}
+	liveMatchers.entries[m.id] = liveEntry{matcher: weak.Make(m), created: time.Now()}
+	liveMatchers.mu.Unlock()
+	runtime.AddCleanup(m, untrackMatcher, m.id)
+}
+
+func untrackMatcher(id uint64) {
+	liveMatchers.mu.Lock()
+	delete(liveMatchers.entries, id)
+	liveMatchers.mu.Unlock()
+}
+
+// LiveMatchers lists the matchers compiled and not yet closed or garbage
+// collected, clones and cached matchers included, in the order they were
+// compiled, with their statistics. It helps operate services that compile
+// matchers dynamically: a growing list points to matchers that are never
+// closed.
+func LiveMatchers() []LiveMatcher {
+	type live struct {
+		m       *matcher
+		created time.Time
+	}
+	liveMatchers.mu.Lock()
+	matchers := make([]live, 0, len(liveMatchers.entries))
+	for _, entry := range liveMatchers.entries {
+		if m := entry.matcher.Value(); m != nil {
+			matchers = append(matchers, live{m, entry.created})
+		}
+	}
+	liveMatchers.mu.Unlock()
+	sort.Slice(matchers, func(i, j int) bool { return matchers[i].m.id < matchers[j].m.id })
+	out := make([]LiveMatcher, 0, len(matchers))
+	for _, l := range matchers {
+		stats := l.m.Stats()
+		lm := LiveMatcher{
+			ID:              l.m.id,
+			Name:            l.m.name,
+			Hash:            l.m.condition.Hash(),
+			Condition:       l.m.condition.String(),
+			Created:         l.created,
+			Matches:         stats.Matches,
+			Hits:            stats.Hits,
+			Errors:          stats.Errors,
+			LatencyNanos:    int64(stats.Latency),
+			PeakNativeBytes: l.m.PeakNativeBytes(),
+		}
+		if stats.LastError != nil {
+			lm.LastError = stats.LastError.Error()
+		}
+		out = append(out, lm)
+	}
+	return out
+}
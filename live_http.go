@@ -0,0 +1,25 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+)
+
+// LiveMatchersHandler serves LiveMatchers as a JSON array, for a debug
+// endpoint such as /debug/mongory/matchers.
+func LiveMatchersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LiveMatchers())
+	})
+}
+
+// PublishLiveMatchers publishes LiveMatchers as the expvar variable name,
+// served with the other variables on /debug/vars. Like expvar.Publish, it
+// panics if name is already published.
+func PublishLiveMatchers(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return LiveMatchers() }))
+}
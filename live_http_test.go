@@ -0,0 +1,39 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLiveMatchersHandler(t *testing.T) {
+	m, err := NewMatcherWithOptions(map[string]any{"status": "active"}, MatcherOptions{Name: "live-http"})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer m.Close()
+
+	rec := httptest.NewRecorder()
+	LiveMatchersHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/mongory/matchers", nil))
+	var listed []LiveMatcher
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Handler = %d %q: %v\n%s", rec.Code, rec.Header().Get("Content-Type"), err, rec.Body.String())
+	}
+	found := false
+	for _, lm := range listed {
+		found = found || lm.Name == "live-http" && lm.Hash == m.Condition().Hash()
+	}
+	if !found {
+		t.Fatalf("Handler does not list the matcher:\n%s", rec.Body.String())
+	}
+
+	PublishLiveMatchers("mongory_test_live_matchers")
+	if v := expvar.Get("mongory_test_live_matchers"); v == nil || !strings.Contains(v.String(), `"name":"live-http"`) {
+		t.Fatalf("expvar = %v", v)
+	}
+}
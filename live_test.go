@@ -0,0 +1,69 @@
+package mongory
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// liveMatcher returns the entry of LiveMatchers for m, if listed.
+func liveMatcher(m Matcher) (LiveMatcher, bool) {
+	id := unwrapMatcher(m).id
+	for _, lm := range LiveMatchers() {
+		if lm.ID == id {
+			return lm, true
+		}
+	}
+	return LiveMatcher{}, false
+}
+
+func TestLiveMatchers(t *testing.T) {
+	condition := map[string]any{"age": map[string]any{"$gte": 18}}
+	m, err := NewMatcherWithOptions(condition, MatcherOptions{Name: "adults"})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	for _, age := range []int{20, 10} {
+		if _, err := m.Match(map[string]any{"age": age}); err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+	}
+	lm, ok := liveMatcher(m)
+	if !ok {
+		t.Fatal("LiveMatchers does not list the matcher")
+	}
+	if lm.Name != "adults" || lm.Hash != ConditionHash(condition) || lm.Condition != m.String() || lm.Matches != 2 || lm.Hits != 1 || lm.PeakNativeBytes <= 0 || time.Since(lm.Created) > time.Minute {
+		t.Fatalf("LiveMatchers entry = %+v", lm)
+	}
+
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if lc, ok := liveMatcher(clone); !ok || lc.ID <= lm.ID || lc.Name != "adults" {
+		t.Fatalf("clone entry = %+v, %v", lc, ok)
+	}
+	clone.Close()
+	if _, ok := liveMatcher(clone); ok {
+		t.Fatal("LiveMatchers lists a closed matcher")
+	}
+	m.Close()
+	if _, ok := liveMatcher(m); ok {
+		t.Fatal("LiveMatchers lists a closed matcher")
+	}
+
+	// Matchers that are never closed leave the list once collected.
+	before := len(LiveMatchers())
+	func() {
+		if _, err := NewMatcher(map[string]any{"dropped": true}); err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+	}()
+	for i := 0; i < 20 && len(LiveMatchers()) > before; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if n := len(LiveMatchers()); n > before {
+		t.Fatalf("%d live matchers after collection, want at most %d", n, before)
+	}
+}
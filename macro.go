@@ -0,0 +1,178 @@
+package mongory
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MacroKey is the condition key that expands a named macro in place, e.g.
+// {"$macro": "adult"} or {"$macro": ["adult", "active"]}.
+const MacroKey = "$macro"
+
+var (
+	macrosMu sync.RWMutex
+	macros   = map[string]map[string]any{}
+)
+
+// DefineMacro registers a reusable condition fragment under name. Macros may
+// reference other macros; cycles are rejected when the macro is defined.
+// Redefining a name replaces the previous fragment.
+func DefineMacro(name string, condition map[string]any) error {
+	if name == "" {
+		return fmt.Errorf("mongory: macro name must not be empty")
+	}
+	fragment, ok := cloneCondition(condition).(map[string]any)
+	if !ok || fragment == nil {
+		fragment = map[string]any{}
+	}
+
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+	previous, existed := macros[name]
+	macros[name] = fragment
+	if _, err := expandMacrosLocked(fragment, []string{name}); err != nil {
+		if existed {
+			macros[name] = previous
+		} else {
+			delete(macros, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// UndefineMacro removes a previously defined macro.
+func UndefineMacro(name string) {
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+	delete(macros, name)
+}
+
+func expandMacros(condition map[string]any) (map[string]any, error) {
+	macrosMu.RLock()
+	defer macrosMu.RUnlock()
+	return expandMacrosLocked(condition, nil)
+}
+
+func expandMacrosLocked(condition map[string]any, stack []string) (map[string]any, error) {
+	expanded, err := expandMacroValue(condition, stack)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := expanded.(map[string]any)
+	return result, nil
+}
+
+func expandMacroValue(value any, stack []string) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		return expandMacroTable(v, stack)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			expanded, err := expandMacroValue(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+func expandMacroTable(table map[string]any, stack []string) (any, error) {
+	if table == nil {
+		return table, nil
+	}
+	rest := make(map[string]any, len(table))
+	for key, value := range table {
+		if key == MacroKey {
+			continue
+		}
+		expanded, err := expandMacroValue(value, stack)
+		if err != nil {
+			return nil, err
+		}
+		rest[key] = expanded
+	}
+	ref, ok := table[MacroKey]
+	if !ok {
+		return rest, nil
+	}
+
+	names, err := macroNames(ref)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]any, 0, len(names)+1)
+	for _, name := range names {
+		for _, seen := range stack {
+			if seen == name {
+				return nil, fmt.Errorf("%w: macro %q is recursive", ErrInvalidCondition, name)
+			}
+		}
+		fragment, ok := macros[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: undefined macro %q", ErrInvalidCondition, name)
+		}
+		expanded, err := expandMacroTable(fragment, append(stack, name))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, expanded)
+	}
+	if len(rest) > 0 {
+		parts = append(parts, rest)
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return map[string]any{"$and": parts}, nil
+}
+
+func macroNames(ref any) ([]string, error) {
+	switch v := ref.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []any:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s entries must be strings, got %T", ErrInvalidCondition, MacroKey, item)
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	default:
+		return nil, fmt.Errorf("%w: %s must be a string or a list of strings, got %T", ErrInvalidCondition, MacroKey, ref)
+	}
+}
+
+// cloneCondition deep-copies the map[string]any / []any skeleton of a
+// condition so callers can keep mutating their own literals.
+func cloneCondition(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = cloneCondition(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneCondition(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,41 @@
+package mongory
+
+import "testing"
+
+func TestMacroExpansion(t *testing.T) {
+	if err := DefineMacro("adult", map[string]any{"age": map[string]any{"$gte": 18}}); err != nil {
+		t.Fatalf("DefineMacro failed: %v", err)
+	}
+	defer UndefineMacro("adult")
+
+	matcher, err := NewCMatcher(map[string]any{"$macro": "adult", "status": "active"}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	cases := []struct {
+		record map[string]any
+		want   bool
+	}{
+		{map[string]any{"age": 20, "status": "active"}, true},
+		{map[string]any{"age": 12, "status": "active"}, false},
+		{map[string]any{"age": 20, "status": "inactive"}, false},
+	}
+	for _, c := range cases {
+		got, err := matcher.Match(c.record)
+		if err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+		if got != c.want {
+			t.Errorf("Match(%v) = %v, want %v", c.record, got, c.want)
+		}
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	if _, err := NewCMatcher(map[string]any{"$macro": "missing"}, nil); err == nil {
+		t.Fatalf("NewMatcher should fail for undefined macro")
+	}
+	if err := DefineMacro("loop", map[string]any{"$macro": "loop"}); err == nil {
+		t.Fatalf("DefineMacro should reject recursive macro")
+	}
+}
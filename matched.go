@@ -0,0 +1,132 @@
+package mongory
+
+import (
+	"sort"
+	"strings"
+)
+
+// Matched is a record kept by FilterMatched, with where it came from and why
+// it matched.
+type Matched[T any] struct {
+	Value T
+	// Index is the record's position in the input.
+	Index int
+	// Score is the fraction of the condition's field clauses the record
+	// satisfies, between 0 and 1. A record satisfying more branches of an
+	// $or scores higher than one satisfying fewer.
+	Score float64
+	// MatchedFields are the fields of the clauses the record satisfies,
+	// sorted, each listed once.
+	MatchedFields []string
+}
+
+// FilterMatched is Filter keeping each record's index and match metadata, so
+// downstream processing need not derive them again. The field clauses
+// scored are the conditions on fields at the top of condition and inside
+// its $and and $or lists, nested ones included; each is matched on its own
+// against the records that match, so scoring costs one match per clause
+// per kept record. Fields under $nor and operators on whole documents, such
+// as $func, are not clauses. A condition without clauses scores every
+// record 1.
+func FilterMatched[T any](records []T, condition map[string]any, policy ...ErrorPolicy) ([]Matched[T], error) {
+	m, err := NewMatcher(condition)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	clauses, err := compileClauses(m.Condition().Map())
+	defer func() {
+		for _, c := range clauses {
+			c.matcher.Close()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	match, done := batchMatch(m)
+	defer done()
+	var matched []Matched[T]
+	err = runBatch(resolvePolicy(policy), records, match, func(i int, record T, ok bool) bool {
+		if ok {
+			matched = append(matched, scoreRecord(i, record, clauses))
+		}
+		return true
+	})
+	if _, partial := err.(*MultiError); err != nil && !partial {
+		return nil, err
+	}
+	return matched, err
+}
+
+// fieldClause is the condition on one field, matched on its own.
+type fieldClause struct {
+	field   string
+	matcher Matcher
+}
+
+// compileClauses compiles the field clauses of condition, in key order.
+func compileClauses(condition map[string]any) ([]fieldClause, error) {
+	var clauses []fieldClause
+	var walk func(condition map[string]any) error
+	walk = func(condition map[string]any) error {
+		keys := make([]string, 0, len(condition))
+		for key := range condition {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := condition[key]
+			switch {
+			case key == "$and" || key == "$or":
+				for _, child := range clauseList(value) {
+					if err := walk(child); err != nil {
+						return err
+					}
+				}
+			case strings.HasPrefix(key, "$"):
+			default:
+				m, err := NewMatcher(map[string]any{key: value})
+				if err != nil {
+					return err
+				}
+				clauses = append(clauses, fieldClause{field: key, matcher: m})
+			}
+		}
+		return nil
+	}
+	err := walk(condition)
+	return clauses, err
+}
+
+// clauseList returns the conditions of an $and or $or operand.
+func clauseList(value any) []map[string]any {
+	switch list := value.(type) {
+	case []map[string]any:
+		return list
+	case []any:
+		return conditionList(list)
+	}
+	return nil
+}
+
+func scoreRecord[T any](i int, record T, clauses []fieldClause) Matched[T] {
+	result := Matched[T]{Value: record, Index: i, Score: 1}
+	if len(clauses) == 0 {
+		return result
+	}
+	hits := 0
+	seen := map[string]bool{}
+	for _, c := range clauses {
+		if ok, err := c.matcher.Match(record); err != nil || !ok {
+			continue
+		}
+		hits++
+		if !seen[c.field] {
+			seen[c.field] = true
+			result.MatchedFields = append(result.MatchedFields, c.field)
+		}
+	}
+	sort.Strings(result.MatchedFields)
+	result.Score = float64(hits) / float64(len(clauses))
+	return result
+}
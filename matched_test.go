@@ -0,0 +1,54 @@
+package mongory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFilterMatched(t *testing.T) {
+	records := []map[string]any{
+		{"name": "ann", "age": 30, "vip": true},
+		{"name": "bob", "age": 12, "vip": false},
+		{"name": "cat", "age": 40, "vip": false},
+		{"name": "dan", "age": 10, "vip": true},
+	}
+	condition := map[string]any{
+		"name": map[string]any{"$ne": "zed"},
+		"$or":  []any{map[string]any{"age": map[string]any{"$gte": 18}}, map[string]any{"vip": true}},
+	}
+	matched, err := FilterMatched(records, condition)
+	if err != nil {
+		t.Fatalf("FilterMatched failed: %v", err)
+	}
+	want := []struct {
+		index  int
+		score  float64
+		fields []string
+	}{
+		{0, 1, []string{"age", "name", "vip"}},
+		{2, 2.0 / 3, []string{"age", "name"}},
+		{3, 2.0 / 3, []string{"name", "vip"}},
+	}
+	if len(matched) != len(want) {
+		t.Fatalf("FilterMatched returned %d records, want %d: %+v", len(matched), len(want), matched)
+	}
+	for i, w := range want {
+		got := matched[i]
+		if got.Index != w.index || got.Score != w.score || !reflect.DeepEqual(got.MatchedFields, w.fields) || got.Value["name"] != records[w.index]["name"] {
+			t.Fatalf("record %d = %+v, want index %d, score %v, fields %v", i, got, w.index, w.score, w.fields)
+		}
+	}
+
+	// A condition without field clauses scores every match 1.
+	all, err := FilterMatched(records, map[string]any{})
+	if err != nil || len(all) != len(records) || all[1].Score != 1 || all[1].MatchedFields != nil {
+		t.Fatalf("FilterMatched(all) = %+v, %v", all, err)
+	}
+
+	kept, err := FilterMatched([]any{map[string]any{"age": 20}, map[int]any{1: 1}}, map[string]any{"age": map[string]any{"$gte": 18}}, SkipAndCollect)
+	var multi *MultiError
+	if !errors.As(err, &multi) || !reflect.DeepEqual(multi.Indices(), []int{1}) || len(kept) != 1 || kept[0].Index != 0 {
+		t.Fatalf("FilterMatched with a bad record: err = %v", err)
+	}
+}
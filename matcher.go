@@ -1,29 +1,200 @@
 package mongory
 
 import (
-	"runtime"
+	"context"
+	"iter"
+	"reflect"
+	"regexp"
 
-	"github.com/mongoryhq/mongory-go/cgo"
+	"github.com/mongoryhq/mongory-go/internal/cgo"
 )
 
-type CMatcher interface {
+// ErrMatcherFreed is returned by a matcher used after Close.
+var ErrMatcherFreed = cgo.ErrMatcherFreed
+
+// ErrCallbackPanic is wrapped by the error Match returns when Go code called
+// back from the native core, such as converting an unsupported document or a
+// custom operator, panics. The panic is recovered instead of killing the
+// process.
+var ErrCallbackPanic = cgo.ErrCallbackPanic
+
+// Matcher is a compiled condition. Every operation that can fail returns an
+// error, and matchers are safe for concurrent use. The native core behind it
+// is not part of the API: conditions, documents and results are plain Go
+// values.
+type Matcher interface {
 	Match(value any) (bool, error)
+	// MatchContext is Match passing ctx to the operators registered with
+	// RegisterOperatorContext and to func(context.Context, any) bool $func
+	// predicates. It fails with ctx.Err() if ctx is already done.
+	MatchContext(ctx context.Context, value any) (bool, error)
+	Clone() (Matcher, error)
+	FilterFunc(records []any, onMatch func(i int, doc any) bool, policy ...ErrorPolicy) error
+	FilterContext(ctx context.Context, records []any, policy ...ErrorPolicy) ([]any, error)
+	MatchAll(seq iter.Seq[any], policy ...ErrorPolicy) iter.Seq[any]
+	MatchBatch(values []any, policy ...ErrorPolicy) ([]bool, error)
 	Explain() error
+	ExplainJSON() ([]byte, error)
+	ExplainPlan() (*ExplainNode, error)
+	ExplainMongoJSON(namespace string) ([]byte, error)
 	Trace(value any) (bool, error)
+	TraceJSON(value any) (bool, []byte, error)
+	TraceWith(value any, fn func(ev TraceEvent)) (bool, error)
 	PrintTrace() error
 	EnableTrace() error
 	DisableTrace() error
+	Condition() Condition
+	String() string
+	MarshalJSON() ([]byte, error)
+	MarshalBinary() ([]byte, error)
+	// Deprecated: the returned map is the matcher's own and changing it
+	// makes it disagree with what was compiled. Use Condition.
 	GetCondition() *map[string]any
+	Context() any
+	// Deprecated: use Context or MatcherContext.
 	GetContext() *any
+	SetMemoryLimit(bytes int64)
+	PeakNativeBytes() int64
+	Stats() MatcherStats
+	ResetStats()
+	Close() error
+}
+
+// CMatcher is the former name of Matcher.
+//
+// Deprecated: use Matcher.
+type CMatcher = Matcher
+
+type matcher struct {
+	*cgo.Matcher
+	condition Condition
+	name      string
+	id        uint64 // in LiveMatchers
+}
+
+// NewMatcher compiles condition, or takes it from the cache set by
+// SetMatcherCache. Documents need not be maps: top-level operators apply to
+// the document itself, and field conditions do not match scalars.
+func NewMatcher(condition map[string]any) (Matcher, error) {
+	if cache := matcherCache.Load(); cache != nil {
+		return cache.Get(condition)
+	}
+	return newMatcher(condition, nil)
+}
+
+// MustNewMatcher is NewMatcher for conditions known to be valid, such as
+// package-level matchers compiled at init time. It panics with the error
+// NewMatcher returns.
+func MustNewMatcher(condition map[string]any) Matcher {
+	m, err := NewMatcher(condition)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// NewCMatcher is NewMatcher with a context. A non-nil context is
+// dereferenced and kept as the matcher's context.
+//
+// Deprecated: use NewMatcher, or NewMatcherWithContext to pass a context.
+func NewCMatcher(condition map[string]any, context *any) (Matcher, error) {
+	var ctx any
+	if context != nil {
+		ctx = *context
+	}
+	return newMatcher(condition, ctx)
+}
+
+// NewMatcherWithContext compiles condition with a typed context, which
+// operators see as they are compiled and callers get back with
+// MatcherContext.
+func NewMatcherWithContext[TCtx any](condition map[string]any, ctx TCtx) (Matcher, error) {
+	return newMatcher(condition, ctx)
+}
+
+// MatcherContext returns m's context if it holds a TCtx.
+func MatcherContext[TCtx any](m Matcher) (TCtx, bool) {
+	ctx, ok := m.Context().(TCtx)
+	return ctx, ok
+}
+
+// NewValueMatcher compiles a condition on the matched value itself rather
+// than on the fields of a document, for matching scalars and arrays:
+// {"$in": [1, 2, 3]} matches the document 2. A condition that is not a map
+// matches the way it would as the value of a field: scalars and regular
+// expressions also match arrays containing a matching element, and arrays
+// match equal arrays and arrays containing them.
+func NewValueMatcher(condition any) (Matcher, error) {
+	rv := reflect.ValueOf(condition)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		fields, _ := snapshotCondition(rv).(map[string]any)
+		return newMatcher(fields, nil)
+	}
+	op := "$eq"
+	if _, ok := condition.(*regexp.Regexp); ok {
+		op = "$regex"
+	}
+	return newMatcher(map[string]any{"$or": []any{
+		map[string]any{op: condition},
+		map[string]any{"$elemMatch": map[string]any{op: condition}},
+	}}, nil)
 }
 
-func NewCMatcher(condition map[string]any, context *any) (CMatcher, error) {
-	matcher, err := cgo.NewMatcher(condition, context)
+func newMatcher(condition map[string]any, context any) (Matcher, error) {
+	return NewMatcherWithOptions(condition, MatcherOptions{Context: context})
+}
+
+// Clone returns an independent matcher for the same condition. The compiled
+// structure is rebuilt in fresh native pools, which hold everything the
+// clone allocates and are released by its Close. The converted condition is
+// shared and only read, so cloning skips converting it again. Matchers are
+// safe for concurrent use, so a clone is only needed for independent trace
+// state, which is not copied.
+func (m *matcher) Clone() (Matcher, error) {
+	inner, err := m.Matcher.Clone()
 	if err != nil {
 		return nil, err
 	}
-	runtime.SetFinalizer(matcher, func(m *cgo.Matcher) {
-		m.Free()
-	})
-	return matcher, nil
+	return wrapMatcher(inner, m.condition, m.name), nil
+}
+
+// Condition returns the condition the matcher compiled, with macros
+// expanded, as it was when the matcher was created.
+func (m *matcher) Condition() Condition {
+	return m.condition
+}
+
+// String returns the canonical JSON of the matcher's condition, so matchers
+// can be logged.
+func (m *matcher) String() string {
+	return m.condition.String()
+}
+
+// MarshalJSON encodes the matcher as the canonical JSON of its condition, so
+// it can be persisted and compiled again with ParseConditionJSON.
+func (m *matcher) MarshalJSON() ([]byte, error) {
+	return m.condition.MarshalJSON()
+}
+
+// Close releases the matcher's native memory: its compiled structure and its
+// scratch and trace pools, and the converted condition once no clone uses
+// it. It is safe to call more than once, and later calls to other methods
+// return ErrMatcherFreed. Matchers that are never closed are released once
+// they become unreachable, but only when the garbage collector gets to
+// them, so long-running servers should close matchers they replace.
+func (m *matcher) Close() error {
+	untrackMatcher(m.id)
+	m.Free()
+	return nil
+}
+
+// wrapMatcher adapts a cgo matcher to the public interface. Its native
+// memory is released by Close or, failing that, once it becomes unreachable.
+func wrapMatcher(inner *cgo.Matcher, condition Condition, name string) *matcher {
+	if metrics := activeMetrics.Load(); metrics != nil {
+		inner.SetObserver(metrics.observer(name))
+	}
+	m := &matcher{Matcher: inner, condition: condition, name: name}
+	trackMatcher(m)
+	return m
 }
@@ -0,0 +1,207 @@
+package mongory
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms of
+// NewMetrics when it is given none.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Microsecond, 5 * time.Microsecond, 10 * time.Microsecond, 50 * time.Microsecond,
+	100 * time.Microsecond, 500 * time.Microsecond, time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, time.Second,
+}
+
+// Metrics counts the matches of the matchers compiled while it is set with
+// SetMetrics: documents matched, hits, misses and errors, a latency
+// histogram and the peak native memory, per matcher name. Matchers are named
+// by MatcherOptions.Name; those without one, including every matcher from
+// NewMatcher, count under "default". Read it with Snapshot, WritePrometheus,
+// or through expvar and HTTP with PublishExpvar and Handler.
+type Metrics struct {
+	buckets []time.Duration
+
+	mu     sync.RWMutex
+	series map[string]*matcherSeries
+}
+
+// matcherSeries are the counters of one matcher name.
+type matcherSeries struct {
+	matches   atomic.Int64
+	hits      atomic.Int64
+	errors    atomic.Int64
+	nanos     atomic.Int64
+	counts    []atomic.Int64 // per bucket, then above the last bucket
+	peakBytes atomic.Int64
+}
+
+// NewMetrics returns empty metrics with latency histograms bounded by
+// buckets, in increasing order, or by DefaultLatencyBuckets.
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &Metrics{buckets: buckets, series: map[string]*matcherSeries{}}
+}
+
+var activeMetrics atomic.Pointer[Metrics]
+
+// SetMetrics makes every matcher compiled from now on, and clones of it,
+// count its matches in metrics. Nil, the default, stops counting matches of
+// matchers compiled afterwards; matchers already counting keep doing so.
+func SetMetrics(metrics *Metrics) {
+	activeMetrics.Store(metrics)
+}
+
+func (m *Metrics) seriesFor(name string) *matcherSeries {
+	if name == "" {
+		name = "default"
+	}
+	m.mu.RLock()
+	s := m.series[name]
+	m.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s = m.series[name]; s == nil {
+		s = &matcherSeries{counts: make([]atomic.Int64, len(m.buckets)+1)}
+		m.series[name] = s
+	}
+	return s
+}
+
+// observer returns the function counting a matcher's matches under name.
+func (m *Metrics) observer(name string) func(cgo.MatchEvent) {
+	s := m.seriesFor(name)
+	buckets := m.buckets
+	return func(ev cgo.MatchEvent) {
+		s.matches.Add(1)
+		switch {
+		case ev.Err != nil:
+			s.errors.Add(1)
+		case ev.Matched:
+			s.hits.Add(1)
+		}
+		s.nanos.Add(int64(ev.Latency))
+		s.counts[sort.Search(len(buckets), func(i int) bool { return ev.Latency <= buckets[i] })].Add(1)
+		for {
+			peak := s.peakBytes.Load()
+			if ev.PeakBytes <= peak || s.peakBytes.CompareAndSwap(peak, ev.PeakBytes) {
+				break
+			}
+		}
+	}
+}
+
+// MatcherMetrics are the counts of one matcher name.
+type MatcherMetrics struct {
+	Name string
+	// Matches counts documents matched; each was a hit, a miss or an
+	// error.
+	Matches int64
+	Hits    int64
+	Misses  int64
+	Errors  int64
+	// Latency is the total time spent matching, and Buckets its histogram.
+	Latency time.Duration
+	Buckets []LatencyBucket
+	// PeakNativeBytes is the most native memory one of the matchers held
+	// during a match.
+	PeakNativeBytes int64
+}
+
+// LatencyBucket counts the matches that took at most Le, so the counts of a
+// histogram are cumulative.
+type LatencyBucket struct {
+	Le    time.Duration
+	Count int64
+}
+
+// Snapshot returns the counts of every matcher name, sorted by name.
+func (m *Metrics) Snapshot() []MatcherMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]MatcherMetrics, 0, len(m.series))
+	for name, s := range m.series {
+		mm := MatcherMetrics{
+			Name:            name,
+			Matches:         s.matches.Load(),
+			Hits:            s.hits.Load(),
+			Errors:          s.errors.Load(),
+			Latency:         time.Duration(s.nanos.Load()),
+			Buckets:         make([]LatencyBucket, len(m.buckets)),
+			PeakNativeBytes: s.peakBytes.Load(),
+		}
+		mm.Misses = mm.Matches - mm.Hits - mm.Errors
+		var count int64
+		for i, le := range m.buckets {
+			count += s.counts[i].Load()
+			mm.Buckets[i] = LatencyBucket{Le: le, Count: count}
+		}
+		out = append(out, mm)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format, along with the number of live native memory pools, so a scrape
+// endpoint or a custom collector can serve them.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	out := bufio.NewWriter(w)
+	out.WriteString("# HELP mongory_matches_total Documents matched, by result.\n")
+	out.WriteString("# TYPE mongory_matches_total counter\n")
+	for _, mm := range snapshot {
+		label := promLabel(mm.Name)
+		for _, result := range []struct {
+			name  string
+			count int64
+		}{{"hit", mm.Hits}, {"miss", mm.Misses}, {"error", mm.Errors}} {
+			out.WriteString("mongory_matches_total{matcher=" + label + `,result="` + result.name + `"} ` + strconv.FormatInt(result.count, 10) + "\n")
+		}
+	}
+	out.WriteString("# HELP mongory_match_duration_seconds Time spent matching one document.\n")
+	out.WriteString("# TYPE mongory_match_duration_seconds histogram\n")
+	for _, mm := range snapshot {
+		label := promLabel(mm.Name)
+		for _, b := range mm.Buckets {
+			out.WriteString("mongory_match_duration_seconds_bucket{matcher=" + label + `,le="` + promFloat(b.Le.Seconds()) + `"} ` + strconv.FormatInt(b.Count, 10) + "\n")
+		}
+		out.WriteString("mongory_match_duration_seconds_bucket{matcher=" + label + `,le="+Inf"} ` + strconv.FormatInt(mm.Matches, 10) + "\n")
+		out.WriteString("mongory_match_duration_seconds_sum{matcher=" + label + "} " + promFloat(mm.Latency.Seconds()) + "\n")
+		out.WriteString("mongory_match_duration_seconds_count{matcher=" + label + "} " + strconv.FormatInt(mm.Matches, 10) + "\n")
+	}
+	out.WriteString("# HELP mongory_peak_native_bytes Most native memory a matcher held during a match.\n")
+	out.WriteString("# TYPE mongory_peak_native_bytes gauge\n")
+	for _, mm := range snapshot {
+		out.WriteString("mongory_peak_native_bytes{matcher=" + promLabel(mm.Name) + "} " + strconv.FormatInt(mm.PeakNativeBytes, 10) + "\n")
+	}
+	out.WriteString("# HELP mongory_live_pools Native memory pools currently allocated.\n")
+	out.WriteString("# TYPE mongory_live_pools gauge\n")
+	out.WriteString("mongory_live_pools " + strconv.FormatInt(cgo.LivePools(), 10) + "\n")
+	return out.Flush()
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabel(value string) string {
+	return `"` + promEscaper.Replace(value) + `"`
+}
+
+func promFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
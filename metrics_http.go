@@ -0,0 +1,24 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Handler serves the metrics in the Prometheus text exposition format, for
+// Prometheus to scrape.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(w)
+	})
+}
+
+// PublishExpvar publishes Snapshot as the expvar variable name, served with
+// the other variables on /debug/vars. Like expvar.Publish, it panics if name
+// is already published.
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return m.Snapshot() }))
+}
@@ -0,0 +1,29 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.observer("rules")(cgo.MatchEvent{Matched: true})
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `mongory_matches_total{matcher="rules",result="hit"} 1`) {
+		t.Fatalf("Handler = %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	metrics.PublishExpvar("mongory_test_metrics")
+	if v := expvar.Get("mongory_test_metrics"); v == nil || !strings.Contains(v.String(), `"Name":"rules"`) {
+		t.Fatalf("expvar = %v", v)
+	}
+}
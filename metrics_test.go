@@ -0,0 +1,80 @@
+package mongory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics(time.Hour)
+	SetMetrics(metrics)
+	defer SetMetrics(nil)
+
+	m, err := NewMatcherWithOptions(map[string]any{"age": map[string]any{"$gte": 18}}, MatcherOptions{Name: `adults "v2"`})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer m.Close()
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+	for _, doc := range []any{map[string]any{"age": 20}, map[string]any{"age": 10}} {
+		m.Match(doc)
+		clone.Match(doc)
+	}
+	m.SetMemoryLimit(1)
+	if _, err := m.Match(map[string]any{"age": 20}); err == nil {
+		t.Fatalf("Match over the memory limit should fail")
+	}
+	m.SetMemoryLimit(0)
+	plain, err := NewMatcher(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer plain.Close()
+	plain.Match(map[string]any{"a": 1})
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 2 || snapshot[0].Name != `adults "v2"` || snapshot[1].Name != "default" {
+		t.Fatalf("Snapshot = %+v", snapshot)
+	}
+	adults := snapshot[0]
+	if adults.Matches != 5 || adults.Hits != 2 || adults.Misses != 2 || adults.Errors != 1 {
+		t.Fatalf("adults = %+v", adults)
+	}
+	if len(adults.Buckets) != 1 || adults.Buckets[0].Count != 5 || adults.PeakNativeBytes <= 0 {
+		t.Fatalf("adults histogram = %+v, peak %d", adults.Buckets, adults.PeakNativeBytes)
+	}
+
+	var out bytes.Buffer
+	if err := metrics.WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	for _, line := range []string{
+		`mongory_matches_total{matcher="adults \"v2\"",result="hit"} 2`,
+		`mongory_matches_total{matcher="default",result="hit"} 1`,
+		`mongory_match_duration_seconds_bucket{matcher="adults \"v2\"",le="3600"} 5`,
+		`mongory_match_duration_seconds_bucket{matcher="adults \"v2\"",le="+Inf"} 5`,
+		`mongory_match_duration_seconds_count{matcher="default"} 1`,
+		"# TYPE mongory_live_pools gauge",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Prometheus output lacks %q:\n%s", line, out.String())
+		}
+	}
+
+	SetMetrics(nil)
+	after, err := NewMatcher(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer after.Close()
+	after.Match(map[string]any{"a": 1})
+	if got := metrics.Snapshot()[1].Matches; got != 1 {
+		t.Fatalf("matchers compiled after SetMetrics(nil) counted: %d", got)
+	}
+}
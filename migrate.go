@@ -0,0 +1,223 @@
+package mongory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MigrationRule rewrites a stored condition into a newer shape. Rules receive
+// a private copy of the condition and may modify it in place.
+type MigrationRule func(condition map[string]any) (map[string]any, error)
+
+// Migrator applies versioned rewrite rules to stored conditions. Rules
+// registered under version N upgrade a condition from version N-1 to N.
+type Migrator struct {
+	mu    sync.RWMutex
+	steps map[int][]MigrationRule
+}
+
+func NewMigrator() *Migrator {
+	return &Migrator{steps: map[int][]MigrationRule{}}
+}
+
+// Register adds rules to the step that produces the given version.
+func (m *Migrator) Register(version int, rules ...MigrationRule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.steps[version] = append(m.steps[version], rules...)
+}
+
+// Latest returns the highest registered version, or 0 if none.
+func (m *Migrator) Latest() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	latest := 0
+	for version := range m.steps {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// Migrate upgrades a condition stored at version from to the latest
+// registered version and reports the version it ends up at. The input map is
+// never modified.
+func (m *Migrator) Migrate(condition map[string]any, from int) (map[string]any, int, error) {
+	m.mu.RLock()
+	versions := make([]int, 0, len(m.steps))
+	for version := range m.steps {
+		if version > from {
+			versions = append(versions, version)
+		}
+	}
+	sort.Ints(versions)
+	steps := make([][]MigrationRule, len(versions))
+	for i, version := range versions {
+		steps[i] = m.steps[version]
+	}
+	m.mu.RUnlock()
+
+	current, _ := cloneCondition(condition).(map[string]any)
+	version := from
+	for i, rules := range steps {
+		for _, rule := range rules {
+			next, err := rule(current)
+			if err != nil {
+				return nil, version, fmt.Errorf("mongory: migration to version %d: %w", versions[i], err)
+			}
+			current = next
+		}
+		version = versions[i]
+	}
+	return current, version, nil
+}
+
+var defaultMigrator = NewMigrator()
+
+// RegisterMigration adds rules to the package-level migrator.
+func RegisterMigration(version int, rules ...MigrationRule) {
+	defaultMigrator.Register(version, rules...)
+}
+
+// MigrateCondition upgrades a stored condition with the package-level migrator.
+func MigrateCondition(condition map[string]any, from int) (map[string]any, int, error) {
+	return defaultMigrator.Migrate(condition, from)
+}
+
+// RenameField moves the constraint on field from to field to. Both names may
+// be dotted paths, which address nested sub-document conditions such as
+// {"user": {"name": ...}}. Fields inside $and / $or branches are renamed too.
+func RenameField(from, to string) MigrationRule {
+	fromPath := strings.Split(from, ".")
+	toPath := strings.Split(to, ".")
+	return func(condition map[string]any) (map[string]any, error) {
+		return condition, renameFieldIn(condition, fromPath, toPath)
+	}
+}
+
+func renameFieldIn(table map[string]any, from, to []string) error {
+	if table == nil {
+		return nil
+	}
+	for _, op := range []string{"$and", "$or"} {
+		if branches, ok := table[op].([]any); ok {
+			for _, branch := range branches {
+				if sub, ok := branch.(map[string]any); ok {
+					if err := renameFieldIn(sub, from, to); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	value, ok := takeFieldPath(table, from)
+	if !ok {
+		return nil
+	}
+	return putFieldPath(table, to, value)
+}
+
+func takeFieldPath(table map[string]any, path []string) (any, bool) {
+	value, ok := table[path[0]]
+	if !ok {
+		return nil, false
+	}
+	if len(path) == 1 {
+		delete(table, path[0])
+		return value, true
+	}
+	sub, ok := value.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	taken, ok := takeFieldPath(sub, path[1:])
+	if ok && len(sub) == 0 {
+		delete(table, path[0])
+	}
+	return taken, ok
+}
+
+func putFieldPath(table map[string]any, path []string, value any) error {
+	if len(path) == 1 {
+		if _, exists := table[path[0]]; exists {
+			return fmt.Errorf("field %q is already constrained", path[0])
+		}
+		table[path[0]] = value
+		return nil
+	}
+	existing, exists := table[path[0]]
+	if !exists {
+		sub := map[string]any{}
+		table[path[0]] = sub
+		return putFieldPath(sub, path[1:], value)
+	}
+	sub, ok := existing.(map[string]any)
+	if !ok {
+		return fmt.Errorf("field %q is not a sub-document condition", path[0])
+	}
+	return putFieldPath(sub, path[1:], value)
+}
+
+// RenameOperator replaces every occurrence of operator from with to.
+func RenameOperator(from, to string) MigrationRule {
+	return RewriteOperator(from, func(operand any) (string, any, error) {
+		return to, operand, nil
+	})
+}
+
+// RewriteOperator lets fn replace every occurrence of operator name with a new
+// operator key and operand, e.g. to translate a deprecated operator.
+func RewriteOperator(name string, fn func(operand any) (string, any, error)) MigrationRule {
+	return func(condition map[string]any) (map[string]any, error) {
+		rewritten, err := rewriteOperatorIn(condition, name, fn)
+		if err != nil {
+			return nil, err
+		}
+		table, _ := rewritten.(map[string]any)
+		return table, nil
+	}
+}
+
+func rewriteOperatorIn(value any, name string, fn func(operand any) (string, any, error)) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			rewritten, err := rewriteOperatorIn(item, name, fn)
+			if err != nil {
+				return nil, err
+			}
+			if key != name {
+				out[key] = rewritten
+				continue
+			}
+			newKey, newOperand, err := fn(rewritten)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := v[newKey]; exists && newKey != name {
+				return nil, fmt.Errorf("operator %q already present alongside %q", newKey, name)
+			}
+			out[newKey] = newOperand
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			rewritten, err := rewriteOperatorIn(item, name, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rewritten
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
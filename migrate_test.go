@@ -0,0 +1,53 @@
+package mongory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigratorRenames(t *testing.T) {
+	migrator := NewMigrator()
+	migrator.Register(1, RenameField("username", "user.name"))
+	migrator.Register(2, RenameOperator("$present", "$exists"))
+
+	stored := map[string]any{
+		"username": "john",
+		"$or": []any{
+			map[string]any{"username": "jane"},
+			map[string]any{"email": map[string]any{"$present": true}},
+		},
+	}
+	migrated, version, err := migrator.Migrate(stored, 0)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if version != 2 {
+		t.Fatalf("version = %d, want 2", version)
+	}
+	want := map[string]any{
+		"user": map[string]any{"name": "john"},
+		"$or": []any{
+			map[string]any{"user": map[string]any{"name": "jane"}},
+			map[string]any{"email": map[string]any{"$exists": true}},
+		},
+	}
+	if !reflect.DeepEqual(migrated, want) {
+		t.Fatalf("Migrate = %v, want %v", migrated, want)
+	}
+	if _, ok := stored["username"]; !ok {
+		t.Fatalf("Migrate must not modify its input")
+	}
+
+	again, version, err := migrator.Migrate(migrated, 2)
+	if err != nil || version != 2 || !reflect.DeepEqual(again, migrated) {
+		t.Fatalf("Migrate from latest should be a no-op, got %v (v%d, %v)", again, version, err)
+	}
+}
+
+func TestMigratorConflict(t *testing.T) {
+	migrator := NewMigrator()
+	migrator.Register(1, RenameField("a", "b"))
+	if _, _, err := migrator.Migrate(map[string]any{"a": 1, "b": 2}, 0); err == nil {
+		t.Fatalf("Migrate should fail when the target field is already constrained")
+	}
+}
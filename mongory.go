@@ -2,22 +2,84 @@ package mongory
 
 import (
 	"sync"
+	"sync/atomic"
 
-	"github.com/mongoryhq/mongory-go/cgo"
+	"github.com/mongoryhq/mongory-go/internal/cgo"
 )
 
+var initialized atomic.Bool
+
 func Cleanup() {
 	cgo.Cleanup()
+	initialized.Store(false)
 }
 
-var once sync.Once
+var (
+	once    sync.Once
+	initErr error
+)
 
-func Init() {
+// InitE initializes the native runtime and reports whether it is usable.
+// Failures are remembered: later calls, and every matcher constructor, return
+// the same error so a service can keep running in a degraded mode instead of
+// crashing at startup.
+func InitE() error {
 	once.Do(func() {
-		cgo.Init()
+		initErr = cgo.Init()
+		initialized.Store(initErr == nil)
 	})
+	return initErr
+}
+
+// Init is InitE for callers that check readiness later via Healthy.
+func Init() {
+	_ = InitE()
 }
 
 func init() {
 	Init()
 }
+
+// SetDeterministicCompilation controls whether condition keys are compiled
+// in sorted order, making matcher structure, explain output and traces
+// reproducible across runs. It is enabled by default; disabling it skips the
+// sort for very large conditions.
+func SetDeterministicCompilation(enabled bool) {
+	cgo.SetSortConditionKeys(enabled)
+}
+
+// SetInSetThreshold sets the length from which $in lists made only of strings
+// or only of numbers are compiled to hash sets, turning each membership test
+// from a linear scan into a lookup. The default is 16; zero disables the
+// optimization. Results do not depend on it: integers and doubles match each
+// other in sets as they do in the scan, so {"$in": [1, 2.0]} matches 2 and
+// 2.0, and under NumericExact 2^53+1 matches neither 2^53 nor 2^53 as a
+// double.
+func SetInSetThreshold(n int) {
+	cgo.SetInSetThreshold(n)
+}
+
+// SetRangeThreshold sets how many branches an $or needs before it is compiled
+// to a sorted interval search, when every branch bounds the same field with
+// $gt, $gte, $lt or $lte on numbers (price tiers, ID ranges). The default is
+// 4; zero disables the optimization.
+func SetRangeThreshold(n int) {
+	cgo.SetRangeThreshold(n)
+}
+
+// SetPrefixThreshold sets how many branches an $or needs before it is
+// compiled to a single prefix-trie lookup, when every branch tests the same
+// field with an anchored literal $regex ("^/api/") or a trailing-star $glob
+// ("/api/*"). The default is 4; zero disables the optimization.
+func SetPrefixThreshold(n int) {
+	cgo.SetPrefixThreshold(n)
+}
+
+// SetStringInterning keeps up to capacity recently seen document strings
+// (status names, hostnames) in a shared native cache, so matching documents
+// that repeat them reuses one copy instead of allocating it on every Match.
+// Strings longer than 128 bytes are never cached. It is off by default; zero
+// turns it off again and releases the cache.
+func SetStringInterning(capacity int) {
+	cgo.SetStringInterning(capacity)
+}
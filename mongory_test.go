@@ -1,9 +1,16 @@
 package mongory
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
 )
 
 func TestBasic(t *testing.T) {
@@ -18,6 +25,54 @@ func TestBasic(t *testing.T) {
 	fmt.Println("result", result)
 }
 
+func TestNewMatcher(t *testing.T) {
+	m, err := NewMatcher(map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	if ok, err := m.Match(map[string]any{"age": 20}); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+	if m.Context() != nil {
+		t.Fatalf("Context = %v, want nil", m.Context())
+	}
+
+	// The former names still compile the same matcher.
+	var old CMatcher
+	ctx := any("tenant")
+	if old, err = NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, &ctx); err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer old.Close()
+	if ok, err := old.Match(map[string]any{"age": 20}); err != nil || !ok {
+		t.Fatalf("NewCMatcher Match = %v, %v; want true", ok, err)
+	}
+	if old.Context() != "tenant" {
+		t.Fatalf("Context = %v, want tenant", old.Context())
+	}
+	if _, err := NewMatcher(map[string]any{"$and": "bad"}); err == nil {
+		t.Fatalf("NewMatcher should reject invalid conditions")
+	}
+}
+
+func TestMustNewMatcher(t *testing.T) {
+	m := MustNewMatcher(map[string]any{"status": "active"})
+	defer m.Close()
+	if ok, err := m.Match(map[string]any{"status": "active"}); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrInvalidCondition) {
+			t.Fatalf("MustNewMatcher panicked with %v, want ErrInvalidCondition", err)
+		}
+	}()
+	MustNewMatcher(map[string]any{"$and": "bad"})
+	t.Fatalf("MustNewMatcher should panic on invalid conditions")
+}
+
 func TestTrace(t *testing.T) {
 	matcher, err := NewCMatcher(map[string]any{"key2": "hello"}, nil)
 	if err != nil {
@@ -79,3 +134,468 @@ func TestMain(m *testing.M) {
 	Cleanup()
 	os.Exit(code)
 }
+
+func TestInitE(t *testing.T) {
+	if err := InitE(); err != nil {
+		t.Fatalf("InitE failed: %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	original, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	clone, err := original.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	// Free the original first: the clone must keep the shared condition alive.
+	original.(*matcher).Free()
+	for _, tc := range []struct {
+		age  int
+		want bool
+	}{{30, true}, {10, false}} {
+		got, err := clone.Match(map[string]any{"age": tc.age})
+		if err != nil || got != tc.want {
+			t.Fatalf("clone.Match(age=%d) = %v, %v; want %v", tc.age, got, err, tc.want)
+		}
+	}
+	if _, ok := clone.Condition().Get("age"); !ok {
+		t.Fatalf("clone lost its condition")
+	}
+}
+
+// TestCloneConcurrent clones and matches from many goroutines. Run it with
+// -race: clones compile from the condition their original shares, which
+// must only be read while they do.
+// settlePools runs the garbage collector until the cleanups of matchers
+// dropped earlier, by this test or others, have freed their pools. Tests
+// counting pools call it before taking their baseline, so that only the
+// pools they open and close themselves change the count.
+func settlePools(t *testing.T) {
+	t.Helper()
+	type counts struct {
+		live  int64
+		stats cgo.PoolStats
+	}
+	read := func() counts { return counts{cgo.LivePools(), cgo.ReadPoolStats()} }
+	last := read()
+	deadline := time.Now().Add(5 * time.Second)
+	for stable := 0; stable < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("native pools did not settle: %+v", last)
+		}
+		runtime.GC()
+		time.Sleep(2 * time.Millisecond)
+		if now := read(); now == last {
+			stable++
+		} else {
+			last, stable = now, 0
+		}
+	}
+}
+
+func TestCloneConcurrent(t *testing.T) {
+	original, err := NewMatcher(map[string]any{
+		"age":  map[string]any{"$gte": 18},
+		"tags": map[string]any{"$in": []any{"go", "c"}},
+		"home": map[string]any{"city": "Taipei"},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer original.Close()
+	docs := []any{
+		map[string]any{"age": 30, "tags": []any{"go"}, "home": map[string]any{"city": "Taipei"}},
+		map[string]any{"age": 40, "tags": "c", "home": []any{map[string]any{"city": "Taipei"}}},
+		map[string]any{"age": 10},
+		"scalar",
+		[]any{1, 2},
+	}
+	want := []bool{true, true, false, false, false}
+	run := func() {
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 25 {
+					clone, err := original.Clone()
+					if err != nil {
+						t.Errorf("Clone failed: %v", err)
+						return
+					}
+					for i, doc := range docs {
+						if got, err := clone.Match(doc); err != nil || got != want[i] {
+							t.Errorf("clone.Match(%v) = %v, %v; want %v", doc, got, err, want[i])
+						}
+					}
+					clone.Close()
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	// Closed clones give back everything they allocated, so repeating the
+	// run leaves the native pools as they were.
+	run()
+	settlePools(t)
+	before := cgo.ReadPoolStats()
+	run()
+	if after := cgo.ReadPoolStats(); after != before {
+		t.Fatalf("pool stats after clones = %+v; want %+v", after, before)
+	}
+}
+
+func TestCloneFromManyGoroutines(t *testing.T) {
+	original, err := NewMatcher(map[string]any{"items": map[string]any{"sku": "a", "qty": map[string]any{"$gt": 1}}})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer original.Close()
+	doc := map[string]any{"items": []any{map[string]any{"sku": "a", "qty": 2}}}
+	// Clones of clones share the original's condition too, and all of them
+	// compile from it while the original matches.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if got, err := original.Match(doc); err != nil || !got {
+					t.Errorf("original.Match = %v, %v; want true", got, err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			from := original
+			for range 10 {
+				clone, err := from.Clone()
+				if err != nil {
+					t.Errorf("Clone failed: %v", err)
+					return
+				}
+				if got, err := clone.Match(doc); err != nil || !got {
+					t.Errorf("clone.Match = %v, %v; want true", got, err)
+				}
+				if from != original {
+					from.Close()
+				}
+				from = clone
+			}
+			from.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+// BenchmarkClone compares cloning, which reuses the converted condition,
+// with compiling the same condition from scratch.
+func BenchmarkClone(b *testing.B) {
+	in := make([]any, 200)
+	for i := range in {
+		in[i] = fmt.Sprint("tag", i)
+	}
+	condition := map[string]any{
+		"age":  map[string]any{"$gte": 18, "$lt": 65},
+		"tags": map[string]any{"$in": in},
+		"$or":  []any{map[string]any{"role": "admin"}, map[string]any{"verified": true}},
+	}
+	original, err := NewCMatcher(condition, nil)
+	if err != nil {
+		b.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer original.Close()
+	b.Run("Clone", func(b *testing.B) {
+		for b.Loop() {
+			clone, err := original.Clone()
+			if err != nil {
+				b.Fatal(err)
+			}
+			clone.Close()
+		}
+	})
+	b.Run("NewCMatcher", func(b *testing.B) {
+		for b.Loop() {
+			m, err := NewCMatcher(condition, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			m.Close()
+		}
+	})
+}
+
+func TestUseAfterFree(t *testing.T) {
+	m, err := NewCMatcher(map[string]any{"a": 1}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	m.(*matcher).Free()
+	m.(*matcher).Free()
+	if _, err := m.Match(map[string]any{"a": 1}); !errors.Is(err, ErrMatcherFreed) {
+		t.Fatalf("Match after Free: err = %v, want ErrMatcherFreed", err)
+	}
+	if err := m.Explain(); !errors.Is(err, ErrMatcherFreed) {
+		t.Fatalf("Explain after Free: err = %v, want ErrMatcherFreed", err)
+	}
+	if _, err := m.Clone(); !errors.Is(err, ErrMatcherFreed) {
+		t.Fatalf("Clone after Free: err = %v, want ErrMatcherFreed", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	before := cgo.LivePools()
+	m, err := NewCMatcher(map[string]any{"a": 1}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	if err := m.EnableTrace(); err != nil {
+		t.Fatalf("EnableTrace failed: %v", err)
+	}
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+	if _, err := m.Match(map[string]any{"a": 1}); !errors.Is(err, ErrMatcherFreed) {
+		t.Fatalf("Match after Close: err = %v, want ErrMatcherFreed", err)
+	}
+	// The clone still holds the shared condition.
+	if matched, err := clone.Match(map[string]any{"a": 1}); err != nil || !matched {
+		t.Fatalf("clone Match after Close = %v, %v; want true", matched, err)
+	}
+	clone.Close()
+	// Cleanups of matchers from other tests can only lower the count.
+	if live := cgo.LivePools(); live > before {
+		t.Fatalf("LivePools = %d after Close, want at most %d", live, before)
+	}
+}
+
+// Array values are matched by matchers the core would otherwise build on
+// first use, from the matcher's pool, which concurrent matches would race on.
+func TestConcurrentArrayMatch(t *testing.T) {
+	m, err := NewCMatcher(map[string]any{
+		"tags":  "b",
+		"paths": map[string]any{"$glob": "/api/*"},
+		"items": map[string]any{"qty": map[string]any{"$gt": 1}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	before, err := m.ExplainJSON()
+	if err != nil {
+		t.Fatalf("ExplainJSON failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				doc := map[string]any{
+					"tags":  []any{"a", "b"},
+					"paths": "/api/v1",
+					"items": []any{map[string]any{"qty": i % 3}},
+				}
+				matched, err := m.Match(doc)
+				if err != nil || matched != (i%3 > 1) {
+					errs <- fmt.Errorf("Match(%v) = %v, %v", doc, matched, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	after, err := m.ExplainJSON()
+	if err != nil {
+		t.Fatalf("ExplainJSON failed: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Fatalf("ExplainJSON changed after matching arrays:\n%s\n%s", before, after)
+	}
+}
+
+func TestConcurrentMatch(t *testing.T) {
+	m, err := NewCMatcher(map[string]any{
+		"age":  map[string]any{"$gte": 18},
+		"tags": map[string]any{"$in": []any{"a", "b"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				doc := map[string]any{"age": 10 + i%20, "tags": []any{fmt.Sprint("a", i), "b"}}
+				matched, err := m.Match(doc)
+				if err != nil || matched != (10+i%20 >= 18) {
+					errs <- fmt.Errorf("Match(%v) = %v, %v", doc, matched, err)
+					return
+				}
+				if g == 0 && i%100 == 0 {
+					if err := m.EnableTrace(); err != nil {
+						errs <- err
+						return
+					}
+					m.DisableTrace()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// Close waits for matches in progress; later ones fail cleanly.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := m.Match(map[string]any{"age": 20}); err != nil {
+				if !errors.Is(err, ErrMatcherFreed) {
+					t.Errorf("Match during Close: err = %v, want ErrMatcherFreed", err)
+				}
+				return
+			}
+		}
+	}()
+	m.Close()
+	<-done
+}
+
+func TestMatcherContext(t *testing.T) {
+	type tenant struct{ ID string }
+	m, err := NewMatcherWithContext(map[string]any{"a": 1}, tenant{ID: "acme"})
+	if err != nil {
+		t.Fatalf("NewMatcherWithContext failed: %v", err)
+	}
+	if ctx, ok := MatcherContext[tenant](m); !ok || ctx.ID != "acme" {
+		t.Fatalf("MatcherContext = %v, %v; want acme", ctx, ok)
+	}
+	if _, ok := MatcherContext[string](m); ok {
+		t.Fatalf("MatcherContext[string] succeeded for a tenant context")
+	}
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if ctx, ok := MatcherContext[tenant](clone); !ok || ctx.ID != "acme" {
+		t.Fatalf("clone MatcherContext = %v, %v; want acme", ctx, ok)
+	}
+
+	var legacy any = 42
+	m, err = NewCMatcher(map[string]any{"a": 1}, &legacy)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	if ctx, ok := MatcherContext[int](m); !ok || ctx != 42 || *m.GetContext() != 42 {
+		t.Fatalf("MatcherContext = %v, %v; want 42", ctx, ok)
+	}
+	m, _ = NewCMatcher(map[string]any{"a": 1}, nil)
+	if m.Context() != nil || m.GetContext() != nil {
+		t.Fatalf("Context() = %v without a context", m.Context())
+	}
+}
+
+func TestCallbackPanic(t *testing.T) {
+	m, err := NewCMatcher(map[string]any{"a": 1}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	// Looking up a string key in a map[int]any panics in reflect.
+	if _, err := m.Match(map[int]any{1: 1}); !errors.Is(err, ErrCallbackPanic) {
+		t.Fatalf("Match(map[int]any): err = %v, want ErrCallbackPanic", err)
+	}
+	matched, err := m.Match(map[string]any{"a": 1})
+	if err != nil || !matched {
+		t.Fatalf("Match after recovered panic = %v, %v; want true", matched, err)
+	}
+}
+
+func TestUnreachableMatchersAreCleanedUp(t *testing.T) {
+	ids, err := NewSharedValue([]any{1, 2, 3})
+	if err != nil {
+		t.Fatalf("NewSharedValue failed: %v", err)
+	}
+	before := cgo.LivePools()
+	for i := 0; i < 20; i++ {
+		m, err := NewCMatcher(map[string]any{"id": map[string]any{"$in": ids}}, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		if _, err := m.Match(map[string]any{"id": i}); err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+	}
+	ids = nil
+	for i := 0; i < 10 && cgo.LivePools() >= before; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if live := cgo.LivePools(); live >= before {
+		t.Fatalf("LivePools = %d after GC, want fewer than %d", live, before)
+	}
+}
+
+func TestValueMatcher(t *testing.T) {
+	cases := []struct {
+		condition any
+		doc       any
+		want      bool
+	}{
+		{map[string]any{"$in": []any{1, 2, 3}}, 2, true},
+		{map[string]any{"$in": []any{1, 2, 3}}, 5, false},
+		{map[string]int{"$gt": 1}, 2.5, true},
+		{map[string]any{"$regex": "^a"}, "abc", true},
+		{map[string]any{"$size": 2}, []any{1, 2}, true},
+		{map[string]any{"$elemMatch": map[string]any{"$gt": 1}}, []int{0, 2}, true},
+		{map[string]any{"$not": map[string]any{"$gt": 1}}, 0, true},
+		{"go", "go", true},
+		{"go", []any{"c", "go"}, true},
+		{"go", "rust", false},
+		{regexp.MustCompile("^g"), []string{"go"}, true},
+		{[]any{1, 2}, []any{1, 2}, true},
+		{[]any{1, 2}, []any{[]any{1, 2}, 3}, true},
+		{[]any{1, 2}, []any{1}, false},
+		{3, 3.0, true},
+		{map[string]any{"a": 1}, 5, false},
+	}
+	for _, tc := range cases {
+		m, err := NewValueMatcher(tc.condition)
+		if err != nil {
+			t.Fatalf("NewValueMatcher(%v) failed: %v", tc.condition, err)
+		}
+		if got, err := m.Match(tc.doc); err != nil || got != tc.want {
+			t.Fatalf("NewValueMatcher(%v).Match(%v) = %v, %v; want %v", tc.condition, tc.doc, got, err, tc.want)
+		}
+		m.Close()
+	}
+	var none map[string]int
+	m, err := NewValueMatcher(none)
+	if err != nil {
+		t.Fatalf("NewValueMatcher(nil map) failed: %v", err)
+	}
+	if got, err := m.Match(1); err != nil || !got {
+		t.Fatalf("nil condition Match(1) = %v, %v; want true", got, err)
+	}
+}
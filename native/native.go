@@ -0,0 +1,271 @@
+// Package native is the low-level API to the values the core matches
+// against. It exposes memory pools and the native values allocated in them
+// as opaque handles, with no C types in its signatures, and lets operators
+// read matched values in place:
+//
+//	native.RegisterOperator("$hasPrefix", func(v native.Value, operand any) bool {
+//		prefix, _ := operand.(string)
+//		return v.Kind() == native.String && strings.HasPrefix(v.String(), prefix)
+//	})
+//
+// The types and functions here are covered by the module's compatibility
+// promise. The internal binding they are built on is not: it mirrors the core
+// and changes with it.
+package native
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go"
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// Kind is the type of a native value.
+type Kind int
+
+const (
+	Null Kind = iota
+	Bool
+	Int
+	Double
+	String
+	Array
+	Table
+	Regex
+	Pointer
+	Unsupported
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Null:
+		return "null"
+	case Bool:
+		return "bool"
+	case Int:
+		return "int"
+	case Double:
+		return "double"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Table:
+		return "table"
+	case Regex:
+		return "regex"
+	case Pointer:
+		return "pointer"
+	case Unsupported:
+		return "unsupported"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+var kinds = map[cgo.MongoryType]Kind{
+	cgo.MONGORY_TYPE_NULL:        Null,
+	cgo.MONGORY_TYPE_BOOL:        Bool,
+	cgo.MONGORY_TYPE_INT:         Int,
+	cgo.MONGORY_TYPE_DOUBLE:      Double,
+	cgo.MONGORY_TYPE_STRING:      String,
+	cgo.MONGORY_TYPE_ARRAY:       Array,
+	cgo.MONGORY_TYPE_TABLE:       Table,
+	cgo.MONGORY_TYPE_REGEX:       Regex,
+	cgo.MONGORY_TYPE_POINTER:     Pointer,
+	cgo.MONGORY_TYPE_UNSUPPORTED: Unsupported,
+}
+
+// Pool owns native memory. Values allocated in it stay valid until it is
+// reset or freed. A Pool is not safe for concurrent use.
+type Pool struct {
+	pool *cgo.MemoryPool
+}
+
+// NewPool returns an empty pool, or the error of mongory.InitE if the
+// native runtime is unusable. Free the pool when done.
+func NewPool() (*Pool, error) {
+	if err := mongory.InitE(); err != nil {
+		return nil, err
+	}
+	return &Pool{pool: cgo.NewMemoryPool()}, nil
+}
+
+// Reset releases every value allocated in the pool, keeping the pool for
+// reuse.
+func (p *Pool) Reset() {
+	p.pool.Reset()
+}
+
+// Free releases the pool and every value allocated in it.
+func (p *Pool) Free() {
+	if p.pool != nil {
+		p.pool.Free()
+		p.pool = nil
+	}
+}
+
+// Bytes reports how much native memory the pool has handed out since it
+// was last reset.
+func (p *Pool) Bytes() int64 {
+	return p.pool.Bytes()
+}
+
+// Convert allocates doc in the pool the way documents are converted for
+// matching: maps and slices are bridged and read lazily, so doc must not be
+// modified while the value is in use.
+func (p *Pool) Convert(doc any) (Value, error) {
+	v := p.pool.ConvertDocument(doc)
+	if v == nil {
+		return Value{}, p.pool.Err()
+	}
+	return Value{v: v}, nil
+}
+
+// Int allocates an integer.
+func (p *Pool) Int(i int64) Value {
+	return Value{v: cgo.NewValueInt(p.pool, i)}
+}
+
+// Double allocates a floating-point number.
+func (p *Pool) Double(d float64) Value {
+	return Value{v: cgo.NewValueDouble(p.pool, d)}
+}
+
+// String allocates a copy of s.
+func (p *Pool) String(s string) Value {
+	return Value{v: cgo.NewValueString(p.pool, s)}
+}
+
+// Bool allocates a boolean.
+func (p *Pool) Bool(b bool) Value {
+	return Value{v: cgo.NewValueBool(p.pool, b)}
+}
+
+// Value is a handle to a native value. It is valid until the pool holding
+// it is reset or freed or, for values passed to an operator, until the
+// operator returns. The zero Value is null.
+type Value struct {
+	v *cgo.Value
+}
+
+// Kind returns the type of the value.
+func (v Value) Kind() Kind {
+	return kinds[v.v.Kind()]
+}
+
+// Bool returns the value of a Bool, and false for other kinds.
+func (v Value) Bool() bool {
+	return v.Kind() == Bool && v.v.GetBool()
+}
+
+// Int returns the value of an Int, and zero for other kinds.
+func (v Value) Int() int64 {
+	if v.Kind() != Int {
+		return 0
+	}
+	return v.v.GetInt()
+}
+
+// Double returns the value of a Double, and zero for other kinds.
+func (v Value) Double() float64 {
+	if v.Kind() != Double {
+		return 0
+	}
+	return v.v.GetDouble()
+}
+
+// String returns the value of a String, and "" for other kinds.
+func (v Value) String() string {
+	if v.Kind() != String {
+		return ""
+	}
+	return v.v.GetString()
+}
+
+// Len returns the length of an Array or the number of keys of a Table, and
+// zero for other kinds.
+func (v Value) Len() int {
+	switch v.Kind() {
+	case Array:
+		if a := v.v.GetArray(); a != nil {
+			return a.Len()
+		}
+	case Table:
+		if t := v.v.GetTable(); t != nil {
+			return t.Len()
+		}
+	}
+	return 0
+}
+
+// Index returns the element i of an Array, and null if there is none.
+func (v Value) Index(i int) Value {
+	if v.Kind() != Array || i < 0 {
+		return Value{}
+	}
+	a := v.v.GetArray()
+	if a == nil || i >= a.Len() {
+		return Value{}
+	}
+	return Value{v: a.Get(i)}
+}
+
+// Get returns the value under key in a Table, reporting whether there is
+// one.
+func (v Value) Get(key string) (Value, bool) {
+	if v.Kind() != Table {
+		return Value{}, false
+	}
+	t := v.v.GetTable()
+	if t == nil {
+		return Value{}, false
+	}
+	item := t.Get(key)
+	if item == nil {
+		return Value{}, false
+	}
+	return Value{v: item}, true
+}
+
+// Interface returns the Go value v represents: the original Go value for
+// converted documents, map[string]any and []any for other tables and
+// arrays, and bool, int64, float64, string or nil for scalars.
+func (v Value) Interface() any {
+	return v.v.Recover()
+}
+
+// RegisterOperator is mongory.RegisterOperator for operators that read the
+// matched value as a Value instead of having it converted to a Go value
+// first, which saves converting documents and lists the operator only looks
+// into. The operand is converted once, when a condition is compiled. Remove
+// the operator with mongory.UnregisterOperator.
+func RegisterOperator(name string, fn func(value Value, operand any) bool) error {
+	if !strings.HasPrefix(name, "$") || len(name) < 2 {
+		return fmt.Errorf("mongory: operator name %q must start with $", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("mongory: operator %s needs a function", name)
+	}
+	for _, doc := range mongory.Operators() {
+		if doc.Name == name {
+			if !doc.Custom {
+				return fmt.Errorf("mongory: operator %s is built in", name)
+			}
+			break
+		}
+	}
+	return cgo.RegisterValueOperator(name, func(value *cgo.Value, operand any) bool {
+		return fn(Value{v: value}, operand)
+	})
+}
+
+// CoreOperators returns the operators registered in the linked
+// mongory-core's matcher table, sorted, or nil before mongory.Init. They tell
+// which core a binary was built with: they include the built-ins mongory
+// overrides there, such as $in and $or, but not the operators it implements
+// in Go.
+func CoreOperators() []string {
+	return cgo.CoreOperators()
+}
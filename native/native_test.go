@@ -0,0 +1,126 @@
+package native
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+func TestPoolValues(t *testing.T) {
+	pool, err := NewPool()
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+	defer pool.Free()
+
+	if v := pool.Int(42); v.Kind() != Int || v.Int() != 42 || v.String() != "" {
+		t.Fatalf("Int value = %v %d", v.Kind(), v.Int())
+	}
+	if v := pool.Double(1.5); v.Kind() != Double || v.Double() != 1.5 {
+		t.Fatalf("Double value = %v %v", v.Kind(), v.Double())
+	}
+	if v := pool.String("hi"); v.Kind() != String || v.String() != "hi" {
+		t.Fatalf("String value = %v %q", v.Kind(), v.String())
+	}
+	if v := pool.Bool(true); v.Kind() != Bool || !v.Bool() {
+		t.Fatalf("Bool value = %v %v", v.Kind(), v.Bool())
+	}
+	if (Value{}).Kind() != Null {
+		t.Fatalf("zero Value should be null")
+	}
+
+	doc, err := pool.Convert(map[string]any{"name": "ada", "tags": []any{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if doc.Kind() != Table || doc.Len() != 2 {
+		t.Fatalf("doc = %v with %d keys", doc.Kind(), doc.Len())
+	}
+	if name, ok := doc.Get("name"); !ok || name.String() != "ada" {
+		t.Fatalf("Get(name) = %q, %v", name.String(), ok)
+	}
+	if _, ok := doc.Get("missing"); ok {
+		t.Fatalf("Get(missing) should report no value")
+	}
+	tags, _ := doc.Get("tags")
+	if tags.Kind() != Array || tags.Len() != 2 || tags.Index(1).String() != "b" || tags.Index(2).Kind() != Null {
+		t.Fatalf("tags = %v %v", tags.Kind(), tags.Interface())
+	}
+	if got, ok := doc.Interface().(map[string]any); !ok || got["name"] != "ada" {
+		t.Fatalf("Interface = %#v", doc.Interface())
+	}
+	if Unsupported.String() != "unsupported" || Kind(99).String() != "Kind(99)" {
+		t.Fatalf("Kind.String = %q, %q", Unsupported, Kind(99))
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+	var calls atomic.Int32
+	hasPrefix := func(v Value, operand any) bool {
+		calls.Add(1)
+		prefix, _ := operand.(string)
+		return v.Kind() == String && strings.HasPrefix(v.String(), prefix)
+	}
+	if err := RegisterOperator("$hasPrefix", hasPrefix); err != nil {
+		t.Fatalf("RegisterOperator failed: %v", err)
+	}
+	t.Cleanup(func() { mongory.UnregisterOperator("$hasPrefix") })
+	for _, name := range []string{"hasPrefix", "$", "$in", "$hasPrefix"} {
+		if err := RegisterOperator(name, hasPrefix); err == nil {
+			t.Errorf("RegisterOperator(%q) succeeded", name)
+		}
+	}
+	if err := mongory.RegisterOperator("$hasPrefix", func(any, any) bool { return true }); err == nil {
+		t.Errorf("mongory.RegisterOperator should reject a name taken here")
+	}
+
+	condition := map[string]any{"name": map[string]any{"$hasPrefix": "ad"}}
+	if err := mongory.ValidateCondition(condition); err != nil {
+		t.Fatalf("ValidateCondition failed: %v", err)
+	}
+	m, err := mongory.NewMatcher(condition)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	for name, want := range map[string]bool{"ada": true, "bob": false} {
+		if got, err := m.Match(map[string]any{"name": name}); err != nil || got != want {
+			t.Errorf("Match(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if calls.Load() == 0 {
+		t.Fatalf("operator was not called")
+	}
+
+	found := false
+	for _, doc := range mongory.Operators() {
+		if doc.Name == "$hasPrefix" {
+			found = doc.Custom
+		}
+	}
+	if !found {
+		t.Fatalf("Operators should list $hasPrefix as custom")
+	}
+	if !mongory.UnregisterOperator("$hasPrefix") {
+		t.Fatalf("UnregisterOperator should remove $hasPrefix")
+	}
+}
+
+func TestCoreOperators(t *testing.T) {
+	mongory.Init()
+	names := CoreOperators()
+	if !slices.IsSorted(names) {
+		t.Fatalf("CoreOperators() = %v, not sorted", names)
+	}
+	for _, want := range []string{"$eq", "$in", "$elemMatch"} {
+		if !slices.Contains(names, want) {
+			t.Fatalf("CoreOperators() = %v, lacks %s", names, want)
+		}
+	}
+	if slices.Contains(names, "$glob") {
+		t.Fatalf("CoreOperators() = %v, includes the Go operator $glob", names)
+	}
+}
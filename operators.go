@@ -0,0 +1,374 @@
+package mongory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// operandKind describes the shape an operator expects as its operand.
+type operandKind string
+
+const (
+	operandAny        operandKind = "any"
+	operandArray      operandKind = "array"
+	operandBoolean    operandKind = "boolean"
+	operandString     operandKind = "string"
+	operandCondition  operandKind = "condition"
+	operandConditions operandKind = "conditions"
+	operandFieldValue operandKind = "fieldValue"
+	operandMacro      operandKind = "macro"
+	operandRollout    operandKind = "rollout"
+	operandFunc       operandKind = "func"
+	operandDistinct   operandKind = "distinct"
+	operandRange      operandKind = "range"
+)
+
+// VariadicArity marks operators taking a list of sub-conditions.
+const VariadicArity = -1
+
+// OperatorExample is a condition and document pair with its expected result.
+type OperatorExample struct {
+	Condition map[string]any `json:"condition"`
+	Document  any            `json:"document"`
+	Matches   bool           `json:"matches"`
+}
+
+// OperatorDoc describes one operator for documentation, playground hints and
+// schema generation.
+type OperatorDoc struct {
+	Name         string            `json:"name"`
+	Arity        int               `json:"arity"`
+	OperandTypes []string          `json:"operandTypes"`
+	Summary      string            `json:"summary"`
+	Examples     []OperatorExample `json:"examples,omitempty"`
+	Custom       bool              `json:"custom,omitempty"`
+
+	operand operandKind
+}
+
+func field(name string, condition any) map[string]any {
+	return map[string]any{name: condition}
+}
+
+var builtinOperators = []OperatorDoc{
+	{
+		Name: "$eq", Arity: 1, OperandTypes: []string{"any"}, operand: operandAny,
+		Summary: "Matches values equal to the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$eq", 1)), field("a", 1), true},
+			{field("a", field("$eq", 1)), field("a", 2), false},
+		},
+	},
+	{
+		Name: "$ne", Arity: 1, OperandTypes: []string{"any"}, operand: operandAny,
+		Summary: "Matches values not equal to the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$ne", 1)), field("a", 2), true},
+			{field("a", field("$ne", 1)), field("a", 1), false},
+		},
+	},
+	{
+		Name: "$gt", Arity: 1, OperandTypes: []string{"number", "string"}, operand: operandAny,
+		Summary: "Matches values greater than the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$gt", 1)), field("a", 2), true},
+			{field("a", field("$gt", 1)), field("a", 1), false},
+		},
+	},
+	{
+		Name: "$gte", Arity: 1, OperandTypes: []string{"number", "string"}, operand: operandAny,
+		Summary: "Matches values greater than or equal to the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$gte", 2)), field("a", 2), true},
+			{field("a", field("$gte", 2)), field("a", 1), false},
+		},
+	},
+	{
+		Name: "$lt", Arity: 1, OperandTypes: []string{"number", "string"}, operand: operandAny,
+		Summary: "Matches values less than the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$lt", 2)), field("a", 1), true},
+			{field("a", field("$lt", 2)), field("a", 2), false},
+		},
+	},
+	{
+		Name: "$lte", Arity: 1, OperandTypes: []string{"number", "string"}, operand: operandAny,
+		Summary: "Matches values less than or equal to the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$lte", 1)), field("a", 1), true},
+			{field("a", field("$lte", 1)), field("a", 2), false},
+		},
+	},
+	{
+		Name: "$in", Arity: 1, OperandTypes: []string{"array"}, operand: operandArray,
+		Summary: "Matches values equal to any element of the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$in", []any{1, 2})), field("a", 2), true},
+			{field("a", field("$in", []any{1, 2})), field("a", 3), false},
+		},
+	},
+	{
+		Name: "$nin", Arity: 1, OperandTypes: []string{"array"}, operand: operandArray,
+		Summary: "Matches values equal to none of the elements of the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$nin", []any{1, 2})), field("a", 3), true},
+			{field("a", field("$nin", []any{1, 2})), field("a", 1), false},
+		},
+	},
+	{
+		Name: "$exists", Arity: 1, OperandTypes: []string{"boolean"}, operand: operandBoolean,
+		Summary: "Matches when the field is present (true) or absent (false).",
+		Examples: []OperatorExample{
+			{field("a", field("$exists", true)), field("a", 1), true},
+			{field("a", field("$exists", false)), field("b", 1), true},
+		},
+	},
+	{
+		Name: "$present", Arity: 1, OperandTypes: []string{"boolean"}, operand: operandBoolean,
+		Summary: "Matches when the field holds a non-empty value (true) or not (false).",
+		Examples: []OperatorExample{
+			{field("a", field("$present", true)), field("a", "x"), true},
+			{field("a", field("$present", true)), field("a", ""), false},
+			{field("a", field("$present", false)), field("b", 1), true},
+		},
+	},
+	{
+		Name: "$regex", Arity: 1, OperandTypes: []string{"string"}, operand: operandString,
+		Summary: "Matches strings against a regular expression (Go regexp syntax).",
+		Examples: []OperatorExample{
+			{field("a", field("$regex", "^ab+c")), field("a", "abbc"), true},
+			{field("a", field("$regex", "^ab+c")), field("a", "xabc"), false},
+		},
+	},
+	{
+		Name: "$glob", Arity: 1, OperandTypes: []string{"string"}, operand: operandString,
+		Summary: `Matches strings against a wildcard pattern: "*" matches any run of characters, "?" exactly one.`,
+		Examples: []OperatorExample{
+			{field("path", field("$glob", "/api/*")), field("path", "/api/v1/users"), true},
+			{field("path", field("$glob", "/api/*")), field("path", "/static/app.js"), false},
+		},
+	},
+	{
+		Name: "$rollout", Arity: 1, OperandTypes: []string{"number", "object"}, operand: operandRollout,
+		Summary: "Matches a stable percentage of field values by hashing them into buckets; the operand is a percentage or {percent, salt}.",
+		Examples: []OperatorExample{
+			{field("user", field("$rollout", 100)), field("user", "u-1"), true},
+			{field("user", field("$rollout", map[string]any{"percent": 0, "salt": "beta"})), field("user", "u-1"), false},
+		},
+	},
+	{
+		Name: "$distinctElems", Arity: 1, OperandTypes: []string{"boolean", "string"}, operand: operandDistinct,
+		Summary: "Matches arrays whose elements (true) or the values at a field path in their elements are all different; elements without the field are skipped.",
+		Examples: []OperatorExample{
+			{field("items", field("$distinctElems", "sku")), field("items", []any{field("sku", "a"), field("sku", "b")}), true},
+			{field("items", field("$distinctElems", "sku")), field("items", []any{field("sku", "a"), field("sku", "a")}), false},
+			{field("tags", field("$distinctElems", true)), field("tags", []any{"x", "y", "x"}), false},
+		},
+	},
+	{
+		Name: "$sumOf", Arity: 1, OperandTypes: []string{"condition", "any"}, operand: operandFieldValue,
+		Summary: "Matches arrays whose numbers, or the numbers at a field path in their elements, sum to a value matching the operand; {\"items.price\": {\"$sumOf\": c}} is short for {\"items\": {\"$sumOf\": {\"price\": c}}}.",
+		Examples: []OperatorExample{
+			{field("items", field("$sumOf", field("price", field("$gt", 100)))), field("items", []any{field("price", 60), field("price", 50)}), true},
+			{field("items.price", field("$sumOf", field("$gt", 100))), field("items", []any{field("price", 60), field("price", 30)}), false},
+			{field("scores", field("$sumOf", 10)), field("scores", []any{4, 6}), true},
+		},
+	},
+	{
+		Name: "$minOf", Arity: 1, OperandTypes: []string{"condition", "any"}, operand: operandFieldValue,
+		Summary: "Matches arrays whose smallest number, or smallest number at a field path in their elements, matches the operand; arrays without numbers have none.",
+		Examples: []OperatorExample{
+			{field("scores", field("$minOf", field("$gte", 60))), field("scores", []any{60, 75}), true},
+			{field("scores", field("$minOf", field("$gte", 60))), field("scores", []any{50, 75}), false},
+		},
+	},
+	{
+		Name: "$maxOf", Arity: 1, OperandTypes: []string{"condition", "any"}, operand: operandFieldValue,
+		Summary: "Matches arrays whose largest number, or largest number at a field path in their elements, matches the operand; arrays without numbers have none.",
+		Examples: []OperatorExample{
+			{field("items", field("$maxOf", field("qty", field("$lte", 5)))), field("items", []any{field("qty", 2), field("qty", 5)}), true},
+			{field("items", field("$maxOf", field("qty", field("$lte", 5)))), field("items", []any{field("qty", 2), field("qty", 9)}), false},
+		},
+	},
+	{
+		Name: "$avgOf", Arity: 1, OperandTypes: []string{"condition", "any"}, operand: operandFieldValue,
+		Summary: "Matches arrays whose mean number, or mean number at a field path in their elements, matches the operand; arrays without numbers have none.",
+		Examples: []OperatorExample{
+			{field("ratings", field("$avgOf", field("$gte", 4))), field("ratings", []any{5, 4, 3.5}), true},
+			{field("ratings", field("$avgOf", field("$gte", 4))), field("ratings", []any{5, 2}), false},
+		},
+	},
+	{
+		Name: "$strLen", Arity: 1, OperandTypes: []string{"number", "condition"}, operand: operandFieldValue,
+		Summary: "Matches strings whose length in characters matches the operand.",
+		Examples: []OperatorExample{
+			{field("name", field("$strLen", field("$between", []any{3, 20}))), field("name", "Zoë"), true},
+			{field("name", field("$strLen", field("$between", []any{3, 20}))), field("name", "Al"), false},
+			{field("code", field("$strLen", 4)), field("code", "ABCD"), true},
+		},
+	},
+	{
+		Name: "$between", Arity: 1, OperandTypes: []string{"array"}, operand: operandRange,
+		Summary: "Matches values from low to high inclusive, given as [low, high], two numbers or two strings; it is short for {\"$gte\": low, \"$lte\": high}.",
+		Examples: []OperatorExample{
+			{field("score", field("$between", []any{10, 20})), field("score", 20), true},
+			{field("score", field("$between", []any{10, 20})), field("score", 20.5), false},
+			{field("grade", field("$between", []any{"A", "C"})), field("grade", "B"), true},
+		},
+	},
+	{
+		Name: "$func", Arity: 1, OperandTypes: []string{"func"}, operand: operandFunc,
+		Summary: "Matches values for which the operand, a Go func(any) bool or func(context.Context, any) bool, returns true; at the top of a condition it is called with the whole document. Conditions using it cannot be written as JSON.",
+	},
+	{
+		Name: "$and", Arity: VariadicArity, OperandTypes: []string{"condition"}, operand: operandConditions,
+		Summary: "Matches when every condition in the operand matches.",
+		Examples: []OperatorExample{
+			{field("$and", []any{field("a", 1), field("b", 2)}), map[string]any{"a": 1, "b": 2}, true},
+			{field("$and", []any{field("a", 1), field("b", 2)}), map[string]any{"a": 1, "b": 3}, false},
+		},
+	},
+	{
+		Name: "$or", Arity: VariadicArity, OperandTypes: []string{"condition"}, operand: operandConditions,
+		Summary: "Matches when at least one condition in the operand matches.",
+		Examples: []OperatorExample{
+			{field("$or", []any{field("a", 1), field("b", 2)}), field("b", 2), true},
+			{field("$or", []any{field("a", 1), field("b", 2)}), field("b", 3), false},
+		},
+	},
+	{
+		Name: "$not", Arity: 1, OperandTypes: []string{"condition", "any"}, operand: operandFieldValue,
+		Summary: "Inverts the operand condition.",
+		Examples: []OperatorExample{
+			{field("a", field("$not", field("$gt", 5))), field("a", 1), true},
+			{field("a", field("$not", field("$gt", 5))), field("a", 9), false},
+		},
+	},
+	{
+		Name: "$elemMatch", Arity: 1, OperandTypes: []string{"condition"}, operand: operandCondition,
+		Summary: "Matches arrays with at least one element matching the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$elemMatch", field("$gt", 5))), field("a", []any{1, 9}), true},
+			{field("a", field("$elemMatch", field("$gt", 5))), field("a", []any{1, 2}), false},
+		},
+	},
+	{
+		Name: "$every", Arity: 1, OperandTypes: []string{"condition"}, operand: operandCondition,
+		Summary: "Matches non-empty arrays whose elements all match the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$every", field("$gt", 0))), field("a", []any{1, 9}), true},
+			{field("a", field("$every", field("$gt", 5))), field("a", []any{1, 9}), false},
+		},
+	},
+	{
+		Name: "$size", Arity: 1, OperandTypes: []string{"number", "condition"}, operand: operandFieldValue,
+		Summary: "Matches arrays whose length matches the operand.",
+		Examples: []OperatorExample{
+			{field("a", field("$size", 2)), field("a", []any{1, 9}), true},
+			{field("a", field("$size", field("$gt", 2))), field("a", []any{1, 9}), false},
+		},
+	},
+	{
+		Name: MacroKey, Arity: VariadicArity, OperandTypes: []string{"string"}, operand: operandMacro,
+		Summary: "Expands one or more named macros in place.",
+	},
+}
+
+var (
+	customMu        sync.RWMutex
+	customOperators = map[string]OperatorDoc{}
+)
+
+// RegisterOperator adds the operator name, which must start with "$", to
+// every condition compiled from now on. fn is called with the matched value
+// and the operand: documents' maps, slices and structs as they were passed,
+// scalars as bool, int64, float64 or string, and nil for null or missing
+// values. The operand is converted the same way once, when a condition is
+// compiled, with lists as []any and documents as map[string]any. fn must be
+// safe for concurrent use; a panic in it fails the match with
+// ErrCallbackPanic. Built-in operators cannot be replaced, and each name can
+// be registered once.
+func RegisterOperator(name string, fn func(fieldValue any, operand any) bool) error {
+	if fn == nil {
+		return fmt.Errorf("mongory: operator %s needs a function", name)
+	}
+	return registerOperator(name, "RegisterOperator", func() error { return cgo.RegisterOperator(name, fn) })
+}
+
+// RegisterOperatorContext is RegisterOperator for functions that take the
+// context of the match: the one given to MatchContext or FilterContext, and
+// context.Background() for other matches. Operators can use it for
+// deadline-aware lookups or request-scoped data.
+func RegisterOperatorContext(name string, fn func(ctx context.Context, fieldValue any, operand any) bool) error {
+	if fn == nil {
+		return fmt.Errorf("mongory: operator %s needs a function", name)
+	}
+	return registerOperator(name, "RegisterOperatorContext", func() error { return cgo.RegisterOperatorContext(name, fn) })
+}
+
+// registerOperator checks name and adds it with register, documented as
+// registered by the function from.
+func registerOperator(name, from string, register func() error) error {
+	if !strings.HasPrefix(name, "$") || len(name) < 2 {
+		return fmt.Errorf("mongory: operator name %q must start with $", name)
+	}
+	if _, builtin := builtinOperator(name); builtin {
+		return fmt.Errorf("mongory: operator %s is built in", name)
+	}
+	customMu.Lock()
+	defer customMu.Unlock()
+	if err := register(); err != nil {
+		return err
+	}
+	customOperators[name] = OperatorDoc{
+		Name: name, Arity: 1, OperandTypes: []string{"any"}, operand: operandAny,
+		Summary: "Registered with " + from + ".",
+		Custom:  true,
+	}
+	return nil
+}
+
+// valueOperatorDoc describes an operator registered through the native
+// package, which works below this package's bookkeeping.
+func valueOperatorDoc(name string) OperatorDoc {
+	return OperatorDoc{
+		Name: name, Arity: 1, OperandTypes: []string{"any"}, operand: operandAny,
+		Summary: "Registered with native.RegisterOperator.",
+		Custom:  true,
+	}
+}
+
+// UnregisterOperator removes an operator added with RegisterOperator,
+// RegisterOperatorContext or native.RegisterOperator, reporting whether
+// there was one. Matchers compiled with it keep using it; conditions
+// compiled afterwards treat it as a field name again.
+func UnregisterOperator(name string) bool {
+	customMu.Lock()
+	defer customMu.Unlock()
+	delete(customOperators, name)
+	return cgo.UnregisterOperator(name)
+}
+
+// Operators describes every operator known to the package, including those
+// added with RegisterOperator, sorted by name.
+func Operators() []OperatorDoc {
+	customMu.RLock()
+	docs := make([]OperatorDoc, len(builtinOperators), len(builtinOperators)+len(customOperators))
+	copy(docs, builtinOperators)
+	for _, doc := range customOperators {
+		docs = append(docs, doc)
+	}
+	for _, name := range cgo.CustomOperators() {
+		if _, ok := customOperators[name]; !ok {
+			docs = append(docs, valueOperatorDoc(name))
+		}
+	}
+	customMu.RUnlock()
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs
+}
@@ -0,0 +1,345 @@
+package mongory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOperatorExamples(t *testing.T) {
+	for _, doc := range Operators() {
+		if doc.Name == "" || doc.Summary == "" || len(doc.OperandTypes) == 0 {
+			t.Errorf("operator %q is missing documentation", doc.Name)
+		}
+		for _, example := range doc.Examples {
+			matcher, err := NewCMatcher(example.Condition, nil)
+			if err != nil {
+				t.Fatalf("%s: NewMatcher(%v) failed: %v", doc.Name, example.Condition, err)
+			}
+			got, err := matcher.Match(example.Document)
+			if err != nil {
+				t.Fatalf("%s: Match failed: %v", doc.Name, err)
+			}
+			if got != example.Matches {
+				t.Errorf("%s: %v against %v = %v, want %v", doc.Name, example.Condition, example.Document, got, example.Matches)
+			}
+		}
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+	var calls atomic.Int32
+	divisible := func(value, operand any) bool {
+		calls.Add(1)
+		n, ok := value.(int64)
+		d, _ := operand.(int64)
+		return ok && d != 0 && n%d == 0
+	}
+	if err := RegisterOperator("$divisibleBy", divisible); err != nil {
+		t.Fatalf("RegisterOperator failed: %v", err)
+	}
+	t.Cleanup(func() { UnregisterOperator("$divisibleBy") })
+	for _, name := range []string{"divisibleBy", "$", "$in", "$glob", MacroKey, "$divisibleBy"} {
+		if err := RegisterOperator(name, divisible); err == nil {
+			t.Errorf("RegisterOperator(%q) succeeded", name)
+		}
+	}
+
+	m, err := NewCMatcher(map[string]any{"n": map[string]any{"$divisibleBy": 3}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	for doc, want := range map[int]bool{9: true, 10: false} {
+		if matched, err := m.Match(map[string]any{"n": doc}); err != nil || matched != want {
+			t.Fatalf("Match(%d) = %v, %v; want %v", doc, matched, err, want)
+		}
+	}
+	if matched, err := m.Match(map[string]any{}); err != nil || matched {
+		t.Fatalf("Match(missing) = %v, %v; want false", matched, err)
+	}
+	if calls.Load() == 0 {
+		t.Fatalf("operator was never called")
+	}
+
+	// Operands and values arrive as Go values, documents unchanged.
+	var seen any
+	if err := RegisterOperator("$inspect", func(value, operand any) bool {
+		seen = value
+		return operand.(map[string]any)["want"] == value.(map[string]any)["kind"]
+	}); err != nil {
+		t.Fatalf("RegisterOperator failed: %v", err)
+	}
+	t.Cleanup(func() { UnregisterOperator("$inspect") })
+	inspect, err := NewCMatcher(map[string]any{"meta": map[string]any{"$inspect": map[string]any{"want": "x"}}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer inspect.Close()
+	meta := map[string]any{"kind": "x"}
+	if matched, err := inspect.Match(map[string]any{"meta": meta}); err != nil || !matched {
+		t.Fatalf("Match($inspect) = %v, %v; want true", matched, err)
+	}
+	if !reflect.DeepEqual(seen, meta) {
+		t.Fatalf("operator saw %#v, want %#v", seen, meta)
+	}
+
+	if err := ValidateCondition(map[string]any{"n": map[string]any{"$divisibleBy": 2}}); err != nil {
+		t.Fatalf("ValidateCondition rejected a registered operator: %v", err)
+	}
+	found := false
+	for _, doc := range Operators() {
+		found = found || doc.Name == "$divisibleBy" && doc.Custom
+	}
+	if !found {
+		t.Fatalf("Operators does not list $divisibleBy")
+	}
+
+	if !UnregisterOperator("$divisibleBy") || UnregisterOperator("$divisibleBy") || UnregisterOperator("$in") {
+		t.Fatalf("UnregisterOperator reported the wrong result")
+	}
+	if matched, err := m.Match(map[string]any{"n": 6}); err != nil || !matched {
+		t.Fatalf("compiled matcher after UnregisterOperator = %v, %v; want true", matched, err)
+	}
+	if err := ValidateCondition(map[string]any{"n": map[string]any{"$divisibleBy": 2}}); !errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatalf("ValidateCondition after UnregisterOperator = %v, want ErrUnsupportedOperator", err)
+	}
+}
+
+func TestFuncOperator(t *testing.T) {
+	adult := func(doc any) bool {
+		fields, _ := doc.(map[string]any)
+		return fields["age"].(int) >= 18
+	}
+	m, err := NewCMatcher(map[string]any{"$func": adult, "name": map[string]any{"$func": func(v any) bool {
+		return v == "ann"
+	}}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	for _, tc := range []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"name": "ann", "age": 30}, true},
+		{map[string]any{"name": "ann", "age": 12}, false},
+		{map[string]any{"name": "bob", "age": 30}, false},
+	} {
+		if matched, err := m.Match(tc.doc); err != nil || matched != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, matched, err, tc.want)
+		}
+	}
+
+	panicking, err := NewCMatcher(map[string]any{"$func": func(any) bool { panic("boom") }}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer panicking.Close()
+	if _, err := panicking.Match(map[string]any{}); !errors.Is(err, ErrCallbackPanic) {
+		t.Fatalf("Match with panicking $func: err = %v, want ErrCallbackPanic", err)
+	}
+
+	for _, operand := range []any{"age > 18", (func(any) bool)(nil)} {
+		condition := map[string]any{"$func": operand}
+		if _, err := NewCMatcher(condition, nil); err == nil {
+			t.Errorf("NewCMatcher(%v) succeeded", condition)
+		}
+		if err := ValidateCondition(condition); !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("ValidateCondition(%v) = %v, want ErrInvalidCondition", condition, err)
+		}
+	}
+	if err := ValidateCondition(map[string]any{"$func": adult}); err != nil {
+		t.Fatalf("ValidateCondition rejected $func: %v", err)
+	}
+
+	// Closures made by one literal are distinct predicates.
+	above := func(n int) func(any) bool {
+		return func(v any) bool { return v.(int64) > int64(n) }
+	}
+	rules, err := CompileRules(
+		Rule{ID: "gt1", Condition: map[string]any{"n": map[string]any{"$func": above(1)}}},
+		Rule{ID: "gt5", Condition: map[string]any{"n": map[string]any{"$func": above(5)}}},
+	)
+	if err != nil {
+		t.Fatalf("CompileRules failed: %v", err)
+	}
+	defer rules.Close()
+	if ids, err := rules.Match(map[string]any{"n": 3}); err != nil || !reflect.DeepEqual(ids, []string{"gt1"}) {
+		t.Fatalf("RuleSet.Match = %v, %v; want [gt1]", ids, err)
+	}
+}
+
+func TestMatchContext(t *testing.T) {
+	type key struct{}
+	var seen []any
+	if err := RegisterOperatorContext("$allowed", func(ctx context.Context, value, operand any) bool {
+		seen = append(seen, ctx.Value(key{}))
+		allowed, _ := ctx.Value(key{}).([]string)
+		return slices.Contains(allowed, fmt.Sprint(value))
+	}); err != nil {
+		t.Fatalf("RegisterOperatorContext failed: %v", err)
+	}
+	t.Cleanup(func() { UnregisterOperator("$allowed") })
+	if err := RegisterOperatorContext("$allowed", nil); err == nil {
+		t.Fatal("RegisterOperatorContext with a nil function succeeded")
+	}
+
+	m, err := NewMatcher(map[string]any{
+		"tenant": map[string]any{"$allowed": true},
+		"$func": func(ctx context.Context, doc any) bool {
+			return ctx.Value(key{}) != nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	ctx := context.WithValue(context.Background(), key{}, []string{"acme"})
+	if matched, err := m.MatchContext(ctx, map[string]any{"tenant": "acme"}); err != nil || !matched {
+		t.Fatalf("MatchContext(acme) = %v, %v; want true", matched, err)
+	}
+	if matched, err := m.MatchContext(ctx, map[string]any{"tenant": "other"}); err != nil || matched {
+		t.Fatalf("MatchContext(other) = %v, %v; want false", matched, err)
+	}
+	if matched, err := m.Match(map[string]any{"tenant": "acme"}); err != nil || matched {
+		t.Fatalf("Match without a context = %v, %v; want false", matched, err)
+	}
+	kept, err := m.FilterContext(ctx, []any{map[string]any{"tenant": "acme"}, map[string]any{"tenant": "x"}})
+	if err != nil || len(kept) != 1 {
+		t.Fatalf("FilterContext = %v, %v; want one record", kept, err)
+	}
+	// Missing fields are matched in the context too.
+	seen = nil
+	if matched, err := m.MatchContext(ctx, map[string]any{}); err != nil || matched {
+		t.Fatalf("MatchContext(missing) = %v, %v; want false", matched, err)
+	}
+	if len(seen) != 1 || seen[0] == nil {
+		t.Fatalf("operator saw contexts %v for a missing field", seen)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := m.MatchContext(cancelled, map[string]any{"tenant": "acme"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("MatchContext(cancelled): err = %v, want context.Canceled", err)
+	}
+	if err := ValidateCondition(map[string]any{"$func": func(context.Context, any) bool { return true }}); err != nil {
+		t.Fatalf("ValidateCondition rejected a context $func: %v", err)
+	}
+}
+
+func TestDistinctElems(t *testing.T) {
+	for _, tc := range []struct {
+		operand any
+		value   any
+		want    bool
+	}{
+		{"sku", []any{map[string]any{"sku": "a"}, map[string]any{"sku": "b"}}, true},
+		{"sku", []any{map[string]any{"sku": "a"}, map[string]any{"qty": 1}, map[string]any{"sku": "a"}}, false},
+		{"sku", []any{map[string]any{"sku": "a"}, map[string]any{"qty": 1}, "loose"}, true},
+		{"sku", []map[string]any{{"sku": 1}, {"sku": 1.0}}, false},
+		{"sku", []map[string]any{{"sku": 1}, {"sku": "1"}}, true},
+		{"meta.id", []any{map[string]any{"meta": map[string]any{"id": 7}}, map[string]any{"meta": map[string]any{"id": 7}}}, false},
+		{"meta.id", []any{map[string]any{"meta": map[string]any{"id": 7}}, map[string]any{"meta": map[string]any{"id": 8}}}, true},
+		{true, []any{"x", "y"}, true},
+		{true, []any{map[string]any{"a": 1, "b": 2}, map[string]any{"b": 2, "a": 1}}, false},
+		{true, []any{[]any{1, 2}, []any{2, 1}}, true},
+		{true, []any{}, true},
+		{true, "not an array", false},
+	} {
+		m, err := NewMatcher(map[string]any{"items": map[string]any{"$distinctElems": tc.operand}})
+		if err != nil {
+			t.Fatalf("NewMatcher(%v) failed: %v", tc.operand, err)
+		}
+		matched, err := m.Match(map[string]any{"items": tc.value})
+		m.Close()
+		if err != nil || matched != tc.want {
+			t.Fatalf("$distinctElems %v on %v = %v, %v; want %v", tc.operand, tc.value, matched, err, tc.want)
+		}
+	}
+	for _, operand := range []any{false, "", 1} {
+		condition := map[string]any{"items": map[string]any{"$distinctElems": operand}}
+		if _, err := NewMatcher(condition); err == nil {
+			t.Errorf("NewMatcher(%v) succeeded", condition)
+		}
+		if err := ValidateCondition(condition); !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("ValidateCondition(%v) = %v, want ErrInvalidCondition", condition, err)
+		}
+	}
+}
+
+func TestStrLen(t *testing.T) {
+	for _, tc := range []struct {
+		operand any
+		value   any
+		want    bool
+	}{
+		{4, "ABCD", true},
+		{4, "ABC", false},
+		{3, "Zoë", true},
+		{2, "日本", true},
+		{map[string]any{"$between": []any{3, 20}}, "ada", true},
+		{map[string]any{"$between": []any{3, 20}}, "al", false},
+		{map[string]any{"$gt": 0}, "", false},
+		{map[string]any{"$gte": 0}, 42, false},
+		{map[string]any{"$gte": 0}, nil, false},
+	} {
+		m, err := NewMatcher(map[string]any{"name": map[string]any{"$strLen": tc.operand}})
+		if err != nil {
+			t.Fatalf("NewMatcher(%v) failed: %v", tc.operand, err)
+		}
+		matched, err := m.Match(map[string]any{"name": tc.value})
+		m.Close()
+		if err != nil || matched != tc.want {
+			t.Fatalf("$strLen %v on %q = %v, %v; want %v", tc.operand, tc.value, matched, err, tc.want)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	may := []any{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)}
+	for _, tc := range []struct {
+		operand any
+		value   any
+		want    bool
+	}{
+		{[]any{10, 20}, 10, true},
+		{[]any{10, 20}, 20.0, true},
+		{[]any{10, 20}, 9.99, false},
+		{[]any{10, 20}, 21, false},
+		{[]any{10, 20}, "15", false},
+		{[]any{10, 20}, nil, false},
+		// Arrays are not searched, as by {"$gte": 10, "$lte": 20}.
+		{[]any{10, 20}, []any{5, 15}, false},
+		{[]float64{0.5, 1.5}, 1, true},
+		{[]any{20, 10}, 15, false},
+		{[]any{"b", "d"}, "c", true},
+		{[]any{"b", "d"}, "e", false},
+		{may, time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC), true},
+		{may, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), false},
+	} {
+		m, err := NewMatcher(map[string]any{"score": map[string]any{"$between": tc.operand}})
+		if err != nil {
+			t.Fatalf("NewMatcher(%v) failed: %v", tc.operand, err)
+		}
+		matched, err := m.Match(map[string]any{"score": tc.value})
+		m.Close()
+		if err != nil || matched != tc.want {
+			t.Fatalf("$between %v on %v = %v, %v; want %v", tc.operand, tc.value, matched, err, tc.want)
+		}
+	}
+	for _, operand := range []any{10, []any{10}, []any{10, "20"}, []any{nil, 20}, []any{1, 2, 3}} {
+		condition := map[string]any{"score": map[string]any{"$between": operand}}
+		if _, err := NewMatcher(condition); err == nil {
+			t.Errorf("NewMatcher(%v) succeeded", condition)
+		}
+		if err := ValidateCondition(condition); !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("ValidateCondition(%v) = %v, want ErrInvalidCondition", condition, err)
+		}
+	}
+}
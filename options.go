@@ -0,0 +1,121 @@
+package mongory
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// NumericMode selects how integers compare with floating-point numbers.
+type NumericMode int
+
+const (
+	// NumericFloat compares an integer with a float by converting the
+	// integer to a float64, which rounds integers beyond 2^53. It is the
+	// default and the core's own comparison.
+	NumericFloat NumericMode = iota
+	// NumericExact compares integers with floats by their exact values, as
+	// MongoDB does, so 9007199254740993 no longer equals 9007199254740992.0.
+	// Numbers inside SharedValue operands keep the default comparison.
+	NumericExact
+)
+
+func (m NumericMode) String() string {
+	switch m {
+	case NumericFloat:
+		return "float"
+	case NumericExact:
+		return "exact"
+	default:
+		return "unknown"
+	}
+}
+
+// UnknownOperatorMode selects how keys that start with "$" but name no
+// operator are compiled.
+type UnknownOperatorMode int
+
+const (
+	// UnknownOperatorField treats them as field names, so they match
+	// documents with such a field. It is the default.
+	UnknownOperatorField UnknownOperatorMode = iota
+	// UnknownOperatorError rejects the condition with an error matching
+	// ErrUnsupportedOperator, catching misspelled operators.
+	UnknownOperatorError
+)
+
+func (m UnknownOperatorMode) String() string {
+	switch m {
+	case UnknownOperatorField:
+		return "field"
+	case UnknownOperatorError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// MatcherOptions configures a matcher compiled by NewMatcherWithOptions. The
+// zero value compiles as NewMatcher does.
+type MatcherOptions struct {
+	// Context is the matcher's context, as NewMatcherWithContext takes it.
+	Context any
+	// StrictTypes rejects the operands ValidateCondition rejects, with an
+	// error matching ErrInvalidCondition. Otherwise the core decides, and
+	// some, such as a $regex that does not parse, compile to matchers that
+	// never match.
+	StrictTypes bool
+	// Numeric sets how integers compare with floats.
+	Numeric NumericMode
+	// UnknownOperators sets how unknown operators compile.
+	UnknownOperators UnknownOperatorMode
+	// Trace enables trace mode from the start, as EnableTrace does.
+	Trace bool
+	// Name labels the matcher's counts in the Metrics set with SetMetrics.
+	Name string
+	// ParseTimes compares strings holding RFC 3339 timestamps, in documents
+	// and in the condition, as times rather than as text, so timestamps
+	// decoded from JSON compare with each other and with time.Time values
+	// whatever their offsets.
+	ParseTimes bool
+}
+
+// NewMatcherWithOptions compiles condition configured by opts.
+func NewMatcherWithOptions(condition map[string]any, opts MatcherOptions) (Matcher, error) {
+	if err := InitE(); err != nil {
+		return nil, err
+	}
+	condition, err := expandMacros(condition)
+	if err != nil {
+		return nil, err
+	}
+	if condition, err = foldConstants(condition, time.Now()); err != nil {
+		return nil, err
+	}
+	if opts.StrictTypes || opts.UnknownOperators == UnknownOperatorError {
+		v := validator{types: opts.StrictTypes, unknown: opts.UnknownOperators == UnknownOperatorError}
+		if err := v.table(nil, reflect.ValueOf(condition)); err != nil {
+			return nil, err
+		}
+	}
+	compiled := flattenLogical(expandReducerPaths(condition))
+	if err := checkConditionDepth(compiled); err != nil {
+		return nil, err
+	}
+	inner, err := cgo.NewMatcherWithOptions(compiled, opts.Context, cgo.Options{
+		ExactNumbers: opts.Numeric == NumericExact,
+		ParseTimes:   opts.ParseTimes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := wrapMatcher(inner, NewCondition(condition), opts.Name)
+	if opts.Trace {
+		if err := m.EnableTrace(); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
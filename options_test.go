@@ -0,0 +1,78 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewMatcherWithOptions(t *testing.T) {
+	compile := func(condition map[string]any, opts MatcherOptions) Matcher {
+		t.Helper()
+		m, err := NewMatcherWithOptions(condition, opts)
+		if err != nil {
+			t.Fatalf("NewMatcherWithOptions(%v, %+v) failed: %v", condition, opts, err)
+		}
+		t.Cleanup(func() { m.Close() })
+		return m
+	}
+	match := func(m Matcher, doc any, want bool) {
+		t.Helper()
+		if matched, err := m.Match(doc); err != nil || matched != want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", doc, matched, err, want)
+		}
+	}
+
+	m := compile(map[string]any{"a": 1}, MatcherOptions{Context: "ctx"})
+	match(m, map[string]any{"a": 1}, true)
+	if ctx, ok := MatcherContext[string](m); !ok || ctx != "ctx" {
+		t.Fatalf("MatcherContext = %q, %v", ctx, ok)
+	}
+
+	badRegex := map[string]any{"a": map[string]any{"$regex": "("}}
+	compile(badRegex, MatcherOptions{})
+	if _, err := NewMatcherWithOptions(badRegex, MatcherOptions{StrictTypes: true}); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("StrictTypes: err = %v, want ErrInvalidCondition", err)
+	}
+	misspelled := map[string]any{"a": map[string]any{"$eqq": 1}}
+	compile(misspelled, MatcherOptions{StrictTypes: true})
+	if _, err := NewMatcherWithOptions(misspelled, MatcherOptions{UnknownOperators: UnknownOperatorError}); !errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatalf("UnknownOperatorError: err = %v, want ErrUnsupportedOperator", err)
+	}
+	nested := map[string]any{"$or": []any{map[string]any{"a": map[string]any{"$regex": "("}}, map[string]any{"b": map[string]any{"$eqq": 1}}}}
+	compile(nested, MatcherOptions{UnknownOperators: UnknownOperatorField})
+	if _, err := NewMatcherWithOptions(nested, MatcherOptions{UnknownOperators: UnknownOperatorError}); !errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatalf("UnknownOperatorError nested: err = %v, want ErrUnsupportedOperator", err)
+	}
+
+	const big = 1<<53 + 1
+	for _, tc := range []struct {
+		condition map[string]any
+		doc       any
+		float     bool
+		exact     bool
+	}{
+		{map[string]any{"n": float64(1 << 53)}, map[string]any{"n": big}, true, false},
+		{map[string]any{"n": big}, map[string]any{"n": float64(1 << 53)}, true, false},
+		{map[string]any{"n": map[string]any{"$gt": float64(1 << 53)}}, map[string]any{"n": big}, false, true},
+		{map[string]any{"n": map[string]any{"$lt": big}}, map[string]any{"n": float64(1 << 53)}, false, true},
+		{map[string]any{"n": map[string]any{"$in": []any{0.5, float64(1 << 53)}}}, map[string]any{"n": big}, true, false},
+		{map[string]any{"n": map[string]any{"$gt": 1}}, map[string]any{"n": 1.5}, true, true},
+		{map[string]any{"n": map[string]any{"$lte": 2.0}}, map[string]any{"n": 2}, true, true},
+		{map[string]any{"n": 1.5}, map[string]any{"n": 1}, false, false},
+	} {
+		match(compile(tc.condition, MatcherOptions{}), tc.doc, tc.float)
+		match(compile(tc.condition, MatcherOptions{Numeric: NumericExact}), tc.doc, tc.exact)
+	}
+	SetInSetThreshold(1)
+	defer SetInSetThreshold(16)
+	in := map[string]any{"n": map[string]any{"$in": []any{0.5, float64(1 << 53)}}}
+	match(compile(in, MatcherOptions{Numeric: NumericExact}), map[string]any{"n": big}, false)
+
+	traced := compile(map[string]any{"a": 1}, MatcherOptions{Trace: true})
+	if !traced.(*matcher).TraceEnabled() {
+		t.Fatalf("Trace option did not enable trace")
+	}
+	if NumericExact.String() != "exact" || UnknownOperatorError.String() != "error" {
+		t.Fatalf("String = %q, %q", NumericExact, UnknownOperatorError)
+	}
+}
@@ -0,0 +1,121 @@
+// Package policy evaluates ordered allow/deny rules written as mongory
+// conditions and returns structured decisions, in the spirit of an OPA
+// policy but without running a policy engine:
+//
+//	p, err := policy.New(policy.Deny,
+//		policy.Rule{Name: "owner", Effect: policy.Allow, Reason: "owner may edit",
+//			Condition: map[string]any{"action": "edit", "role": "owner"}},
+//		policy.Rule{Name: "readers", Effect: policy.Allow,
+//			Condition: map[string]any{"action": "read"}},
+//	)
+//	decision, err := p.Decide(map[string]any{"action": "read", "role": "guest"})
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Effect is the outcome a rule grants.
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Rule applies Effect to inputs matching Condition. Reason explains the
+// decision to the caller; it defaults to naming the rule.
+type Rule struct {
+	Name      string
+	Effect    Effect
+	Condition map[string]any
+	Reason    string
+}
+
+// Decision is the result of evaluating a policy against one input.
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Effect Effect `json:"effect"`
+	// Rule is the name of the rule that decided, or empty when no rule
+	// matched and the default applied.
+	Rule    string   `json:"rule,omitempty"`
+	Reasons []string `json:"reasons"`
+}
+
+// Policy is an ordered list of rules with a default effect. The first rule
+// whose condition matches decides. A Policy is safe for concurrent use.
+type Policy struct {
+	defaultEffect Effect
+	rules         []Rule
+	matchers      []mongory.Matcher
+}
+
+// New compiles rules in order. Every invalid rule is reported, not only the
+// first.
+func New(defaultEffect Effect, rules ...Rule) (*Policy, error) {
+	if err := checkEffect(defaultEffect); err != nil {
+		return nil, fmt.Errorf("policy: default: %w", err)
+	}
+	p := &Policy{defaultEffect: defaultEffect, rules: rules}
+	var errs []error
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		if err := checkEffect(rule.Effect); err != nil {
+			errs = append(errs, fmt.Errorf("policy: rule %s: %w", name, err))
+			continue
+		}
+		m, err := mongory.NewMatcher(rule.Condition)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policy: rule %s: %w", name, err))
+			continue
+		}
+		p.matchers = append(p.matchers, m)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func checkEffect(effect Effect) error {
+	if effect != Allow && effect != Deny {
+		return fmt.Errorf("effect must be %q or %q, got %q", Allow, Deny, effect)
+	}
+	return nil
+}
+
+// Decide evaluates the rules against input and returns the first matching
+// rule's decision, or the default when none matches.
+func (p *Policy) Decide(input any) (Decision, error) {
+	for i, m := range p.matchers {
+		matched, err := m.Match(input)
+		if err != nil {
+			return Decision{}, fmt.Errorf("policy: rule %s: %w", p.rules[i].Name, err)
+		}
+		if matched {
+			rule := p.rules[i]
+			reason := rule.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("matched rule %q", rule.Name)
+			}
+			return Decision{Allow: rule.Effect == Allow, Effect: rule.Effect, Rule: rule.Name, Reasons: []string{reason}}, nil
+		}
+	}
+	return Decision{
+		Allow:   p.defaultEffect == Allow,
+		Effect:  p.defaultEffect,
+		Reasons: []string{fmt.Sprintf("no rule matched; default is %s", p.defaultEffect)},
+	}, nil
+}
+
+// Allowed is Decide reduced to a boolean. Errors deny.
+func (p *Policy) Allowed(input any) bool {
+	decision, err := p.Decide(input)
+	return err == nil && decision.Allow
+}
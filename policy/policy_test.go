@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecide(t *testing.T) {
+	p, err := New(Deny,
+		Rule{Name: "banned", Effect: Deny, Reason: "user is banned", Condition: map[string]any{"banned": true}},
+		Rule{Name: "owner", Effect: Allow, Condition: map[string]any{"action": "edit", "role": "owner"}},
+		Rule{Name: "readers", Effect: Allow, Reason: "anyone may read", Condition: map[string]any{"action": "read"}},
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cases := []struct {
+		input  map[string]any
+		allow  bool
+		rule   string
+		reason string
+	}{
+		{map[string]any{"action": "read", "role": "guest"}, true, "readers", "anyone may read"},
+		{map[string]any{"action": "read", "banned": true}, false, "banned", "user is banned"},
+		{map[string]any{"action": "edit", "role": "owner"}, true, "owner", `matched rule "owner"`},
+		{map[string]any{"action": "edit", "role": "guest"}, false, "", "no rule matched; default is deny"},
+	}
+	for _, tc := range cases {
+		decision, err := p.Decide(tc.input)
+		if err != nil {
+			t.Fatalf("Decide(%v) failed: %v", tc.input, err)
+		}
+		if decision.Allow != tc.allow || decision.Rule != tc.rule || len(decision.Reasons) != 1 || decision.Reasons[0] != tc.reason {
+			t.Fatalf("Decide(%v) = %+v, want allow=%v rule=%q reason=%q", tc.input, decision, tc.allow, tc.rule, tc.reason)
+		}
+		if p.Allowed(tc.input) != tc.allow {
+			t.Fatalf("Allowed(%v) != %v", tc.input, tc.allow)
+		}
+	}
+
+	decision, _ := p.Decide(map[string]any{"action": "read"})
+	encoded, err := json.Marshal(decision)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `{"allow":true,"effect":"allow","rule":"readers","reasons":["anyone may read"]}`; string(encoded) != want {
+		t.Fatalf("Marshal = %s, want %s", encoded, want)
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	if _, err := New("maybe"); err == nil {
+		t.Fatalf("New accepted an invalid default effect")
+	}
+	_, err := New(Deny,
+		Rule{Name: "bad-effect", Effect: "permit", Condition: map[string]any{}},
+		Rule{Name: "bad-condition", Effect: Allow, Condition: map[string]any{"$and": "x"}},
+	)
+	if err == nil {
+		t.Fatalf("New accepted invalid rules")
+	}
+	for _, name := range []string{"bad-effect", "bad-condition"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("error %q does not mention %s", err, name)
+		}
+	}
+}
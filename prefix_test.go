@@ -0,0 +1,99 @@
+package mongory
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRegex(t *testing.T) {
+	for _, pattern := range []any{"^ab+c", regexp.MustCompile("^ab+c")} {
+		matcher, err := NewCMatcher(map[string]any{"a": map[string]any{"$regex": pattern}}, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		for doc, want := range map[string]bool{"abbbc": true, "xabc": false} {
+			got, err := matcher.Match(map[string]any{"a": doc})
+			if err != nil || got != want {
+				t.Fatalf("$regex %v on %q = %v, %v; want %v", pattern, doc, got, err, want)
+			}
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"/api/*", "/api/v1/users", true},
+		{"/api/*", "/apx", false},
+		{"*.js", "app.min.js", true},
+		{"file-?.txt", "file-1.txt", true},
+		{"file-?.txt", "file-10.txt", false},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+	}
+	for _, tc := range cases {
+		matcher, err := NewCMatcher(map[string]any{"p": map[string]any{"$glob": tc.pattern}}, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		got, err := matcher.Match(map[string]any{"p": tc.value})
+		if err != nil || got != tc.want {
+			t.Fatalf("$glob %q on %q = %v, %v; want %v", tc.pattern, tc.value, got, err, tc.want)
+		}
+	}
+	if _, err := NewCMatcher(map[string]any{"p": map[string]any{"$glob": 1}}, nil); err == nil {
+		t.Fatalf("expected an error for a non-string $glob operand")
+	}
+}
+
+func TestPrefixOr(t *testing.T) {
+	defer SetPrefixThreshold(4)
+	condition := map[string]any{"$or": []any{
+		map[string]any{"path": map[string]any{"$regex": "^/api/v1/"}},
+		map[string]any{"path": map[string]any{"$regex": `^/static/.*`}},
+		map[string]any{"path": map[string]any{"$glob": "/health*"}},
+		map[string]any{"path": map[string]any{"$glob": `/weird\*path*`}},
+		map[string]any{"path": map[string]any{"$regex": regexp.MustCompile(`^/admin\.`)}},
+	}}
+	docs := []any{
+		map[string]any{"path": "/api/v1/users"},
+		map[string]any{"path": "/api/v2/users"},
+		map[string]any{"path": "/static/app.js"},
+		map[string]any{"path": "/healthz"},
+		map[string]any{"path": "/weird*path/x"},
+		map[string]any{"path": "/weirdXpath"},
+		map[string]any{"path": "/admin.php"},
+		map[string]any{"path": "/adminXphp"},
+		map[string]any{"path": ""},
+		map[string]any{"path": []any{"/x", "/api/v1/"}},
+		map[string]any{"path": 42},
+		map[string]any{"other": "/api/v1/"},
+	}
+
+	SetPrefixThreshold(0)
+	linear, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	SetPrefixThreshold(4)
+	trie, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	entries, err := trie.(*matcher).ExplainEntries()
+	if err != nil || entries[0].Name != "Prefixes" {
+		t.Fatalf("expected a compiled prefix matcher, got %+v (%v)", entries, err)
+	}
+	for _, doc := range docs {
+		want, err := linear.Match(doc)
+		if err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+		got, err := trie.Match(doc)
+		if err != nil || got != want {
+			t.Fatalf("prefix Match(%v) = %v, %v; linear $or gives %v", doc, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Parse reads a condition written as builder calls, the way it is written
+// in Go, for command lines and REPLs:
+//
+//	Field("age").Gte(18).Lt(65).Or(query.Field("status").Eq("active"))
+//
+// It accepts the functions Field, Value, And, Or, Macro and Raw, optionally
+// prefixed with "query.", and the Cond and FieldCond methods that build
+// conditions. Operands are JSON values, and Raw takes a JSON object; strings
+// may also be Go string literals, double-quoted or backquoted. Whole numbers
+// become int64 and others float64, as mongory.NormalizeJSONNumbers converts
+// them.
+func Parse(src string) (Cond, error) {
+	p := &parser{src: src}
+	value, err := p.parseCall()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.src) {
+			err = p.errorf(p.pos, "unexpected %q after the condition", p.src[p.pos:])
+		}
+	}
+	if err != nil {
+		return Cond{}, fmt.Errorf("query: invalid condition: %w", err)
+	}
+	if f, ok := value.(FieldCond); ok {
+		return f.Cond, nil
+	}
+	return value.(Cond), nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// ident reads a Go identifier, or returns "" when there is none.
+func (p *parser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || p.pos > start && '0' <= c && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// parseCall parses a function call followed by any method calls on its
+// result, which is a Cond or a FieldCond.
+func (p *parser) parseCall() (any, error) {
+	start := p.pos
+	name := p.ident()
+	if name == "query" && p.peek() == '.' {
+		p.pos++
+		name = p.ident()
+	}
+	if name == "" {
+		if p.peek() == 0 {
+			return nil, p.errorf(p.pos, "expected a condition such as Field(\"a\").Eq(1)")
+		}
+		return nil, p.errorf(p.pos, "expected a function name, got %q", p.src[p.pos:])
+	}
+	args, err := p.parseArgs(name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := callFunction(name, args)
+	if err != nil {
+		return nil, p.errorf(start, "%v", err)
+	}
+	for p.peek() == '.' {
+		p.pos++
+		start = p.pos
+		name = p.ident()
+		if name == "" {
+			return nil, p.errorf(p.pos, "expected a method name after .")
+		}
+		if args, err = p.parseArgs(name); err != nil {
+			return nil, err
+		}
+		if value, err = callMethod(value, name, args); err != nil {
+			return nil, p.errorf(start, "%v", err)
+		}
+	}
+	return value, nil
+}
+
+// parseArgs parses the parenthesized arguments of a call to name.
+func (p *parser) parseArgs(name string) ([]any, error) {
+	if p.peek() != '(' {
+		return nil, p.errorf(p.pos, "expected ( after %s", name)
+	}
+	p.pos++
+	var args []any
+	for {
+		if p.peek() == ')' {
+			p.pos++
+			return args, nil
+		}
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+		case 0:
+			return nil, p.errorf(p.pos, "unclosed arguments of %s", name)
+		default:
+			return nil, p.errorf(p.pos, "expected , or ) in the arguments of %s, got %q", name, p.src[p.pos:])
+		}
+	}
+}
+
+// parseArg parses a nested call or an operand value.
+func (p *parser) parseArg() (any, error) {
+	start := p.pos
+	switch word := p.ident(); word {
+	case "":
+	case "true", "false", "null":
+		p.pos = start
+	default:
+		p.pos = start
+		return p.parseCall()
+	}
+	start = p.pos
+	if p.pos == len(p.src) {
+		return nil, p.errorf(p.pos, "unexpected end of the condition")
+	}
+	if c := p.src[p.pos]; c == '`' || c == '"' {
+		// A JSON string that is not also a Go one, with \/ in it, falls
+		// through to the JSON decoder.
+		if quoted, err := strconv.QuotedPrefix(p.src[p.pos:]); err == nil {
+			p.pos += len(quoted)
+			return strconv.Unquote(quoted)
+		}
+	}
+	decoder := json.NewDecoder(strings.NewReader(p.src[p.pos:]))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, p.errorf(start, "invalid operand: %v", err)
+	}
+	p.pos += int(decoder.InputOffset())
+	value, err := mongory.NormalizeJSONNumbers(value)
+	if err != nil {
+		return nil, p.errorf(start, "%v", err)
+	}
+	return value, nil
+}
+
+func callFunction(name string, args []any) (any, error) {
+	switch name {
+	case "Field":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		field, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return Field(field), nil
+	case "Value":
+		if err := arity(name, args, 0); err != nil {
+			return nil, err
+		}
+		return Value(), nil
+	case "And", "Or":
+		exprs, err := exprArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+		if name == "And" {
+			return And(exprs...), nil
+		}
+		return Or(exprs...), nil
+	case "Macro":
+		names := make([]string, len(args))
+		for i, arg := range args {
+			s, err := stringArg(name, arg)
+			if err != nil {
+				return nil, err
+			}
+			names[i] = s
+		}
+		return Macro(names...), nil
+	case "Raw":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		condition, ok := args[0].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("Raw takes a JSON object, got %s", describe(args[0]))
+		}
+		return Raw(condition), nil
+	}
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+func callMethod(recv any, name string, args []any) (any, error) {
+	if name == "And" || name == "Or" {
+		exprs, err := exprArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+		if name == "And" {
+			return And(append([]Expr{recv.(Expr)}, exprs...)...), nil
+		}
+		return Or(append([]Expr{recv.(Expr)}, exprs...)...), nil
+	}
+	f, ok := recv.(FieldCond)
+	if !ok {
+		return nil, fmt.Errorf("only And and Or can follow And, Or, Macro and Raw, not %s", name)
+	}
+	switch name {
+	case "Eq", "Ne", "Gt", "Gte", "Lt", "Lte":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		v, err := valueArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f.with("$"+strings.ToLower(name), v), nil
+	case "In", "Nin":
+		for _, arg := range args {
+			if _, err := valueArg(name, arg); err != nil {
+				return nil, err
+			}
+		}
+		if name == "In" {
+			return f.In(args...), nil
+		}
+		return f.Nin(args...), nil
+	case "Exists", "Present":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		b, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s takes true or false, got %s", name, describe(args[0]))
+		}
+		if name == "Exists" {
+			return f.Exists(b), nil
+		}
+		return f.Present(b), nil
+	case "Regex", "Glob":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		pattern, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if name == "Regex" {
+			return f.Regex(pattern), nil
+		}
+		return f.Glob(pattern), nil
+	case "Rollout":
+		if err := arity(name, args, 2); err != nil {
+			return nil, err
+		}
+		var percent float64
+		switch n := args[0].(type) {
+		case int64:
+			percent = float64(n)
+		case float64:
+			percent = n
+		default:
+			return nil, fmt.Errorf("Rollout takes a percentage, got %s", describe(args[0]))
+		}
+		salt, err := stringArg(name, args[1])
+		if err != nil {
+			return nil, err
+		}
+		return f.Rollout(percent, salt), nil
+	case "Between":
+		if err := arity(name, args, 2); err != nil {
+			return nil, err
+		}
+		for _, arg := range args {
+			if _, err := valueArg(name, arg); err != nil {
+				return nil, err
+			}
+		}
+		return f.Between(args[0], args[1]), nil
+	case "Size":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		n, ok := args[0].(int64)
+		if !ok {
+			return nil, fmt.Errorf("Size takes a whole number, got %s", describe(args[0]))
+		}
+		return f.Size(int(n)), nil
+	case "StrLen", "ElemMatch", "Every", "Not":
+		if err := arity(name, args, 1); err != nil {
+			return nil, err
+		}
+		expr, ok := args[0].(Expr)
+		if !ok {
+			return nil, fmt.Errorf("%s takes a condition such as Value().Gt(1), got %s", name, describe(args[0]))
+		}
+		switch name {
+		case "StrLen":
+			return f.StrLen(expr), nil
+		case "ElemMatch":
+			return f.ElemMatch(expr), nil
+		case "Every":
+			return f.Every(expr), nil
+		}
+		return f.Not(expr), nil
+	}
+	return nil, fmt.Errorf("unknown method %s", name)
+}
+
+func arity(name string, args []any, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%s takes %d argument(s), got %d", name, n, len(args))
+	}
+	return nil
+}
+
+func stringArg(name string, arg any) (string, error) {
+	s, ok := arg.(string)
+	if !ok {
+		return "", fmt.Errorf("%s takes a string, got %s", name, describe(arg))
+	}
+	return s, nil
+}
+
+// valueArg checks that arg is an operand value rather than a condition.
+func valueArg(name string, arg any) (any, error) {
+	if _, ok := arg.(Expr); ok {
+		return nil, fmt.Errorf("%s takes a value, got a condition", name)
+	}
+	return arg, nil
+}
+
+func exprArgs(name string, args []any) ([]Expr, error) {
+	exprs := make([]Expr, len(args))
+	for i, arg := range args {
+		expr, ok := arg.(Expr)
+		if !ok {
+			return nil, fmt.Errorf("%s takes conditions, got %s", name, describe(arg))
+		}
+		exprs[i] = expr
+	}
+	return exprs, nil
+}
+
+// describe names the kind of an argument for error messages.
+func describe(arg any) string {
+	switch v := arg.(type) {
+	case Expr:
+		return "a condition"
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	default:
+		return fmt.Sprint(v)
+	}
+}
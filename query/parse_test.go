@@ -0,0 +1,90 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		src  string
+		want Expr
+	}{
+		{`Field("age").Gte(18).Or(query.Field("status").Eq("active"))`,
+			Field("age").Gte(int64(18)).Or(Field("status").Eq("active"))},
+		{` query.Field( "age" ) .Gte(18).Lt(6.5e1).Ne(1e2) `, Field("age").Gte(int64(18)).Lt(int64(65)).Ne(int64(100))},
+		{"Field(`a\\b`).Regex(`^x\\d+$`)", Field(`a\b`).Regex(`^x\d+$`)},
+		{`Field("s").Eq("café\/")`, Field("s").Eq("café/")},
+		{`Field("a").In(1, "b", null, [1, 2], {"c": true},)`,
+			Field("a").In(int64(1), "b", nil, []any{int64(1), int64(2)}, map[string]any{"c": true})},
+		{`Field("a").Gt(-0.5).Nin().Exists(true).Present(false).Size(2)`, Field("a").Gt(-0.5).Nin().Exists(true).Present(false).Size(2)},
+		{`Field("d").Between("a", "m").Glob("*.go").Rollout(50, "salt")`,
+			Field("d").Between("a", "m").Glob("*.go").Rollout(50, "salt")},
+		{`Field("tags").ElemMatch(Value().In("go")).Every(Value().Ne(""))`,
+			Field("tags").ElemMatch(Value().In("go")).Every(Value().Ne(""))},
+		{`Field("name").StrLen(Value().Gt(2)).Not(Value().Eq("bob"))`,
+			Field("name").StrLen(Value().Gt(int64(2))).Not(Value().Eq("bob"))},
+		{`And(Field("a").Eq(1), Or(), Raw({"b": {"$gt": 2}})).And(Macro("adult", "active"))`,
+			And(Field("a").Eq(int64(1)), Or(), Raw(map[string]any{"b": map[string]any{"$gt": int64(2)}})).And(Macro("adult", "active"))},
+		{`Field("a")`, Cond{}},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.src)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tc.src, err)
+		}
+		if want := And(tc.want).Map(); !reflect.DeepEqual(got.Map(), want) {
+			t.Fatalf("Parse(%s) = %v, want %v", tc.src, got.Map(), want)
+		}
+	}
+
+	q, err := Parse(`Field("age").Gte(18)`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	m, err := q.Matcher()
+	if err != nil {
+		t.Fatalf("Matcher() failed: %v", err)
+	}
+	defer m.Close()
+	if ok, err := m.Match(map[string]any{"age": 20}); err != nil || !ok {
+		t.Fatalf("parsed condition did not match: %v, %v", ok, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"", "offset 0: expected a condition"},
+		{`{"a": 1}`, `offset 0: expected a function name`},
+		{`Field("a").Eq(1) x`, `offset 17: unexpected "x" after the condition`},
+		{`Field("a"`, "unclosed arguments of Field"},
+		{`Field("a").Eq(`, "unexpected end of the condition"},
+		{`Field("a").Eq(1 2)`, "expected , or ) in the arguments of Eq"},
+		{`Field("a").Eq`, "expected ( after Eq"},
+		{`Field("a").`, "expected a method name"},
+		{`Where("a")`, "offset 0: unknown function Where"},
+		{`Field("a").Like("x")`, "offset 11: unknown method Like"},
+		{`Field(1)`, "Field takes a string, got 1"},
+		{`Field("a", "b")`, "Field takes 1 argument(s), got 2"},
+		{`Field("a").Eq(Value())`, "Eq takes a value, got a condition"},
+		{`Field("a").Exists("yes")`, `Exists takes true or false, got "yes"`},
+		{`Field("a").Size(1.5)`, "Size takes a whole number, got 1.5"},
+		{`Field("a").Not(1)`, "Not takes a condition such as Value().Gt(1), got 1"},
+		{`Field("a").Rollout("half", "")`, `Rollout takes a percentage, got "half"`},
+		{`And(1)`, "And takes conditions, got 1"},
+		{`Raw([1])`, "Raw takes a JSON object, got an array"},
+		{`Macro("a").Eq(1)`, "only And and Or can follow And, Or, Macro and Raw, not Eq"},
+		{`Field("a").Eq(tru)`, "offset 17: expected ( after tru"},
+		{`Field("a").Eq({"b": })`, "offset 14: invalid operand"},
+	}
+	for _, tc := range cases {
+		_, err := Parse(tc.src)
+		if err == nil || !strings.HasPrefix(err.Error(), "query: invalid condition: ") || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("Parse(%s) err = %v, want it to contain %q", tc.src, err, tc.want)
+		}
+	}
+}
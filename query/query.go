@@ -0,0 +1,246 @@
+// Package query builds mongory conditions with typed calls instead of nested
+// map literals:
+//
+//	q := query.Field("age").Gte(18).Lt(65).
+//		Or(query.Field("status").Eq("active"))
+//	m, err := q.Matcher()
+//
+// Builders are immutable values: every call returns a new one, so partial
+// queries can be shared and extended freely. Operands are stored as given,
+// not copied; Map returns a deep copy of the result.
+package query
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Expr is anything that builds a condition: a Cond or a FieldCond.
+type Expr interface {
+	condition() map[string]any
+}
+
+// Cond is a built condition. The zero Cond is empty and matches every
+// document.
+type Cond struct {
+	cond map[string]any
+}
+
+// Raw wraps a hand-written condition, for operators the builder does not
+// cover.
+func Raw(condition map[string]any) Cond {
+	return Cond{cond: condition}
+}
+
+// Macro expands the named macros, as {"$macro": names}.
+func Macro(names ...string) Cond {
+	return Cond{cond: map[string]any{mongory.MacroKey: slices.Clone(names)}}
+}
+
+func (c Cond) condition() map[string]any {
+	return c.cond
+}
+
+// And matches documents matching every expression. Empty expressions are
+// dropped and nested $and lists are flattened.
+func And(exprs ...Expr) Cond {
+	var parts []any
+	for _, expr := range exprs {
+		cond := expr.condition()
+		if len(cond) == 0 {
+			continue
+		}
+		parts = appendFlattened(parts, "$and", cond)
+	}
+	switch len(parts) {
+	case 0:
+		return Cond{}
+	case 1:
+		return Cond{cond: parts[0].(map[string]any)}
+	default:
+		return Cond{cond: map[string]any{"$and": parts}}
+	}
+}
+
+// Or matches documents matching any expression; with none it matches
+// nothing. Nested $or lists are flattened.
+func Or(exprs ...Expr) Cond {
+	parts := []any{}
+	for _, expr := range exprs {
+		parts = appendFlattened(parts, "$or", expr.condition())
+	}
+	if len(parts) == 1 {
+		if cond, ok := parts[0].(map[string]any); ok {
+			return Cond{cond: cond}
+		}
+	}
+	return Cond{cond: map[string]any{"$or": parts}}
+}
+
+// appendFlattened appends cond to parts, or the branches of cond when it is
+// only an op list itself.
+func appendFlattened(parts []any, op string, cond map[string]any) []any {
+	if len(cond) == 1 {
+		if branches, ok := cond[op].([]any); ok {
+			return append(parts, branches...)
+		}
+	}
+	if cond == nil {
+		cond = map[string]any{}
+	}
+	return append(parts, cond)
+}
+
+// And matches documents matching c and every other expression.
+func (c Cond) And(others ...Expr) Cond {
+	return And(append([]Expr{c}, others...)...)
+}
+
+// Or matches documents matching c or any other expression.
+func (c Cond) Or(others ...Expr) Cond {
+	return Or(append([]Expr{c}, others...)...)
+}
+
+// Condition returns a snapshot of the built condition.
+func (c Cond) Condition() mongory.Condition {
+	return mongory.NewCondition(c.cond)
+}
+
+// Map returns a copy of the built condition.
+func (c Cond) Map() map[string]any {
+	return c.Condition().Map()
+}
+
+// String returns the built condition's canonical JSON.
+func (c Cond) String() string {
+	return c.Condition().String()
+}
+
+// Validate checks the built condition with mongory.ValidateCondition.
+func (c Cond) Validate() error {
+	return mongory.ValidateCondition(c.cond)
+}
+
+// Matcher compiles the built condition.
+func (c Cond) Matcher() (mongory.Matcher, error) {
+	return mongory.NewMatcher(c.Map())
+}
+
+// FieldCond accumulates operators on one field, or on the matched value
+// itself when built with Value. It is also a Cond, so it can be combined and
+// compiled directly.
+type FieldCond struct {
+	Cond
+	name  string
+	value bool
+	ops   map[string]any
+}
+
+// Field starts a condition on a field. A dotted name reaches into nested
+// documents, and the arrays of them, a level per segment: Field("user.age")
+// builds {"user": {"age": ...}}, since conditions name fields, not paths.
+func Field(name string) FieldCond {
+	return FieldCond{name: name}
+}
+
+// Value starts an operator-only condition on the matched value itself, for
+// ElemMatch, Every, Not and StrLen operands.
+func Value() FieldCond {
+	return FieldCond{value: true}
+}
+
+func (f FieldCond) with(op string, operand any) FieldCond {
+	ops := maps.Clone(f.ops)
+	if ops == nil {
+		ops = map[string]any{}
+	}
+	ops[op] = operand
+	f.ops = ops
+	if f.value {
+		f.Cond = Cond{cond: ops}
+		return f
+	}
+	cond := ops
+	names := strings.Split(f.name, ".")
+	for i := len(names) - 1; i >= 0; i-- {
+		cond = map[string]any{names[i]: cond}
+	}
+	f.Cond = Cond{cond: cond}
+	return f
+}
+
+// Eq matches values equal to v.
+func (f FieldCond) Eq(v any) FieldCond { return f.with("$eq", v) }
+
+// Ne matches values not equal to v.
+func (f FieldCond) Ne(v any) FieldCond { return f.with("$ne", v) }
+
+// Gt matches values greater than v.
+func (f FieldCond) Gt(v any) FieldCond { return f.with("$gt", v) }
+
+// Gte matches values greater than or equal to v.
+func (f FieldCond) Gte(v any) FieldCond { return f.with("$gte", v) }
+
+// Lt matches values less than v.
+func (f FieldCond) Lt(v any) FieldCond { return f.with("$lt", v) }
+
+// Lte matches values less than or equal to v.
+func (f FieldCond) Lte(v any) FieldCond { return f.with("$lte", v) }
+
+// In matches values equal to any of values.
+func (f FieldCond) In(values ...any) FieldCond { return f.with("$in", slices.Clone(values)) }
+
+// Nin matches values equal to none of values.
+func (f FieldCond) Nin(values ...any) FieldCond { return f.with("$nin", slices.Clone(values)) }
+
+// Exists matches when the field is present (true) or absent (false).
+func (f FieldCond) Exists(exists bool) FieldCond { return f.with("$exists", exists) }
+
+// Present matches when the field holds a non-empty value (true) or not
+// (false).
+func (f FieldCond) Present(present bool) FieldCond { return f.with("$present", present) }
+
+// Regex matches strings against a regular expression.
+func (f FieldCond) Regex(pattern string) FieldCond { return f.with("$regex", pattern) }
+
+// Glob matches strings against a shell-style pattern.
+func (f FieldCond) Glob(pattern string) FieldCond { return f.with("$glob", pattern) }
+
+// Rollout matches a stable percentage of values, salted with salt when it
+// is not empty.
+func (f FieldCond) Rollout(percent float64, salt string) FieldCond {
+	if salt == "" {
+		return f.with("$rollout", percent)
+	}
+	return f.with("$rollout", map[string]any{"percent": percent, "salt": salt})
+}
+
+// Between matches values from low to high inclusive, two numbers or two
+// strings.
+func (f FieldCond) Between(low, high any) FieldCond { return f.with("$between", []any{low, high}) }
+
+// StrLen matches strings whose length in characters matches ops, usually
+// built with Value.
+func (f FieldCond) StrLen(ops Expr) FieldCond { return f.with("$strLen", conditionOf(ops)) }
+
+// Size matches arrays with n elements.
+func (f FieldCond) Size(n int) FieldCond { return f.with("$size", n) }
+
+// ElemMatch matches arrays with at least one element matching expr.
+func (f FieldCond) ElemMatch(expr Expr) FieldCond { return f.with("$elemMatch", conditionOf(expr)) }
+
+// Every matches arrays whose elements all match expr.
+func (f FieldCond) Every(expr Expr) FieldCond { return f.with("$every", conditionOf(expr)) }
+
+// Not matches values not matching ops, usually built with Value.
+func (f FieldCond) Not(ops Expr) FieldCond { return f.with("$not", conditionOf(ops)) }
+
+func conditionOf(expr Expr) map[string]any {
+	if cond := expr.condition(); cond != nil {
+		return cond
+	}
+	return map[string]any{}
+}
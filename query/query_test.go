@@ -0,0 +1,122 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	q := Field("age").Gte(18).Or(Field("status").Eq("active"))
+	want := map[string]any{"$or": []any{
+		map[string]any{"age": map[string]any{"$gte": 18}},
+		map[string]any{"status": map[string]any{"$eq": "active"}},
+	}}
+	if got := q.Map(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Map() = %v, want %v", got, want)
+	}
+	if got := q.String(); got != `{"$or":[{"age":{"$gte":18}},{"status":{"$eq":"active"}}]}` {
+		t.Fatalf("String() = %s", got)
+	}
+
+	adult := Field("age").Gte(18)
+	working := adult.Lt(65)
+	if got := adult.Map(); !reflect.DeepEqual(got, map[string]any{"age": map[string]any{"$gte": 18}}) {
+		t.Fatalf("extending a builder changed it: %v", got)
+	}
+	if got := working.Map(); !reflect.DeepEqual(got, map[string]any{"age": map[string]any{"$gte": 18, "$lt": 65}}) {
+		t.Fatalf("working.Map() = %v", got)
+	}
+
+	nested := And(
+		Field("tags").ElemMatch(Value().In("go", "c")),
+		And(Field("a").Exists(true), Cond{}),
+		Field("score").Not(Value().Lt(5)),
+	).Or(Or(Field("vip").Eq(true), Field("staff").Eq(true)))
+	want = map[string]any{"$or": []any{
+		map[string]any{"$and": []any{
+			map[string]any{"tags": map[string]any{"$elemMatch": map[string]any{"$in": []any{"go", "c"}}}},
+			map[string]any{"a": map[string]any{"$exists": true}},
+			map[string]any{"score": map[string]any{"$not": map[string]any{"$lt": 5}}},
+		}},
+		map[string]any{"vip": map[string]any{"$eq": true}},
+		map[string]any{"staff": map[string]any{"$eq": true}},
+	}}
+	if got := nested.Map(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("nested.Map() = %v, want %v", got, want)
+	}
+	if err := nested.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	m, err := nested.Matcher()
+	if err != nil {
+		t.Fatalf("Matcher failed: %v", err)
+	}
+	defer m.Close()
+	docs := []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"tags": []any{"go"}, "a": 1, "score": 7}, true},
+		{map[string]any{"tags": []any{"go"}, "a": 1, "score": 3}, false},
+		{map[string]any{"staff": true}, true},
+	}
+	for _, tc := range docs {
+		if got, err := m.Match(tc.doc); err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, got, err, tc.want)
+		}
+	}
+
+	ranged := Field("name").StrLen(Value().Between(3, 20)).And(Field("score").Between(10, 20))
+	want = map[string]any{"$and": []any{
+		map[string]any{"name": map[string]any{"$strLen": map[string]any{"$between": []any{3, 20}}}},
+		map[string]any{"score": map[string]any{"$between": []any{10, 20}}},
+	}}
+	if got := ranged.Map(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranged.Map() = %v, want %v", got, want)
+	}
+	if err := ranged.Validate(); err != nil {
+		t.Fatalf("ranged Validate failed: %v", err)
+	}
+
+	if got := And().Map(); len(got) != 0 {
+		t.Fatalf("And() = %v, want empty", got)
+	}
+	if got := Or().Map(); !reflect.DeepEqual(got, map[string]any{"$or": []any{}}) {
+		t.Fatalf("Or() = %v", got)
+	}
+	rollout := Field("id").Rollout(10, "flag").And(Macro("adult"), Raw(map[string]any{"x": 1}))
+	if err := Field("id").Rollout(10, "flag").Validate(); err != nil {
+		t.Fatalf("Rollout Validate failed: %v", err)
+	}
+	if got := rollout.String(); got != `{"$and":[{"$macro":["adult"]},{"id":{"$rollout":{"percent":10,"salt":"flag"}}},{"x":1}]}` {
+		t.Fatalf("rollout.String() = %s", got)
+	}
+
+	dotted := Field("user.age").Gte(18).Lt(65).And(Field("items.sku").Eq("a"))
+	want = map[string]any{"$and": []any{
+		map[string]any{"user": map[string]any{"age": map[string]any{"$gte": 18, "$lt": 65}}},
+		map[string]any{"items": map[string]any{"sku": map[string]any{"$eq": "a"}}},
+	}}
+	if got := dotted.Map(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("dotted.Map() = %v, want %v", got, want)
+	}
+	dm, err := dotted.Matcher()
+	if err != nil {
+		t.Fatalf("Matcher failed: %v", err)
+	}
+	defer dm.Close()
+	docs = []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"user": map[string]any{"age": 30}, "items": []any{map[string]any{"sku": "b"}, map[string]any{"sku": "a"}}}, true},
+		{map[string]any{"user": map[string]any{"age": 70}, "items": []any{map[string]any{"sku": "a"}}}, false},
+		{map[string]any{"user.age": 30, "items.sku": "a"}, false},
+	}
+	for _, tc := range docs {
+		if got, err := dm.Match(tc.doc); err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package mongory
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRangeOr(t *testing.T) {
+	defer SetRangeThreshold(4)
+	condition := map[string]any{"$or": []any{
+		map[string]any{"price": map[string]any{"$gte": 0, "$lt": 10}},
+		map[string]any{"price": map[string]any{"$gte": 10, "$lt": 20}},
+		map[string]any{"price": map[string]any{"$gt": 50, "$lte": 60.5}},
+		map[string]any{"price": map[string]any{"$gte": 100}},
+		map[string]any{"price": map[string]any{"$lt": -100}},
+	}}
+	docs := []any{
+		map[string]any{"price": 0},
+		map[string]any{"price": 10},
+		map[string]any{"price": 19.99},
+		map[string]any{"price": 20},
+		map[string]any{"price": 50},
+		map[string]any{"price": 60.5},
+		map[string]any{"price": 60.6},
+		map[string]any{"price": 1e9},
+		map[string]any{"price": -100},
+		map[string]any{"price": -101},
+		map[string]any{"price": math.NaN()},
+		map[string]any{"price": []any{30, 5}},
+		map[string]any{"price": "5"},
+		map[string]any{"other": 5},
+	}
+
+	SetRangeThreshold(0)
+	linear, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	SetRangeThreshold(4)
+	ranged, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	entries, err := ranged.(*matcher).ExplainEntries()
+	if err != nil || entries[0].Name != "Ranges" {
+		t.Fatalf("expected a compiled range matcher, got %+v (%v)", entries, err)
+	}
+	for _, doc := range docs {
+		want, err := linear.Match(doc)
+		if err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+		got, err := ranged.Match(doc)
+		if err != nil || got != want {
+			t.Fatalf("range Match(%v) = %v, %v; linear $or gives %v", doc, got, err, want)
+		}
+	}
+}
+
+func TestRangeOrLargeIntegers(t *testing.T) {
+	defer SetRangeThreshold(4)
+	const big = int64(1) << 53
+	ids := func(bounds ...map[string]any) map[string]any {
+		branches := make([]any, len(bounds))
+		for i, bound := range bounds {
+			branches[i] = map[string]any{"id": bound}
+		}
+		return map[string]any{"$or": branches}
+	}
+	cases := []struct {
+		condition map[string]any
+		matches   []any
+	}{
+		// Integer bounds compare with integers as integers: 2^53+1 is not 2^53.
+		{ids(
+			map[string]any{"$gte": big + 1, "$lt": big + 3},
+			map[string]any{"$gt": big + 10, "$lte": big + 12},
+			map[string]any{"$lt": -big - 1},
+			map[string]any{"$gte": 0, "$lt": 10},
+		), []any{big + 1, big + 2, big + 11, big + 12, -big - 2, int64(5), float64(big + 2)}},
+		// Beside a double bound, integers beyond 2^53 compare as doubles.
+		{ids(
+			map[string]any{"$gte": big + 1, "$lt": big + 3},
+			map[string]any{"$gt": 100.5, "$lte": 200},
+			map[string]any{"$gte": float64(big + 10)},
+			map[string]any{"$gte": 0, "$lt": 10},
+		), []any{big + 1, big + 2, big + 10, int64(101), float64(big + 2), float64(big)}},
+	}
+	values := []any{
+		big - 1, big, big + 1, big + 2, big + 3, big + 9, big + 10, big + 11, big + 12, big + 13,
+		-big - 2, -big - 1, int64(5), int64(101),
+		float64(big), float64(big + 2), float64(big + 4), float64(big + 12), float64(-big - 2),
+	}
+	for _, tc := range cases {
+		SetRangeThreshold(0)
+		linear, err := NewCMatcher(tc.condition, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		SetRangeThreshold(4)
+		ranged, err := NewCMatcher(tc.condition, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		if entries, err := ranged.(*matcher).ExplainEntries(); err != nil || entries[0].Name != "Ranges" {
+			t.Fatalf("expected a compiled range matcher, got %+v (%v)", entries, err)
+		}
+		for _, value := range values {
+			doc := map[string]any{"id": value}
+			want, err := linear.Match(doc)
+			if err != nil {
+				t.Fatalf("Match failed: %v", err)
+			}
+			got, err := ranged.Match(doc)
+			if err != nil || got != want {
+				t.Fatalf("range Match(%v %T) = %v, %v; linear $or gives %v", value, value, got, err, want)
+			}
+		}
+		for _, value := range tc.matches {
+			if ok, _ := ranged.Match(map[string]any{"id": value}); !ok {
+				t.Fatalf("range Match(%v %T) = false, want true", value, value)
+			}
+		}
+		if ok, _ := ranged.Match(map[string]any{"id": big}); ok {
+			t.Fatalf("range Match(2^53) = true against a lower bound of 2^53+1")
+		}
+		linear.Close()
+		ranged.Close()
+	}
+}
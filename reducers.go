@@ -0,0 +1,80 @@
+package mongory
+
+import (
+	"sort"
+	"strings"
+)
+
+// reducers are the operators that reduce an array field to one number.
+var reducers = map[string]bool{
+	"$sumOf": true,
+	"$minOf": true,
+	"$maxOf": true,
+	"$avgOf": true,
+}
+
+// expandReducerPaths rewrites dotted fields whose condition is only array
+// reducers into the path form the reducers take, so that
+// {"items.price": {"$sumOf": {"$gt": 100}}} compiles as
+// {"items": {"$sumOf": {"price": {"$gt": 100}}}}. Conditions name fields, not
+// paths, so other dotted keys are left alone. Rewrites landing on a field
+// the condition already tests are joined to it with $and.
+func expandReducerPaths(condition map[string]any) map[string]any {
+	expanded, _ := expandReducerValue(condition).(map[string]any)
+	return expanded
+}
+
+func expandReducerValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		var rewrites []string
+		for key, item := range v {
+			head, rest, dotted := strings.Cut(key, ".")
+			ops, ok := item.(map[string]any)
+			if !dotted || strings.HasPrefix(key, "$") || head == "" || rest == "" || !ok || !onlyReducers(ops) {
+				out[key] = expandReducerValue(item)
+				continue
+			}
+			rewrites = append(rewrites, key)
+		}
+		sort.Strings(rewrites)
+		for i, key := range rewrites {
+			head, rest, _ := strings.Cut(key, ".")
+			rewritten := map[string]any{}
+			for op, operand := range v[key].(map[string]any) {
+				rewritten[op] = map[string]any{rest: expandReducerValue(operand)}
+			}
+			if _, taken := out[head]; !taken && (i+1 == len(rewrites) || !strings.HasPrefix(rewrites[i+1], head+".")) {
+				out[head] = rewritten
+				continue
+			}
+			and, isList := out["$and"].([]any)
+			if !isList && out["$and"] != nil {
+				and = []any{map[string]any{"$and": out["$and"]}}
+			}
+			out["$and"] = append(and, map[string]any{head: rewritten})
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = expandReducerValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func onlyReducers(ops map[string]any) bool {
+	if len(ops) == 0 {
+		return false
+	}
+	for op := range ops {
+		if !reducers[op] {
+			return false
+		}
+	}
+	return true
+}
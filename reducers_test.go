@@ -0,0 +1,116 @@
+package mongory
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReducers(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	cart := map[string]any{
+		"items": []any{
+			map[string]any{"price": 60, "qty": 2},
+			map[string]any{"price": 45.5, "qty": 1},
+			map[string]any{"sku": "gift-card"},
+		},
+		"scores": []any{3, 9, "n/a", 6},
+		"empty":  []any{},
+		"total":  7,
+		"orders": []any{
+			map[string]any{"items": []any{map[string]any{"price": 10}, map[string]any{"price": 20}}},
+			map[string]any{"items": map[string]any{"price": 5}},
+		},
+	}
+	cases := []struct {
+		condition map[string]any
+		want      bool
+	}{
+		{map[string]any{"items": map[string]any{"$sumOf": map[string]any{"price": map[string]any{"$gt": 100}}}}, true},
+		{map[string]any{"items": map[string]any{"$sumOf": map[string]any{"price": 105.5}}}, true},
+		{map[string]any{"items.price": map[string]any{"$sumOf": map[string]any{"$gt": 110}}}, false},
+		{map[string]any{"items.price": map[string]any{"$minOf": 45.5, "$maxOf": 60}}, true},
+		{map[string]any{"items.price": map[string]any{"$sumOf": map[string]any{"$gt": 100}}, "items.qty": map[string]any{"$sumOf": 3}}, true},
+		{map[string]any{"items.price": map[string]any{"$sumOf": map[string]any{"$gt": 100}}, "items.qty": map[string]any{"$sumOf": 4}}, false},
+		{map[string]any{"items": map[string]any{"$size": 3}, "items.qty": map[string]any{"$maxOf": 2}}, true},
+		{map[string]any{"scores": map[string]any{"$sumOf": 18}}, true},
+		{map[string]any{"scores": map[string]any{"$minOf": 3, "$maxOf": 9}}, true},
+		{map[string]any{"scores": map[string]any{"$avgOf": 6}}, true},
+		{map[string]any{"scores": map[string]any{"$avgOf": map[string]any{"$lt": 6}}}, false},
+		{map[string]any{"empty": map[string]any{"$sumOf": 0}}, true},
+		{map[string]any{"empty": map[string]any{"$avgOf": map[string]any{"$exists": false}}}, true},
+		{map[string]any{"empty": map[string]any{"$maxOf": map[string]any{"$gte": 0}}}, false},
+		{map[string]any{"total": map[string]any{"$sumOf": 7}}, true},
+		{map[string]any{"missing": map[string]any{"$sumOf": 0}}, false},
+		{map[string]any{"orders.items.price": map[string]any{"$sumOf": 35}}, true},
+		{map[string]any{"$or": []any{
+			map[string]any{"scores.x": map[string]any{"$sumOf": 1}},
+			map[string]any{"items.price": map[string]any{"$maxOf": 60}},
+		}}, true},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewMatcher(tc.condition)
+			if err != nil {
+				t.Fatalf("NewMatcher(%v) failed: %v", tc.condition, err)
+			}
+			got, err := m.Match(cart)
+			m.Close()
+			if err != nil || got != tc.want {
+				t.Fatalf("%v: Match with %v = %v, %v; want %v", mode, tc.condition, got, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestReducerSums(t *testing.T) {
+	// Integer sums that overflow continue as doubles.
+	for _, tc := range []struct {
+		condition map[string]any
+		values    []any
+	}{
+		{map[string]any{"$gt": 9.2e18}, []any{math.MaxInt64, 10}},
+		{map[string]any{"$lt": -1.8e19}, []any{math.MinInt64, math.MinInt64}},
+	} {
+		m, err := NewMatcher(map[string]any{"n": map[string]any{"$sumOf": tc.condition}})
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+		got, err := m.Match(map[string]any{"n": tc.values})
+		m.Close()
+		if err != nil || !got {
+			t.Fatalf("sum of %v = %v, %v; want a match for %v", tc.values, got, err, tc.condition)
+		}
+	}
+
+	exact, err := NewMatcherWithOptions(map[string]any{"ids": map[string]any{"$maxOf": 9007199254740993}}, MatcherOptions{Numeric: NumericExact})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer exact.Close()
+	if got, err := exact.Match(map[string]any{"ids": []any{9007199254740993, 1}}); err != nil || !got {
+		t.Fatalf("$maxOf should keep integers exact, got %v, %v", got, err)
+	}
+}
+
+func TestExpandReducerPaths(t *testing.T) {
+	got := expandReducerPaths(map[string]any{
+		"a.b":   map[string]any{"$sumOf": 1},
+		"a.c":   map[string]any{"$maxOf": 2},
+		"x.y":   map[string]any{"$gt": 1},
+		"$and":  []any{map[string]any{"z": 1}},
+		"n.m.k": map[string]any{"$avgOf": map[string]any{"$lt": 3}},
+	})
+	want := map[string]any{
+		"a":   map[string]any{"$maxOf": map[string]any{"c": 2}},
+		"x.y": map[string]any{"$gt": 1},
+		"$and": []any{
+			map[string]any{"z": 1},
+			map[string]any{"a": map[string]any{"$sumOf": map[string]any{"b": 1}}},
+		},
+		"n": map[string]any{"$avgOf": map[string]any{"m.k": map[string]any{"$lt": 3}}},
+	}
+	if !EquivalentConditions(got, want) {
+		t.Fatalf("expandReducerPaths = %s; want %s", CanonicalJSON(got), CanonicalJSON(want))
+	}
+}
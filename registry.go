@@ -0,0 +1,255 @@
+package mongory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry holds named conditions and compiles each on demand. A matcher
+// left unused for the registry's idle TTL is closed, releasing its native
+// memory, and compiled again the next time its name is matched, so services
+// with a long tail of rarely used rules keep only the busy ones compiled.
+type Registry struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+	stop    chan struct{}
+	closed  bool
+}
+
+type registryEntry struct {
+	// condition is compiled again after eviction. It is a snapshot with
+	// macros expanded, so neither the caller's map nor later macro changes
+	// alter what the name matches.
+	condition Condition
+	opts      MatcherOptions
+	// mu is held shared while the matcher is used and exclusively while it
+	// is compiled or closed.
+	mu       sync.RWMutex
+	matcher  Matcher
+	removed  bool         // replaced, removed or closed with the registry
+	lastUsed atomic.Int64 // UnixNano
+	compiles atomic.Int64
+}
+
+// NewRegistry returns a registry that closes matchers idle for longer than
+// idleTTL, checking every idleTTL/2. Zero or less never closes them. Close
+// the registry to stop the check and free every matcher.
+func NewRegistry(idleTTL time.Duration) *Registry {
+	r := &Registry{ttl: idleTTL, entries: map[string]*registryEntry{}, stop: make(chan struct{})}
+	if idleTTL > 0 {
+		go r.evictLoop(idleTTL / 2)
+	}
+	return r
+}
+
+func (r *Registry) evictLoop(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.EvictIdle(r.ttl)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Register compiles condition under name, replacing and closing any matcher
+// registered under it before. The condition is compiled now, so an invalid
+// one is reported here rather than on first use. Its macros are expanded and
+// the result copied, so changing condition or redefining a macro afterwards
+// does not change what name matches, even once its matcher is evicted.
+func (r *Registry) Register(name string, condition map[string]any, opts MatcherOptions) error {
+	expanded, err := expandMacros(condition)
+	if err != nil {
+		return fmt.Errorf("mongory: register %q: %w", name, err)
+	}
+	snapshot := NewCondition(expanded)
+	m, err := NewMatcherWithOptions(snapshot.Map(), opts)
+	if err != nil {
+		return fmt.Errorf("mongory: register %q: %w", name, err)
+	}
+	e := &registryEntry{condition: snapshot, opts: opts, matcher: m}
+	e.lastUsed.Store(time.Now().UnixNano())
+	e.compiles.Store(1)
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		m.Close()
+		return fmt.Errorf("mongory: register %q: registry is closed", name)
+	}
+	old := r.entries[name]
+	r.entries[name] = e
+	r.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return nil
+}
+
+// Remove closes and forgets the matcher registered under name.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	e := r.entries[name]
+	delete(r.entries, name)
+	r.mu.Unlock()
+	if e != nil {
+		e.close()
+	}
+}
+
+// Names returns the registered names, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Match matches doc against the condition registered under name, compiling
+// it first if it was evicted.
+func (r *Registry) Match(name string, doc any) (bool, error) {
+	var matched bool
+	err := r.Use(name, func(m Matcher) error {
+		var err error
+		matched, err = m.Match(doc)
+		return err
+	})
+	return matched, err
+}
+
+// Use calls fn with the matcher registered under name, compiling it first
+// if it was evicted. The matcher is not evicted while fn runs, and must not
+// be kept or closed once fn returns.
+func (r *Registry) Use(name string, fn func(Matcher) error) error {
+	r.mu.RLock()
+	e := r.entries[name]
+	r.mu.RUnlock()
+	if e == nil {
+		return fmt.Errorf("mongory: no condition registered as %q", name)
+	}
+	m, err := e.acquire()
+	if err != nil {
+		return fmt.Errorf("mongory: compile %q: %w", name, err)
+	}
+	defer e.mu.RUnlock()
+	e.lastUsed.Store(time.Now().UnixNano())
+	return fn(m)
+}
+
+var errRegistryRemoved = errors.New("mongory: condition was removed from the registry")
+
+// acquire returns the entry's matcher with e.mu held shared, compiling it
+// if it was evicted.
+func (e *registryEntry) acquire() (Matcher, error) {
+	for {
+		e.mu.RLock()
+		if e.matcher != nil {
+			return e.matcher, nil
+		}
+		e.mu.RUnlock()
+		e.mu.Lock()
+		if e.removed {
+			e.mu.Unlock()
+			return nil, errRegistryRemoved
+		}
+		if e.matcher == nil {
+			m, err := NewMatcherWithOptions(e.condition.Map(), e.opts)
+			if err != nil {
+				e.mu.Unlock()
+				return nil, err
+			}
+			e.matcher = m
+			e.compiles.Add(1)
+		}
+		e.mu.Unlock()
+	}
+}
+
+// close closes the entry's matcher, waiting for matches in progress.
+func (e *registryEntry) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.removed = true
+	if e.matcher != nil {
+		e.matcher.Close()
+		e.matcher = nil
+	}
+}
+
+// EvictIdle closes the matchers not used for longer than idle and returns
+// how many it closed. Matchers in use are skipped. The registry calls it
+// with its own TTL; call it directly to shed memory on demand.
+func (r *Registry) EvictIdle(idle time.Duration) int {
+	cutoff := time.Now().Add(-idle).UnixNano()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	evicted := 0
+	for _, e := range r.entries {
+		if e.lastUsed.Load() > cutoff || !e.mu.TryLock() {
+			continue
+		}
+		if e.matcher != nil {
+			e.matcher.Close()
+			e.matcher = nil
+			evicted++
+		}
+		e.mu.Unlock()
+	}
+	return evicted
+}
+
+// RegistryStats describes a registry's matchers.
+type RegistryStats struct {
+	// Registered counts the registered conditions and Compiled those whose
+	// matcher is compiled now.
+	Registered int
+	Compiled   int
+	// Compiles counts compilations, including the first of each condition.
+	Compiles int64
+}
+
+// Stats returns the registry's counts.
+func (r *Registry) Stats() RegistryStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := RegistryStats{Registered: len(r.entries)}
+	for _, e := range r.entries {
+		e.mu.RLock()
+		if e.matcher != nil {
+			s.Compiled++
+		}
+		e.mu.RUnlock()
+		s.Compiles += e.compiles.Load()
+	}
+	return s
+}
+
+// Close stops the idle check and closes every matcher. Registering after
+// Close fails; matching fails for every name.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	entries := r.entries
+	r.entries = map[string]*registryEntry{}
+	close(r.stop)
+	r.mu.Unlock()
+	for _, e := range entries {
+		e.close()
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package mongory
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(0)
+	defer r.Close()
+	if err := r.Register("adults", map[string]any{"age": map[string]any{"$gte": 18}}, MatcherOptions{}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register("bad", map[string]any{"$and": "bad"}, MatcherOptions{}); err == nil {
+		t.Fatalf("Register should reject invalid conditions")
+	}
+	if ok, err := r.Match("adults", map[string]any{"age": 20}); err != nil || !ok {
+		t.Fatalf("Match = %v, %v; want true", ok, err)
+	}
+	if _, err := r.Match("missing", map[string]any{}); err == nil {
+		t.Fatalf("Match should fail for unregistered names")
+	}
+
+	if n := r.EvictIdle(time.Hour); n != 0 {
+		t.Fatalf("EvictIdle(1h) = %d, want 0", n)
+	}
+	if n := r.EvictIdle(0); n != 1 {
+		t.Fatalf("EvictIdle(0) = %d, want 1", n)
+	}
+	if s := r.Stats(); s != (RegistryStats{Registered: 1, Compiled: 0, Compiles: 1}) {
+		t.Fatalf("Stats = %+v after eviction", s)
+	}
+	if ok, err := r.Match("adults", map[string]any{"age": 10}); err != nil || ok {
+		t.Fatalf("Match after eviction = %v, %v; want false", ok, err)
+	}
+	if s := r.Stats(); s != (RegistryStats{Registered: 1, Compiled: 1, Compiles: 2}) {
+		t.Fatalf("Stats = %+v after recompiling", s)
+	}
+
+	r.Remove("adults")
+	if names := r.Names(); len(names) != 0 {
+		t.Fatalf("Names = %v after Remove", names)
+	}
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	if err := DefineMacro("registry_adult", map[string]any{"age": map[string]any{"$gte": 18}}); err != nil {
+		t.Fatalf("DefineMacro failed: %v", err)
+	}
+	defer UndefineMacro("registry_adult")
+	r := NewRegistry(0)
+	defer r.Close()
+	condition := map[string]any{
+		"status": map[string]any{"$eq": "active"},
+		"$macro": "registry_adult",
+	}
+	if err := r.Register("active", condition, MatcherOptions{}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Neither the caller's map nor the macro changes what was registered,
+	// even once the matcher is evicted and compiled again.
+	condition["status"].(map[string]any)["$eq"] = "banned"
+	delete(condition, "$macro")
+	if err := DefineMacro("registry_adult", map[string]any{"age": map[string]any{"$gte": 65}}); err != nil {
+		t.Fatalf("DefineMacro failed: %v", err)
+	}
+	if n := r.EvictIdle(0); n != 1 {
+		t.Fatalf("EvictIdle(0) = %d, want 1", n)
+	}
+	for _, tc := range []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"status": "active", "age": 30}, true},
+		{map[string]any{"status": "banned", "age": 30}, false},
+		{map[string]any{"status": "active", "age": 10}, false},
+	} {
+		if ok, err := r.Match("active", tc.doc); err != nil || ok != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, ok, err, tc.want)
+		}
+	}
+	if s := r.Stats(); s.Compiles != 2 {
+		t.Fatalf("Stats = %+v, want the condition compiled again", s)
+	}
+}
+
+func TestRegistryIdleEviction(t *testing.T) {
+	r := NewRegistry(20 * time.Millisecond)
+	if err := r.Register("active", map[string]any{"status": "active"}, MatcherOptions{}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for r.Stats().Compiled != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle matcher was not evicted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if ok, err := r.Match("active", map[string]any{"status": "active"}); err != nil || !ok {
+					t.Errorf("Match = %v, %v; want true", ok, err)
+					return
+				}
+				r.EvictIdle(0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	r.Close()
+	if _, err := r.Match("active", map[string]any{}); err == nil {
+		t.Fatalf("Match should fail after Close")
+	}
+	if err := r.Register("active", map[string]any{}, MatcherOptions{}); err == nil {
+		t.Fatalf("Register should fail after Close")
+	}
+}
@@ -0,0 +1,158 @@
+// Package routing maps conditions to destinations, the alert-routing pattern
+// of matching each event against every registered route:
+//
+//	t, err := routing.NewTable(
+//		routing.Route{Name: "pager", Destinations: []string{"pagerduty"},
+//			Condition: map[string]any{"severity": "critical"}},
+//		routing.Route{Name: "db-team", Destinations: []string{"slack:#db"},
+//			Condition: map[string]any{"service": map[string]any{"$glob": "db-*"}}},
+//	)
+//	result, err := t.Route(event)
+//
+// Every matching route fires; there is no first-match short circuit.
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Route sends events matching Condition to Destinations.
+type Route struct {
+	Name         string
+	Condition    map[string]any
+	Destinations []string
+}
+
+// Stats counts a route's activity since it was added.
+type Stats struct {
+	Evaluated uint64 `json:"evaluated"`
+	Matched   uint64 `json:"matched"`
+	Errors    uint64 `json:"errors"`
+}
+
+// Result lists what one event was routed to.
+type Result struct {
+	// Routes are the names of the matching routes in table order.
+	Routes []string `json:"routes"`
+	// Destinations are the matching routes' destinations, in route order
+	// without duplicates.
+	Destinations []string `json:"destinations"`
+}
+
+type route struct {
+	Route
+	matcher   mongory.Matcher
+	evaluated atomic.Uint64
+	matched   atomic.Uint64
+	errors    atomic.Uint64
+}
+
+func (r *route) match(event any) (bool, error) {
+	r.evaluated.Add(1)
+	matched, err := r.matcher.Match(event)
+	switch {
+	case err != nil:
+		r.errors.Add(1)
+	case matched:
+		r.matched.Add(1)
+	}
+	return matched, err
+}
+
+// Table is an ordered set of routes. It is safe for concurrent use, and
+// routes can be added or removed while events are routed.
+type Table struct {
+	mu     sync.RWMutex
+	routes []*route
+}
+
+// NewTable compiles routes in order.
+func NewTable(routes ...Route) (*Table, error) {
+	t := &Table{}
+	for _, r := range routes {
+		if err := t.Add(r); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Add compiles r and appends it. Route names must be unique.
+func (t *Table) Add(r Route) error {
+	if r.Name == "" {
+		return errors.New("routing: route name must not be empty")
+	}
+	matcher, err := mongory.NewMatcher(r.Condition)
+	if err != nil {
+		return fmt.Errorf("routing: route %q: %w", r.Name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, existing := range t.routes {
+		if existing.Name == r.Name {
+			return fmt.Errorf("routing: route %q already exists", r.Name)
+		}
+	}
+	t.routes = append(t.routes, &route{Route: r, matcher: matcher})
+	return nil
+}
+
+// Remove deletes the named route and its statistics, reporting whether it
+// existed.
+func (t *Table) Remove(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, r := range t.routes {
+		if r.Name == name {
+			t.routes = append(t.routes[:i:i], t.routes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Route evaluates event against every route. A route that fails to match is
+// skipped and its error joined into the returned error, so one bad route
+// does not stop delivery to the others.
+func (t *Table) Route(event any) (Result, error) {
+	t.mu.RLock()
+	routes := t.routes
+	t.mu.RUnlock()
+	var result Result
+	var errs []error
+	seen := map[string]bool{}
+	for _, r := range routes {
+		matched, err := r.match(event)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("routing: route %q: %w", r.Name, err))
+			continue
+		}
+		if !matched {
+			continue
+		}
+		result.Routes = append(result.Routes, r.Name)
+		for _, destination := range r.Destinations {
+			if !seen[destination] {
+				seen[destination] = true
+				result.Destinations = append(result.Destinations, destination)
+			}
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// Stats returns every route's counters by name.
+func (t *Table) Stats() map[string]Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	stats := make(map[string]Stats, len(t.routes))
+	for _, r := range t.routes {
+		stats[r.Name] = Stats{Evaluated: r.evaluated.Load(), Matched: r.matched.Load(), Errors: r.errors.Load()}
+	}
+	return stats
+}
@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	table, err := NewTable(
+		Route{Name: "pager", Destinations: []string{"pagerduty", "slack:#ops"}, Condition: map[string]any{"severity": "critical"}},
+		Route{Name: "db-team", Destinations: []string{"slack:#db", "slack:#ops"}, Condition: map[string]any{"service": map[string]any{"$glob": "db-*"}}},
+		Route{Name: "archive", Destinations: []string{"s3"}, Condition: map[string]any{}},
+	)
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+	result, err := table.Route(map[string]any{"severity": "critical", "service": "db-main"})
+	if err != nil {
+		t.Fatalf("Route failed: %v", err)
+	}
+	if !slices.Equal(result.Routes, []string{"pager", "db-team", "archive"}) {
+		t.Fatalf("Routes = %v", result.Routes)
+	}
+	if !slices.Equal(result.Destinations, []string{"pagerduty", "slack:#ops", "slack:#db", "s3"}) {
+		t.Fatalf("Destinations = %v", result.Destinations)
+	}
+
+	result, err = table.Route(map[string]any{"severity": "info", "service": "web"})
+	if err != nil || !slices.Equal(result.Destinations, []string{"s3"}) {
+		t.Fatalf("Route(info) = %v, %v; want [s3]", result.Destinations, err)
+	}
+
+	// A failing document is reported but the other routes are still tried.
+	if _, err := table.Route(map[int]any{1: 1}); err == nil {
+		t.Fatalf("Route(map[int]any) succeeded, want an error")
+	}
+
+	stats := table.Stats()
+	if got := stats["pager"]; got.Evaluated != 3 || got.Matched != 1 || got.Errors != 1 {
+		t.Fatalf("pager stats = %+v", got)
+	}
+	// The empty condition never reads the document, so it matches all three.
+	if got := stats["archive"]; got.Matched != 3 {
+		t.Fatalf("archive stats = %+v", got)
+	}
+
+	if err := table.Add(Route{Name: "pager", Condition: map[string]any{}}); err == nil {
+		t.Fatalf("Add accepted a duplicate route name")
+	}
+	if !table.Remove("archive") || table.Remove("archive") {
+		t.Fatalf("Remove did not report existence correctly")
+	}
+	if _, ok := table.Stats()["archive"]; ok {
+		t.Fatalf("removed route still has stats")
+	}
+}
+
+func TestRouteConcurrent(t *testing.T) {
+	table, err := NewTable(Route{Name: "even", Destinations: []string{"a"}, Condition: map[string]any{"n": map[string]any{"$in": []any{0, 2, 4, 6, 8}}}})
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := table.Route(map[string]any{"n": i % 10}); err != nil {
+					t.Errorf("Route failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := table.Stats()["even"]; got.Evaluated != 800 || got.Matched != 400 {
+		t.Fatalf("stats = %+v, want 800 evaluated and 400 matched", got)
+	}
+}
@@ -0,0 +1,87 @@
+package mongory
+
+import "encoding/json"
+
+//go:generate go run ./cmd/mongory schema -o schema/condition.schema.json
+
+// ConditionSchema returns a JSON Schema (draft 2020-12) describing valid
+// condition documents, covering every operator known to the package. Editors
+// can use it to validate and autocomplete rule files.
+func ConditionSchema() ([]byte, error) {
+	operators := map[string]any{}
+	for _, doc := range Operators() {
+		operand := operandSchema(doc.operand)
+		operand["description"] = doc.Summary
+		operators[doc.Name] = operand
+	}
+	schema := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "https://github.com/mongoryhq/mongory-go/schema/condition.schema.json",
+		"title":   "Mongory condition",
+		"$ref":    "#/$defs/condition",
+		"$defs": map[string]any{
+			"condition": map[string]any{
+				"type":                 "object",
+				"properties":           operators,
+				"patternProperties":    map[string]any{"^[^$]": map[string]any{"$ref": "#/$defs/fieldValue"}},
+				"additionalProperties": false,
+			},
+			"fieldValue": map[string]any{
+				"anyOf": []any{
+					map[string]any{"$ref": "#/$defs/condition"},
+					map[string]any{"not": map[string]any{"type": "object"}},
+				},
+			},
+		},
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func operandSchema(kind operandKind) map[string]any {
+	switch kind {
+	case operandArray:
+		return map[string]any{"type": "array"}
+	case operandBoolean:
+		return map[string]any{"type": "boolean"}
+	case operandString:
+		return map[string]any{"type": "string"}
+	case operandCondition:
+		return map[string]any{"$ref": "#/$defs/condition"}
+	case operandConditions:
+		return map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/condition"}}
+	case operandFieldValue:
+		return map[string]any{"$ref": "#/$defs/fieldValue"}
+	case operandRollout:
+		return map[string]any{"anyOf": []any{
+			map[string]any{"type": "number", "minimum": 0, "maximum": 100},
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"percent": map[string]any{"type": "number", "minimum": 0, "maximum": 100},
+					"salt":    map[string]any{"type": "string"},
+				},
+				"required":             []any{"percent"},
+				"additionalProperties": false,
+			},
+		}}
+	case operandDistinct:
+		return map[string]any{"anyOf": []any{
+			map[string]any{"const": true},
+			map[string]any{"type": "string", "minLength": 1},
+		}}
+	case operandRange:
+		return map[string]any{
+			"type":     "array",
+			"items":    map[string]any{"type": []any{"number", "string"}},
+			"minItems": 2,
+			"maxItems": 2,
+		}
+	case operandMacro:
+		return map[string]any{"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,38 @@
+package mongory
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestConditionSchema(t *testing.T) {
+	schema, err := ConditionSchema()
+	if err != nil {
+		t.Fatalf("ConditionSchema failed: %v", err)
+	}
+	var decoded struct {
+		Defs struct {
+			Condition struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"condition"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(schema, &decoded); err != nil {
+		t.Fatalf("ConditionSchema produced invalid JSON: %v", err)
+	}
+	for _, name := range []string{"$and", "$in", "$regex", MacroKey} {
+		if _, ok := decoded.Defs.Condition.Properties[name]; !ok {
+			t.Errorf("schema is missing operator %s", name)
+		}
+	}
+
+	committed, err := os.ReadFile("schema/condition.schema.json")
+	if err != nil {
+		t.Fatalf("reading committed schema: %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(committed), bytes.TrimSpace(schema)) {
+		t.Errorf("schema/condition.schema.json is stale; run go generate")
+	}
+}
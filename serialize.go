@@ -0,0 +1,119 @@
+package mongory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// planFormat and planVersion identify the bytes of MarshalBinary. The
+// version changes when the format does, not when the engine does: a plan an
+// engine compiles differently fails LoadMatcher with ErrPlanMismatch.
+const (
+	planFormat  = "mongory-plan"
+	planVersion = 1
+)
+
+// ErrPlanVersion is returned by LoadMatcher for bytes that are not a plan
+// of a format version this package reads.
+var ErrPlanVersion = errors.New("mongory: unsupported plan format")
+
+// serializedPlan is the encoding of MarshalBinary.
+type serializedPlan struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Name    string `json:"name,omitempty"`
+	Numeric string `json:"numeric,omitempty"`
+	// ParseTimes is MatcherOptions.ParseTimes.
+	ParseTimes bool `json:"parseTimes,omitempty"`
+	// Hash is the ConditionHash of the plan's canonical condition, checked
+	// on load so a corrupted or edited plan is not compiled.
+	Hash string       `json:"hash"`
+	Plan planJSONNode `json:"plan"`
+}
+
+// MarshalBinary serializes the matcher as a plan: its condition with macros
+// expanded, in canonical form, the compiled tree ExplainJSON renders, and
+// the options that change results, its NumericMode, ParseTimes and Name. LoadMatcher
+// compiles it again, so fleets can distribute conditions validated and
+// normalized once instead of raw user input. Conditions holding Go values
+// with no JSON form, such as $func predicates, fail to serialize.
+func (m *matcher) MarshalBinary() ([]byte, error) {
+	if err := checkSerializable(m.condition.Map()); err != nil {
+		return nil, err
+	}
+	explained, err := m.ExplainJSON()
+	if err != nil {
+		return nil, err
+	}
+	options := m.Options()
+	plan := serializedPlan{Format: planFormat, Version: planVersion, Name: m.name, ParseTimes: options.ParseTimes, Hash: m.condition.Hash()}
+	if options.ExactNumbers {
+		plan.Numeric = NumericExact.String()
+	}
+	if err := json.Unmarshal(explained, &plan.Plan); err != nil {
+		return nil, err
+	}
+	return json.Marshal(plan)
+}
+
+// checkSerializable fails for the operands canonical JSON cannot carry.
+func checkSerializable(condition map[string]any) error {
+	var check func(path string, v any) error
+	check = func(path string, v any) error {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, item := range v {
+				if err := check(path+"."+key, item); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for _, item := range v {
+				if err := check(path, item); err != nil {
+					return err
+				}
+			}
+		case func(any) bool, func(context.Context, any) bool:
+			return fmt.Errorf("mongory: cannot serialize the Go function at %s", path[1:])
+		}
+		return nil
+	}
+	return check("", condition)
+}
+
+// LoadMatcher compiles a plan serialized by MarshalBinary with the options it
+// was serialized with. It fails with ErrPlanVersion for bytes of another
+// format, with ErrInvalidCondition if the condition does not match the
+// plan's hash, and with ErrPlanMismatch if the condition compiles to a
+// different tree than the plan records, as after an engine upgrade that
+// changes how conditions compile.
+func LoadMatcher(data []byte) (Matcher, error) {
+	var plan serializedPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPlanVersion, err)
+	}
+	if plan.Format != planFormat || plan.Version != planVersion {
+		return nil, fmt.Errorf("%w: %q version %d", ErrPlanVersion, plan.Format, plan.Version)
+	}
+	opts := MatcherOptions{Name: plan.Name, ParseTimes: plan.ParseTimes}
+	switch plan.Numeric {
+	case "":
+	case NumericExact.String():
+		opts.Numeric = NumericExact
+	default:
+		return nil, fmt.Errorf("%w: numeric mode %q", ErrPlanVersion, plan.Numeric)
+	}
+	if plan.Plan.Canonical == nil {
+		return nil, fmt.Errorf("mongory: plan has no condition: %w", ErrInvalidCondition)
+	}
+	condition, err := ParseConditionJSON(plan.Plan.Canonical)
+	if err != nil {
+		return nil, err
+	}
+	if ConditionHash(condition) != plan.Hash {
+		return nil, fmt.Errorf("mongory: plan condition does not match its hash: %w", ErrInvalidCondition)
+	}
+	return compileSnapshot(condition, opts, &plan.Plan)
+}
@@ -0,0 +1,110 @@
+package mongory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestLoadMatcher(t *testing.T) {
+	condition := map[string]any{
+		"$or":  []any{map[string]any{"age": map[string]any{"$gte": 18}}, map[string]any{"vip": true}},
+		"name": regexp.MustCompile("^a"),
+		"id":   map[string]any{"$in": []any{int64(9007199254740993), 3}},
+	}
+	m, err := NewMatcherWithOptions(condition, MatcherOptions{Name: "plans", Numeric: NumericExact})
+	if err != nil {
+		t.Fatalf("NewMatcherWithOptions failed: %v", err)
+	}
+	defer m.Close()
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	loaded, err := LoadMatcher(data)
+	if err != nil {
+		t.Fatalf("LoadMatcher failed: %v\n%s", err, data)
+	}
+	defer loaded.Close()
+	if loaded.Condition().Hash() != m.Condition().Hash() || unwrapMatcher(loaded).name != "plans" {
+		t.Fatalf("loaded %s named %q, want %s", loaded, unwrapMatcher(loaded).name, m)
+	}
+	for _, doc := range []map[string]any{
+		{"name": "ann", "age": 20, "id": 3},
+		{"name": "ann", "vip": true, "id": int64(9007199254740993)},
+		{"name": "ann", "vip": true, "id": 9007199254740992.0},
+		{"name": "bob", "age": 20, "id": 3},
+		{"name": "ann", "age": 10, "id": 3},
+	} {
+		want, _ := m.Match(doc)
+		if got, err := loaded.Match(doc); err != nil || got != want {
+			t.Fatalf("loaded Match(%v) = %v, %v; want %v", doc, got, err, want)
+		}
+	}
+	// A loaded plan is already normalized, so it serializes to itself.
+	again, err := loaded.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary of the loaded matcher failed: %v", err)
+	}
+	reloaded, err := LoadMatcher(again)
+	if err != nil {
+		t.Fatalf("LoadMatcher of the loaded matcher's plan failed: %v", err)
+	}
+	defer reloaded.Close()
+	if third, err := reloaded.MarshalBinary(); err != nil || !bytes.Equal(third, again) {
+		t.Fatalf("plan is not stable across loads:\n%s\n%s", third, again)
+	}
+}
+
+func TestLoadMatcherErrors(t *testing.T) {
+	m, err := NewMatcher(map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	edit := func(fn func(plan map[string]any)) []byte {
+		var plan map[string]any
+		if err := json.Unmarshal(data, &plan); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		fn(plan)
+		out, _ := json.Marshal(plan)
+		return out
+	}
+	for name, tc := range map[string]struct {
+		data []byte
+		want error
+	}{
+		"not a plan": {[]byte(`{"age": 1}`), ErrPlanVersion},
+		"garbage":    {[]byte("\x00"), ErrPlanVersion},
+		"version":    {edit(func(p map[string]any) { p["version"] = 99 }), ErrPlanVersion},
+		"tampered": {edit(func(p map[string]any) {
+			p["plan"].(map[string]any)["canonical"] = map[string]any{"age": map[string]any{"$gte": 0}}
+		}), ErrInvalidCondition},
+		"tree": {edit(func(p map[string]any) {
+			p["plan"].(map[string]any)["children"].([]any)[0].(map[string]any)["field"] = "other"
+		}), ErrPlanMismatch},
+	} {
+		if loaded, err := LoadMatcher(tc.data); !errors.Is(err, tc.want) {
+			if loaded != nil {
+				loaded.Close()
+			}
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	fn, err := NewMatcher(map[string]any{"$func": func(any) bool { return true }})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer fn.Close()
+	if _, err := fn.MarshalBinary(); err == nil {
+		t.Fatal("MarshalBinary of a $func condition succeeded")
+	}
+}
@@ -0,0 +1,150 @@
+package mongory
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// Group runs functions concurrently and reports the first error from Wait.
+// *errgroup.Group from golang.org/x/sync satisfies it.
+type Group interface {
+	Go(f func() error)
+	Wait() error
+}
+
+// Bitmap is a set of record indices.
+type Bitmap struct {
+	words []uint64
+	size  int
+}
+
+func newBitmap(size int) *Bitmap {
+	return &Bitmap{words: make([]uint64, (size+63)/64), size: size}
+}
+
+func (b *Bitmap) set(i int) {
+	b.words[i/64] |= 1 << (uint(i) % 64)
+}
+
+// Has reports whether index i is in the set.
+func (b *Bitmap) Has(i int) bool {
+	if i < 0 || i >= b.size {
+		return false
+	}
+	return b.words[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+// Len returns the number of records the bitmap covers.
+func (b *Bitmap) Len() int { return b.size }
+
+// Count returns the number of indices in the set.
+func (b *Bitmap) Count() int {
+	n := 0
+	for _, word := range b.words {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Indices returns the indices in the set in ascending order.
+func (b *Bitmap) Indices() []int {
+	indices := make([]int, 0, b.Count())
+	for w, word := range b.words {
+		for word != 0 {
+			indices = append(indices, w*64+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return indices
+}
+
+// MatchSharded matches records across shards goroutines and returns the
+// indices of the matching records. shards <= 0 uses GOMAXPROCS.
+func MatchSharded(m Matcher, records []any, shards int) (*Bitmap, error) {
+	var g waitGroup
+	bitmap, err := MatchShardedGroup(&g, m, records, shards)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return bitmap, nil
+}
+
+// MatchShardedGroup clones m once per shard and schedules the
+// shards on g, so they can run alongside the caller's other tasks. The
+// returned bitmap is complete once g.Wait returns nil. Each shard covers a
+// whole number of bitmap words, so shards never write the same memory, and
+// closes its clone when it finishes.
+func MatchShardedGroup(g Group, m Matcher, records []any, shards int) (*Bitmap, error) {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	bitmap := newBitmap(len(records))
+	words := len(bitmap.words)
+	if shards > words {
+		shards = words
+	}
+	if shards == 0 {
+		return bitmap, nil
+	}
+	perShard := (words + shards - 1) / shards * 64
+	// Rounding shards up to whole words may leave the last ones empty.
+	shards = (len(records) + perShard - 1) / perShard
+	clones := make([]Matcher, 0, shards)
+	for range shards {
+		clone, err := m.Clone()
+		if err != nil {
+			for _, clone := range clones {
+				clone.Close()
+			}
+			return nil, err
+		}
+		clones = append(clones, clone)
+	}
+
+	for i, clone := range clones {
+		start := i * perShard
+		end := min(start+perShard, len(records))
+		g.Go(func() error {
+			defer clone.Close()
+			match, done := batchMatch(clone)
+			defer done()
+			for j := start; j < end; j++ {
+				matched, err := match(records[j])
+				if err != nil {
+					return &DocumentError{Index: j, Err: err}
+				}
+				if matched {
+					bitmap.set(j)
+				}
+			}
+			return nil
+		})
+	}
+	return bitmap, nil
+}
+
+// waitGroup is a minimal Group for callers that do not bring their own.
+type waitGroup struct {
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+func (g *waitGroup) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.once.Do(func() { g.err = err })
+		}
+	}()
+}
+
+func (g *waitGroup) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
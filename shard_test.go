@@ -0,0 +1,42 @@
+package mongory
+
+import (
+	"testing"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+func TestMatchSharded(t *testing.T) {
+	records := make([]any, 300)
+	for i := range records {
+		records[i] = map[string]any{"n": i}
+	}
+	matcher, err := NewCMatcher(map[string]any{"n": map[string]any{"$gte": 100, "$lt": 200}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	for _, shards := range []int{0, 1, 3, 16} {
+		settlePools(t)
+		live := cgo.LivePools()
+		bitmap, err := MatchSharded(matcher, records, shards)
+		// The shards close their clones when they finish.
+		if got := cgo.LivePools(); got != live {
+			t.Fatalf("MatchSharded(%d) left %d live pools, want %d", shards, got, live)
+		}
+		if err != nil {
+			t.Fatalf("MatchSharded(%d) failed: %v", shards, err)
+		}
+		indices := bitmap.Indices()
+		if bitmap.Count() != 100 || len(indices) != 100 || indices[0] != 100 || indices[99] != 199 {
+			t.Fatalf("MatchSharded(%d) = %d matches starting at %v", shards, bitmap.Count(), indices[:1])
+		}
+		if !bitmap.Has(150) || bitmap.Has(250) || bitmap.Has(-1) || bitmap.Has(300) {
+			t.Fatalf("MatchSharded(%d) bitmap membership is wrong", shards)
+		}
+	}
+
+	matcher.Close()
+	if _, err := MatchSharded(matcher, records, 2); err == nil {
+		t.Fatalf("MatchSharded on a closed matcher succeeded")
+	}
+}
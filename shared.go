@@ -0,0 +1,26 @@
+package mongory
+
+import "github.com/mongoryhq/mongory-go/internal/cgo"
+
+// SharedValue is a condition operand, typically a large $in list, converted
+// to native memory once and referenced by every matcher that uses it:
+//
+//	ids, _ := mongory.NewSharedValue(allowedIDs)
+//	m, _ := mongory.NewMatcher(map[string]any{"id": map[string]any{"$in": ids}})
+//
+// Matchers keep the native value alive after the SharedValue is released.
+type SharedValue = cgo.SharedValue
+
+// ErrSharedValueReleased is wrapped, along with ErrInvalidCondition, by the
+// error for a condition using a SharedValue after its Release.
+var ErrSharedValueReleased = cgo.ErrSharedValueReleased
+
+// NewSharedValue converts value for sharing between conditions. Call Release
+// once no new conditions will use it; otherwise it is released when garbage
+// collected.
+func NewSharedValue(value any) (*SharedValue, error) {
+	if err := InitE(); err != nil {
+		return nil, err
+	}
+	return cgo.NewSharedValue(value)
+}
@@ -0,0 +1,57 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSharedValue(t *testing.T) {
+	ids := make([]any, 1000)
+	for i := range ids {
+		ids[i] = i * 2
+	}
+	shared, err := NewSharedValue(ids)
+	if err != nil {
+		t.Fatalf("NewSharedValue failed: %v", err)
+	}
+	condition := map[string]any{"id": map[string]any{"$in": shared}}
+	first, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	second, err := NewCMatcher(map[string]any{"owner": map[string]any{"$in": shared}}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	shared.Release()
+
+	for _, tc := range []struct {
+		matcher Matcher
+		doc     map[string]any
+		want    bool
+	}{
+		{first, map[string]any{"id": 1998}, true},
+		{first, map[string]any{"id": 3}, false},
+		{second, map[string]any{"owner": 10}, true},
+	} {
+		got, err := tc.matcher.Match(tc.doc)
+		if err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, got, err, tc.want)
+		}
+	}
+
+	if !EquivalentConditions(condition, map[string]any{"id": map[string]any{"$in": ids}}) {
+		t.Fatalf("shared operand should canonicalize like its source value")
+	}
+
+	// A released value is still held by first and second, but new
+	// conditions cannot take it.
+	if _, err := NewMatcher(condition); !errors.Is(err, ErrSharedValueReleased) || !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("NewMatcher after Release: err = %v, want ErrSharedValueReleased", err)
+	}
+	first.Close()
+	second.Close()
+	if _, err := NewMatcher(condition); !errors.Is(err, ErrSharedValueReleased) {
+		t.Fatalf("NewMatcher after the last reference: err = %v, want ErrSharedValueReleased", err)
+	}
+}
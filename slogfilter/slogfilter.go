@@ -0,0 +1,248 @@
+// Package slogfilter drops or routes log/slog records by mongory conditions
+// over their attributes:
+//
+//	h, err := slogfilter.Drop(slog.NewJSONHandler(os.Stdout, nil),
+//		map[string]any{"path": "/healthz", "status": map[string]any{"$lt": 400}})
+//	slog.SetDefault(slog.New(h))
+//
+// Each record is matched as a document with its "time", "level" and "msg",
+// the level as its name such as "WARN", and its attributes, including those
+// added with Logger.With. Attributes in groups are nested documents under
+// the group name. Time values are matched as RFC 3339 strings, durations as
+// nanoseconds, errors as their message, and values implementing
+// slog.LogValuer as the value they resolve to.
+package slogfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Route sends the records matching Condition to Handler. A nil Handler
+// discards them.
+type Route struct {
+	Condition map[string]any
+	Handler   slog.Handler
+}
+
+// Handler is a slog.Handler passing each record to the handler of the first
+// route it matches, or to its fallback if it matches none. A record that
+// fails to match, for instance because it is nested too deeply, matches no
+// route.
+type Handler struct {
+	routes   []route
+	fallback slog.Handler
+	matchers []mongory.Matcher // shared by the handlers derived with With*
+	// attrs are the attributes added with WithAttrs, already nested in the
+	// groups open when they were added; groups are the groups open now.
+	attrs  map[string]any
+	groups []string
+}
+
+type route struct {
+	matcher mongory.Matcher
+	handler slog.Handler
+}
+
+// New returns a handler routing records by routes, in order, and passing
+// those matching no route to fallback. A nil fallback discards them.
+func New(fallback slog.Handler, routes ...Route) (*Handler, error) {
+	h := &Handler{fallback: fallback, attrs: map[string]any{}}
+	for i, r := range routes {
+		m, err := mongory.NewMatcherWithOptions(r.Condition, mongory.MatcherOptions{Name: "slogfilter"})
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("slogfilter: route %d: %w", i, err)
+		}
+		h.matchers = append(h.matchers, m)
+		h.routes = append(h.routes, route{matcher: m, handler: r.Handler})
+	}
+	return h, nil
+}
+
+// Keep returns a handler passing only the records matching condition to
+// next.
+func Keep(next slog.Handler, condition map[string]any) (*Handler, error) {
+	return New(nil, Route{Condition: condition, Handler: next})
+}
+
+// Drop returns a handler discarding the records matching condition and
+// passing the others to next.
+func Drop(next slog.Handler, condition map[string]any) (*Handler, error) {
+	return New(next, Route{Condition: condition})
+}
+
+// Close frees the handler's matchers. Handlers derived from it with
+// WithAttrs and WithGroup share them and must not be used afterwards.
+func (h *Handler) Close() error {
+	var errs []error
+	for _, m := range h.matchers {
+		errs = append(errs, m.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether any handler a record can be passed to is enabled
+// for level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, r := range h.routes {
+		if r.handler != nil && r.handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return h.fallback != nil && h.fallback.Enabled(ctx, level)
+}
+
+// Handle passes r to the handler of the first route it matches.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	doc := h.document(r)
+	for _, route := range h.routes {
+		if matched, err := route.matcher.Match(doc); err != nil || !matched {
+			continue
+		}
+		if route.handler == nil || !route.handler.Enabled(ctx, r.Level) {
+			return nil
+		}
+		return route.handler.Handle(ctx, r)
+	}
+	if h.fallback == nil || !h.fallback.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.fallback.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler matching and passing on attrs with every
+// record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	derived := h.derive(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+	derived.attrs = cloneDocument(h.attrs)
+	addAttrs(groupDocument(derived.attrs, h.groups), attrs)
+	return derived
+}
+
+// WithGroup returns a handler nesting the attributes added afterwards under
+// name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := h.derive(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+	derived.groups = append(append([]string(nil), h.groups...), name)
+	return derived
+}
+
+// derive copies h with every handler passed through with.
+func (h *Handler) derive(with func(slog.Handler) slog.Handler) *Handler {
+	derived := &Handler{matchers: h.matchers, attrs: h.attrs, groups: h.groups}
+	derived.routes = make([]route, len(h.routes))
+	for i, r := range h.routes {
+		derived.routes[i] = r
+		if r.handler != nil {
+			derived.routes[i].handler = with(r.handler)
+		}
+	}
+	if h.fallback != nil {
+		derived.fallback = with(h.fallback)
+	}
+	return derived
+}
+
+// document returns the record as the document its routes match.
+func (h *Handler) document(r slog.Record) map[string]any {
+	doc := cloneDocument(h.attrs)
+	if !r.Time.IsZero() {
+		doc[slog.TimeKey] = r.Time.Format(time.RFC3339Nano)
+	}
+	doc[slog.LevelKey] = r.Level.String()
+	doc[slog.MessageKey] = r.Message
+	group := groupDocument(doc, h.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		addAttrs(group, []slog.Attr{a})
+		return true
+	})
+	return doc
+}
+
+// groupDocument returns the document of doc nested under groups, creating
+// it if needed.
+func groupDocument(doc map[string]any, groups []string) map[string]any {
+	for _, name := range groups {
+		inner, ok := doc[name].(map[string]any)
+		if !ok {
+			inner = map[string]any{}
+			doc[name] = inner
+		}
+		doc = inner
+	}
+	return doc
+}
+
+func addAttrs(doc map[string]any, attrs []slog.Attr) {
+	for _, a := range attrs {
+		value := a.Value.Resolve()
+		if value.Kind() == slog.KindGroup {
+			group := value.Group()
+			if len(group) == 0 {
+				continue
+			}
+			if a.Key == "" {
+				// Inline the attributes of a group without a key.
+				addAttrs(doc, group)
+				continue
+			}
+			addAttrs(groupDocument(doc, []string{a.Key}), group)
+			continue
+		}
+		if a.Key == "" {
+			continue
+		}
+		doc[a.Key] = attrValue(value)
+	}
+}
+
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return int64(v.Duration())
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	}
+	switch a := v.Any().(type) {
+	case error:
+		return a.Error()
+	case fmt.Stringer:
+		return a.String()
+	default:
+		return a
+	}
+}
+
+// cloneDocument copies doc and the groups nested in it.
+func cloneDocument(doc map[string]any) map[string]any {
+	out := make(map[string]any, len(doc))
+	for key, value := range doc {
+		if inner, ok := value.(map[string]any); ok {
+			value = cloneDocument(inner)
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package slogfilter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrop(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := Drop(slog.NewTextHandler(&buf, nil), map[string]any{
+		"path":   "/healthz",
+		"status": map[string]any{"$lt": 400},
+	})
+	if err != nil {
+		t.Fatalf("Drop: %v", err)
+	}
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Info("request", "path", "/healthz", "status", 200)
+	logger.Info("request", "path", "/healthz", "status", 500)
+	logger.Info("request", "path", "/users", "status", 200)
+	out := buf.String()
+	if strings.Count(out, "\n") != 2 || strings.Contains(out, "status=200 path=/healthz") || !strings.Contains(out, "status=500") || !strings.Contains(out, "path=/users") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestKeepRecordFields(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := Keep(slog.NewTextHandler(&buf, nil), map[string]any{
+		"level": map[string]any{"$in": []any{"WARN", "ERROR"}},
+		"msg":   map[string]any{"$regex": "^db"},
+	})
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Warn("db slow")
+	logger.Info("db fast")
+	logger.Error("cache down")
+	if out := buf.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, "db slow") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestAttrsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := Keep(slog.NewTextHandler(&buf, nil), map[string]any{
+		"service": "api",
+		"http": map[string]any{
+			"status":  map[string]any{"$gte": 500},
+			"latency": map[string]any{"$gt": int64(time.Second)},
+		},
+		"err": "timeout",
+	})
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	defer h.Close()
+	logger := slog.New(h).With("service", "api").WithGroup("http")
+	logger.Info("served", "status", 503, "latency", 2*time.Second, slog.Any("err", errors.New("timeout")))
+	// The error is nested in the group, so the top-level "err" is missing.
+	slog.New(h).With("service", "api", "err", errors.New("timeout")).Info("served",
+		slog.Group("http", "status", 503, "latency", 2*time.Second))
+	slog.New(h).With("service", "web", "err", errors.New("timeout")).Info("served",
+		slog.Group("http", "status", 503, "latency", 2*time.Second))
+	if out := buf.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, "service=api") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var errs, audit, rest bytes.Buffer
+	h, err := New(slog.NewTextHandler(&rest, nil),
+		Route{Condition: map[string]any{"level": "ERROR"}, Handler: slog.NewTextHandler(&errs, nil)},
+		Route{Condition: map[string]any{"audit": true}, Handler: slog.NewTextHandler(&audit, nil)},
+		Route{Condition: map[string]any{"noise": true}},
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Error("failed", "audit", true)
+	logger.Info("login", "audit", true)
+	logger.Info("tick", "noise", true)
+	logger.Info("started")
+	for _, tc := range []struct {
+		name string
+		buf  *bytes.Buffer
+		want string
+	}{{"errors", &errs, "failed"}, {"audit", &audit, "login"}, {"rest", &rest, "started"}} {
+		if out := tc.buf.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, tc.want) {
+			t.Fatalf("%s: unexpected output:\n%s", tc.name, out)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	h, err := Keep(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn}), map[string]any{})
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	defer h.Close()
+	if h.Enabled(context.Background(), slog.LevelInfo) || !h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("Enabled does not follow the wrapped handler")
+	}
+}
+
+func TestInvalidCondition(t *testing.T) {
+	if _, err := Keep(slog.DiscardHandler, map[string]any{"tags": map[string]any{"$in": 5}}); err == nil {
+		t.Fatal("expected an error for a bad $in")
+	}
+}
@@ -0,0 +1,50 @@
+package mongory
+
+import "time"
+
+// MatcherStats are the cumulative counts of a matcher's matches since it was
+// compiled or ResetStats was last called. Match, the filtering methods and
+// the batch helpers all count; Trace does not. A clone starts from zero.
+type MatcherStats struct {
+	// Matches counts documents matched, including those that failed.
+	Matches int64
+	// Hits counts the matches that returned true.
+	Hits int64
+	// Errors counts the matches that returned an error.
+	Errors int64
+	// Latency is the total time spent in those matches.
+	Latency time.Duration
+	// LastError is the most recent match error, returned at LastErrorTime.
+	LastError     error
+	LastErrorTime time.Time
+}
+
+// HitRate returns the fraction of successful matches that returned true, or
+// 0 before any has.
+func (s MatcherStats) HitRate() float64 {
+	if ok := s.Matches - s.Errors; ok > 0 {
+		return float64(s.Hits) / float64(ok)
+	}
+	return 0
+}
+
+// AverageLatency returns the mean time per match, or 0 before any.
+func (s MatcherStats) AverageLatency() time.Duration {
+	if s.Matches == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Matches)
+}
+
+// Stats returns m's match statistics.
+func (m *matcher) Stats() MatcherStats {
+	s := m.Matcher.Stats()
+	return MatcherStats{
+		Matches:       s.Matches,
+		Hits:          s.Hits,
+		Errors:        s.Errors,
+		Latency:       s.Latency,
+		LastError:     s.LastError,
+		LastErrorTime: s.LastErrorTime,
+	}
+}
@@ -0,0 +1,59 @@
+package mongory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatcherStats(t *testing.T) {
+	m, err := NewMatcher(map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	if s := m.Stats(); s.Matches != 0 || s.HitRate() != 0 || s.AverageLatency() != 0 {
+		t.Fatalf("new matcher Stats = %+v, want zero", s)
+	}
+
+	for _, age := range []int{10, 20, 30} {
+		if _, err := m.Match(map[string]any{"age": age}); err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+	}
+	if err := m.FilterFunc([]any{map[string]any{"age": 40}}, func(int, any) bool { return true }); err != nil {
+		t.Fatalf("FilterFunc failed: %v", err)
+	}
+	SetNilDocumentMode(NilDocumentError)
+	defer SetNilDocumentMode(NilDocumentNoMatch)
+	if _, err := m.Match(nil); !errors.Is(err, ErrNilDocument) {
+		t.Fatalf("Match(nil) error = %v, want ErrNilDocument", err)
+	}
+
+	s := m.Stats()
+	if s.Matches != 5 || s.Hits != 3 || s.Errors != 1 {
+		t.Fatalf("Stats = %+v, want 5 matches, 3 hits, 1 error", s)
+	}
+	if s.HitRate() != 0.75 {
+		t.Fatalf("HitRate = %v, want 0.75", s.HitRate())
+	}
+	if s.Latency <= 0 || s.AverageLatency() != s.Latency/5 {
+		t.Fatalf("Latency = %v, AverageLatency = %v", s.Latency, s.AverageLatency())
+	}
+	if !errors.Is(s.LastError, ErrNilDocument) || s.LastErrorTime.IsZero() {
+		t.Fatalf("LastError = %v at %v, want ErrNilDocument", s.LastError, s.LastErrorTime)
+	}
+
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+	if clone.Stats().Matches != 0 {
+		t.Fatalf("clone Stats = %+v, want zero", clone.Stats())
+	}
+
+	m.ResetStats()
+	if s := m.Stats(); s != (MatcherStats{}) {
+		t.Fatalf("Stats after ResetStats = %+v, want zero", s)
+	}
+}
@@ -0,0 +1,34 @@
+package mongory
+
+import (
+	"reflect"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// RegisterStruct compiles the field layout of struct type T up front, so
+// that documents of type T or *T are matched by reading their fields at
+// precomputed offsets rather than reflecting over them per document. Pass
+// *T to avoid copying each document. Structs held in fields of T convert the
+// same way.
+//
+// Fields are matched under their mongory tag, else their json tag, else
+// their Go name; a tag of "-" hides a field, and unexported fields are never
+// visible. Fields of embedded structs are promoted as in encoding/json.
+// Struct types that are not registered convert the same way, their layout
+// compiled the first time one is matched; structs without exported fields,
+// such as time.Time, and driver.Valuer types are not documents.
+func RegisterStruct[T any]() error {
+	_, err := cgo.RegisterStruct(reflect.TypeFor[T]())
+	return err
+}
+
+// StructFields returns the field names a registered struct type T is
+// matched under, in declaration order, and false if T is not registered.
+func StructFields[T any]() ([]string, bool) {
+	layout := cgo.RegisteredStruct(reflect.TypeFor[T]())
+	if layout == nil {
+		return nil, false
+	}
+	return layout.Fields(), true
+}
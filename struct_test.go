@@ -0,0 +1,233 @@
+package mongory
+
+import (
+	"slices"
+	"testing"
+)
+
+type structAddress struct {
+	City string `json:"city"`
+	Zip  int
+}
+
+type structAudit struct {
+	Created int64  `json:"created"`
+	Owner   string `json:"owner"`
+}
+
+type structUser struct {
+	structAudit
+	Name    string         `mongory:"name" json:"full_name"`
+	Age     int            `json:"age,omitempty"`
+	Score   float32        `json:"score"`
+	Active  bool           `json:"active"`
+	Tags    []string       `json:"tags"`
+	Home    structAddress  `json:"home"`
+	Work    *structAddress `json:"work"`
+	Owner   string         `json:"owner"` // shadows structAudit.Owner
+	Secret  string         `json:"-"`
+	private int
+}
+
+func structUsers() []structUser {
+	return []structUser{
+		{Name: "ann", Age: 30, Score: 9.5, Active: true, Tags: []string{"admin"}, Home: structAddress{City: "Taipei", Zip: 100}, Work: &structAddress{City: "Tokyo"}, Owner: "ops", structAudit: structAudit{Created: 7, Owner: "root"}, Secret: "x"},
+		{Name: "bob", Age: 12, Home: structAddress{City: "Osaka"}},
+	}
+}
+
+func TestRegisterStruct(t *testing.T) {
+	if err := RegisterStruct[int](); err == nil {
+		t.Fatalf("RegisterStruct[int] succeeded")
+	}
+	if _, ok := StructFields[structAddress](); ok {
+		t.Fatalf("StructFields reported an unregistered type")
+	}
+	if err := RegisterStruct[structUser](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	fields, ok := StructFields[structUser]()
+	want := []string{"name", "age", "score", "active", "tags", "home", "work", "owner", "created"}
+	if !ok || !slices.Equal(fields, want) {
+		t.Fatalf("StructFields = %v, %v; want %v", fields, ok, want)
+	}
+
+	users := structUsers()
+	cases := []struct {
+		condition map[string]any
+		want      []bool
+	}{
+		{map[string]any{"name": "ann"}, []bool{true, false}},
+		{map[string]any{"age": map[string]any{"$gte": 18}}, []bool{true, false}},
+		{map[string]any{"score": map[string]any{"$gt": 9}}, []bool{true, false}},
+		{map[string]any{"active": false}, []bool{false, true}},
+		{map[string]any{"tags": map[string]any{"$in": []any{"admin"}}}, []bool{true, false}},
+		{map[string]any{"home": map[string]any{"city": "Osaka"}}, []bool{false, true}},
+		{map[string]any{"home.city": "Osaka"}, []bool{false, false}},
+		{map[string]any{"work": map[string]any{"city": "Tokyo"}}, []bool{true, false}},
+		{map[string]any{"work": nil}, []bool{false, true}},
+		{map[string]any{"owner": "ops", "created": 7}, []bool{true, false}},
+		{map[string]any{"Secret": map[string]any{"$exists": true}}, []bool{false, false}},
+		{map[string]any{"private": map[string]any{"$exists": true}}, []bool{false, false}},
+		{map[string]any{"full_name": map[string]any{"$exists": true}}, []bool{false, false}},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, c := range cases {
+			m, err := NewCMatcher(c.condition, nil)
+			if err != nil {
+				t.Fatalf("NewCMatcher(%v) failed: %v", c.condition, err)
+			}
+			for i := range users {
+				for _, doc := range []any{&users[i], users[i]} {
+					matched, err := m.Match(doc)
+					if err != nil || matched != c.want[i] {
+						t.Fatalf("%v: Match(%v, %T) = %v, %v; want %v", mode, c.condition, doc, matched, err, c.want[i])
+					}
+				}
+			}
+			m.Close()
+		}
+	}
+	SetConversionMode(ShallowConversion)
+
+	adults, err := Filter(users, map[string]any{"age": map[string]any{"$gte": 18}})
+	if err != nil || len(adults) != 1 || adults[0].Name != "ann" {
+		t.Fatalf("Filter = %v, %v", adults, err)
+	}
+}
+
+type structNode struct {
+	Value int         `json:"value"`
+	Next  *structNode `json:"next"`
+}
+
+func TestRegisterStructRecursive(t *testing.T) {
+	if err := RegisterStruct[structNode](); err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	list := &structNode{Value: 1, Next: &structNode{Value: 2}}
+	m, err := NewCMatcher(map[string]any{"next": map[string]any{"value": 2, "next": nil}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	if matched, err := m.Match(list); err != nil || !matched {
+		t.Fatalf("Match = %v, %v; want true", matched, err)
+	}
+}
+
+type structOrder struct {
+	ID     string         `json:"id"`
+	Lines  []structLine   `json:"lines"`
+	Ship   *structAddress `json:"ship"`
+	Extra  any            `json:"extra"`
+	secret string
+}
+
+type structLine struct {
+	SKU string
+	Qty int `json:"qty"`
+}
+
+func TestUnregisteredStruct(t *testing.T) {
+	defer SetConversionMode(ShallowConversion)
+	order := structOrder{
+		ID:     "o-1",
+		Lines:  []structLine{{SKU: "a", Qty: 2}, {SKU: "b", Qty: 5}},
+		Ship:   &structAddress{City: "Taipei"},
+		Extra:  structLine{SKU: "gift"},
+		secret: "x",
+	}
+	cases := []struct {
+		condition map[string]any
+		want      bool
+	}{
+		{map[string]any{"id": "o-1"}, true},
+		{map[string]any{"lines": map[string]any{"$elemMatch": map[string]any{"SKU": "b", "qty": map[string]any{"$gt": 3}}}}, true},
+		{map[string]any{"lines": map[string]any{"$sumOf": map[string]any{"qty": 7}}}, true},
+		{map[string]any{"ship": map[string]any{"city": "Taipei"}}, true},
+		{map[string]any{"extra": map[string]any{"SKU": "gift"}}, true},
+		{map[string]any{"secret": map[string]any{"$exists": true}}, false},
+	}
+	for _, mode := range []ConversionMode{ShallowConversion, DeepConversion} {
+		SetConversionMode(mode)
+		for _, tc := range cases {
+			m, err := NewCMatcher(tc.condition, nil)
+			if err != nil {
+				t.Fatalf("NewCMatcher(%v) failed: %v", tc.condition, err)
+			}
+			for _, doc := range []any{order, &order} {
+				matched, err := m.Match(doc)
+				if err != nil || matched != tc.want {
+					t.Fatalf("%v: Match(%T) with %v = %v, %v; want %v", mode, doc, tc.condition, matched, err, tc.want)
+				}
+			}
+			m.Close()
+		}
+	}
+	if _, ok := StructFields[structOrder](); ok {
+		t.Fatalf("StructFields reported a matched but unregistered type")
+	}
+
+	// Structs without exported fields are still not documents.
+	m, err := NewCMatcher(map[string]any{"v": map[string]any{"$exists": true}}, nil)
+	if err != nil {
+		t.Fatalf("NewCMatcher failed: %v", err)
+	}
+	defer m.Close()
+	if matched, err := m.Match(struct{ v int }{1}); matched {
+		t.Fatalf("Match(struct without exported fields) = %v, %v; want no match", matched, err)
+	}
+}
+
+type benchRecord struct {
+	Age    int    `json:"age"`
+	Status string `json:"status"`
+}
+
+func BenchmarkStructMatch(b *testing.B) {
+	if err := RegisterStruct[benchRecord](); err != nil {
+		b.Fatal(err)
+	}
+	records := make([]benchRecord, 1000)
+	maps := make([]any, len(records))
+	for i := range records {
+		records[i] = benchRecord{Age: i % 100, Status: []string{"active", "inactive"}[i%2]}
+		maps[i] = map[string]any{"age": records[i].Age, "status": records[i].Status}
+	}
+	m, err := NewCMatcher(map[string]any{"age": map[string]any{"$gte": 18}, "status": "active"}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer m.Close()
+	b.Run("struct", func(b *testing.B) {
+		for b.Loop() {
+			for i := range records {
+				if _, err := m.Match(&records[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for b.Loop() {
+			for _, doc := range maps {
+				if _, err := m.Match(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		n := 0
+		for b.Loop() {
+			for i := range records {
+				if records[i].Age >= 18 && records[i].Status == "active" {
+					n++
+				}
+			}
+		}
+		_ = n
+	})
+}
@@ -0,0 +1,178 @@
+// Package targeting evaluates feature flags whose rules are mongory
+// conditions over user or request attributes:
+//
+//	e, err := targeting.NewEvaluator(targeting.Flag{
+//		Key:     "new-checkout",
+//		Default: false,
+//		Rules: []targeting.Rule{
+//			{Name: "staff", Condition: map[string]any{"email": map[string]any{"$glob": "*@example.com"}}, Value: true},
+//			{Name: "beta", Condition: map[string]any{"id": map[string]any{"$rollout": 10}}, Value: true},
+//		},
+//	})
+//	on := e.Bool("new-checkout", map[string]any{"id": "u-42", "email": "a@b.c"}, false)
+//
+// $rollout buckets the field value by a stable hash. Its salt defaults to the
+// flag key, so each flag rolls out to an independent slice of users, and
+// raising the percentage only ever adds users.
+package targeting
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Rule serves Value to contexts matching Condition.
+type Rule struct {
+	Name      string
+	Condition map[string]any
+	Value     any
+}
+
+// Flag is an ordered list of rules; the first match decides and Default is
+// served when none does.
+type Flag struct {
+	Key     string
+	Rules   []Rule
+	Default any
+}
+
+// Reason explains why an Evaluation has its value.
+type Reason string
+
+const (
+	ReasonRuleMatch    Reason = "RULE_MATCH"
+	ReasonDefault      Reason = "DEFAULT"
+	ReasonFlagNotFound Reason = "FLAG_NOT_FOUND"
+	ReasonError        Reason = "ERROR"
+)
+
+// Evaluation is the result of evaluating one flag for one context.
+type Evaluation struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Rule   string `json:"rule,omitempty"`
+	Reason Reason `json:"reason"`
+	Err    error  `json:"-"`
+}
+
+type compiledFlag struct {
+	flag     Flag
+	matchers []mongory.Matcher
+}
+
+// Evaluator holds compiled flags. It is safe for concurrent use, and flags
+// can be replaced while it serves evaluations.
+type Evaluator struct {
+	mu    sync.RWMutex
+	flags map[string]*compiledFlag
+}
+
+// NewEvaluator compiles flags. Any invalid flag fails the whole call.
+func NewEvaluator(flags ...Flag) (*Evaluator, error) {
+	e := &Evaluator{flags: map[string]*compiledFlag{}}
+	for _, flag := range flags {
+		if err := e.Set(flag); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Set compiles flag and replaces any flag with the same key. On error the
+// previous version stays in place.
+func (e *Evaluator) Set(flag Flag) error {
+	if flag.Key == "" {
+		return fmt.Errorf("targeting: flag key must not be empty")
+	}
+	compiled := &compiledFlag{flag: flag}
+	salt := saltRollouts(flag.Key)
+	for i, rule := range flag.Rules {
+		condition, err := salt(rule.Condition)
+		if err == nil {
+			var m mongory.Matcher
+			if m, err = mongory.NewMatcher(condition); err == nil {
+				compiled.matchers = append(compiled.matchers, m)
+				continue
+			}
+		}
+		for _, m := range compiled.matchers {
+			m.Close()
+		}
+		return fmt.Errorf("targeting: flag %q rule %d (%s): %w", flag.Key, i, rule.Name, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.flags[flag.Key] = compiled
+	return nil
+}
+
+// Remove deletes a flag.
+func (e *Evaluator) Remove(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.flags, key)
+}
+
+// saltRollouts fills in the flag key as the salt of every $rollout operand
+// that does not set one.
+func saltRollouts(key string) mongory.MigrationRule {
+	return mongory.RewriteOperator("$rollout", func(operand any) (string, any, error) {
+		switch v := operand.(type) {
+		case map[string]any:
+			if _, ok := v["salt"]; ok {
+				return "$rollout", v, nil
+			}
+			salted := map[string]any{"salt": key}
+			for k, item := range v {
+				salted[k] = item
+			}
+			return "$rollout", salted, nil
+		default:
+			return "$rollout", map[string]any{"percent": operand, "salt": key}, nil
+		}
+	})
+}
+
+// Evaluate returns the value flag key serves to context. Errors are reported
+// in the Evaluation together with the flag's default.
+func (e *Evaluator) Evaluate(key string, context any) Evaluation {
+	e.mu.RLock()
+	compiled := e.flags[key]
+	e.mu.RUnlock()
+	if compiled == nil {
+		return Evaluation{Key: key, Reason: ReasonFlagNotFound}
+	}
+	for i, m := range compiled.matchers {
+		matched, err := m.Match(context)
+		if err != nil {
+			return Evaluation{Key: key, Value: compiled.flag.Default, Reason: ReasonError, Err: err}
+		}
+		if matched {
+			rule := compiled.flag.Rules[i]
+			return Evaluation{Key: key, Value: rule.Value, Rule: rule.Name, Reason: ReasonRuleMatch}
+		}
+	}
+	return Evaluation{Key: key, Value: compiled.flag.Default, Reason: ReasonDefault}
+}
+
+// Bool evaluates a boolean flag, returning fallback when the flag is missing,
+// fails or does not serve a bool.
+func (e *Evaluator) Bool(key string, context any, fallback bool) bool {
+	evaluation := e.Evaluate(key, context)
+	if value, ok := evaluation.Value.(bool); ok && evaluation.Reason != ReasonFlagNotFound {
+		return value
+	}
+	return fallback
+}
+
+// String evaluates a string flag, returning fallback when the flag is
+// missing, fails or does not serve a string.
+func (e *Evaluator) String(key string, context any, fallback string) string {
+	evaluation := e.Evaluate(key, context)
+	if value, ok := evaluation.Value.(string); ok && evaluation.Reason != ReasonFlagNotFound {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,89 @@
+package targeting
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	e, err := NewEvaluator(Flag{
+		Key:     "new-checkout",
+		Default: "control",
+		Rules: []Rule{
+			{Name: "staff", Condition: map[string]any{"email": map[string]any{"$glob": "*@example.com"}}, Value: "treatment"},
+			{Name: "blocked", Condition: map[string]any{"country": map[string]any{"$in": []any{"XX"}}}, Value: "control"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEvaluator failed: %v", err)
+	}
+	cases := []struct {
+		context map[string]any
+		value   string
+		rule    string
+		reason  Reason
+	}{
+		{map[string]any{"email": "ann@example.com"}, "treatment", "staff", ReasonRuleMatch},
+		{map[string]any{"email": "bob@else.org", "country": "XX"}, "control", "blocked", ReasonRuleMatch},
+		{map[string]any{"email": "bob@else.org"}, "control", "", ReasonDefault},
+	}
+	for _, tc := range cases {
+		got := e.Evaluate("new-checkout", tc.context)
+		if got.Value != tc.value || got.Rule != tc.rule || got.Reason != tc.reason {
+			t.Fatalf("Evaluate(%v) = %+v, want %s/%s/%s", tc.context, got, tc.value, tc.rule, tc.reason)
+		}
+	}
+	if got := e.Evaluate("missing", nil); got.Reason != ReasonFlagNotFound {
+		t.Fatalf("Evaluate(missing).Reason = %s", got.Reason)
+	}
+	if got := e.String("missing", nil, "fallback"); got != "fallback" {
+		t.Fatalf("String(missing) = %q", got)
+	}
+	if err := e.Set(Flag{Key: "bad", Rules: []Rule{{Condition: map[string]any{"$and": "x"}}}}); err == nil {
+		t.Fatalf("Set accepted an invalid rule")
+	}
+}
+
+func TestRollout(t *testing.T) {
+	flag := func(key string, percent float64) Flag {
+		return Flag{Key: key, Default: false, Rules: []Rule{
+			{Name: "rollout", Condition: map[string]any{"id": map[string]any{"$rollout": percent}}, Value: true},
+		}}
+	}
+	e, err := NewEvaluator(flag("a", 20), flag("b", 20))
+	if err != nil {
+		t.Fatalf("NewEvaluator failed: %v", err)
+	}
+	const users = 5000
+	inA, inBoth := map[int]bool{}, 0
+	for i := 0; i < users; i++ {
+		context := map[string]any{"id": fmt.Sprintf("user-%d", i)}
+		if e.Bool("a", context, false) {
+			inA[i] = true
+			if e.Bool("b", context, false) {
+				inBoth++
+			}
+		}
+		if e.Bool("a", context, false) != inA[i] {
+			t.Fatalf("rollout is not stable for user-%d", i)
+		}
+	}
+	if share := float64(len(inA)) / users; math.Abs(share-0.2) > 0.03 {
+		t.Fatalf("rollout share = %.3f, want about 0.2", share)
+	}
+	// Flags are salted by key, so their 20% slices are independent.
+	if overlap := float64(inBoth) / float64(len(inA)); math.Abs(overlap-0.2) > 0.06 {
+		t.Fatalf("overlap between flags = %.3f, want about 0.2", overlap)
+	}
+
+	// Raising the percentage keeps everyone already included.
+	if err := e.Set(flag("a", 50)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for i := range inA {
+		if !e.Bool("a", map[string]any{"id": fmt.Sprintf("user-%d", i)}, false) {
+			t.Fatalf("user-%d dropped out when the rollout grew", i)
+		}
+	}
+}
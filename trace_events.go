@@ -0,0 +1,43 @@
+package mongory
+
+// TraceEvent is one matcher node evaluated by TraceWith.
+type TraceEvent struct {
+	// Operator is the core's name for the node, as in ExplainNode.
+	Operator string
+	// Path is the dotted field path of the node, as in ExplainNode.
+	Path string
+	// Depth is the node's nesting level; the root condition is 0.
+	Depth int
+	// Operand is the node's condition, decoded as in ExplainNode.
+	Operand any
+	// Value is the value the node evaluated, decoded from the core's JSON
+	// rendering of it: a Field node gets the document it looks its field up
+	// in, and the operators under it the field's value, or nil if missing.
+	Value any
+	// Matched is the node's result.
+	Matched bool
+}
+
+// TraceWith matches value with tracing and calls fn with each evaluated
+// node, parent first, once the match is done, so services can attach the
+// trace to their own logs instead of having it printed like Trace. Nodes
+// skipped by short-circuiting, such as the rest of an $and after a miss,
+// are not reported.
+func (m *matcher) TraceWith(value any, fn func(ev TraceEvent)) (bool, error) {
+	matched, entries, err := m.TraceEntries(value)
+	if err != nil {
+		return false, err
+	}
+	paths := fieldPaths{}
+	for _, entry := range entries {
+		fn(TraceEvent{
+			Operator: entry.Name,
+			Path:     paths.next(entry.ExplainEntry),
+			Depth:    entry.Level,
+			Operand:  explainOperand(entry.Condition),
+			Value:    explainOperand(entry.Record),
+			Matched:  entry.Matched,
+		})
+	}
+	return matched, nil
+}
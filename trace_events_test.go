@@ -0,0 +1,55 @@
+package mongory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTraceWith(t *testing.T) {
+	m, err := NewMatcher(map[string]any{
+		"a":   map[string]any{"b": map[string]any{"$gt": 1}},
+		"$or": []any{map[string]any{"e": 4}},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+
+	var events []TraceEvent
+	doc := map[string]any{"a": map[string]any{"b": 5}, "e": 4}
+	matched, err := m.TraceWith(doc, func(ev TraceEvent) { events = append(events, ev) })
+	if err != nil || !matched {
+		t.Fatalf("TraceWith = %v, %v; want true", matched, err)
+	}
+	if len(events) == 0 || events[0].Operator != "Condition" || events[0].Depth != 0 || !events[0].Matched {
+		t.Fatalf("first event = %+v, want the matched root", events)
+	}
+	byPath := map[string]TraceEvent{}
+	for _, ev := range events {
+		byPath[ev.Operator+" "+ev.Path] = ev
+	}
+	gt, ok := byPath["Gt a.b"]
+	if !ok || gt.Value != int64(5) || gt.Operand != int64(1) || !gt.Matched || gt.Depth != 3 {
+		t.Fatalf("Gt event = %+v", gt)
+	}
+	if field := byPath["Field a.b"]; !reflect.DeepEqual(field.Value, map[string]any{"b": int64(5)}) {
+		t.Fatalf("Field a.b event = %+v", field)
+	}
+	if eq := byPath["Eq e"]; eq.Value != int64(4) || !eq.Matched {
+		t.Fatalf("Eq e event = %+v", eq)
+	}
+
+	events = nil
+	matched, err = m.TraceWith(map[string]any{"e": 4}, func(ev TraceEvent) { events = append(events, ev) })
+	if err != nil || matched {
+		t.Fatalf("TraceWith = %v, %v; want false", matched, err)
+	}
+	for _, ev := range events {
+		if ev.Operator == "Gt" && (ev.Value != nil || ev.Matched) {
+			t.Fatalf("Gt on a missing field = %+v", ev)
+		}
+	}
+	if events[0].Matched {
+		t.Fatalf("root event should record the miss: %+v", events[0])
+	}
+}
@@ -0,0 +1,200 @@
+// Package tracing records spans around compiling matchers, matching and
+// batch operations, so matcher latency shows up in distributed traces. It
+// does not depend on OpenTelemetry: its Tracer and Span are the subset of
+// OpenTelemetry's the package uses, and an OpenTelemetry tracer takes a few
+// lines to adapt:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttributes(attrs ...tracing.Attribute) {
+//		for _, a := range attrs {
+//			switch v := a.Value.(type) {
+//			case string:
+//				s.Span.SetAttributes(attribute.String(a.Key, v))
+//			case int64:
+//				s.Span.SetAttributes(attribute.Int64(a.Key, v))
+//			case bool:
+//				s.Span.SetAttributes(attribute.Bool(a.Key, v))
+//			}
+//		}
+//	}
+//
+//	func (s otelSpan) RecordError(err error) {
+//		s.Span.RecordError(err)
+//		s.Span.SetStatus(codes.Error, err.Error())
+//	}
+//
+//	func (s otelSpan) End() { s.Span.End() }
+//
+// and then:
+//
+//	m, err := tracing.NewMatcher(ctx, otelTracer{otel.Tracer("mongory")}, condition)
+//	matched, err := m.MatchContext(ctx, doc)
+//
+// Every span carries the condition's hash as mongory.condition.hash.
+package tracing
+
+import (
+	"context"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// Span names and attribute keys.
+const (
+	SpanNewMatcher   = "mongory.NewMatcher"
+	SpanMatch        = "mongory.Match"
+	SpanMatchBatch   = "mongory.MatchBatch"
+	SpanFilter       = "mongory.Filter"
+	AttrHash         = "mongory.condition.hash"
+	AttrMatched      = "mongory.matched"
+	AttrBatchSize    = "mongory.batch.size"
+	AttrBatchMatches = "mongory.batch.matches"
+)
+
+// Tracer starts spans, as an OpenTelemetry trace.Tracer does.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a started span, as an OpenTelemetry trace.Span.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError records the error the span's operation failed with.
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a span attribute. Value is a string, an int64 or a bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Matcher is a mongory.Matcher recording a span for each match and batch
+// operation. Methods without a context record root spans. MatchAll and the
+// explain and trace methods are the wrapped matcher's and record none.
+type Matcher struct {
+	mongory.Matcher
+	tracer Tracer
+	hash   string
+}
+
+// NewMatcher compiles condition in a span and returns a matcher tracing its
+// operations with tracer.
+func NewMatcher(ctx context.Context, tracer Tracer, condition map[string]any) (*Matcher, error) {
+	return NewMatcherWithOptions(ctx, tracer, condition, mongory.MatcherOptions{})
+}
+
+// NewMatcherWithOptions is NewMatcher with options.
+func NewMatcherWithOptions(ctx context.Context, tracer Tracer, condition map[string]any, opts mongory.MatcherOptions) (*Matcher, error) {
+	hash := mongory.ConditionHash(condition)
+	_, span := tracer.Start(ctx, SpanNewMatcher)
+	defer span.End()
+	span.SetAttributes(Attribute{AttrHash, hash})
+	m, err := mongory.NewMatcherWithOptions(condition, opts)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return &Matcher{Matcher: m, tracer: tracer, hash: hash}, nil
+}
+
+// Wrap returns m tracing its operations with tracer.
+func Wrap(m mongory.Matcher, tracer Tracer) *Matcher {
+	return &Matcher{Matcher: m, tracer: tracer, hash: m.Condition().Hash()}
+}
+
+// Unwrap returns the wrapped matcher.
+func (m *Matcher) Unwrap() mongory.Matcher {
+	return m.Matcher
+}
+
+func (m *Matcher) start(ctx context.Context, name string) (context.Context, Span) {
+	ctx, span := m.tracer.Start(ctx, name)
+	span.SetAttributes(Attribute{AttrHash, m.hash})
+	return ctx, span
+}
+
+// end records the outcome of a span's operation and ends it.
+func end(span Span, err error, attrs ...Attribute) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.SetAttributes(attrs...)
+	span.End()
+}
+
+// MatchContext matches value in a span, a child of the span in ctx if any,
+// with the result as mongory.matched.
+func (m *Matcher) MatchContext(ctx context.Context, value any) (bool, error) {
+	_, span := m.start(ctx, SpanMatch)
+	matched, err := m.Matcher.MatchContext(ctx, value)
+	end(span, err, Attribute{AttrMatched, matched})
+	return matched, err
+}
+
+// Match matches value in a root span.
+func (m *Matcher) Match(value any) (bool, error) {
+	return m.MatchContext(context.Background(), value)
+}
+
+// MatchBatchContext matches values in one span, with their number as
+// mongory.batch.size and the number matched as mongory.batch.matches.
+func (m *Matcher) MatchBatchContext(ctx context.Context, values []any, policy ...mongory.ErrorPolicy) ([]bool, error) {
+	_, span := m.start(ctx, SpanMatchBatch)
+	results, err := m.Matcher.MatchBatch(values, policy...)
+	var matches int64
+	for _, matched := range results {
+		if matched {
+			matches++
+		}
+	}
+	end(span, err, Attribute{AttrBatchSize, int64(len(values))}, Attribute{AttrBatchMatches, matches})
+	return results, err
+}
+
+// MatchBatch is MatchBatchContext in a root span.
+func (m *Matcher) MatchBatch(values []any, policy ...mongory.ErrorPolicy) ([]bool, error) {
+	return m.MatchBatchContext(context.Background(), values, policy...)
+}
+
+// FilterContext filters records in one span, with their number as
+// mongory.batch.size and the number kept as mongory.batch.matches.
+func (m *Matcher) FilterContext(ctx context.Context, records []any, policy ...mongory.ErrorPolicy) ([]any, error) {
+	ctx, span := m.start(ctx, SpanFilter)
+	kept, err := m.Matcher.FilterContext(ctx, records, policy...)
+	end(span, err, Attribute{AttrBatchSize, int64(len(records))}, Attribute{AttrBatchMatches, int64(len(kept))})
+	return kept, err
+}
+
+// FilterFunc filters records in a root span, counting the matches passed to
+// onMatch as mongory.batch.matches.
+func (m *Matcher) FilterFunc(records []any, onMatch func(i int, doc any) bool, policy ...mongory.ErrorPolicy) error {
+	_, span := m.start(context.Background(), SpanFilter)
+	var matches int64
+	err := m.Matcher.FilterFunc(records, func(i int, doc any) bool {
+		matches++
+		return onMatch(i, doc)
+	}, policy...)
+	end(span, err, Attribute{AttrBatchSize, int64(len(records))}, Attribute{AttrBatchMatches, matches})
+	return err
+}
+
+// Clone returns a clone of the matcher tracing with the same tracer.
+func (m *Matcher) Clone() (mongory.Matcher, error) {
+	clone, err := m.Matcher.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{Matcher: clone, tracer: m.tracer, hash: m.hash}, nil
+}
+
+var _ mongory.Matcher = (*Matcher)(nil)
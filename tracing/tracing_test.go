@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type spanKey struct{}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recordedSpan{name: name, attrs: map[string]any{}}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		s.parent = parent.name
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+func TestMatcherSpans(t *testing.T) {
+	r := &recorder{}
+	condition := map[string]any{"age": map[string]any{"$gte": 18}}
+	ctx, parent := r.Start(context.Background(), "request")
+	m, err := NewMatcher(ctx, r, condition)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	if matched, err := m.MatchContext(ctx, map[string]any{"age": 20}); err != nil || !matched {
+		t.Fatalf("MatchContext = %v, %v", matched, err)
+	}
+	if _, err := m.MatchBatch([]any{map[string]any{"age": 20}, map[string]any{"age": 10}, map[string]any{"age": 30}}); err != nil {
+		t.Fatalf("MatchBatch failed: %v", err)
+	}
+	kept, err := m.FilterContext(ctx, []any{map[string]any{"age": 10}, map[string]any{"age": 30}})
+	if err != nil || len(kept) != 1 {
+		t.Fatalf("FilterContext = %v, %v", kept, err)
+	}
+	parent.End()
+
+	hash := mongory.ConditionHash(condition)
+	want := []struct {
+		name, parent string
+		attrs        map[string]any
+	}{
+		{"request", "", map[string]any{}},
+		{SpanNewMatcher, "request", map[string]any{AttrHash: hash}},
+		{SpanMatch, "request", map[string]any{AttrHash: hash, AttrMatched: true}},
+		{SpanMatchBatch, "", map[string]any{AttrHash: hash, AttrBatchSize: int64(3), AttrBatchMatches: int64(2)}},
+		{SpanFilter, "request", map[string]any{AttrHash: hash, AttrBatchSize: int64(2), AttrBatchMatches: int64(1)}},
+	}
+	if len(r.spans) != len(want) {
+		t.Fatalf("recorded %d spans, want %d", len(r.spans), len(want))
+	}
+	for i, w := range want {
+		s := r.spans[i]
+		if s.name != w.name || s.parent != w.parent || !s.ended || s.err != nil {
+			t.Fatalf("span %d = %+v, want %s under %q", i, s, w.name, w.parent)
+		}
+		for key, value := range w.attrs {
+			if s.attrs[key] != value {
+				t.Fatalf("span %s: %s = %v, want %v", s.name, key, s.attrs[key], value)
+			}
+		}
+	}
+}
+
+func TestMatcherSpanErrors(t *testing.T) {
+	r := &recorder{}
+	if _, err := NewMatcher(context.Background(), r, map[string]any{"tags": map[string]any{"$in": 5}}); !errors.Is(err, mongory.ErrInvalidCondition) {
+		t.Fatalf("NewMatcher: err = %v, want ErrInvalidCondition", err)
+	}
+	if len(r.spans) != 1 || r.spans[0].err == nil || !r.spans[0].ended {
+		t.Fatalf("NewMatcher did not record its error: %+v", r.spans)
+	}
+
+	m, err := NewMatcher(context.Background(), r, map[string]any{"a": 1})
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer m.Close()
+	if _, err := m.Match(map[int]any{1: 1}); err == nil {
+		t.Fatal("Match(map[int]any) succeeded")
+	}
+	if s := r.spans[len(r.spans)-1]; s.name != SpanMatch || s.err == nil || s.attrs[AttrMatched] != false {
+		t.Fatalf("Match span = %+v", s)
+	}
+
+	clone, err := m.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+	before := len(r.spans)
+	if _, err := clone.Match(map[string]any{"a": 1}); err != nil || len(r.spans) != before+1 {
+		t.Fatalf("clone did not trace: err = %v, %d new spans", err, len(r.spans)-before)
+	}
+}
@@ -0,0 +1,141 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// urlOperators maps parameter name suffixes to operators.
+var urlOperators = map[string]string{
+	"eq":     "$eq",
+	"ne":     "$ne",
+	"gt":     "$gt",
+	"gte":    "$gte",
+	"lt":     "$lt",
+	"lte":    "$lte",
+	"in":     "$in",
+	"nin":    "$nin",
+	"exists": "$exists",
+	"regex":  "$regex",
+}
+
+// FromURLValues builds a condition from query parameters such as
+// age_gte=18&status=active. A parameter is a field name, optionally followed
+// by an underscore and one of eq, ne, gt, gte, lt, lte, in, nin, exists or
+// regex; a bare field means equality, or $in when repeated. List operators
+// take repeated or comma-separated values. Values are coerced to the field's
+// type in schema, and parameters naming a field not in schema are rejected,
+// so remove paging or sorting parameters first. A nil schema accepts every
+// field as a string. A dotted field such as user.email becomes a condition on
+// the nested document, {"user": {"email": ...}}.
+func FromURLValues(values url.Values, schema FieldTypes) (map[string]any, error) {
+	condition := map[string]any{}
+	for param, raw := range values {
+		field, op, err := splitURLParam(param, schema)
+		if err != nil {
+			return nil, err
+		}
+		kind := schema[field]
+		if op == "" {
+			if len(raw) == 1 {
+				op = "$eq"
+			} else {
+				op = "$in"
+			}
+		}
+		operand, err := urlOperand(op, kind, raw)
+		if err != nil {
+			return nil, fmt.Errorf("mongory: parameter %q: %w", param, err)
+		}
+		ops := fieldConditions(condition, field)
+		if _, exists := ops[op]; exists {
+			return nil, fmt.Errorf("mongory: parameter %q: %s given more than once for %q", param, op, field)
+		}
+		ops[op] = operand
+	}
+	return condition, nil
+}
+
+// fieldConditions returns the operator table of field in condition, adding
+// it if needed. A dotted field is nested a level per segment, since
+// conditions name fields, not paths: "user.email" is the "email" table of
+// the "user" table, which matches {"user": {"email": ...}}.
+func fieldConditions(condition map[string]any, field string) map[string]any {
+	table := condition
+	for _, name := range strings.Split(field, ".") {
+		sub, _ := table[name].(map[string]any)
+		if sub == nil {
+			sub = map[string]any{}
+			table[name] = sub
+		}
+		table = sub
+	}
+	return table
+}
+
+// splitURLParam separates a parameter into its field and operator. With a
+// schema, a name that is itself a field is never split, so fields such as
+// "is_in" still work.
+func splitURLParam(param string, schema FieldTypes) (field, op string, err error) {
+	if _, ok := schema[param]; ok {
+		return param, "", nil
+	}
+	if i := strings.LastIndexByte(param, '_'); i > 0 {
+		if op, ok := urlOperators[param[i+1:]]; ok {
+			if _, known := schema[param[:i]]; known || schema == nil {
+				return param[:i], op, nil
+			}
+		}
+	}
+	if schema == nil && param != "" {
+		return param, "", nil
+	}
+	return "", "", fmt.Errorf("mongory: unknown filter parameter %q", param)
+}
+
+func urlOperand(op string, kind FieldType, raw []string) (any, error) {
+	switch op {
+	case "$in", "$nin":
+		var items []any
+		for _, value := range raw {
+			for _, part := range strings.Split(value, ",") {
+				item, err := coerceURLValue(kind, part)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	if len(raw) != 1 {
+		return nil, fmt.Errorf("%s takes a single value, got %d", op, len(raw))
+	}
+	switch op {
+	case "$exists":
+		return coerceURLValue(BoolField, raw[0])
+	case "$regex":
+		return raw[0], nil
+	default:
+		return coerceURLValue(kind, raw[0])
+	}
+}
+
+func coerceURLValue(kind FieldType, text string) (any, error) {
+	switch kind {
+	case IntField:
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case FloatField:
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case BoolField:
+		return strconv.ParseBool(strings.TrimSpace(text))
+	case StringField:
+		return text, nil
+	default:
+		return nil, fmt.Errorf("%s fields cannot be filtered from a query string", kind)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build !mongory_nohttp
+
+package mongory
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFromURLValues(t *testing.T) {
+	schema := FieldTypes{
+		"age":        IntField,
+		"score":      FloatField,
+		"verified":   BoolField,
+		"status":     StringField,
+		"user.email": StringField,
+		"is_in":      StringField,
+	}
+	cases := []struct {
+		query string
+		want  map[string]any
+	}{
+		{"age_gte=18&age_lt=65", map[string]any{"age": map[string]any{"$gte": int64(18), "$lt": int64(65)}}},
+		{"status=active", map[string]any{"status": map[string]any{"$eq": "active"}}},
+		{"status=active&status=pending", map[string]any{"status": map[string]any{"$in": []any{"active", "pending"}}}},
+		{"age_in=1,2&age_in=3", map[string]any{"age": map[string]any{"$in": []any{int64(1), int64(2), int64(3)}}}},
+		{"score_gt=1.5&verified=true", map[string]any{
+			"score":    map[string]any{"$gt": 1.5},
+			"verified": map[string]any{"$eq": true},
+		}},
+		{"user.email_exists=false", map[string]any{"user": map[string]any{"email": map[string]any{"$exists": false}}}},
+		{"user.email=a&user.email_ne=b", map[string]any{"user": map[string]any{"email": map[string]any{"$eq": "a", "$ne": "b"}}}},
+		{"is_in=x", map[string]any{"is_in": map[string]any{"$eq": "x"}}},
+	}
+	for _, tc := range cases {
+		values, err := url.ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) failed: %v", tc.query, err)
+		}
+		got, err := FromURLValues(values, schema)
+		if err != nil {
+			t.Fatalf("FromURLValues(%q) failed: %v", tc.query, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("FromURLValues(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	for _, query := range []string{"page=2", "age_gte=x", "age_gte=1&age_gte=2", "verified=maybe"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := FromURLValues(values, schema); err == nil {
+			t.Fatalf("FromURLValues(%q) succeeded, want an error", query)
+		}
+	}
+
+	values, _ := url.ParseQuery("name=ann&age_gte=18")
+	condition, err := FromURLValues(values, nil)
+	if err != nil {
+		t.Fatalf("FromURLValues without schema failed: %v", err)
+	}
+	matcher, err := NewCMatcher(condition, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	// Without a schema values stay strings.
+	if matched, err := matcher.Match(map[string]any{"name": "ann", "age": "30"}); err != nil || !matched {
+		t.Fatalf("Match = %v, %v; want true", matched, err)
+	}
+
+	// Dotted fields test the nested document.
+	values, _ = url.ParseQuery("user.email=ann@example.com&age_gte=18")
+	if condition, err = FromURLValues(values, schema); err != nil {
+		t.Fatalf("FromURLValues(user.email) failed: %v", err)
+	}
+	nested, err := NewMatcher(condition)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	defer nested.Close()
+	for _, tc := range []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"age": 30, "user": map[string]any{"email": "ann@example.com"}}, true},
+		{map[string]any{"age": 30, "user": map[string]any{"email": "bob@example.com"}}, false},
+		{map[string]any{"age": 30, "user.email": "ann@example.com"}, false},
+	} {
+		if matched, err := nested.Match(tc.doc); err != nil || matched != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, matched, err, tc.want)
+		}
+	}
+}
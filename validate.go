@@ -0,0 +1,326 @@
+package mongory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mongoryhq/mongory-go/internal/cgo"
+)
+
+// ValidateCondition checks that condition is well formed without compiling
+// it: every operator is known, every operand has a type the operator
+// accepts, $and and $or hold lists of conditions, and referenced macros are
+// defined. Services accepting user-supplied conditions can call it before
+// paying for NewMatcher. It is stricter than compiling, which treats
+// unknown operators as field names. Errors name the offending key path.
+func ValidateCondition(condition map[string]any) error {
+	expanded, err := expandMacros(condition)
+	if err != nil {
+		return err
+	}
+	if expanded, err = foldConstants(expanded, time.Now()); err != nil {
+		return err
+	}
+	return validator{types: true, unknown: true}.table(nil, reflect.ValueOf(expanded))
+}
+
+// validator walks a condition checking operand types, unknown operators or
+// both. Unchecked unknown operators are walked as field names, the way
+// compiling treats them.
+type validator struct {
+	types   bool
+	unknown bool
+}
+
+func (v validator) table(path []string, table reflect.Value) error {
+	iter := table.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		keyPath := append(path[:len(path):len(path)], key)
+		value := indirectOperand(iter.Value())
+		doc, ok := OperatorDoc{}, false
+		if strings.HasPrefix(key, "$") {
+			doc, ok = operatorDoc(key)
+			if !ok && v.unknown {
+				err := validationError(keyPath, "unknown operator %s", key)
+				err.unknown = true
+				return err
+			}
+		}
+		if !ok {
+			if isConditionTable(value) {
+				if err := v.table(keyPath, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := v.operand(keyPath, doc, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeError returns the error for a mistyped operand, or nil when types are
+// not checked.
+func (v validator) typeError(path []string, format string, args ...any) error {
+	if !v.types {
+		return nil
+	}
+	return validationError(path, format, args...)
+}
+
+func (v validator) operand(path []string, doc OperatorDoc, operand reflect.Value) error {
+	switch doc.operand {
+	case operandArray:
+		if _, shared := operandInterface(operand).(*SharedValue); shared {
+			return nil
+		}
+		if !isList(operand) {
+			return v.typeError(path, "%s operand must be an array, got %s", doc.Name, operandType(operand))
+		}
+	case operandBoolean:
+		if !operand.IsValid() || operand.Kind() != reflect.Bool {
+			return v.typeError(path, "%s operand must be a boolean, got %s", doc.Name, operandType(operand))
+		}
+	case operandString:
+		if v.types {
+			return validatePattern(path, doc.Name, operand)
+		}
+	case operandCondition:
+		if !isConditionTable(operand) {
+			return v.typeError(path, "%s operand must be a condition, got %s", doc.Name, operandType(operand))
+		}
+		return v.table(path, operand)
+	case operandConditions:
+		if !isList(operand) {
+			return v.typeError(path, "%s operand must be a list of conditions, got %s", doc.Name, operandType(operand))
+		}
+		for i := 0; i < operand.Len(); i++ {
+			itemPath := append(path[:len(path):len(path)], fmt.Sprint(i))
+			item := indirectOperand(operand.Index(i))
+			if !isConditionTable(item) {
+				if err := v.typeError(itemPath, "%s entries must be conditions, got %s", doc.Name, operandType(item)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := v.table(itemPath, item); err != nil {
+				return err
+			}
+		}
+	case operandFieldValue:
+		if isConditionTable(operand) {
+			return v.table(path, operand)
+		}
+	case operandRollout:
+		if v.types {
+			return validateRollout(path, operand)
+		}
+	case operandDistinct:
+		if operand.IsValid() && (operand.Kind() == reflect.Bool && operand.Bool() || operand.Kind() == reflect.String && operand.Len() > 0) {
+			return nil
+		}
+		return v.typeError(path, "%s operand must be true or a field path, got %s", doc.Name, operandType(operand))
+	case operandRange:
+		if !isList(operand) || operand.Len() != 2 {
+			return v.typeError(path, "%s operand must be [low, high], got %s", doc.Name, operandType(operand))
+		}
+		low, high := rangeBound(indirectOperand(operand.Index(0))), rangeBound(indirectOperand(operand.Index(1)))
+		if low == "" || low != high {
+			return v.typeError(path, "%s bounds must be two numbers or two strings, got %s and %s", doc.Name,
+				operandType(indirectOperand(operand.Index(0))), operandType(indirectOperand(operand.Index(1))))
+		}
+	case operandFunc:
+		switch fn := operandInterface(operand).(type) {
+		case func(any) bool:
+			if fn != nil {
+				return nil
+			}
+		case func(context.Context, any) bool:
+			if fn != nil {
+				return nil
+			}
+		}
+		return v.typeError(path, "%s operand must be a func(any) bool or a func(context.Context, any) bool, got %s", doc.Name, operandType(operand))
+	}
+	return nil
+}
+
+// validatePattern checks $regex and $glob operands the way compiling them
+// would, plus that regular expressions parse.
+func validatePattern(path []string, name string, operand reflect.Value) error {
+	if re, ok := operandInterface(operand).(*regexp.Regexp); ok && name == "$regex" && re != nil {
+		return nil
+	}
+	if !operand.IsValid() || operand.Kind() != reflect.String {
+		return validationError(path, "%s operand must be a string, got %s", name, operandType(operand))
+	}
+	pattern := operand.String()
+	switch name {
+	case "$regex":
+		if _, err := regexp.Compile(pattern); err != nil {
+			return validationError(path, "%s operand is not a valid regular expression: %v", name, err)
+		}
+	case "$glob":
+		trailing := len(pattern) - len(strings.TrimRight(pattern, `\`))
+		if trailing%2 == 1 {
+			return validationError(path, "%s pattern must not end with an unescaped backslash", name)
+		}
+	}
+	return nil
+}
+
+func validateRollout(path []string, operand reflect.Value) error {
+	percent := operand
+	if isConditionTable(operand) {
+		percent = reflect.Value{}
+		iter := operand.MapRange()
+		for iter.Next() {
+			switch key, value := iter.Key().String(), indirectOperand(iter.Value()); key {
+			case "percent":
+				percent = value
+			case "salt":
+				if !value.IsValid() || value.Kind() != reflect.String {
+					return validationError(path, "$rollout salt must be a string, got %s", operandType(value))
+				}
+			default:
+				return validationError(path, "$rollout has unknown key %q", key)
+			}
+		}
+	}
+	value, ok := operandNumber(percent)
+	if !ok {
+		return validationError(path, "$rollout percent must be a number, got %s", operandType(percent))
+	}
+	if value < 0 || value > 100 {
+		return validationError(path, "$rollout percent must be between 0 and 100")
+	}
+	return nil
+}
+
+func operatorDoc(name string) (OperatorDoc, bool) {
+	if doc, ok := builtinOperator(name); ok {
+		return doc, true
+	}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	if doc, ok := customOperators[name]; ok {
+		return doc, true
+	}
+	if cgo.CustomOperator(name) {
+		return valueOperatorDoc(name), true
+	}
+	return OperatorDoc{}, false
+}
+
+func builtinOperator(name string) (OperatorDoc, bool) {
+	for _, doc := range builtinOperators {
+		if doc.Name == name {
+			return doc, true
+		}
+	}
+	return OperatorDoc{}, false
+}
+
+// ConditionError is a problem ValidateCondition found in a condition. It
+// matches ErrInvalidCondition, and ErrUnsupportedOperator for unknown
+// operators.
+type ConditionError struct {
+	// Path is the dotted key path of the offending entry.
+	Path    string
+	Message string
+	unknown bool
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("mongory: invalid condition at %q: %s", e.Path, e.Message)
+}
+
+func (e *ConditionError) Is(target error) bool {
+	return target == ErrInvalidCondition || e.unknown && target == ErrUnsupportedOperator
+}
+
+func validationError(path []string, format string, args ...any) *ConditionError {
+	return &ConditionError{Path: strings.Join(path, "."), Message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	regexpType      = reflect.TypeOf((*regexp.Regexp)(nil))
+	sharedValueType = reflect.TypeOf((*SharedValue)(nil))
+)
+
+// indirectOperand unwraps interfaces and non-nil pointers, except to
+// regular expressions and shared values, which are operands themselves.
+func indirectOperand(rv reflect.Value) reflect.Value {
+	for rv.IsValid() {
+		switch {
+		case rv.Kind() == reflect.Interface && !rv.IsNil():
+			rv = rv.Elem()
+		case rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Type() != regexpType && rv.Type() != sharedValueType:
+			rv = rv.Elem()
+		default:
+			return rv
+		}
+	}
+	return rv
+}
+
+func operandInterface(rv reflect.Value) any {
+	if !rv.IsValid() || !rv.CanInterface() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+func isConditionTable(rv reflect.Value) bool {
+	return rv.IsValid() && rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String
+}
+
+func isList(rv reflect.Value) bool {
+	return rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array)
+}
+
+func operandNumber(rv reflect.Value) (float64, bool) {
+	if !rv.IsValid() {
+		return 0, false
+	}
+	if n, ok := operandInterface(rv).(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// rangeBound returns what a $between bound compares as, "number" or
+// "string", or "" if it cannot be one. Times compare as strings.
+func rangeBound(rv reflect.Value) string {
+	if _, ok := operandNumber(rv); ok {
+		return "number"
+	}
+	if _, ok := operandInterface(rv).(time.Time); ok || rv.IsValid() && rv.Kind() == reflect.String {
+		return "string"
+	}
+	return ""
+}
+
+func operandType(rv reflect.Value) string {
+	if !rv.IsValid() {
+		return "null"
+	}
+	return rv.Type().String()
+}
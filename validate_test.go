@@ -0,0 +1,81 @@
+package mongory
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateCondition(t *testing.T) {
+	for _, doc := range Operators() {
+		for _, example := range doc.Examples {
+			if err := ValidateCondition(example.Condition); err != nil {
+				t.Fatalf("%s example %v: %v", doc.Name, example.Condition, err)
+			}
+		}
+	}
+	if err := DefineMacro("validate-adult", map[string]any{"age": map[string]any{"$gte": 18}}); err != nil {
+		t.Fatalf("DefineMacro failed: %v", err)
+	}
+	defer UndefineMacro("validate-adult")
+	ids, err := NewSharedValue([]any{1, 2})
+	if err != nil {
+		t.Fatalf("NewSharedValue failed: %v", err)
+	}
+	defer ids.Release()
+
+	valid := []map[string]any{
+		nil,
+		{},
+		{"a": 1, "b": map[string]any{"c": "x"}},
+		{"$and": []map[string]any{{"a": 1}, {"b": map[string]any{"$in": []int{1, 2}}}}},
+		{"a": map[string]any{"$regex": regexp.MustCompile("^x")}},
+		{"a": map[string]any{"$not": map[string]any{"$gt": 1}}, "b": map[string]any{"$size": 2}},
+		{"id": map[string]any{"$in": ids}},
+		{"id": map[string]any{"$rollout": map[string]any{"percent": 10, "salt": "s"}}},
+		{"$macro": "validate-adult"},
+	}
+	for _, condition := range valid {
+		if err := ValidateCondition(condition); err != nil {
+			t.Fatalf("ValidateCondition(%v) = %v, want nil", condition, err)
+		}
+	}
+
+	invalid := []struct {
+		condition map[string]any
+		want      string
+	}{
+		{map[string]any{"a": map[string]any{"$foo": 1}}, `at "a.$foo": unknown operator $foo`},
+		{map[string]any{"a": map[string]any{"$in": 1}}, `at "a.$in": $in operand must be an array, got int`},
+		{map[string]any{"a": map[string]any{"$exists": "yes"}}, `$exists operand must be a boolean, got string`},
+		{map[string]any{"a": map[string]any{"$regex": "("}}, `$regex operand is not a valid regular expression`},
+		{map[string]any{"a": map[string]any{"$glob": `a\`}}, `unescaped backslash`},
+		{map[string]any{"$or": map[string]any{"a": 1}}, `$or operand must be a list of conditions`},
+		{map[string]any{"$and": []any{map[string]any{"a": 1}, 2}}, `at "$and.1": $and entries must be conditions, got int`},
+		{map[string]any{"$and": []any{map[string]any{"a": map[string]any{"$lt": nil, "$bad": 1}}}}, `at "$and.0.a.$bad"`},
+		{map[string]any{"a": map[string]any{"$elemMatch": 1}}, `$elemMatch operand must be a condition`},
+		{map[string]any{"a": map[string]any{"$rollout": 120}}, `between 0 and 100`},
+		{map[string]any{"a": map[string]any{"$rollout": map[string]any{"percent": 5, "seed": 1}}}, `unknown key "seed"`},
+		{map[string]any{"$macro": "validate-missing"}, `undefined macro "validate-missing"`},
+	}
+	for _, tc := range invalid {
+		err := ValidateCondition(tc.condition)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("ValidateCondition(%v) = %v, want error containing %q", tc.condition, err, tc.want)
+		}
+	}
+}
+
+func BenchmarkValidateCondition(b *testing.B) {
+	condition := map[string]any{
+		"age":  map[string]any{"$gte": 18, "$lt": 65},
+		"tags": map[string]any{"$in": []any{"a", "b", "c"}},
+		"$or":  []any{map[string]any{"name": map[string]any{"$regex": "^a"}}, map[string]any{"vip": true}},
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := ValidateCondition(condition); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
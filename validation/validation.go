@@ -0,0 +1,163 @@
+// Package validation checks datasets against named expectations written as
+// mongory conditions and reports how well each one holds:
+//
+//	suite, err := validation.NewSuite(
+//		validation.Expectation{Name: "age is adult", Condition: map[string]any{"age": map[string]any{"$gte": 18}}},
+//		validation.Expectation{Name: "has email", Condition: map[string]any{"email": map[string]any{"$regex": "@"}},
+//			MinPassRate: 0.99},
+//	)
+//	report, err := suite.Run(records)
+//	if !report.Success { log.Print(report) }
+package validation
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/mongoryhq/mongory-go"
+)
+
+// DefaultMaxSamples is how many failing records a report keeps per
+// expectation unless the suite says otherwise.
+const DefaultMaxSamples = 5
+
+// Expectation is a condition every record is expected to satisfy.
+type Expectation struct {
+	Name        string
+	Condition   map[string]any
+	Description string
+	// MinPassRate is the share of records, from 0 to 1, that must pass for
+	// the expectation to succeed. Zero means every record must pass.
+	MinPassRate float64
+}
+
+// Sample is a record that failed an expectation.
+type Sample struct {
+	Index  int    `json:"index"`
+	Record any    `json:"record"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Result is how one expectation fared over the dataset. Records that could
+// not be matched count as failures and are also counted in Errors.
+type Result struct {
+	Name     string   `json:"name"`
+	Passed   int      `json:"passed"`
+	Failed   int      `json:"failed"`
+	Errors   int      `json:"errors"`
+	PassRate float64  `json:"passRate"`
+	Success  bool     `json:"success"`
+	Samples  []Sample `json:"samples,omitempty"`
+}
+
+// Report is the outcome of running a suite.
+type Report struct {
+	Records int      `json:"records"`
+	Results []Result `json:"results"`
+	Success bool     `json:"success"`
+}
+
+// String renders the report as one line per expectation.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d records\n", r.Records)
+	for _, result := range r.Results {
+		status := "PASS"
+		if !result.Success {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s %s: %d passed, %d failed (%.2f%%)", status, result.Name, result.Passed, result.Failed, result.PassRate*100)
+		if result.Errors > 0 {
+			fmt.Fprintf(&b, ", %d errors", result.Errors)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Suite is a compiled set of expectations.
+type Suite struct {
+	// MaxSamples caps the failing records kept per expectation; zero uses
+	// DefaultMaxSamples and a negative value keeps none.
+	MaxSamples int
+
+	expectations []Expectation
+	matchers     []mongory.Matcher
+}
+
+// NewSuite compiles expectations. Names must be unique.
+func NewSuite(expectations ...Expectation) (*Suite, error) {
+	s := &Suite{expectations: expectations}
+	seen := map[string]bool{}
+	for _, e := range expectations {
+		if e.Name == "" || seen[e.Name] {
+			return nil, fmt.Errorf("validation: expectation names must be unique and non-empty, got %q", e.Name)
+		}
+		seen[e.Name] = true
+		if e.MinPassRate < 0 || e.MinPassRate > 1 {
+			return nil, fmt.Errorf("validation: expectation %q: MinPassRate must be between 0 and 1", e.Name)
+		}
+		m, err := mongory.NewMatcher(e.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("validation: expectation %q: %w", e.Name, err)
+		}
+		s.matchers = append(s.matchers, m)
+	}
+	return s, nil
+}
+
+// Run checks every record against every expectation.
+func (s *Suite) Run(records []any) (*Report, error) {
+	return s.RunSeq(slices.Values(records))
+}
+
+// RunSeq is Run over a sequence, so large datasets can be streamed. A Suite
+// runs one dataset at a time.
+func (s *Suite) RunSeq(records iter.Seq[any]) (*Report, error) {
+	maxSamples := s.MaxSamples
+	if maxSamples == 0 {
+		maxSamples = DefaultMaxSamples
+	}
+	results := make([]Result, len(s.expectations))
+	for i, e := range s.expectations {
+		results[i].Name = e.Name
+	}
+	count := 0
+	for record := range records {
+		for i, m := range s.matchers {
+			result := &results[i]
+			matched, err := m.Match(record)
+			if err == nil && matched {
+				result.Passed++
+				continue
+			}
+			result.Failed++
+			sample := Sample{Index: count, Record: record}
+			if err != nil {
+				result.Errors++
+				sample.Error = err.Error()
+			}
+			if len(result.Samples) < maxSamples {
+				result.Samples = append(result.Samples, sample)
+			}
+		}
+		count++
+	}
+	report := &Report{Records: count, Results: results, Success: true}
+	for i := range results {
+		result := &results[i]
+		result.PassRate = 1
+		if count > 0 {
+			result.PassRate = float64(result.Passed) / float64(count)
+		}
+		if minRate := s.expectations[i].MinPassRate; minRate == 0 {
+			result.Success = result.Failed == 0
+		} else {
+			result.Success = result.PassRate >= minRate
+		}
+		report.Success = report.Success && result.Success
+	}
+	return report, nil
+}
@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	suite, err := NewSuite(
+		Expectation{Name: "adult", Condition: map[string]any{"age": map[string]any{"$gte": 18}}},
+		Expectation{Name: "email", Condition: map[string]any{"email": map[string]any{"$regex": "@"}}, MinPassRate: 0.5},
+		Expectation{Name: "status", Condition: map[string]any{"status": map[string]any{"$in": []any{"active", "banned"}}}},
+	)
+	if err != nil {
+		t.Fatalf("NewSuite failed: %v", err)
+	}
+	suite.MaxSamples = 1
+	records := []any{
+		map[string]any{"age": 30, "email": "a@x", "status": "active"},
+		map[string]any{"age": 10, "email": "b@x", "status": "active"},
+		map[string]any{"age": 12, "status": "active"},
+		map[string]any{"age": 40, "email": "d@x", "status": "banned"},
+	}
+	report, err := suite.Run(records)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Records != 4 || report.Success {
+		t.Fatalf("report = %+v, want 4 records and failure", report)
+	}
+	adult, email, status := report.Results[0], report.Results[1], report.Results[2]
+	if adult.Passed != 2 || adult.Failed != 2 || adult.Success || len(adult.Samples) != 1 || adult.Samples[0].Index != 1 {
+		t.Fatalf("adult = %+v", adult)
+	}
+	if email.PassRate != 0.75 || !email.Success {
+		t.Fatalf("email = %+v", email)
+	}
+	if !status.Success || status.Failed != 0 {
+		t.Fatalf("status = %+v", status)
+	}
+	if text := report.String(); !strings.Contains(text, "FAIL adult: 2 passed, 2 failed (50.00%)") {
+		t.Fatalf("String() = %q", text)
+	}
+}
+
+func TestNewSuiteRejectsInvalid(t *testing.T) {
+	for _, expectations := range [][]Expectation{
+		{{Name: ""}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", MinPassRate: 2}},
+		{{Name: "a", Condition: map[string]any{"$and": "x"}}},
+	} {
+		if _, err := NewSuite(expectations...); err == nil {
+			t.Fatalf("NewSuite(%v) succeeded, want an error", expectations)
+		}
+	}
+}
@@ -0,0 +1,702 @@
+//go:build !mongory_noformats
+
+package mongory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ParseConditionYAML decodes a YAML rule file into a condition. It reads the
+// YAML most rule files use, without pulling in a YAML library: block and
+// flow mappings and sequences, plain and quoted scalars, literal (|) and
+// folded (>) block scalars, and comments. Anchors, aliases, tags and multiple
+// documents are rejected.
+//
+// Scalars resolve as in the YAML 1.2 core schema: null, booleans, integers
+// (decimal, 0x hex, 0o octal) as int64, and floats (including .inf and .nan)
+// as float64. Unquoted timestamps such as 2024-05-01 or
+// 2024-05-01 10:00:00 +08:00 become RFC 3339 strings in UTC, which order
+// correctly against documents that store timestamps the same way. Quoted
+// scalars are always strings.
+func ParseConditionYAML(data []byte) (map[string]any, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("mongory: invalid YAML condition: not valid UTF-8")
+	}
+	p := newYAMLParser(string(data))
+	value, err := p.parseDocument()
+	if err != nil {
+		return nil, fmt.Errorf("mongory: invalid YAML condition: %w", err)
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return v, nil
+	case nil:
+		return map[string]any{}, nil
+	default:
+		return nil, fmt.Errorf("mongory: YAML condition must be a mapping, got %T", value)
+	}
+}
+
+// NewMatcherFromYAML compiles a YAML rule file, $ operators included.
+func NewMatcherFromYAML(data []byte) (Matcher, error) {
+	condition, err := ParseConditionYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewMatcher(condition)
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string // content after the indentation, comment removed
+	raw    string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func newYAMLParser(src string) *yamlParser {
+	src = strings.TrimPrefix(src, "\ufeff")
+	raws := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	p := &yamlParser{lines: make([]yamlLine, len(raws))}
+	for i, raw := range raws {
+		trimmed := strings.TrimLeft(raw, " ")
+		p.lines[i] = yamlLine{
+			number: i + 1,
+			indent: len(raw) - len(trimmed),
+			text:   strings.TrimRight(stripYAMLComment(trimmed), " \t"),
+			raw:    raw,
+		}
+	}
+	return p
+}
+
+func (p *yamlParser) errorf(line *yamlLine, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", line.number, fmt.Sprintf(format, args...))
+}
+
+// current skips blank and comment-only lines and returns the next content
+// line, or nil at the end of the document.
+func (p *yamlParser) current() *yamlLine {
+	for p.pos < len(p.lines) {
+		line := &p.lines[p.pos]
+		if line.text != "" {
+			if line.indent == 0 && (line.text == "..." || strings.HasPrefix(line.text, "... ")) {
+				return nil
+			}
+			return line
+		}
+		p.pos++
+	}
+	return nil
+}
+
+func (p *yamlParser) parseDocument() (any, error) {
+	if line := p.current(); line != nil && line.indent == 0 && (line.text == "---" || strings.HasPrefix(line.text, "--- ")) {
+		if rest := strings.TrimSpace(line.text[3:]); rest != "" {
+			return nil, p.errorf(line, "content after the document marker is not supported")
+		}
+		p.pos++
+	}
+	line := p.current()
+	if line == nil {
+		return nil, nil
+	}
+	value, err := p.parseNode(line.indent)
+	if err != nil {
+		return nil, err
+	}
+	if line := p.current(); line != nil {
+		if line.indent == 0 && strings.HasPrefix(line.text, "---") {
+			return nil, p.errorf(line, "multiple documents are not supported")
+		}
+		return nil, p.errorf(line, "unexpected content %q", line.text)
+	}
+	return value, nil
+}
+
+// parseNode parses the block node starting at the current line, which is
+// indented by indent.
+func (p *yamlParser) parseNode(indent int) (any, error) {
+	line := p.current()
+	if line == nil || line.indent < indent {
+		return nil, nil
+	}
+	if line.text[0] == '\t' {
+		return nil, p.errorf(line, "tabs are not allowed for indentation")
+	}
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(line.indent)
+	}
+	if _, _, ok, err := splitYAMLKey(line.text); err != nil {
+		return nil, p.errorf(line, "%v", err)
+	} else if ok {
+		return p.parseMapping(line.indent)
+	}
+	p.pos++
+	return p.parseInline(line, line.text)
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	mapping := map[string]any{}
+	for {
+		line := p.current()
+		if line == nil || line.indent < indent {
+			return mapping, nil
+		}
+		if line.indent > indent || line.text[0] == '\t' {
+			return nil, p.errorf(line, "unexpected indentation")
+		}
+		key, rest, ok, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, p.errorf(line, "%v", err)
+		}
+		if !ok {
+			return nil, p.errorf(line, "expected a mapping entry, got %q", line.text)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, p.errorf(line, "duplicate key %q", key)
+		}
+		p.pos++
+		var value any
+		switch {
+		case rest == "":
+			next := p.current()
+			switch {
+			case next != nil && next.indent > indent:
+				value, err = p.parseNode(next.indent)
+			case next != nil && next.indent == indent && isYAMLSequenceItem(next.text):
+				value, err = p.parseSequence(indent)
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			value, err = p.parseBlockScalar(line, rest, indent)
+		default:
+			value, err = p.parseInline(line, rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapping[key] = value
+	}
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	items := []any{}
+	for {
+		line := p.current()
+		if line == nil || line.indent < indent || (line.indent == indent && !isYAMLSequenceItem(line.text)) {
+			return items, nil
+		}
+		if line.indent > indent || line.text[0] == '\t' {
+			return nil, p.errorf(line, "unexpected indentation")
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		var value any
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			if next := p.current(); next != nil && next.indent > indent {
+				value, err = p.parseNode(next.indent)
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			p.pos++
+			value, err = p.parseBlockScalar(line, rest, indent)
+		default:
+			// Re-read the item's content as a node indented to where it
+			// starts, so "- key: value" continues as a mapping on the
+			// following lines.
+			offset := len(line.text) - len(rest)
+			line.indent += offset
+			line.text = rest
+			line.raw = strings.Repeat(" ", line.indent) + rest
+			value, err = p.parseNode(line.indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+}
+
+// parseInline parses a scalar or flow collection that starts on line. Flow
+// collections may continue on the following lines.
+func (p *yamlParser) parseInline(line *yamlLine, text string) (any, error) {
+	if text[0] == '[' || text[0] == '{' {
+		for !yamlFlowClosed(text) {
+			next := p.current()
+			if next == nil {
+				return nil, p.errorf(line, "unterminated flow collection")
+			}
+			text += " " + next.text
+			p.pos++
+		}
+	}
+	f := &yamlFlow{src: text}
+	value, err := f.parseValue(false)
+	if err == nil {
+		f.skipSpace()
+		if f.pos < len(f.src) {
+			err = fmt.Errorf("unexpected %q after value", f.src[f.pos:])
+		}
+	}
+	if err != nil {
+		return nil, p.errorf(line, "%v", err)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) parseBlockScalar(line *yamlLine, header string, parentIndent int) (string, error) {
+	style, chomp := header[0], byte(0)
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			return "", p.errorf(line, "explicit block scalar indentation is not supported")
+		default:
+			return "", p.errorf(line, "invalid block scalar header %q", header)
+		}
+	}
+	var body []string
+	indent := -1
+	for p.pos < len(p.lines) {
+		raw := p.lines[p.pos].raw
+		trimmed := strings.TrimLeft(raw, " ")
+		if trimmed == "" {
+			body = append(body, "")
+			p.pos++
+			continue
+		}
+		lineIndent := len(raw) - len(trimmed)
+		if indent < 0 {
+			if lineIndent <= parentIndent {
+				break
+			}
+			indent = lineIndent
+		}
+		if lineIndent < indent {
+			break
+		}
+		body = append(body, raw[indent:])
+		p.pos++
+	}
+	// Trailing blank lines belong to the chomping indicator, not the text.
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+	var text string
+	if style == '|' {
+		text = strings.Join(body, "\n")
+	} else {
+		text = foldYAMLLines(body)
+	}
+	switch {
+	case len(body) == 0:
+		return "", nil
+	case chomp == '-':
+		return text, nil
+	case chomp == '+':
+		return text + strings.Repeat("\n", trailing+1), nil
+	default:
+		return text + "\n", nil
+	}
+}
+
+// foldYAMLLines joins folded block scalar lines with spaces; blank lines and
+// more-indented lines keep their line breaks.
+func foldYAMLLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case line == "" || prev == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(prev, " "):
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// stripYAMLComment removes a trailing comment. A "#" starts a comment at the
+// beginning of the text or after whitespace, outside quoted scalars.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				if i+1 < len(text) && text[i+1] == '\'' {
+					i++
+				} else {
+					quote = 0
+				}
+			}
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			if i == 0 || strings.IndexByte(" \t[{,:-", text[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '\t' {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+// splitYAMLKey splits a block mapping entry "key: value" into its key and
+// the rest of the line. ok is false when text is not a mapping entry.
+func splitYAMLKey(text string) (key, rest string, ok bool, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		f := &yamlFlow{src: text}
+		quoted, err := f.parseQuoted()
+		if err != nil {
+			return "", "", false, err
+		}
+		f.skipSpace()
+		if f.pos < len(f.src) && f.src[f.pos] == ':' && (f.pos+1 == len(f.src) || f.src[f.pos+1] == ' ') {
+			return quoted, strings.TrimSpace(f.src[f.pos+1:]), true, nil
+		}
+		return "", "", false, nil
+	}
+	if text[0] == '[' || text[0] == '{' {
+		return "", "", false, nil
+	}
+	if strings.HasPrefix(text, "? ") || text == "?" {
+		return "", "", false, errors.New("complex mapping keys are not supported")
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// yamlFlowClosed reports whether every bracket opened in text is closed.
+func yamlFlowClosed(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// yamlFlow parses one line's worth of flow content: scalars and [...] or
+// {...} collections.
+type yamlFlow struct {
+	src string
+	pos int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.src) && (f.src[f.pos] == ' ' || f.src[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) parseValue(inFlow bool) (any, error) {
+	f.skipSpace()
+	if f.pos >= len(f.src) {
+		return nil, nil
+	}
+	switch c := f.src[f.pos]; c {
+	case '[':
+		return f.parseFlowSequence()
+	case '{':
+		return f.parseFlowMapping()
+	case '"', '\'':
+		return f.parseQuoted()
+	case '&', '*':
+		return nil, errors.New("anchors and aliases are not supported")
+	case '!':
+		return nil, errors.New("tags are not supported")
+	case '|', '>':
+		if inFlow {
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return resolveYAMLScalar(f.parsePlain(inFlow))
+}
+
+func (f *yamlFlow) parsePlain(inFlow bool) string {
+	start := f.pos
+	for f.pos < len(f.src) {
+		c := f.src[f.pos]
+		if inFlow && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if inFlow && c == ':' && (f.pos+1 == len(f.src) || strings.IndexByte(" ,]}", f.src[f.pos+1]) >= 0) {
+			break
+		}
+		f.pos++
+	}
+	return strings.TrimSpace(f.src[start:f.pos])
+}
+
+func (f *yamlFlow) parseQuoted() (string, error) {
+	quote := f.src[f.pos]
+	f.pos++
+	var b strings.Builder
+	for f.pos < len(f.src) {
+		c := f.src[f.pos]
+		switch {
+		case c == quote && quote == '\'':
+			if f.pos+1 < len(f.src) && f.src[f.pos+1] == '\'' {
+				b.WriteByte('\'')
+				f.pos += 2
+				continue
+			}
+			f.pos++
+			return b.String(), nil
+		case c == quote:
+			f.pos++
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if err := f.parseEscape(&b); err != nil {
+				return "", err
+			}
+			continue
+		default:
+			b.WriteByte(c)
+		}
+		f.pos++
+	}
+	return "", errors.New("unterminated quoted scalar")
+}
+
+func (f *yamlFlow) parseEscape(b *strings.Builder) error {
+	if f.pos+1 >= len(f.src) {
+		return errors.New("unterminated escape sequence")
+	}
+	c := f.src[f.pos+1]
+	f.pos += 2
+	simple := map[byte]string{
+		'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+		'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	}
+	if s, ok := simple[c]; ok {
+		b.WriteString(s)
+		return nil
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if width == 0 || f.pos+width > len(f.src) {
+		return fmt.Errorf("invalid escape sequence \\%c", c)
+	}
+	code, err := strconv.ParseUint(f.src[f.pos:f.pos+width], 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid escape sequence \\%c%s", c, f.src[f.pos:f.pos+width])
+	}
+	b.WriteRune(rune(code))
+	f.pos += width
+	return nil
+}
+
+func (f *yamlFlow) parseFlowSequence() ([]any, error) {
+	f.pos++
+	items := []any{}
+	for {
+		f.skipSpace()
+		if f.pos >= len(f.src) {
+			return nil, errors.New("unterminated flow sequence")
+		}
+		if f.src[f.pos] == ']' {
+			f.pos++
+			return items, nil
+		}
+		item, err := f.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if err := f.flowSeparator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *yamlFlow) parseFlowMapping() (map[string]any, error) {
+	f.pos++
+	mapping := map[string]any{}
+	for {
+		f.skipSpace()
+		if f.pos >= len(f.src) {
+			return nil, errors.New("unterminated flow mapping")
+		}
+		if f.src[f.pos] == '}' {
+			f.pos++
+			return mapping, nil
+		}
+		var key string
+		if c := f.src[f.pos]; c == '"' || c == '\'' {
+			quoted, err := f.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			key = quoted
+		} else {
+			key = f.parsePlain(true)
+		}
+		f.skipSpace()
+		var value any
+		if f.pos < len(f.src) && f.src[f.pos] == ':' {
+			f.pos++
+			var err error
+			if value, err = f.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		mapping[key] = value
+		if err := f.flowSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// flowSeparator consumes the comma after a flow item, leaving a closing
+// bracket for the caller.
+func (f *yamlFlow) flowSeparator(closing byte) error {
+	f.skipSpace()
+	switch {
+	case f.pos >= len(f.src):
+		return fmt.Errorf("expected ',' or %q", closing)
+	case f.src[f.pos] == ',':
+		f.pos++
+		return nil
+	case f.src[f.pos] == closing:
+		return nil
+	default:
+		return fmt.Errorf("expected ',' or %q, got %q", closing, f.src[f.pos:])
+	}
+}
+
+var (
+	yamlIntPattern       = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatPattern     = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlTimestampPattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(([Tt]|[ \t]+)[0-9]{1,2}:[0-9]{2}:[0-9]{2}(\.[0-9]*)?([ \t]*(Z|[-+][0-9]{1,2}(:[0-9]{2})?))?)?$`)
+)
+
+// resolveYAMLScalar types a plain scalar following the YAML 1.2 core schema,
+// plus timestamps.
+func resolveYAMLScalar(s string) (any, error) {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1), nil
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1), nil
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN(), nil
+	}
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		if n, err := strconv.ParseInt(s[2:], 16, 64); err == nil {
+			return n, nil
+		}
+	case strings.HasPrefix(s, "0o"):
+		if n, err := strconv.ParseInt(s[2:], 8, 64); err == nil {
+			return n, nil
+		}
+	case yamlIntPattern.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	case yamlFloatPattern.MatchString(s):
+		return strconv.ParseFloat(s, 64)
+	case yamlTimestampPattern.MatchString(s):
+		t, err := parseYAMLTimestamp(s)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	return s, nil
+}
+
+func parseYAMLTimestamp(s string) (time.Time, error) {
+	if len(s) <= len("2006-01-02") {
+		return time.Parse("2006-1-2", s)
+	}
+	// Normalise the separators so one layout covers every allowed spelling.
+	date, rest, _ := strings.Cut(strings.Replace(s, "t", "T", 1), "T")
+	if !strings.Contains(s, "T") && !strings.Contains(s, "t") {
+		date, rest, _ = strings.Cut(s, " ")
+	}
+	rest = strings.TrimSpace(rest)
+	zone := "Z"
+	if i := strings.IndexAny(rest, "Z+-"); i >= 0 {
+		rest, zone = strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i:])
+	}
+	if zone != "Z" {
+		sign, offset := zone[:1], zone[1:]
+		hours, minutes, _ := strings.Cut(offset, ":")
+		if len(hours) == 1 {
+			hours = "0" + hours
+		}
+		if minutes == "" {
+			minutes = "00"
+		}
+		zone = sign + hours + ":" + minutes
+	}
+	t, err := time.Parse("2006-1-2T15:4:5.999999999Z07:00", date+"T"+rest+zone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,147 @@
+//go:build !mongory_noformats
+
+package mongory
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseConditionYAML(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		want map[string]any
+	}{
+		{
+			"scalars",
+			`
+# rule owned by ops
+count: 42
+hex: 0x1f
+ratio: 0.5
+big: 1e3
+name: ann   # trailing comment
+quoted: "42"
+single: 'it''s'
+flag: true
+missing: ~
+hash: a#b
+`,
+			map[string]any{
+				"count": int64(42), "hex": int64(31), "ratio": 0.5, "big": 1000.0,
+				"name": "ann", "quoted": "42", "single": "it's", "flag": true,
+				"missing": nil, "hash": "a#b",
+			},
+		},
+		{
+			"timestamps",
+			"day: 2024-05-01\nat: 2024-05-01 10:30:00 +08:00\nexact: 2024-05-01T10:30:00.25Z\n",
+			map[string]any{
+				"day":   "2024-05-01T00:00:00Z",
+				"at":    "2024-05-01T02:30:00Z",
+				"exact": "2024-05-01T10:30:00.25Z",
+			},
+		},
+		{
+			"operators",
+			`---
+age:
+  $gte: 18
+  $lt: 65
+tags: {$in: [a, "b c"]}
+$or:
+  - role: admin
+  - role: staff
+    level: {$gt: 2}
+roles:
+- x
+- [1, 2.5]
+`,
+			map[string]any{
+				"age":  map[string]any{"$gte": int64(18), "$lt": int64(65)},
+				"tags": map[string]any{"$in": []any{"a", "b c"}},
+				"$or": []any{
+					map[string]any{"role": "admin"},
+					map[string]any{"role": "staff", "level": map[string]any{"$gt": int64(2)}},
+				},
+				"roles": []any{"x", []any{int64(1), 2.5}},
+			},
+		},
+		{
+			"block scalars",
+			"literal: |\n  a\n  b\nfolded: >-\n  a\n  b\nkept: |+\n  x\n\nafter: 1\n",
+			map[string]any{"literal": "a\nb\n", "folded": "a b", "kept": "x\n\n", "after": int64(1)},
+		},
+		{
+			"multi-line flow",
+			"status:\n  $in: [\n    active,\n    pending,\n  ]\n",
+			map[string]any{"status": map[string]any{"$in": []any{"active", "pending"}}},
+		},
+		{"empty", "# nothing here\n", map[string]any{}},
+	}
+	for _, tc := range cases {
+		got, err := ParseConditionYAML([]byte(tc.yaml))
+		if err != nil {
+			t.Fatalf("%s: ParseConditionYAML failed: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: ParseConditionYAML = %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+
+	got, err := ParseConditionYAML([]byte("score: {$lt: .inf, $ne: .nan}"))
+	if err != nil {
+		t.Fatalf("ParseConditionYAML failed: %v", err)
+	}
+	ops := got["score"].(map[string]any)
+	if !math.IsInf(ops["$lt"].(float64), 1) || !math.IsNaN(ops["$ne"].(float64)) {
+		t.Fatalf("ParseConditionYAML special floats = %v", ops)
+	}
+
+	for _, bad := range []string{
+		"- a\n- b\n",
+		"a: 1\na: 2\n",
+		"a: &x 1\nb: *x\n",
+		"a: !!str 1\n",
+		"a: [1, 2\n",
+		"a: 'open\n",
+		"a: 1\n---\nb: 2\n",
+		"a:\n  b: 1\n    c: 2\n",
+		"a:\n\tb: 1\n",
+	} {
+		if _, err := ParseConditionYAML([]byte(bad)); err == nil {
+			t.Fatalf("ParseConditionYAML(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestNewMatcherFromYAML(t *testing.T) {
+	matcher, err := NewMatcherFromYAML([]byte(`
+age: {$gte: 18}
+$or:
+  - role: admin
+  - since: {$lt: 2024-01-01}
+`))
+	if err != nil {
+		t.Fatalf("NewMatcherFromYAML failed: %v", err)
+	}
+	for _, tc := range []struct {
+		doc  map[string]any
+		want bool
+	}{
+		{map[string]any{"age": 20, "role": "admin"}, true},
+		{map[string]any{"age": 20, "role": "user", "since": "2023-06-01T00:00:00Z"}, true},
+		{map[string]any{"age": 20, "role": "user", "since": "2024-06-01T00:00:00Z"}, false},
+		{map[string]any{"age": 10, "role": "admin"}, false},
+	} {
+		if got, err := matcher.Match(tc.doc); err != nil || got != tc.want {
+			t.Fatalf("Match(%v) = %v, %v; want %v", tc.doc, got, err, tc.want)
+		}
+	}
+
+	if _, err := NewMatcherFromYAML([]byte("$and: hello\n")); err == nil {
+		t.Fatalf("NewMatcherFromYAML with an invalid condition succeeded")
+	}
+}